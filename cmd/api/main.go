@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,8 +23,19 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	// Yeni customer handler importu
+	"Go-CRM/pkg/account"
+	"Go-CRM/pkg/apikey"
 	"Go-CRM/pkg/common"
 	"Go-CRM/pkg/customer"
+	"Go-CRM/pkg/deal"
+	"Go-CRM/pkg/event"
+	"Go-CRM/pkg/mfa"
+	"Go-CRM/pkg/notification"
+	"Go-CRM/pkg/password"
+	"Go-CRM/pkg/segment"
+	"Go-CRM/pkg/storage"
+	"Go-CRM/pkg/task"
+	"Go-CRM/pkg/webhook"
 
 	"github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -59,10 +74,17 @@ type User struct {
 	ID       int    `json:"id"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Role     string `json:"-"`
+	TenantID int    `json:"-"`
 }
 
 type Claims struct {
-	Email string `json:"email"`
+	UserID   int    `json:"user_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	TenantID int    `json:"tenant_id"`
+	// Kullanıcının oturum sürümü; şifre değişince artar ve eski token'lar geçersiz olur
+	SessionVersion int `json:"sv,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -85,20 +107,147 @@ type Contact struct {
 // JWT doğrulama middleware'i
 func jwtAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Entegrasyonlar JWT yerine API anahtarı kullanabilir (X-API-Key veya Bearer crm_...)
+		if key := apikey.KeyFromRequest(r); key != "" {
+			apiKeyAuth(w, r, next, key)
+			return
+		}
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 			http.Error(w, "Yetkilendirme gerekli", http.StatusUnauthorized)
 			return
 		}
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		claims := &Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			return jwtKey, nil
 		})
 		if err != nil || !token.Valid {
 			http.Error(w, "Geçersiz veya süresi dolmuş token", http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+		current, err := password.CheckSession(dbPrimary, claims.UserID, claims.SessionVersion, time.Now())
+		if err != nil {
+			http.Error(w, "Oturum doğrulanamadı", http.StatusInternalServerError)
+			return
+		}
+		if !current {
+			http.Error(w, "Oturum sonlandırılmış, tekrar giriş yapın", http.StatusUnauthorized)
+			return
+		}
+		// Tenant bilgisi olmayan eski token'lar varsayılan tenant'a aittir
+		if claims.TenantID == 0 {
+			claims.TenantID = common.DefaultTenantID
+		}
+		// Oturum kullanıcısını handler'ların erişebilmesi için context'e ekle
+		ctx := common.ContextWithUser(r.Context(), common.AuthUser{ID: claims.UserID, Email: claims.Email, Role: claims.Role, TenantID: claims.TenantID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// API anahtarıyla kimlik doğrulama; anahtar sahibi kullanıcı adına çalışır, kapsamı isteğin
+// kaynağını (ör. deals:write) içermiyorsa 403 döner
+func apiKeyAuth(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	user, k, err := apikey.Authenticate(dbPrimary, key, apikey.RequestIP(r), time.Now())
+	switch {
+	case errors.Is(err, apikey.ErrIPNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, apikey.ErrInvalidKey), errors.Is(err, apikey.ErrKeyRevoked), errors.Is(err, apikey.ErrKeyExpired):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		log.Printf("API anahtarı doğrulanamadı: %v", err)
+		http.Error(w, "API anahtarı doğrulanamadı", http.StatusInternalServerError)
+		return
+	}
+	if scope, ok := apikey.RequiredScope(r.Method, r.URL.Path); !ok || !k.Allows(scope) {
+		http.Error(w, apikey.ErrScopeDenied.Error(), http.StatusForbidden)
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(common.ContextWithUser(r.Context(), user)))
+}
+
+// Başarılı Basic auth doğrulamaları kısa süre saklanır; DAV istemcileri her istekte şifre gönderir
+// ve her seferinde bcrypt çalıştırmak senkronizasyonu yavaşlatır
+const davAuthCacheTTL = 5 * time.Minute
+
+type davAuthEntry struct {
+	user    common.AuthUser
+	version int // Şifre değişince önbellekteki eski şifre kabul edilmesin
+	expires time.Time
+}
+
+var (
+	davAuthMu    sync.Mutex
+	davAuthCache = map[[32]byte]davAuthEntry{}
+)
+
+// CardDAV için kimlik doğrulama: Bearer JWT veya Basic (e-posta/şifre)
+func davAuthMiddleware(next http.Handler) http.Handler {
+	jwtNext := jwtAuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			jwtNext.ServeHTTP(w, r)
+			return
+		}
+		email, pass, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Go-CRM", charset="UTF-8"`)
+			http.Error(w, "Yetkilendirme gerekli", http.StatusUnauthorized)
+			return
+		}
+		key := sha256.Sum256([]byte(email + "\x00" + pass))
+		now := time.Now()
+		davAuthMu.Lock()
+		entry, cached := davAuthCache[key]
+		davAuthMu.Unlock()
+		if cached && !now.After(entry.expires) {
+			if current, err := password.CheckSession(dbPrimary, entry.user.ID, entry.version, now); err != nil || !current {
+				cached = false
+			}
+		}
+		if !cached || now.After(entry.expires) {
+			u, err := checkCredentials(email, pass)
+			if err != nil {
+				if err != errInvalidCredentials {
+					http.Error(w, "Sunucu hatası", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Basic realm="Go-CRM", charset="UTF-8"`)
+				http.Error(w, "Kullanıcı adı veya şifre hatalı", http.StatusUnauthorized)
+				return
+			}
+			if u.TenantID == 0 {
+				u.TenantID = common.DefaultTenantID
+			}
+			user := common.AuthUser{ID: u.ID, Email: u.Email, Role: u.Role, TenantID: u.TenantID}
+			// Basic auth ikinci adımı desteklemez; MFA'lı hesaplar Bearer token kullanmalı
+			purpose, err := mfa.LoginRequirement(dbPrimary, user)
+			if err != nil {
+				http.Error(w, "Sunucu hatası", http.StatusInternalServerError)
+				return
+			}
+			if purpose != "" {
+				http.Error(w, "MFA etkin hesaplar için şifreyle giriş kullanılamaz, Bearer token kullanın", http.StatusUnauthorized)
+				return
+			}
+			version, err := password.SessionVersion(dbPrimary, user.ID)
+			if err != nil {
+				http.Error(w, "Sunucu hatası", http.StatusInternalServerError)
+				return
+			}
+			entry = davAuthEntry{user, version, now.Add(davAuthCacheTTL)}
+			davAuthMu.Lock()
+			for k, e := range davAuthCache {
+				if now.After(e.expires) {
+					delete(davAuthCache, k)
+				}
+			}
+			davAuthCache[key] = entry
+			davAuthMu.Unlock()
+		}
+		next.ServeHTTP(w, r.WithContext(common.ContextWithUser(r.Context(), entry.user)))
 	})
 }
 
@@ -172,6 +321,9 @@ func main() {
 		log.Fatal("JWT secret dosyası okunamadı: ", err)
 	}
 	jwtKey = []byte(jwtSecret)
+	// Liste imleçleri ve indirme bağlantıları da aynı secret'tan türetilen anahtarlarla imzalanır
+	common.SetCursorKey(jwtKey)
+	common.SetURLSigningKey(jwtKey)
 
 	// Ortam değişkenlerinden veritabanı URL'lerini al
 	primaryURL := os.Getenv("DB_PRIMARY_URL")
@@ -205,6 +357,9 @@ func main() {
 	defer dbReplica.Close()
 
 	runMigrationsAndSeed(dbPrimary)
+	// Önceki süreçte yarıda kalan içe/dışa aktarmalar bir daha işlenmez
+	customer.FailInterruptedImports(dbPrimary)
+	customer.FailInterruptedExports(dbPrimary)
 
 	// Redis bağlantısı başlatılıyor
 	if err := common.InitRedis(); err != nil {
@@ -225,10 +380,85 @@ func main() {
 	}
 	defer shutdown()
 
+	// Zamanı gelen görevler için dakikada bir hatırlatma komutu gönder
+	go task.RunReminderScheduler(context.Background(), dbPrimary, time.Minute)
+
+	// Saklama süresi dolan dışa aktarma dosyalarını saatte bir sil
+	go customer.RunExportCleanup(context.Background(), dbPrimary, time.Hour)
+
+	// Gelen e-postaları iletişim kaydına alan SMTP dinleyicisi (örn. INBOUND_SMTP_ADDR=:2525).
+	// Dinleyici kimlik doğrulamadığından alıcı listesi (INBOUND_SMTP_RCPT) olmadan açılmaz.
+	if addr := os.Getenv("INBOUND_SMTP_ADDR"); addr != "" {
+		recipients, err := customer.ParseInboundRecipients(os.Getenv("INBOUND_SMTP_RCPT"))
+		if err != nil {
+			log.Fatalf("INBOUND_SMTP_RCPT geçersiz: %v", err)
+		}
+		go startInboundSMTP(addr, dbPrimary, recipients)
+	}
+
 	// Handler struct'ı oluşturuluyor
 	handler := &customer.Handler{
 		DBPrimary: dbPrimary,
 		DBReplica: dbReplica,
+		Storage:   newAttachmentStorage(),
+	}
+	// Yüklenen ekler CLAMD_ADDR verilmişse ClamAV ile taranır
+	if addr := os.Getenv("CLAMD_ADDR"); addr != "" {
+		handler.Scanner = &storage.Clamd{Addr: addr}
+	}
+	accountHandler := &account.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	segmentHandler := &segment.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	dealHandler := &deal.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	taskHandler := &task.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	eventHandler := &event.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	notificationHandler := &notification.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	webhookHandler := &webhook.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	apiKeyHandler := &apikey.Handler{
+		DBPrimary: dbPrimary,
+		DBReplica: dbReplica,
+	}
+	passwordPolicy, err := password.PolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Şifre politikası yüklenemedi: %v", err)
+	}
+	resetTTL, err := time.ParseDuration(getEnv("PASSWORD_RESET_TTL", password.DefaultResetTTL.String()))
+	if err != nil || resetTTL <= 0 {
+		log.Fatalf("PASSWORD_RESET_TTL geçersiz: %q", os.Getenv("PASSWORD_RESET_TTL"))
+	}
+	passwordHandler := &password.Handler{
+		DBPrimary:  dbPrimary,
+		DBReplica:  dbReplica,
+		Policy:     passwordPolicy,
+		ResetURL:   getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password.html"),
+		ResetTTL:   resetTTL,
+		IssueToken: issueToken,
+	}
+	mfaHandler := &mfa.Handler{
+		DBPrimary:  dbPrimary,
+		DBReplica:  dbReplica,
+		JWTKey:     jwtKey,
+		IssueToken: issueToken,
 	}
 
 	router := mux.NewRouter()
@@ -241,8 +471,33 @@ func main() {
 	router.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
 		common.RateLimitMiddleware(http.HandlerFunc(handleLogin), 1000, time.Minute).ServeHTTP(w, r)
 	}).Methods("POST")
+	// MFA etkin kullanıcılar için girişin ikinci adımı; /api/login'in döndüğü mfa_token ile çağrılır
+	router.HandleFunc("/api/login/mfa", func(w http.ResponseWriter, r *http.Request) {
+		common.RateLimitMiddleware(http.HandlerFunc(mfaHandler.LoginVerifyHandler), 1000, time.Minute).ServeHTTP(w, r)
+	}).Methods("POST")
+	router.HandleFunc("/api/login/mfa/enroll", func(w http.ResponseWriter, r *http.Request) {
+		common.RateLimitMiddleware(http.HandlerFunc(mfaHandler.LoginEnrollHandler), 1000, time.Minute).ServeHTTP(w, r)
+	}).Methods("POST")
+	// Şifre sıfırlama (JWT korumasız); bağlantı notification-svc ile e-postayla gönderilir
+	router.HandleFunc("/api/password/policy", passwordHandler.GetPolicyHandler).Methods("GET")
+	router.HandleFunc("/api/password/forgot", func(w http.ResponseWriter, r *http.Request) {
+		common.RateLimitMiddleware(http.HandlerFunc(passwordHandler.ForgotHandler), 1000, time.Minute).ServeHTTP(w, r)
+	}).Methods("POST")
+	router.HandleFunc("/api/password/reset", func(w http.ResponseWriter, r *http.Request) {
+		common.RateLimitMiddleware(http.HandlerFunc(passwordHandler.ResetHandler), 1000, time.Minute).ServeHTTP(w, r)
+	}).Methods("POST")
 	router.HandleFunc("/healthz", healthzHandler).Methods("GET")
+	// Dışa aktarma dosyaları imzalı ve süreli bağlantıyla indirilir (JWT korumasız)
+	router.HandleFunc("/api/exports/{id:[0-9]+}/download", handler.DownloadExportHandler).Methods("GET")
+	router.HandleFunc("/api/attachments/{id:[0-9]+}/download", handler.DownloadAttachmentHandler).Methods("GET")
 	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	// Kişisel iCalendar aboneliği; gizli anahtar URL'de olduğu için JWT korumasız
+	router.HandleFunc("/calendar/{token:[0-9a-f]+}.ics", eventHandler.CalendarFeedHandler).Methods("GET")
+
+	// CardDAV adres defteri (salt okunur); istemciler Basic auth ile aynı e-posta/şifreyi kullanır
+	router.Handle("/.well-known/carddav", http.RedirectHandler("/carddav/", http.StatusMovedPermanently))
+	router.Handle("/carddav", http.RedirectHandler("/carddav/", http.StatusMovedPermanently))
+	router.PathPrefix("/carddav/").Handler(davAuthMiddleware(http.HandlerFunc(handler.CardDAVHandler)))
 
 	// JWT korumalı alt router
 	api := router.PathPrefix("/api").Subrouter()
@@ -257,14 +512,156 @@ func main() {
 		handler.GetCustomersHandler(w, r)
 	}).Methods("GET", "POST")
 
+	api.HandleFunc("/customers/{id:[0-9]+}", handler.GetCustomerHandler).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}", handler.UpdateCustomerHandler).Methods("PUT")
+
+	// Mükerrer kayıtlar ve birleştirme
+	api.HandleFunc("/customers/duplicates", handler.GetDuplicatesHandler).Methods("GET")
+	api.HandleFunc("/customers/merge", handler.MergeCustomersHandler).Methods("POST")
+
+	// CSV/XLSX toplu içe aktarma
+	api.HandleFunc("/customers/imports", handler.CreateImportHandler).Methods("POST")
+	api.HandleFunc("/customers/imports/{id:[0-9]+}", handler.GetImportHandler).Methods("GET")
+	api.HandleFunc("/customers/imports/{id:[0-9]+}/errors", handler.GetImportErrorsHandler).Methods("GET")
+
+	// vCard içe/dışa aktarma
+	api.HandleFunc("/customers/vcard", handler.ImportVCardHandler).Methods("POST")
+	api.HandleFunc("/customers/{id:[0-9]+}/vcard", handler.GetCustomerVCardHandler).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}/attachments", handler.GetAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}/attachments", handler.UploadAttachmentHandler).Methods("POST")
+	api.HandleFunc("/attachments/{id:[0-9]+}", handler.GetAttachmentHandler).Methods("GET")
+	api.HandleFunc("/attachments/{id:[0-9]+}", handler.DeleteAttachmentHandler).Methods("DELETE")
+
+	// Akış halinde dışa aktarma ve büyük tenant'lar için arka plan dışa aktarma işleri
+	api.HandleFunc("/customers/export", handler.ExportCustomersHandler).Methods("GET")
+	api.HandleFunc("/contacts/export", handler.ExportContactsHandler).Methods("GET")
+	api.HandleFunc("/exports", handler.CreateExportHandler).Methods("POST")
+	api.HandleFunc("/exports/{id:[0-9]+}", handler.GetExportHandler).Methods("GET")
+
+	// Tenant bazlı müşteri özel alanları
+	api.HandleFunc("/customer-fields", handler.GetFieldDefinitionsHandler).Methods("GET")
+	api.HandleFunc("/customer-fields", handler.CreateFieldDefinitionHandler).Methods("POST")
+	api.HandleFunc("/customer-fields/{id}", handler.UpdateFieldDefinitionHandler).Methods("PUT")
+	api.HandleFunc("/customer-fields/{id}", handler.DeleteFieldDefinitionHandler).Methods("DELETE")
+
+	// Müşteri ve iletişim kayıtlarında tam metin arama
+	api.HandleFunc("/search", handler.SearchHandler).Methods("GET")
+
+	// Etiketler ve kayıtlı segmentler
+	api.HandleFunc("/tags", segmentHandler.GetTagsHandler).Methods("GET")
+	api.HandleFunc("/tags", segmentHandler.CreateTagHandler).Methods("POST")
+	api.HandleFunc("/tags/{id}", segmentHandler.UpdateTagHandler).Methods("PUT")
+	api.HandleFunc("/tags/{id}", segmentHandler.DeleteTagHandler).Methods("DELETE")
+	api.HandleFunc("/tags/{id}/assign", segmentHandler.BulkTagHandler).Methods("POST")
+	api.HandleFunc("/tags/{id}/unassign", segmentHandler.BulkTagHandler).Methods("POST")
+	api.HandleFunc("/segments", segmentHandler.GetSegmentsHandler).Methods("GET")
+	api.HandleFunc("/segments", segmentHandler.CreateSegmentHandler).Methods("POST")
+	api.HandleFunc("/segments/{id}", segmentHandler.GetSegmentHandler).Methods("GET")
+	api.HandleFunc("/segments/{id}", segmentHandler.UpdateSegmentHandler).Methods("PUT")
+	api.HandleFunc("/segments/{id}", segmentHandler.DeleteSegmentHandler).Methods("DELETE")
+	api.HandleFunc("/segments/{id}/customers", segmentHandler.GetSegmentCustomersHandler).Methods("GET")
+
+	// Müşteri sorumluları ve otomatik atama kuralları
+	api.HandleFunc("/customers/reassign", handler.BulkReassignHandler).Methods("POST")
+	api.HandleFunc("/customers/{id}/owner", handler.ReassignCustomerHandler).Methods("POST")
+	api.HandleFunc("/customers/{id}/owner-history", handler.GetOwnerHistoryHandler).Methods("GET")
+	api.HandleFunc("/assignment-rules", handler.GetAssignmentRulesHandler).Methods("GET")
+	api.HandleFunc("/assignment-rules", handler.CreateAssignmentRuleHandler).Methods("POST")
+	api.HandleFunc("/assignment-rules/{id}", handler.UpdateAssignmentRuleHandler).Methods("PUT")
+	api.HandleFunc("/assignment-rules/{id}", handler.DeleteAssignmentRuleHandler).Methods("DELETE")
+
+	// Şirketler (hesaplar), hiyerarşi ve bağlı kişiler
+	api.HandleFunc("/accounts", accountHandler.GetAccountsHandler).Methods("GET")
+	api.HandleFunc("/accounts", accountHandler.CreateAccountHandler).Methods("POST")
+	api.HandleFunc("/accounts/{id}", accountHandler.GetAccountHandler).Methods("GET")
+	api.HandleFunc("/accounts/{id}", accountHandler.UpdateAccountHandler).Methods("PUT")
+	api.HandleFunc("/accounts/{id}", accountHandler.DeleteAccountHandler).Methods("DELETE")
+	api.HandleFunc("/accounts/{id}/people", accountHandler.GetAccountPeopleHandler).Methods("GET")
+	api.HandleFunc("/accounts/{id}/people/{customerId}", accountHandler.LinkAccountPersonHandler).Methods("PUT")
+	api.HandleFunc("/accounts/{id}/people/{customerId}", accountHandler.UnlinkAccountPersonHandler).Methods("DELETE")
+	api.HandleFunc("/accounts/{id}/summary", accountHandler.GetAccountSummaryHandler).Methods("GET")
+
 	// İletişim kayıtları işlemleri (yeni handler fonksiyonları)
+	api.HandleFunc("/contacts/stats", handler.GetContactStatsHandler).Methods("GET")
+	api.HandleFunc("/contacts/emails", handler.IngestEmailHandler).Methods("POST")
 	api.HandleFunc("/contacts/{customerId}", handler.GetContactsHandler).Methods("GET")
+	api.HandleFunc("/contacts/{id}", handler.UpdateContactHandler).Methods("PUT")
+	api.HandleFunc("/contacts/{id}", handler.DeleteContactHandler).Methods("DELETE")
+	api.HandleFunc("/contacts/{id}/revisions", handler.GetContactRevisionsHandler).Methods("GET")
+	api.HandleFunc("/contacts/{id}/thread", handler.GetEmailThreadHandler).Methods("GET")
+	api.HandleFunc("/contacts", handler.GetContactFeedHandler).Methods("GET")
 	api.HandleFunc("/contacts", handler.CreateContactHandler).Methods("POST")
 
+	// Satış süreçleri ve fırsatlar
+	api.HandleFunc("/pipelines", dealHandler.GetPipelinesHandler).Methods("GET")
+	api.HandleFunc("/pipelines", dealHandler.CreatePipelineHandler).Methods("POST")
+	api.HandleFunc("/pipelines/{id}/stages", dealHandler.CreateStageHandler).Methods("POST")
+	api.HandleFunc("/deals", dealHandler.GetDealsHandler).Methods("GET")
+	api.HandleFunc("/deals", dealHandler.CreateDealHandler).Methods("POST")
+	api.HandleFunc("/deals/forecast", dealHandler.GetForecastHandler).Methods("GET")
+	api.HandleFunc("/deals/{id}", dealHandler.GetDealHandler).Methods("GET")
+	api.HandleFunc("/deals/{id}", dealHandler.UpdateDealHandler).Methods("PUT")
+	api.HandleFunc("/deals/{id}/stage", dealHandler.MoveDealHandler).Methods("POST")
+	api.HandleFunc("/deals/{id}/history", dealHandler.GetStageHistoryHandler).Methods("GET")
+
+	// Görevler ve takip hatırlatmaları
+	api.HandleFunc("/tasks", taskHandler.GetTasksHandler).Methods("GET")
+	api.HandleFunc("/tasks", taskHandler.CreateTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/my", taskHandler.GetMyTasksHandler).Methods("GET")
+	api.HandleFunc("/tasks/overdue", taskHandler.GetOverdueTasksHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id}", taskHandler.GetTaskHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id}", taskHandler.UpdateTaskHandler).Methods("PUT")
+	api.HandleFunc("/tasks/{id}", taskHandler.DeleteTaskHandler).Methods("DELETE")
+
+	// Takvim etkinlikleri ve abonelik akışı
+	api.HandleFunc("/events", eventHandler.GetEventsHandler).Methods("GET")
+	api.HandleFunc("/events", eventHandler.CreateEventHandler).Methods("POST")
+	api.HandleFunc("/events/feed", eventHandler.CreateFeedHandler).Methods("POST")
+	api.HandleFunc("/events/feed", eventHandler.DeleteFeedHandler).Methods("DELETE")
+	api.HandleFunc("/events/{id:[0-9]+}", eventHandler.GetEventHandler).Methods("GET")
+	api.HandleFunc("/events/{id:[0-9]+}", eventHandler.UpdateEventHandler).Methods("PUT")
+	api.HandleFunc("/events/{id:[0-9]+}", eventHandler.DeleteEventHandler).Methods("DELETE")
+	api.HandleFunc("/events/{id:[0-9]+}/ics", eventHandler.GetEventICSHandler).Methods("GET")
+
+	// Bildirim tercihleri (kanallar, sessiz saatler, özet modu)
+	api.HandleFunc("/notifications/preferences", notificationHandler.GetPreferencesHandler).Methods("GET")
+	api.HandleFunc("/notifications/preferences", notificationHandler.UpdatePreferencesHandler).Methods("PUT")
+
+	// Giden webhook abonelikleri ve teslimat kayıtları (sadece admin)
+	api.HandleFunc("/webhooks", webhookHandler.GetSubscriptionsHandler).Methods("GET")
+	api.HandleFunc("/webhooks", webhookHandler.CreateSubscriptionHandler).Methods("POST")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", webhookHandler.GetSubscriptionHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", webhookHandler.UpdateSubscriptionHandler).Methods("PUT")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", webhookHandler.DeleteSubscriptionHandler).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/secret", webhookHandler.RotateSecretHandler).Methods("POST")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", webhookHandler.GetDeliveriesHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{deliveryId:[0-9]+}/redeliver", webhookHandler.RedeliverHandler).Methods("POST")
+
+	// Entegrasyonlar için API anahtarları (sadece JWT ile oturum açmış admin)
+	api.HandleFunc("/api-keys", apiKeyHandler.GetKeysHandler).Methods("GET")
+	api.HandleFunc("/api-keys", apiKeyHandler.CreateKeyHandler).Methods("POST")
+	api.HandleFunc("/api-keys/scopes", apiKeyHandler.GetScopesHandler).Methods("GET")
+	api.HandleFunc("/api-keys/{id:[0-9]+}", apiKeyHandler.GetKeyHandler).Methods("GET")
+	api.HandleFunc("/api-keys/{id:[0-9]+}", apiKeyHandler.RevokeKeyHandler).Methods("DELETE")
+	api.HandleFunc("/api-keys/{id:[0-9]+}/rotate", apiKeyHandler.RotateKeyHandler).Methods("POST")
+
+	// Şifre değişikliği; diğer oturumlar kapanır
+	api.HandleFunc("/password/change", passwordHandler.ChangeHandler).Methods("POST")
+
+	// TOTP ile iki adımlı doğrulama; politika ve sıfırlama sadece admin
+	api.HandleFunc("/mfa", mfaHandler.GetStatusHandler).Methods("GET")
+	api.HandleFunc("/mfa", mfaHandler.DisableHandler).Methods("DELETE")
+	api.HandleFunc("/mfa/enroll", mfaHandler.StartEnrollmentHandler).Methods("POST")
+	api.HandleFunc("/mfa/confirm", mfaHandler.ConfirmEnrollmentHandler).Methods("POST")
+	api.HandleFunc("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodesHandler).Methods("POST")
+	api.HandleFunc("/mfa/policy", mfaHandler.GetPolicyHandler).Methods("GET")
+	api.HandleFunc("/mfa/policy", mfaHandler.UpdatePolicyHandler).Methods("PUT")
+	api.HandleFunc("/mfa/users/{id:[0-9]+}", mfaHandler.ResetUserHandler).Methods("DELETE")
+
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-Id"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-Id", "X-API-Key"},
 		AllowCredentials: true,
 	})
 	log.Println("API sunucusu 8080 portunda başlatılıyor...")
@@ -273,12 +670,13 @@ func main() {
 
 // handleLogin endpoint'i için Swaggo açıklaması
 // @Summary Kullanıcı girişi
-// @Description Kullanıcı e-posta ve şifresiyle giriş yapar, JWT token döner
+// @Description Kullanıcı e-posta ve şifresiyle giriş yapar, JWT token döner. MFA etkinse veya rolü için
+// @Description zorunluysa token yerine mfa_token döner; giriş POST /api/login/mfa ile tamamlanır.
 // @Tags Kimlik Doğrulama
 // @Accept json
 // @Produce json
 // @Param login body User true "Giriş bilgileri"
-// @Success 200 {object} map[string]string "JWT token"
+// @Success 200 {object} map[string]interface{} "JWT token veya MFA challenge"
 // @Failure 400 {string} string "Geçersiz istek gövdesi"
 // @Failure 401 {string} string "Kullanıcı adı veya şifre hatalı"
 // @Router /api/login [post]
@@ -289,41 +687,89 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var storedUser User
-	// Login işlemi kritik bir okuma olduğu için Primary'den yapılabilir,
-	// veya anlık replikasyon gecikmesini kabul edip Replica'dan da yapılabilir.
-	// Güvenilirlik için Primary'den okuyoruz.
-	err := dbPrimary.QueryRow("SELECT id, password FROM users WHERE email=$1", creds.Email).Scan(&storedUser.ID, &storedUser.Password)
+	storedUser, err := checkCredentials(creds.Email, creds.Password)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == errInvalidCredentials {
 			http.Error(w, "Kullanıcı adı veya şifre hatalı", http.StatusUnauthorized)
 		} else {
 			http.Error(w, "Sunucu hatası", http.StatusInternalServerError)
 		}
 		return
 	}
+	user := common.AuthUser{ID: storedUser.ID, Email: creds.Email, Role: storedUser.Role, TenantID: storedUser.TenantID}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(creds.Password)); err != nil {
-		http.Error(w, "Kullanıcı adı veya şifre hatalı", http.StatusUnauthorized)
+	// Şifre doğru ama ikinci adım gerekiyorsa oturum token'ı yerine kısa ömürlü challenge token'ı döner
+	purpose, err := mfa.LoginRequirement(dbPrimary, user)
+	if err != nil {
+		http.Error(w, "Sunucu hatası", http.StatusInternalServerError)
+		return
+	}
+	if purpose != "" {
+		challenge, err := mfa.NewChallengeToken(jwtKey, user.ID, purpose, time.Now())
+		if err != nil {
+			http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mfa_required":        true,
+			"enrollment_required": purpose == mfa.PurposeEnroll,
+			"mfa_token":           challenge,
+			"expires_in":          int(mfa.ChallengeTTL.Seconds()),
+		})
 		return
 	}
 
+	tokenString, err := issueToken(user)
+	if err != nil {
+		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+}
+
+// Oturum JWT'si; şifreyle girişte, MFA'nın ikinci adımında ve şifre değişikliğinde kullanılır
+func issueToken(user common.AuthUser) (string, error) {
+	version, err := password.SessionVersion(dbPrimary, user.ID)
+	if err != nil {
+		return "", err
+	}
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		Email: creds.Email,
+		UserID:         user.ID,
+		Email:          user.Email,
+		Role:           user.Role,
+		TenantID:       user.TenantID,
+		SessionVersion: version,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	return token.SignedString(jwtKey)
+}
+
+var errInvalidCredentials = errors.New("kullanıcı adı veya şifre hatalı")
+
+// E-posta/şifre doğrulaması; login ve CardDAV Basic auth ortak kullanır
+func checkCredentials(email, password string) (User, error) {
+	var storedUser User
+	// Login işlemi kritik bir okuma olduğu için Primary'den yapılabilir,
+	// veya anlık replikasyon gecikmesini kabul edip Replica'dan da yapılabilir.
+	// Güvenilirlik için Primary'den okuyoruz.
+	err := dbPrimary.QueryRow("SELECT id, password, role, tenant_id FROM users WHERE email=$1", email).Scan(&storedUser.ID, &storedUser.Password, &storedUser.Role, &storedUser.TenantID)
+	if err == sql.ErrNoRows {
+		return User{}, errInvalidCredentials
+	}
 	if err != nil {
-		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
-		return
+		return User{}, err
 	}
-
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+	if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(password)); err != nil {
+		return User{}, errInvalidCredentials
+	}
+	storedUser.Email = email
+	return storedUser, nil
 }
 
 func hashPassword(password string) (string, error) {
@@ -391,6 +837,7 @@ func runMigrationsAndSeed(db *sql.DB) {
 	// Eğer 'users' tablosu zaten varsa, migration ve seed yapıldığını varsay ve çık.
 	if exists {
 		log.Println("Tablolar zaten mevcut. Migration ve seed atlanıyor.")
+		runSchemaUpgrades(db)
 		return
 	}
 
@@ -437,4 +884,77 @@ func runMigrationsAndSeed(db *sql.DB) {
 		log.Fatalf("Örnek kullanıcı eklenemedi: %v", err)
 	}
 	log.Println("Örnek kullanıcı başarıyla eklendi.")
+
+	runSchemaUpgrades(db)
+}
+
+// Sadece INBOUND_SMTP_RCPT'deki alıcılara (ör. crm@acme.com=2,@inbox.globex.com=3) gelen mesajlar kabul edilir.
+// Tenant zarftaki alıcıdan belirlenir; birden fazla tenant'ın adresine gelen mesaj her tenant'a ayrı kaydedilir.
+func startInboundSMTP(addr string, db *sql.DB, recipients customer.InboundRecipients) {
+	server := &common.SMTPServer{
+		Addr:     addr,
+		Hostname: getEnv("INBOUND_SMTP_HOSTNAME", "localhost"),
+		MaxSize:  customer.MaxEmailSize,
+		AcceptRcpt: func(rcpt string) bool {
+			_, ok := recipients.TenantOf(rcpt)
+			return ok
+		},
+		Handler: func(from string, to []string, data []byte) error {
+			ingested := map[int]bool{}
+			for _, rcpt := range to {
+				tenantID, ok := recipients.TenantOf(rcpt)
+				if !ok || ingested[tenantID] {
+					continue
+				}
+				ingested[tenantID] = true
+				result, err := customer.IngestEmail(db, tenantID, 0, data)
+				if errors.Is(err, customer.ErrInvalidEmail) {
+					return fmt.Errorf("%w: %v", common.ErrMessageRejected, err)
+				}
+				if err != nil {
+					return err
+				}
+				log.Printf("Gelen e-posta kaydedildi: %s (tenant=%d, %d kayıt)", result.MessageID, tenantID, len(result.Contacts))
+			}
+			return nil
+		},
+	}
+	log.Printf("Gelen e-posta SMTP dinleyicisi %s adresinde başlatılıyor...", addr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Printf("SMTP dinleyicisi durdu: %v", err)
+	}
+}
+
+// Ek deposu: ATTACHMENT_STORAGE=local (varsayılan, ATTACHMENT_DIR) veya s3 (S3_ENDPOINT, S3_BUCKET, S3_REGION,
+// S3_ACCESS_KEY, S3_SECRET_KEY veya /run/secrets/s3_secret_key, MinIO için S3_PATH_STYLE=true).
+// S3_PUBLIC_ENDPOINT, indirme bağlantılarının tarayıcıdan erişilen adresidir (ör. http://localhost:9000).
+func newAttachmentStorage() storage.Storage {
+	switch driver := getEnv("ATTACHMENT_STORAGE", "local"); driver {
+	case "local":
+		store, err := storage.NewLocal(getEnv("ATTACHMENT_DIR", "data/attachments"))
+		if err != nil {
+			log.Fatalf("Ek dizini oluşturulamadı: %v", err)
+		}
+		return store
+	case "s3":
+		secret := os.Getenv("S3_SECRET_KEY")
+		if secret == "" {
+			secret, _ = readSecretFile("/run/secrets/s3_secret_key")
+		}
+		return &storage.S3{
+			Endpoint:       getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			PublicEndpoint: os.Getenv("S3_PUBLIC_ENDPOINT"),
+			Region:         os.Getenv("S3_REGION"),
+			Bucket:         os.Getenv("S3_BUCKET"),
+			AccessKey:      os.Getenv("S3_ACCESS_KEY"),
+			SecretKey:      secret,
+			PathStyle:      os.Getenv("S3_PATH_STYLE") == "true",
+			Client:         &http.Client{Timeout: 5 * time.Minute},
+		}
+	case "none":
+		return nil
+	default:
+		log.Fatalf("Geçersiz ATTACHMENT_STORAGE değeri: %s (local, s3, none)", driver)
+		return nil
+	}
 }
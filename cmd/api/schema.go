@@ -0,0 +1,593 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+// İlk kurulumdan sonra eklenen şema değişiklikleri.
+// Her açılışta çalışır, bu yüzden tüm ifadeler idempotent olmalı (IF NOT EXISTS).
+var schemaUpgrades = []string{
+	// Bildirim tercihleri, sessiz saatler ve özet (digest) modu
+	`CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		events JSONB NOT NULL DEFAULT '{}',
+		timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Istanbul',
+		quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '',
+		quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '',
+		digest_mode VARCHAR(10) NOT NULL DEFAULT 'off',
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS notification_queue (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		channel VARCHAR(20) NOT NULL,
+		event VARCHAR(100) NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		deliver_after TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_notification_queue_deliver_after ON notification_queue(deliver_after);`,
+	// İletişim kaydını oluşturan kullanıcı ve aktivite akışı için keyset index'i
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS author_id INTEGER REFERENCES users(id) ON DELETE SET NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_contacts_created_at_id ON contacts(created_at DESC, id DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_contacts_author_id ON contacts(author_id);`,
+	// Tipli etkileşimler: arama, e-posta, toplantı, not
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'note';`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS direction VARCHAR(10) NOT NULL DEFAULT '';`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS duration_seconds INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS outcome VARCHAR(30) NOT NULL DEFAULT '';`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS participants TEXT[] NOT NULL DEFAULT '{}';`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMP WITH TIME ZONE;`,
+	`UPDATE contacts SET occurred_at = created_at WHERE occurred_at IS NULL;`,
+	`ALTER TABLE contacts ALTER COLUMN occurred_at SET DEFAULT CURRENT_TIMESTAMP;`,
+	`ALTER TABLE contacts ALTER COLUMN occurred_at SET NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_contacts_type_occurred_at ON contacts(type, occurred_at);`,
+	// Kullanıcı rolleri ve iletişim kaydı düzenleme geçmişi
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'rep';`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;`,
+	`CREATE TABLE IF NOT EXISTS contact_revisions (
+		id SERIAL PRIMARY KEY,
+		contact_id INTEGER NOT NULL,
+		revision INTEGER NOT NULL,
+		action VARCHAR(10) NOT NULL,
+		edited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		snapshot JSONB NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (contact_id, revision)
+	);`,
+	// Satış süreçleri, aşamalar, fırsatlar ve aşama geçmişi
+	`CREATE TABLE IF NOT EXISTS pipelines (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS pipeline_stages (
+		id SERIAL PRIMARY KEY,
+		pipeline_id INTEGER NOT NULL REFERENCES pipelines(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL,
+		position INTEGER NOT NULL,
+		probability INTEGER NOT NULL DEFAULT 0 CHECK (probability BETWEEN 0 AND 100),
+		is_won BOOLEAN NOT NULL DEFAULT FALSE,
+		is_lost BOOLEAN NOT NULL DEFAULT FALSE
+	);`,
+	`CREATE TABLE IF NOT EXISTS deals (
+		id SERIAL PRIMARY KEY,
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		pipeline_id INTEGER NOT NULL REFERENCES pipelines(id),
+		stage_id INTEGER NOT NULL REFERENCES pipeline_stages(id),
+		title VARCHAR(255) NOT NULL,
+		amount NUMERIC(14, 2) NOT NULL DEFAULT 0,
+		currency CHAR(3) NOT NULL DEFAULT 'TRY',
+		expected_close_date DATE,
+		owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		probability INTEGER NOT NULL DEFAULT 0 CHECK (probability BETWEEN 0 AND 100),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_deals_owner_close ON deals(owner_id, expected_close_date);`,
+	`CREATE INDEX IF NOT EXISTS idx_deals_customer_id ON deals(customer_id);`,
+	`CREATE TABLE IF NOT EXISTS deal_stage_history (
+		id SERIAL PRIMARY KEY,
+		deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+		from_stage_id INTEGER REFERENCES pipeline_stages(id),
+		to_stage_id INTEGER NOT NULL REFERENCES pipeline_stages(id),
+		changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_deal_stage_history_deal_id ON deal_stage_history(deal_id);`,
+	// Varsayılan satış süreci (hiç süreç yoksa)
+	`WITH p AS (
+		INSERT INTO pipelines (name, is_default)
+		SELECT 'Satış', TRUE WHERE NOT EXISTS (SELECT 1 FROM pipelines)
+		RETURNING id
+	)
+	INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+	SELECT p.id, s.name, s.position, s.probability, s.is_won, s.is_lost FROM p, (VALUES
+		('Yeni', 1, 10, FALSE, FALSE),
+		('Nitelikli', 2, 25, FALSE, FALSE),
+		('Teklif', 3, 50, FALSE, FALSE),
+		('Müzakere', 4, 75, FALSE, FALSE),
+		('Kazanıldı', 5, 100, TRUE, FALSE),
+		('Kaybedildi', 6, 0, FALSE, TRUE)
+	) AS s(name, position, probability, is_won, is_lost);`,
+	// Görevler ve takip hatırlatmaları
+	`CREATE TABLE IF NOT EXISTS tasks (
+		id SERIAL PRIMARY KEY,
+		title VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		customer_id INTEGER REFERENCES customers(id) ON DELETE CASCADE,
+		deal_id INTEGER REFERENCES deals(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'open',
+		priority VARCHAR(10) NOT NULL DEFAULT 'normal',
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		completed_at TIMESTAMP WITH TIME ZONE,
+		reminded_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_tasks_assignee_due ON tasks(assignee_id, due_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_tasks_due_pending ON tasks(due_at) WHERE reminded_at IS NULL AND status IN ('open', 'in_progress');`,
+	// Müşteri sorumluları ve atama kuralları
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS region VARCHAR(100) NOT NULL DEFAULT '';`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS postal_code VARCHAR(20) NOT NULL DEFAULT '';`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS lead_source VARCHAR(50) NOT NULL DEFAULT '';`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_owner ON customers(owner_id);`,
+	`CREATE TABLE IF NOT EXISTS customer_owner_history (
+		id SERIAL PRIMARY KEY,
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		from_owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		to_owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		reason VARCHAR(255) NOT NULL DEFAULT '',
+		changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_customer_owner_history_customer ON customer_owner_history(customer_id, changed_at);`,
+	`CREATE TABLE IF NOT EXISTS assignment_rules (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		type VARCHAR(20) NOT NULL,
+		match_value VARCHAR(100) NOT NULL DEFAULT '',
+		assignee_ids INTEGER[] NOT NULL,
+		rr_cursor INTEGER NOT NULL DEFAULT 0,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	// Şirketler (hesaplar) ve bağlı kişiler
+	`CREATE TABLE IF NOT EXISTS accounts (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		domain VARCHAR(255) NOT NULL DEFAULT '',
+		industry VARCHAR(100) NOT NULL DEFAULT '',
+		parent_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL,
+		owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_accounts_parent ON accounts(parent_id);`,
+	`CREATE TABLE IF NOT EXISTS account_people (
+		account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		job_title VARCHAR(100) NOT NULL DEFAULT '',
+		is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (account_id, customer_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_account_people_customer ON account_people(customer_id);`,
+	// Tenant bazlı müşteri özel alanları
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_tenant ON customers(tenant_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_custom_fields ON customers USING GIN (custom_fields jsonb_path_ops);`,
+	`CREATE TABLE IF NOT EXISTS custom_field_definitions (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL,
+		key VARCHAR(40) NOT NULL,
+		label VARCHAR(100) NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		required BOOLEAN NOT NULL DEFAULT FALSE,
+		options TEXT[] NOT NULL DEFAULT '{}',
+		default_value JSONB,
+		position INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (tenant_id, key)
+	);`,
+	// Etiketler ve kayıtlı segmentler
+	`CREATE TABLE IF NOT EXISTS tags (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL,
+		name VARCHAR(50) NOT NULL,
+		color VARCHAR(7) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_tenant_name ON tags(tenant_id, LOWER(name));`,
+	`CREATE TABLE IF NOT EXISTS customer_tags (
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (customer_id, tag_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_customer_tags_tag ON customer_tags(tag_id);`,
+	`CREATE TABLE IF NOT EXISTS segments (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		shared BOOLEAN NOT NULL DEFAULT FALSE,
+		filter JSONB NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_segments_tenant_owner ON segments(tenant_id, owner_id);`,
+	// Liste sıralaması (varsayılan -created_at) için indeksler; ilk kurulumda oluşturulan
+	// customers tablosunda created_at bulunmadığından önce sütun eklenir
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_tenant_created ON customers(tenant_id, created_at DESC, id DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_contacts_customer_occurred ON contacts(customer_id, occurred_at DESC);`,
+	// Tam metin arama: Türkçe kök bulma, aksan ve İ/ı farkı olmadan eşleşme
+	`CREATE EXTENSION IF NOT EXISTS unaccent;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'turkish_unaccent') THEN
+			CREATE TEXT SEARCH CONFIGURATION turkish_unaccent (COPY = turkish);
+			ALTER TEXT SEARCH CONFIGURATION turkish_unaccent
+				ALTER MAPPING FOR hword, hword_part, word, asciihword, asciiword WITH unaccent, turkish_stem;
+		END IF;
+	END $$;`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('turkish_unaccent', COALESCE(name, '')), 'A') ||
+		setweight(to_tsvector('turkish_unaccent', translate(COALESCE(email, ''), '@.', '  ')), 'B') ||
+		setweight(to_tsvector('turkish_unaccent', COALESCE(phone, '')), 'C')
+	) STORED;`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_search ON customers USING GIN (search_vector);`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('turkish_unaccent', COALESCE(content, ''))
+	) STORED;`,
+	`CREATE INDEX IF NOT EXISTS idx_contacts_search ON contacts USING GIN (search_vector);`,
+	// Mükerrer kayıt tespiti (normalize e-posta/telefon, trigram isim benzerliği) ve birleştirme logu
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm;`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_normalized VARCHAR(255) GENERATED ALWAYS AS (
+		lower(regexp_replace(btrim(COALESCE(email, '')), '\+[^@]*@', '@'))
+	) STORED;`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_normalized VARCHAR(20) GENERATED ALWAYS AS (
+		right(regexp_replace(COALESCE(phone, ''), '[^0-9]', '', 'g'), 10)
+	) STORED;`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_email_normalized ON customers(tenant_id, email_normalized);`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_phone_normalized ON customers(tenant_id, phone_normalized);`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_name_trgm ON customers USING GIN (name gin_trgm_ops);`,
+	`CREATE TABLE IF NOT EXISTS customer_merges (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL,
+		survivor_id INTEGER REFERENCES customers(id) ON DELETE SET NULL,
+		merged_id INTEGER NOT NULL,
+		merged_snapshot JSONB NOT NULL,
+		moved JSONB NOT NULL DEFAULT '{}',
+		merged_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_customer_merges_survivor ON customer_merges(survivor_id);`,
+	// CSV/XLSX müşteri içe aktarma işleri ve satır bazlı hata raporu
+	`CREATE TABLE IF NOT EXISTS customer_imports (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL,
+		filename VARCHAR(255) NOT NULL,
+		format VARCHAR(10) NOT NULL,
+		mode VARCHAR(20) NOT NULL,
+		dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+		mapping JSONB NOT NULL DEFAULT '{}',
+		headers TEXT[] NOT NULL DEFAULT '{}',
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		total_rows INTEGER NOT NULL DEFAULT 0,
+		processed_rows INTEGER NOT NULL DEFAULT 0,
+		created_count INTEGER NOT NULL DEFAULT 0,
+		updated_count INTEGER NOT NULL DEFAULT 0,
+		skipped_count INTEGER NOT NULL DEFAULT 0,
+		error_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		finished_at TIMESTAMP WITH TIME ZONE
+	);`,
+	`CREATE TABLE IF NOT EXISTS customer_import_errors (
+		id SERIAL PRIMARY KEY,
+		import_id INTEGER NOT NULL REFERENCES customer_imports(id) ON DELETE CASCADE,
+		row_number INTEGER NOT NULL,
+		message TEXT NOT NULL,
+		row_values TEXT[] NOT NULL DEFAULT '{}'
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_customer_import_errors_import ON customer_import_errors(import_id, row_number);`,
+	// Arka plan dışa aktarma işleri; dosyalar expires_at'ten sonra silinir
+	`CREATE TABLE IF NOT EXISTS customer_exports (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		format VARCHAR(10) NOT NULL,
+		query TEXT NOT NULL DEFAULT '',
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		row_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		file_path TEXT NOT NULL DEFAULT '',
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		finished_at TIMESTAMP WITH TIME ZONE,
+		expires_at TIMESTAMP WITH TIME ZONE
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_customer_exports_expires ON customer_exports(expires_at) WHERE status = 'completed';`,
+	// Takvim etkinlikleri; series_end tekrarlayan serinin son bitişi (sonsuz seride NULL)
+	`CREATE TABLE IF NOT EXISTS events (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL DEFAULT 1,
+		title VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		location TEXT NOT NULL DEFAULT '',
+		start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		end_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		all_day BOOLEAN NOT NULL DEFAULT FALSE,
+		rrule TEXT NOT NULL DEFAULT '',
+		series_end TIMESTAMP WITH TIME ZONE,
+		customer_id INTEGER REFERENCES customers(id) ON DELETE SET NULL,
+		deal_id INTEGER REFERENCES deals(id) ON DELETE SET NULL,
+		organizer_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'confirmed',
+		attendees JSONB NOT NULL DEFAULT '[]',
+		sequence INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_events_tenant_start ON events(tenant_id, start_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_events_organizer ON events(organizer_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_events_attendees ON events USING GIN (attendees jsonb_path_ops);`,
+	`CREATE INDEX IF NOT EXISTS idx_events_customer ON events(customer_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_events_deal ON events(deal_id);`,
+	// Kullanıcı başına gizli iCalendar abonelik anahtarı (sadece SHA-256 özeti saklanır)
+	`CREATE TABLE IF NOT EXISTS calendar_feeds (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	// Ertelenen bildirimlerin ekleri (ör. takvim davetleri)
+	`ALTER TABLE notification_queue ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';`,
+	// Gelen e-postalardan oluşan iletişim kayıtlarının mesaj bilgileri; thread_id konuşmanın kök Message-ID'si
+	`CREATE TABLE IF NOT EXISTS contact_emails (
+		contact_id INTEGER PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+		tenant_id INTEGER NOT NULL DEFAULT 1,
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		message_id TEXT NOT NULL,
+		in_reply_to TEXT NOT NULL DEFAULT '',
+		thread_id TEXT NOT NULL,
+		from_address TEXT NOT NULL,
+		to_addresses TEXT[] NOT NULL DEFAULT '{}',
+		cc_addresses TEXT[] NOT NULL DEFAULT '{}',
+		subject TEXT NOT NULL DEFAULT '',
+		attachments JSONB NOT NULL DEFAULT '[]',
+		UNIQUE (customer_id, message_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_contact_emails_message ON contact_emails(tenant_id, message_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_contact_emails_thread ON contact_emails(customer_id, thread_id);`,
+	// Müşteri ve iletişim kaydı ekleri; içerik depolama sürücüsünde storage_key altında tutulur
+	`CREATE TABLE IF NOT EXISTS attachments (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL DEFAULT 1,
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		contact_id INTEGER REFERENCES contacts(id) ON DELETE SET NULL,
+		filename VARCHAR(255) NOT NULL,
+		content_type VARCHAR(255) NOT NULL,
+		size BIGINT NOT NULL,
+		sha256 CHAR(64) NOT NULL,
+		scan_status VARCHAR(20) NOT NULL,
+		uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		storage_key TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_attachments_customer ON attachments(customer_id, created_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_attachments_contact ON attachments(contact_id);`,
+	// Giden webhook abonelikleri ve teslimat kuyruğu/kayıtları (webhook-svc işler)
+	`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL DEFAULT 1,
+		url TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		event_types TEXT[] NOT NULL,
+		secret TEXT NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		consecutive_failures INTEGER NOT NULL DEFAULT 0,
+		disabled_at TIMESTAMP WITH TIME ZONE,
+		disabled_reason TEXT NOT NULL DEFAULT '',
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_tenant ON webhook_subscriptions(tenant_id) WHERE active;`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event_id TEXT NOT NULL,
+		event_type VARCHAR(100) NOT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP WITH TIME ZONE,
+		last_attempt_at TIMESTAMP WITH TIME ZONE,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		response_body TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		redelivery_of BIGINT REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	// Kafka'dan aynı olay tekrar okunsa da aboneliğe bir kez eklenir; elle yeniden gönderimler hariç
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(subscription_id, event_id) WHERE redelivery_of IS NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id);`,
+	// Entegrasyonlar için API anahtarları; anahtarın kendisi değil SHA-256 özeti saklanır
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id SERIAL PRIMARY KEY,
+		tenant_id INTEGER NOT NULL DEFAULT 1,
+		name VARCHAR(100) NOT NULL,
+		prefix VARCHAR(16) NOT NULL UNIQUE,
+		key_hash CHAR(64) NOT NULL,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		scopes TEXT[] NOT NULL,
+		allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+		expires_at TIMESTAMP WITH TIME ZONE,
+		last_used_at TIMESTAMP WITH TIME ZONE,
+		last_used_ip VARCHAR(45) NOT NULL DEFAULT '',
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		replaced_by INTEGER REFERENCES api_keys(id) ON DELETE SET NULL,
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant_id);`,
+	// Keycloak/OIDC ile giriş yapan kullanıcıların sağlayıcıdaki kimliği (auth-svc yazar)
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer TEXT;`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT;`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc ON users(oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;`,
+	// TOTP ile iki adımlı doğrulama, tek kullanımlık kurtarma kodları ve rol bazlı MFA politikası
+	`CREATE TABLE IF NOT EXISTS user_mfa (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		confirmed_at TIMESTAMP WITH TIME ZONE,
+		last_used_step BIGINT NOT NULL DEFAULT 0,
+		failed_attempts INTEGER NOT NULL DEFAULT 0,
+		locked_until TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS user_mfa_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash CHAR(64) NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_user_mfa_recovery_codes_user ON user_mfa_recovery_codes(user_id);`,
+	`CREATE TABLE IF NOT EXISTS mfa_policies (
+		tenant_id INTEGER NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (tenant_id, role)
+	);`,
+	// Şifre sıfırlama bağlantıları ve şifre değişince eski token'ları geçersiz kılan oturum sürümü
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS session_version INTEGER NOT NULL DEFAULT 0;`,
+	`CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		requested_ip VARCHAR(45) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id, created_at);`,
+	// Ertelenmiş bildirimler gönderim başarılı olana kadar kuyrukta kalır; başarısız denemeler sayılır
+	`ALTER TABLE notification_queue ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;`,
+	// Atama kuralları tenant'a bağlanır; mevcut kurallar ilk atanan kullanıcının tenant'ına taşınır
+	`ALTER TABLE assignment_rules ADD COLUMN IF NOT EXISTS tenant_id INTEGER;`,
+	`UPDATE assignment_rules r SET tenant_id = COALESCE((SELECT u.tenant_id FROM users u WHERE u.id = r.assignee_ids[1]), 1)
+		WHERE r.tenant_id IS NULL;`,
+	`ALTER TABLE assignment_rules ALTER COLUMN tenant_id SET NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_assignment_rules_tenant ON assignment_rules(tenant_id, position);`,
+	// Süreçler, fırsatlar, görevler ve hesaplar tenant'a bağlanır.
+	// Mevcut süreçler varsayılan tenant'ta kalır; fırsatlar müşterinin tenant'ını alır.
+	`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;`,
+	`CREATE INDEX IF NOT EXISTS idx_pipelines_tenant ON pipelines(tenant_id);`,
+	`ALTER TABLE deals ADD COLUMN IF NOT EXISTS tenant_id INTEGER;`,
+	`UPDATE deals d SET tenant_id = c.tenant_id FROM customers c
+		WHERE c.id = d.customer_id AND d.tenant_id IS NULL;`,
+	`ALTER TABLE deals ALTER COLUMN tenant_id SET NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_deals_tenant ON deals(tenant_id, id DESC);`,
+	// Başka tenant'ın sürecindeki fırsatlar için süreç o tenant'a kopyalanır; aşamalar sıra numarasıyla eşlenir
+	`DO $$
+	DECLARE
+		r RECORD;
+		new_id INTEGER;
+	BEGIN
+		FOR r IN
+			SELECT DISTINCT d.tenant_id, d.pipeline_id FROM deals d JOIN pipelines p ON p.id = d.pipeline_id
+			WHERE p.tenant_id <> d.tenant_id
+		LOOP
+			INSERT INTO pipelines (name, is_default, tenant_id)
+			SELECT name, is_default, r.tenant_id FROM pipelines WHERE id = r.pipeline_id
+			RETURNING id INTO new_id;
+
+			INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+			SELECT new_id, name, position, probability, is_won, is_lost FROM pipeline_stages WHERE pipeline_id = r.pipeline_id;
+
+			UPDATE deal_stage_history h SET from_stage_id = ns.id
+			FROM deals d, pipeline_stages os, pipeline_stages ns
+			WHERE h.deal_id = d.id AND d.tenant_id = r.tenant_id AND d.pipeline_id = r.pipeline_id
+				AND os.id = h.from_stage_id AND ns.pipeline_id = new_id AND ns.position = os.position;
+
+			UPDATE deal_stage_history h SET to_stage_id = ns.id
+			FROM deals d, pipeline_stages os, pipeline_stages ns
+			WHERE h.deal_id = d.id AND d.tenant_id = r.tenant_id AND d.pipeline_id = r.pipeline_id
+				AND os.id = h.to_stage_id AND ns.pipeline_id = new_id AND ns.position = os.position;
+
+			UPDATE deals d SET pipeline_id = new_id, stage_id = ns.id
+			FROM pipeline_stages os, pipeline_stages ns
+			WHERE d.tenant_id = r.tenant_id AND d.pipeline_id = r.pipeline_id
+				AND os.id = d.stage_id AND ns.pipeline_id = new_id AND ns.position = os.position;
+		END LOOP;
+	END $$;`,
+	// Süreci olmayan tenant'lara varsayılan satış süreci eklenir
+	`WITH p AS (
+		INSERT INTO pipelines (name, is_default, tenant_id)
+		SELECT 'Satış', TRUE, t.tenant_id FROM (SELECT DISTINCT tenant_id FROM users) t
+		WHERE NOT EXISTS (SELECT 1 FROM pipelines WHERE tenant_id = t.tenant_id)
+		RETURNING id
+	)
+	INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+	SELECT p.id, s.name, s.position, s.probability, s.is_won, s.is_lost FROM p, (VALUES
+		('Yeni', 1, 10, FALSE, FALSE),
+		('Nitelikli', 2, 25, FALSE, FALSE),
+		('Teklif', 3, 50, FALSE, FALSE),
+		('Müzakere', 4, 75, FALSE, FALSE),
+		('Kazanıldı', 5, 100, TRUE, FALSE),
+		('Kaybedildi', 6, 0, FALSE, TRUE)
+	) AS s(name, position, probability, is_won, is_lost);`,
+	// Görevler müşterinin, yoksa fırsatın, yoksa oluşturan veya atanan kullanıcının tenant'ını alır
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tenant_id INTEGER;`,
+	`UPDATE tasks t SET tenant_id = COALESCE(
+		(SELECT c.tenant_id FROM customers c WHERE c.id = t.customer_id),
+		(SELECT d.tenant_id FROM deals d WHERE d.id = t.deal_id),
+		(SELECT u.tenant_id FROM users u WHERE u.id = t.created_by),
+		(SELECT u.tenant_id FROM users u WHERE u.id = t.assignee_id),
+		1)
+		WHERE t.tenant_id IS NULL;`,
+	`ALTER TABLE tasks ALTER COLUMN tenant_id SET NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_tasks_tenant_due ON tasks(tenant_id, due_at);`,
+	// Hesaplar sorumlunun, yoksa bağlı kişilerin tenant'ını alır; başka tenant'taki üst hesap bağlantısı kaldırılır
+	`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS tenant_id INTEGER;`,
+	`UPDATE accounts a SET tenant_id = COALESCE(
+		(SELECT u.tenant_id FROM users u WHERE u.id = a.owner_id),
+		(SELECT MIN(c.tenant_id) FROM account_people ap JOIN customers c ON c.id = ap.customer_id WHERE ap.account_id = a.id),
+		1)
+		WHERE a.tenant_id IS NULL;`,
+	`ALTER TABLE accounts ALTER COLUMN tenant_id SET NOT NULL;`,
+	`UPDATE accounts a SET parent_id = NULL FROM accounts p
+		WHERE p.id = a.parent_id AND p.tenant_id <> a.tenant_id;`,
+	`CREATE INDEX IF NOT EXISTS idx_accounts_tenant ON accounts(tenant_id, name);`,
+	// Kişi bağlantıları hesabın tenant'ını alır; kişisi başka tenant'ta olan bağlantılar geçersizdir ve silinir
+	`ALTER TABLE account_people ADD COLUMN IF NOT EXISTS tenant_id INTEGER;`,
+	`UPDATE account_people ap SET tenant_id = a.tenant_id FROM accounts a
+		WHERE a.id = ap.account_id AND ap.tenant_id IS NULL;`,
+	`ALTER TABLE account_people ALTER COLUMN tenant_id SET NOT NULL;`,
+	`DELETE FROM account_people ap USING customers c
+		WHERE c.id = ap.customer_id AND c.tenant_id <> ap.tenant_id;`,
+	`CREATE INDEX IF NOT EXISTS idx_account_people_tenant ON account_people(tenant_id, account_id);`,
+}
+
+// Şema güncellemelerini sırayla uygular
+func runSchemaUpgrades(db *sql.DB) {
+	for _, m := range schemaUpgrades {
+		if _, err := db.Exec(m); err != nil {
+			log.Fatalf("Şema güncellemesi başarısız: %v", err)
+		}
+	}
+	log.Println("Şema güncellemeleri uygulandı.")
+}
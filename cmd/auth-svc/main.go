@@ -1,19 +1,168 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/oidc"
+	"Go-CRM/pkg/password"
+
+	"github.com/golang-jwt/jwt/v5"
+	_ "github.com/lib/pq"
 )
 
+// api servisinin doğruladığı token ile aynı claim'ler
+type Claims struct {
+	UserID   int    `json:"user_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	TenantID int    `json:"tenant_id"`
+	// Oturum sürümü; api servisi şifre değişikliğinden önce verilmiş token'ları reddeder
+	SessionVersion int `json:"sv,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// Docker secrets dosyasından gizli bilgi okuma
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 func main() {
+	// Keycloak hazır olana kadar discovery tekrar denenir; bu sırada giriş uç noktaları 503 döner
+	var handler atomic.Pointer[oidc.Handler]
+	if os.Getenv("OIDC_ISSUER_URL") != "" {
+		go func() {
+			for {
+				h, err := newOIDCHandler(context.Background())
+				if err == nil {
+					handler.Store(h)
+					log.Printf("OIDC sağlayıcısı hazır: %s", h.Client.Provider.Issuer)
+					return
+				}
+				log.Printf("OIDC başlatılamadı, 5 saniye sonra tekrar denenecek: %v", err)
+				time.Sleep(5 * time.Second)
+			}
+		}()
+	} else {
+		log.Println("OIDC_ISSUER_URL tanımlı değil, OIDC girişi kapalı")
+	}
+	withHandler := func(fn func(h *oidc.Handler) http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			h := handler.Load()
+			if h == nil {
+				common.WriteError(w, http.StatusServiceUnavailable, "OIDC girişi kullanılamıyor", nil)
+				return
+			}
+			fn(h)(w, r)
+		}
+	}
+
 	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "ok")
 	})
 
 	http.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, "Auth servis çalışıyor. Keycloak proxy/adapter mantığı ile ilerleyecek.")
+		if h := handler.Load(); h != nil {
+			fmt.Fprintf(w, "Auth servis çalışıyor. OIDC sağlayıcısı: %s\n", h.Client.Provider.Issuer)
+			return
+		}
+		fmt.Fprintln(w, "Auth servis çalışıyor. OIDC girişi hazır değil.")
 	})
 
+	// OIDC authorization code + PKCE akışı
+	http.HandleFunc("/login", withHandler(func(h *oidc.Handler) http.HandlerFunc { return h.LoginHandler }))
+	http.HandleFunc("/callback", withHandler(func(h *oidc.Handler) http.HandlerFunc { return h.CallbackHandler }))
+
 	fmt.Println("Auth servis 8082 portunda başlatıldı...")
-	http.ListenAndServe(":8082", nil)
+	log.Fatal(http.ListenAndServe(":8082", nil))
+}
+
+// Ortam değişkenlerinden OIDC istemcisini, kullanıcı kaydını ve CRM token üretimini kurar
+func newOIDCHandler(ctx context.Context) (*oidc.Handler, error) {
+	jwtSecret, err := readSecretFile(getEnv("JWT_SECRET_FILE", "/run/secrets/jwt_secret"))
+	if err != nil {
+		return nil, fmt.Errorf("JWT secret dosyası okunamadı: %w", err)
+	}
+	jwtKey := []byte(jwtSecret)
+
+	dbURL := os.Getenv("DB_PRIMARY_URL")
+	if dbURL == "" {
+		return nil, fmt.Errorf("DB_PRIMARY_URL ortam değişkeni ayarlanmalı")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := oidc.ParseRoleMapping(getEnv("OIDC_ROLE_MAPPING", oidc.DefaultRoleMapping))
+	if err != nil {
+		return nil, err
+	}
+	tenantID, err := strconv.Atoi(getEnv("OIDC_TENANT_ID", strconv.Itoa(common.DefaultTenantID)))
+	if err != nil || tenantID <= 0 {
+		return nil, fmt.Errorf("OIDC_TENANT_ID geçersiz: %q", os.Getenv("OIDC_TENANT_ID"))
+	}
+	clientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+	if clientSecret == "" {
+		// Secret dosyası yoksa public client (sadece PKCE) olarak devam edilir
+		clientSecret, _ = readSecretFile("/run/secrets/oidc_client_secret")
+	}
+
+	client, err := oidc.Discover(ctx, nil, oidc.Config{
+		IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:     getEnv("OIDC_CLIENT_ID", "crm"),
+		ClientSecret: clientSecret,
+		RedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:8082/callback"),
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &oidc.Handler{
+		Client:       client,
+		StateKey:     jwtKey,
+		RoleMapping:  roles,
+		PostLoginURL: os.Getenv("OIDC_POST_LOGIN_URL"),
+		Provision: func(ctx context.Context, id oidc.Identity, role string) (common.AuthUser, error) {
+			return oidc.ProvisionUser(db, client.Provider.Issuer, id, role, tenantID)
+		},
+		IssueToken: func(u common.AuthUser) (string, error) {
+			version, err := password.SessionVersion(db, u.ID)
+			if err != nil {
+				return "", err
+			}
+			claims := &Claims{
+				UserID:         u.ID,
+				Email:          u.Email,
+				Role:           u.Role,
+				TenantID:       u.TenantID,
+				SessionVersion: version,
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+				},
+			}
+			return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+		},
+	}, nil
 }
@@ -2,18 +2,49 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"time"
+	_ "time/tzdata" // Kullanıcı saat dilimleri için (konteynerde zoneinfo olmayabilir)
 
+	"Go-CRM/pkg/notification"
+
+	_ "github.com/lib/pq"
 	"github.com/segmentio/kafka-go"
 )
 
 func main() {
+	// Tercihler ve ertelenmiş bildirimler için veritabanı
+	dbURL := os.Getenv("DB_PRIMARY_URL")
+	if dbURL == "" {
+		log.Fatal("DB_PRIMARY_URL ortam değişkeni ayarlanmalı!")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Veritabanına bağlanılamadı: %v", err)
+	}
+	defer db.Close()
+
+	senders := notification.DefaultSenders()
+
+	// Sessiz saat sonu ve özet (digest) zamanı gelen bildirimleri dakikada bir gönder
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := notification.FlushDue(context.Background(), db, senders); err != nil {
+				log.Printf("Ertelenmiş bildirimler gönderilemedi: %v", err)
+			}
+		}
+	}()
+
 	// Kafka broker adresi
 	brokers := []string{"localhost:9092"}
 	// Dinlenecek topic
-	topic := "notification.command"
+	topic := notification.CommandTopic
 
 	// Kafka reader oluştur
 	r := kafka.NewReader(kafka.ReaderConfig{
@@ -34,7 +65,16 @@ func main() {
 			log.Printf("Kafka'dan mesaj okunamadı: %v", err)
 			continue
 		}
-		log.Printf("Yeni bildirim komutu: %s", string(m.Value))
-		// Burada email/SMS/WebSocket adapterlerine yönlendirme yapılacak
+		var cmd notification.Command
+		if err := json.Unmarshal(m.Value, &cmd); err != nil {
+			log.Printf("Bildirim komutu çözümlenemedi: %v", err)
+			continue
+		}
+		// Gövde loglanmaz; şifre sıfırlama bağlantısı gibi gizli içerik taşıyabilir
+		log.Printf("Yeni bildirim komutu: olay=%s kullanıcı=%d", cmd.Event, cmd.UserID)
+		// Kullanıcı tercihlerine göre e-posta/SMS/WebSocket adaptörlerine yönlendir veya kuyruğa al
+		if err := notification.Dispatch(context.Background(), db, senders, cmd); err != nil {
+			log.Printf("Bildirim gönderilemedi: %v", err)
+		}
 	}
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"Go-CRM/pkg/webhook"
+
+	_ "github.com/lib/pq"
+	"github.com/segmentio/kafka-go"
+)
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func main() {
+	// Abonelikler ve teslimat kuyruğu için veritabanı
+	dbURL := os.Getenv("DB_PRIMARY_URL")
+	if dbURL == "" {
+		log.Fatal("DB_PRIMARY_URL ortam değişkeni ayarlanmalı!")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Veritabanına bağlanılamadı: %v", err)
+	}
+	defer db.Close()
+
+	// Yerel geliştirmede localhost'a gönderim için WEBHOOK_ALLOW_PRIVATE=true
+	worker := &webhook.Worker{
+		DB:     db,
+		Client: webhook.NewHTTPClient(os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"),
+	}
+	go worker.Run(context.Background())
+
+	// api'nin alan olaylarını yazdığı topic
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  []string{getEnv("KAFKA_ADDR", "localhost:9092")},
+		Topic:    getEnv("KAFKA_TOPIC", "crm-events"),
+		GroupID:  "webhook-svc",
+		MinBytes: 1,
+		MaxBytes: 10e6, // 10MB
+	})
+	defer r.Close()
+
+	fmt.Println("Webhook servis Kafka'dan olay bekliyor...")
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		m, err := r.FetchMessage(ctx)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("Kafka'dan mesaj okunamadı: %v", err)
+			}
+			continue
+		}
+		// Olay teslimat kuyruğuna yazılmadan offset ilerletilmez; veritabanı hatasında tekrar denenir.
+		// Aynı olay iki kez gelirse (subscription_id, event_id) tekilliği tekrar eklenmesini engeller.
+		for {
+			n, err := webhook.EnqueueEvent(db, m.Value)
+			if err == nil {
+				if n > 0 {
+					log.Printf("Olay %d webhook teslimatı olarak kuyruğa alındı (offset %d)", n, m.Offset)
+				}
+				break
+			}
+			if errors.Is(err, webhook.ErrInvalidEvent) {
+				log.Printf("Olay atlandı (offset %d): %v", m.Offset, err)
+				break
+			}
+			log.Printf("Olay kuyruğa alınamadı, tekrar denenecek: %v", err)
+			time.Sleep(5 * time.Second)
+		}
+		if err := r.CommitMessages(context.Background(), m); err != nil {
+			log.Printf("Kafka offset'i kaydedilemedi: %v", err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+-- Bildirim tercihleri (olay -> kanal listesi, saat dilimi, sessiz saatler, özet modu)
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  events JSONB NOT NULL DEFAULT '{}',
+  timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Istanbul',
+  quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '',
+  quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '',
+  digest_mode VARCHAR(10) NOT NULL DEFAULT 'off',
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sessiz saatler / özet modu nedeniyle ertelenen bildirimler
+CREATE TABLE IF NOT EXISTS notification_queue (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  channel VARCHAR(20) NOT NULL,
+  event VARCHAR(100) NOT NULL,
+  subject TEXT NOT NULL,
+  body TEXT NOT NULL,
+  deliver_after TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_queue_deliver_after ON notification_queue(deliver_after);
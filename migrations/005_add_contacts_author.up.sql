@@ -0,0 +1,6 @@
+-- İletişim kaydını oluşturan kullanıcı
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS author_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+-- Aktivite akışı (created_at DESC, id DESC keyset pagination) ve yazar filtresi için index'ler
+CREATE INDEX IF NOT EXISTS idx_contacts_created_at_id ON contacts(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_contacts_author_id ON contacts(author_id);
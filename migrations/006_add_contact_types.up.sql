@@ -0,0 +1,14 @@
+-- Tipli etkileşimler: arama, e-posta, toplantı, not
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'note';
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS direction VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS duration_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS outcome VARCHAR(30) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS participants TEXT[] NOT NULL DEFAULT '{}';
+
+-- Etkileşimin gerçekleştiği zaman, mevcut kayıtlar için oluşturulma zamanı kullanılır
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMP WITH TIME ZONE;
+UPDATE contacts SET occurred_at = created_at WHERE occurred_at IS NULL;
+ALTER TABLE contacts ALTER COLUMN occurred_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE contacts ALTER COLUMN occurred_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_contacts_type_occurred_at ON contacts(type, occurred_at);
@@ -0,0 +1,16 @@
+-- Kullanıcı rolleri: rep, manager, admin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'rep';
+
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+
+-- İletişim kayıtlarının önceki halleri. Kayıt silinse de geçmiş korunur, bu yüzden contacts'a FK yok.
+CREATE TABLE IF NOT EXISTS contact_revisions (
+  id SERIAL PRIMARY KEY,
+  contact_id INTEGER NOT NULL,
+  revision INTEGER NOT NULL,
+  action VARCHAR(10) NOT NULL, -- update, delete
+  edited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  snapshot JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (contact_id, revision)
+);
@@ -0,0 +1,63 @@
+-- Satış süreçleri, aşamalar, fırsatlar ve aşama geçmişi
+CREATE TABLE IF NOT EXISTS pipelines (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  is_default BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS pipeline_stages (
+  id SERIAL PRIMARY KEY,
+  pipeline_id INTEGER NOT NULL REFERENCES pipelines(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  position INTEGER NOT NULL,
+  probability INTEGER NOT NULL DEFAULT 0 CHECK (probability BETWEEN 0 AND 100),
+  is_won BOOLEAN NOT NULL DEFAULT FALSE,
+  is_lost BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS deals (
+  id SERIAL PRIMARY KEY,
+  customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  pipeline_id INTEGER NOT NULL REFERENCES pipelines(id),
+  stage_id INTEGER NOT NULL REFERENCES pipeline_stages(id),
+  title VARCHAR(255) NOT NULL,
+  amount NUMERIC(14, 2) NOT NULL DEFAULT 0,
+  currency CHAR(3) NOT NULL DEFAULT 'TRY',
+  expected_close_date DATE,
+  owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  probability INTEGER NOT NULL DEFAULT 0 CHECK (probability BETWEEN 0 AND 100),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deals_owner_close ON deals(owner_id, expected_close_date);
+
+CREATE INDEX IF NOT EXISTS idx_deals_customer_id ON deals(customer_id);
+
+CREATE TABLE IF NOT EXISTS deal_stage_history (
+  id SERIAL PRIMARY KEY,
+  deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+  from_stage_id INTEGER REFERENCES pipeline_stages(id),
+  to_stage_id INTEGER NOT NULL REFERENCES pipeline_stages(id),
+  changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deal_stage_history_deal_id ON deal_stage_history(deal_id);
+
+-- Varsayılan satış süreci (hiç süreç yoksa)
+WITH p AS (
+  INSERT INTO pipelines (name, is_default)
+  SELECT 'Satış', TRUE WHERE NOT EXISTS (SELECT 1 FROM pipelines)
+  RETURNING id
+)
+INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+SELECT p.id, s.name, s.position, s.probability, s.is_won, s.is_lost FROM p, (VALUES
+  ('Yeni', 1, 10, FALSE, FALSE),
+  ('Nitelikli', 2, 25, FALSE, FALSE),
+  ('Teklif', 3, 50, FALSE, FALSE),
+  ('Müzakere', 4, 75, FALSE, FALSE),
+  ('Kazanıldı', 5, 100, TRUE, FALSE),
+  ('Kaybedildi', 6, 0, FALSE, TRUE)
+) AS s(name, position, probability, is_won, is_lost);
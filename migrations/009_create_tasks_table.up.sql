@@ -0,0 +1,20 @@
+-- Görevler ve takip hatırlatmaları
+CREATE TABLE IF NOT EXISTS tasks (
+  id SERIAL PRIMARY KEY,
+  title VARCHAR(255) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  customer_id INTEGER REFERENCES customers(id) ON DELETE CASCADE,
+  deal_id INTEGER REFERENCES deals(id) ON DELETE CASCADE,
+  status VARCHAR(20) NOT NULL DEFAULT 'open',
+  priority VARCHAR(10) NOT NULL DEFAULT 'normal',
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  completed_at TIMESTAMP WITH TIME ZONE,
+  reminded_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_assignee_due ON tasks(assignee_id, due_at);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_due_pending ON tasks(due_at) WHERE reminded_at IS NULL AND status IN ('open', 'in_progress');
@@ -0,0 +1,34 @@
+-- Müşteri sorumluları ve atama kuralları
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS region VARCHAR(100) NOT NULL DEFAULT '';
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS postal_code VARCHAR(20) NOT NULL DEFAULT '';
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS lead_source VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_customers_owner ON customers(owner_id);
+
+CREATE TABLE IF NOT EXISTS customer_owner_history (
+  id SERIAL PRIMARY KEY,
+  customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  from_owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  to_owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_owner_history_customer ON customer_owner_history(customer_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS assignment_rules (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0,
+  type VARCHAR(20) NOT NULL,
+  match_value VARCHAR(100) NOT NULL DEFAULT '',
+  assignee_ids INTEGER[] NOT NULL,
+  rr_cursor INTEGER NOT NULL DEFAULT 0,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,23 @@
+-- Şirketler (hesaplar) ve bağlı kişiler
+CREATE TABLE IF NOT EXISTS accounts (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  domain VARCHAR(255) NOT NULL DEFAULT '',
+  industry VARCHAR(100) NOT NULL DEFAULT '',
+  parent_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL,
+  owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_parent ON accounts(parent_id);
+
+CREATE TABLE IF NOT EXISTS account_people (
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  job_title VARCHAR(100) NOT NULL DEFAULT '',
+  is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (account_id, customer_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_people_customer ON account_people(customer_id);
@@ -0,0 +1,24 @@
+-- Tenant bazlı müşteri özel alanları
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_customers_tenant ON customers(tenant_id);
+
+CREATE INDEX IF NOT EXISTS idx_customers_custom_fields ON customers USING GIN (custom_fields jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL,
+  key VARCHAR(40) NOT NULL,
+  label VARCHAR(100) NOT NULL,
+  type VARCHAR(20) NOT NULL,
+  required BOOLEAN NOT NULL DEFAULT FALSE,
+  options TEXT[] NOT NULL DEFAULT '{}',
+  default_value JSONB,
+  position INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (tenant_id, key)
+);
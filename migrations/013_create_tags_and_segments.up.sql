@@ -0,0 +1,32 @@
+-- Etiketler ve kayıtlı segmentler
+CREATE TABLE IF NOT EXISTS tags (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  color VARCHAR(7) NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_tenant_name ON tags(tenant_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS customer_tags (
+  customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (customer_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_tags_tag ON customer_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS segments (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  shared BOOLEAN NOT NULL DEFAULT FALSE,
+  filter JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_segments_tenant_owner ON segments(tenant_id, owner_id);
@@ -0,0 +1,7 @@
+-- Liste sıralaması (varsayılan -created_at) için indeksler; ilk kurulumda oluşturulan
+-- customers tablosunda created_at bulunmadığından önce sütun eklenir
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_customers_tenant_created ON customers(tenant_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_contacts_customer_occurred ON contacts(customer_id, occurred_at DESC);
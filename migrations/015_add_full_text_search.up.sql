@@ -0,0 +1,25 @@
+-- Tam metin arama: Türkçe kök bulma, aksan ve İ/ı farkı olmadan eşleşme
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'turkish_unaccent') THEN
+    CREATE TEXT SEARCH CONFIGURATION turkish_unaccent (COPY = turkish);
+    ALTER TEXT SEARCH CONFIGURATION turkish_unaccent
+      ALTER MAPPING FOR hword, hword_part, word, asciihword, asciiword WITH unaccent, turkish_stem;
+  END IF;
+END $$;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('turkish_unaccent', COALESCE(name, '')), 'A') ||
+  setweight(to_tsvector('turkish_unaccent', translate(COALESCE(email, ''), '@.', '  ')), 'B') ||
+  setweight(to_tsvector('turkish_unaccent', COALESCE(phone, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_customers_search ON customers USING GIN (search_vector);
+
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  to_tsvector('turkish_unaccent', COALESCE(content, ''))
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_contacts_search ON contacts USING GIN (search_vector);
@@ -0,0 +1,29 @@
+-- Mükerrer kayıt tespiti (normalize e-posta/telefon, trigram isim benzerliği) ve birleştirme logu
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_normalized VARCHAR(255) GENERATED ALWAYS AS (
+  lower(regexp_replace(btrim(COALESCE(email, '')), '\+[^@]*@', '@'))
+) STORED;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_normalized VARCHAR(20) GENERATED ALWAYS AS (
+  right(regexp_replace(COALESCE(phone, ''), '[^0-9]', '', 'g'), 10)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_customers_email_normalized ON customers(tenant_id, email_normalized);
+
+CREATE INDEX IF NOT EXISTS idx_customers_phone_normalized ON customers(tenant_id, phone_normalized);
+
+CREATE INDEX IF NOT EXISTS idx_customers_name_trgm ON customers USING GIN (name gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS customer_merges (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL,
+  survivor_id INTEGER REFERENCES customers(id) ON DELETE SET NULL,
+  merged_id INTEGER NOT NULL,
+  merged_snapshot JSONB NOT NULL,
+  moved JSONB NOT NULL DEFAULT '{}',
+  merged_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merges_survivor ON customer_merges(survivor_id);
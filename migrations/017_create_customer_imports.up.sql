@@ -0,0 +1,32 @@
+-- CSV/XLSX müşteri içe aktarma işleri ve satır bazlı hata raporu
+CREATE TABLE IF NOT EXISTS customer_imports (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL,
+  filename VARCHAR(255) NOT NULL,
+  format VARCHAR(10) NOT NULL,
+  mode VARCHAR(20) NOT NULL,
+  dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+  mapping JSONB NOT NULL DEFAULT '{}',
+  headers TEXT[] NOT NULL DEFAULT '{}',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  total_rows INTEGER NOT NULL DEFAULT 0,
+  processed_rows INTEGER NOT NULL DEFAULT 0,
+  created_count INTEGER NOT NULL DEFAULT 0,
+  updated_count INTEGER NOT NULL DEFAULT 0,
+  skipped_count INTEGER NOT NULL DEFAULT 0,
+  error_count INTEGER NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS customer_import_errors (
+  id SERIAL PRIMARY KEY,
+  import_id INTEGER NOT NULL REFERENCES customer_imports(id) ON DELETE CASCADE,
+  row_number INTEGER NOT NULL,
+  message TEXT NOT NULL,
+  row_values TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_import_errors_import ON customer_import_errors(import_id, row_number);
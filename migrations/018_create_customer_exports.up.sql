@@ -0,0 +1,18 @@
+-- Arka plan dışa aktarma işleri; dosyalar expires_at'ten sonra silinir
+CREATE TABLE IF NOT EXISTS customer_exports (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL,
+  type VARCHAR(20) NOT NULL,
+  format VARCHAR(10) NOT NULL,
+  query TEXT NOT NULL DEFAULT '',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  row_count INTEGER NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  file_path TEXT NOT NULL DEFAULT '',
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP WITH TIME ZONE,
+  expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_exports_expires ON customer_exports(expires_at) WHERE status = 'completed';
@@ -0,0 +1,41 @@
+-- Takvim etkinlikleri; series_end tekrarlayan serinin son bitişi (sonsuz seride NULL)
+CREATE TABLE IF NOT EXISTS events (
+  id SERIAL PRIMARY KEY,
+  tenant_id INTEGER NOT NULL DEFAULT 1,
+  title VARCHAR(255) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  location TEXT NOT NULL DEFAULT '',
+  start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  end_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  all_day BOOLEAN NOT NULL DEFAULT FALSE,
+  rrule TEXT NOT NULL DEFAULT '',
+  series_end TIMESTAMP WITH TIME ZONE,
+  customer_id INTEGER REFERENCES customers(id) ON DELETE SET NULL,
+  deal_id INTEGER REFERENCES deals(id) ON DELETE SET NULL,
+  organizer_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'confirmed',
+  attendees JSONB NOT NULL DEFAULT '[]',
+  sequence INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_tenant_start ON events(tenant_id, start_at);
+
+CREATE INDEX IF NOT EXISTS idx_events_organizer ON events(organizer_id);
+
+CREATE INDEX IF NOT EXISTS idx_events_attendees ON events USING GIN (attendees jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_events_customer ON events(customer_id);
+
+CREATE INDEX IF NOT EXISTS idx_events_deal ON events(deal_id);
+
+-- Kullanıcı başına gizli iCalendar abonelik anahtarı (sadece SHA-256 özeti saklanır)
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Ertelenen bildirimlerin ekleri (ör. takvim davetleri)
+ALTER TABLE notification_queue ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,19 @@
+-- Gelen e-postalardan oluşan iletişim kayıtlarının mesaj bilgileri; thread_id konuşmanın kök Message-ID'si
+CREATE TABLE IF NOT EXISTS contact_emails (
+  contact_id INTEGER PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+  tenant_id INTEGER NOT NULL DEFAULT 1,
+  customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  message_id TEXT NOT NULL,
+  in_reply_to TEXT NOT NULL DEFAULT '',
+  thread_id TEXT NOT NULL,
+  from_address TEXT NOT NULL,
+  to_addresses TEXT[] NOT NULL DEFAULT '{}',
+  cc_addresses TEXT[] NOT NULL DEFAULT '{}',
+  subject TEXT NOT NULL DEFAULT '',
+  attachments JSONB NOT NULL DEFAULT '[]',
+  UNIQUE (customer_id, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_emails_message ON contact_emails(tenant_id, message_id);
+
+CREATE INDEX IF NOT EXISTS idx_contact_emails_thread ON contact_emails(customer_id, thread_id);
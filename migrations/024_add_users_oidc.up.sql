@@ -0,0 +1,6 @@
+-- Keycloak/OIDC ile giriş yapan kullanıcıların sağlayıcıdaki kimliği (auth-svc yazar)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer TEXT;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc ON users(oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;
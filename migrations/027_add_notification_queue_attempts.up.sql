@@ -0,0 +1,2 @@
+-- Ertelenmiş bildirimler gönderim başarılı olana kadar kuyrukta kalır; başarısız denemeler sayılır
+ALTER TABLE notification_queue ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
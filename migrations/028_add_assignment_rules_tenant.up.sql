@@ -0,0 +1,9 @@
+-- Atama kuralları tenant'a bağlanır; mevcut kurallar ilk atanan kullanıcının tenant'ına taşınır
+ALTER TABLE assignment_rules ADD COLUMN IF NOT EXISTS tenant_id INTEGER;
+
+UPDATE assignment_rules r SET tenant_id = COALESCE((SELECT u.tenant_id FROM users u WHERE u.id = r.assignee_ids[1]), 1)
+WHERE r.tenant_id IS NULL;
+
+ALTER TABLE assignment_rules ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_assignment_rules_tenant ON assignment_rules(tenant_id, position);
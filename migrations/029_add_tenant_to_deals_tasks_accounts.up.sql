@@ -0,0 +1,109 @@
+-- Süreçler, fırsatlar, görevler ve hesaplar tenant'a bağlanır.
+-- Mevcut süreçler varsayılan tenant'ta kalır; fırsatlar müşterinin tenant'ını alır.
+ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_tenant ON pipelines(tenant_id);
+
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS tenant_id INTEGER;
+
+UPDATE deals d SET tenant_id = c.tenant_id FROM customers c
+WHERE c.id = d.customer_id AND d.tenant_id IS NULL;
+
+ALTER TABLE deals ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_deals_tenant ON deals(tenant_id, id DESC);
+
+-- Başka tenant'ın sürecindeki fırsatlar için süreç o tenant'a kopyalanır; aşamalar sıra numarasıyla eşlenir
+DO $$
+DECLARE
+  r RECORD;
+  new_id INTEGER;
+BEGIN
+  FOR r IN
+    SELECT DISTINCT d.tenant_id, d.pipeline_id FROM deals d JOIN pipelines p ON p.id = d.pipeline_id
+    WHERE p.tenant_id <> d.tenant_id
+  LOOP
+    INSERT INTO pipelines (name, is_default, tenant_id)
+    SELECT name, is_default, r.tenant_id FROM pipelines WHERE id = r.pipeline_id
+    RETURNING id INTO new_id;
+
+    INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+    SELECT new_id, name, position, probability, is_won, is_lost FROM pipeline_stages WHERE pipeline_id = r.pipeline_id;
+
+    UPDATE deal_stage_history h SET from_stage_id = ns.id
+    FROM deals d, pipeline_stages os, pipeline_stages ns
+    WHERE h.deal_id = d.id AND d.tenant_id = r.tenant_id AND d.pipeline_id = r.pipeline_id
+      AND os.id = h.from_stage_id AND ns.pipeline_id = new_id AND ns.position = os.position;
+
+    UPDATE deal_stage_history h SET to_stage_id = ns.id
+    FROM deals d, pipeline_stages os, pipeline_stages ns
+    WHERE h.deal_id = d.id AND d.tenant_id = r.tenant_id AND d.pipeline_id = r.pipeline_id
+      AND os.id = h.to_stage_id AND ns.pipeline_id = new_id AND ns.position = os.position;
+
+    UPDATE deals d SET pipeline_id = new_id, stage_id = ns.id
+    FROM pipeline_stages os, pipeline_stages ns
+    WHERE d.tenant_id = r.tenant_id AND d.pipeline_id = r.pipeline_id
+      AND os.id = d.stage_id AND ns.pipeline_id = new_id AND ns.position = os.position;
+  END LOOP;
+END $$;
+
+-- Süreci olmayan tenant'lara varsayılan satış süreci eklenir
+WITH p AS (
+  INSERT INTO pipelines (name, is_default, tenant_id)
+  SELECT 'Satış', TRUE, t.tenant_id FROM (SELECT DISTINCT tenant_id FROM users) t
+  WHERE NOT EXISTS (SELECT 1 FROM pipelines WHERE tenant_id = t.tenant_id)
+  RETURNING id
+)
+INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+SELECT p.id, s.name, s.position, s.probability, s.is_won, s.is_lost FROM p, (VALUES
+  ('Yeni', 1, 10, FALSE, FALSE),
+  ('Nitelikli', 2, 25, FALSE, FALSE),
+  ('Teklif', 3, 50, FALSE, FALSE),
+  ('Müzakere', 4, 75, FALSE, FALSE),
+  ('Kazanıldı', 5, 100, TRUE, FALSE),
+  ('Kaybedildi', 6, 0, FALSE, TRUE)
+) AS s(name, position, probability, is_won, is_lost);
+
+-- Görevler müşterinin, yoksa fırsatın, yoksa oluşturan veya atanan kullanıcının tenant'ını alır
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tenant_id INTEGER;
+
+UPDATE tasks t SET tenant_id = COALESCE(
+  (SELECT c.tenant_id FROM customers c WHERE c.id = t.customer_id),
+  (SELECT d.tenant_id FROM deals d WHERE d.id = t.deal_id),
+  (SELECT u.tenant_id FROM users u WHERE u.id = t.created_by),
+  (SELECT u.tenant_id FROM users u WHERE u.id = t.assignee_id),
+  1)
+WHERE t.tenant_id IS NULL;
+
+ALTER TABLE tasks ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_tenant_due ON tasks(tenant_id, due_at);
+
+-- Hesaplar sorumlunun, yoksa bağlı kişilerin tenant'ını alır; başka tenant'taki üst hesap bağlantısı kaldırılır
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS tenant_id INTEGER;
+
+UPDATE accounts a SET tenant_id = COALESCE(
+  (SELECT u.tenant_id FROM users u WHERE u.id = a.owner_id),
+  (SELECT MIN(c.tenant_id) FROM account_people ap JOIN customers c ON c.id = ap.customer_id WHERE ap.account_id = a.id),
+  1)
+WHERE a.tenant_id IS NULL;
+
+ALTER TABLE accounts ALTER COLUMN tenant_id SET NOT NULL;
+
+UPDATE accounts a SET parent_id = NULL FROM accounts p
+WHERE p.id = a.parent_id AND p.tenant_id <> a.tenant_id;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_tenant ON accounts(tenant_id, name);
+
+-- Kişi bağlantıları hesabın tenant'ını alır; kişisi başka tenant'ta olan bağlantılar geçersizdir ve silinir
+ALTER TABLE account_people ADD COLUMN IF NOT EXISTS tenant_id INTEGER;
+
+UPDATE account_people ap SET tenant_id = a.tenant_id FROM accounts a
+WHERE a.id = ap.account_id AND ap.tenant_id IS NULL;
+
+ALTER TABLE account_people ALTER COLUMN tenant_id SET NOT NULL;
+
+DELETE FROM account_people ap USING customers c
+WHERE c.id = ap.customer_id AND c.tenant_id <> ap.tenant_id;
+
+CREATE INDEX IF NOT EXISTS idx_account_people_tenant ON account_people(tenant_id, account_id);
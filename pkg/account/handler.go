@@ -0,0 +1,261 @@
+package account
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler fonksiyonları sade tutulur, iş mantığı service katmanında
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Hesap listeleme (GET /api/accounts?search=acme&parent_id=1&roots=true&page=1&pageSize=10)
+func (h *Handler) GetAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	params := AccountListParams{
+		TenantID:  tenantOf(user),
+		Search:    strings.TrimSpace(q.Get("search")),
+		RootsOnly: q.Get("roots") == "true",
+	}
+	params.ParentID, _ = strconv.Atoi(q.Get("parent_id"))
+	params.Page, _ = strconv.Atoi(q.Get("page"))
+	params.PageSize, _ = strconv.Atoi(q.Get("pageSize"))
+
+	result, err := GetAccounts(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Hesap listesi alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Hesap ekleme (POST /api/accounts)
+func (h *Handler) CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var a Account
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateAccount(h.DBPrimary, user, &a); err != nil {
+		writeAccountError(w, "Hesap eklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+// Tek hesap (GET /api/accounts/{id})
+func (h *Handler) GetAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/accounts/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap ID", err)
+		return
+	}
+	a, err := GetAccount(h.DBReplica, user, id)
+	if err != nil {
+		writeAccountError(w, "Hesap alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// Hesap güncelleme (PUT /api/accounts/{id})
+func (h *Handler) UpdateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/accounts/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap ID", err)
+		return
+	}
+	var a Account
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	a.ID = id
+	if err := UpdateAccount(h.DBPrimary, user, &a); err != nil {
+		writeAccountError(w, "Hesap güncellenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// Hesap silme (DELETE /api/accounts/{id})
+func (h *Handler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/accounts/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap ID", err)
+		return
+	}
+	if err := DeleteAccount(h.DBPrimary, user, id); err != nil {
+		writeAccountError(w, "Hesap silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Hesaba bağlı kişiler (GET /api/accounts/{id}/people)
+func (h *Handler) GetAccountPeopleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/accounts/", "/people")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap ID", err)
+		return
+	}
+	people, err := GetAccountPeople(h.DBReplica, user, id)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Hesap kişileri alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(people)
+}
+
+// Kişiyi hesaba bağlama veya unvanını güncelleme (PUT /api/accounts/{id}/people/{customerId})
+func (h *Handler) LinkAccountPersonHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	accountID, customerID, err := accountPersonFromPath(r.URL.Path)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap veya müşteri ID", err)
+		return
+	}
+	var req struct {
+		JobTitle  string `json:"job_title"`
+		IsPrimary bool   `json:"is_primary"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := LinkAccountPerson(h.DBPrimary, user, accountID, customerID, req.JobTitle, req.IsPrimary); err != nil {
+		writeAccountError(w, "Kişi hesaba bağlanamadı", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Kişinin hesap bağlantısını kaldırma (DELETE /api/accounts/{id}/people/{customerId})
+func (h *Handler) UnlinkAccountPersonHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	accountID, customerID, err := accountPersonFromPath(r.URL.Path)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap veya müşteri ID", err)
+		return
+	}
+	if err := UnlinkAccountPerson(h.DBPrimary, user, accountID, customerID); err != nil {
+		writeAccountError(w, "Kişi bağlantısı kaldırılamadı", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Hesap ağacı özeti: tüm kişilerin iletişim kayıtları ve fırsatları (GET /api/accounts/{id}/summary)
+func (h *Handler) GetAccountSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/accounts/", "/summary")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz hesap ID", err)
+		return
+	}
+	summary, err := GetAccountSummary(h.DBReplica, user, id)
+	if err != nil {
+		writeAccountError(w, "Hesap özeti alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func writeAccountError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrAccountNotFound), errors.Is(err, customer.ErrCustomerNotFound), errors.Is(err, ErrAccountPersonNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrManagerOnly):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+// /api/accounts/3/people/12 yolundan hesap ve müşteri ID'lerini çıkarır
+func accountPersonFromPath(path string) (int, int, error) {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/accounts/"), "/people/")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("geçersiz yol")
+	}
+	accountID, err := idFromPath(parts[0], "", "")
+	if err != nil {
+		return 0, 0, err
+	}
+	customerID, err := idFromPath(parts[1], "", "")
+	if err != nil {
+		return 0, 0, err
+	}
+	return accountID, customerID, nil
+}
+
+// /api/accounts/12/summary gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
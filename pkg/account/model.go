@@ -0,0 +1,52 @@
+package account
+
+import (
+	"Go-CRM/pkg/customer"
+	"time"
+)
+
+// Şirket (hesap) veri modelleri
+// Veritabanı ve API için ortak kullanılacak
+
+// Şirket (hesap). Hesaplar parent_id ile hiyerarşi oluşturur (ör. holding > şirket > şube),
+// müşteriler (kişiler) bir veya daha fazla hesaba unvanlarıyla bağlanır.
+type Account struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Domain    string `json:"domain,omitempty"`
+	Industry  string `json:"industry,omitempty"`
+	ParentID  int    `json:"parent_id,omitempty"`
+	OwnerID   int    `json:"owner_id,omitempty"`
+	CreatedAt string `json:"created_at"`
+	TenantID  int    `json:"-"`
+}
+
+// Hesaba bağlı kişi
+type AccountPerson struct {
+	customer.Customer
+	AccountID int    `json:"account_id"`
+	JobTitle  string `json:"job_title,omitempty"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+// Hesap ve alt hesaplarındaki tüm kişiler üzerinden toplu özet
+type AccountSummary struct {
+	AccountID      int                 `json:"account_id"`
+	AccountCount   int                 `json:"account_count"` // Hesabın kendisi dahil alt hesap sayısı
+	PeopleCount    int                 `json:"people_count"`
+	ContactCount   int                 `json:"contact_count"`
+	ContactsByType map[string]int      `json:"contacts_by_type"`
+	LastContactAt  *time.Time          `json:"last_contact_at,omitempty"`
+	Deals          []AccountDealRollup `json:"deals"`
+}
+
+// Para birimi bazında fırsat özeti
+type AccountDealRollup struct {
+	Currency    string  `json:"currency"`
+	OpenCount   int     `json:"open_count"`
+	OpenAmount  float64 `json:"open_amount"`
+	WonCount    int     `json:"won_count"`
+	WonAmount   float64 `json:"won_amount"`
+	LostCount   int     `json:"lost_count"`
+	TotalAmount float64 `json:"total_amount"`
+}
@@ -0,0 +1,243 @@
+package account
+
+import (
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const accountColumns = "id, name, domain, industry, parent_id, owner_id, created_at, tenant_id"
+
+func scanAccount(row scanner, a *Account) error {
+	var parentID, ownerID sql.NullInt64
+	var createdAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.Name, &a.Domain, &a.Industry, &parentID, &ownerID, &createdAt, &a.TenantID); err != nil {
+		return err
+	}
+	a.ParentID = int(parentID.Int64)
+	a.OwnerID = int(ownerID.Int64)
+	if createdAt.Valid {
+		a.CreatedAt = createdAt.Time.Format("02-01-2006 15:04")
+	}
+	return nil
+}
+
+func getAccountsRepo(db *sql.DB, params AccountListParams) (AccountListResult, error) {
+	var (
+		args  []interface{}
+		where []string
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where = append(where, "tenant_id = "+arg(params.TenantID))
+	if params.Search != "" {
+		p := arg("%" + strings.ToLower(params.Search) + "%")
+		where = append(where, "(LOWER(name) LIKE "+p+" OR LOWER(domain) LIKE "+p+")")
+	}
+	if params.ParentID > 0 {
+		where = append(where, "parent_id = "+arg(params.ParentID))
+	}
+	if params.RootsOnly {
+		where = append(where, "parent_id IS NULL")
+	}
+	filter := " WHERE " + strings.Join(where, " AND ")
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM accounts"+filter, args...).Scan(&total); err != nil {
+		return AccountListResult{}, err
+	}
+
+	query := "SELECT " + accountColumns + " FROM accounts" + filter +
+		" ORDER BY name, id LIMIT " + arg(params.PageSize) + " OFFSET " + arg((params.Page-1)*params.PageSize)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return AccountListResult{}, err
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		var a Account
+		if err := scanAccount(rows, &a); err != nil {
+			return AccountListResult{}, err
+		}
+		accounts = append(accounts, a)
+	}
+	return AccountListResult{Accounts: accounts, Total: total, Page: params.Page, PageSize: params.PageSize}, rows.Err()
+}
+
+func getAccountRepo(db *sql.DB, tenantID, id int) (Account, error) {
+	var a Account
+	err := scanAccount(db.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE id = $1 AND tenant_id = $2", id, tenantID), &a)
+	return a, err
+}
+
+func createAccountRepo(db *sql.DB, a *Account) error {
+	return scanAccount(db.QueryRow(
+		"INSERT INTO accounts (name, domain, industry, parent_id, owner_id, tenant_id) VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), $6) RETURNING "+accountColumns,
+		a.Name, a.Domain, a.Industry, a.ParentID, a.OwnerID, a.TenantID,
+	), a)
+}
+
+func updateAccountRepo(db *sql.DB, a *Account) error {
+	return scanAccount(db.QueryRow(
+		"UPDATE accounts SET name = $1, domain = $2, industry = $3, parent_id = NULLIF($4, 0), owner_id = NULLIF($5, 0) WHERE id = $6 AND tenant_id = $7 RETURNING "+accountColumns,
+		a.Name, a.Domain, a.Industry, a.ParentID, a.OwnerID, a.ID, a.TenantID,
+	), a)
+}
+
+// Hesabı siler; alt hesaplar kök hesap olur, kişi bağlantıları silinir
+func deleteAccountRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	res, err := db.Exec("DELETE FROM accounts WHERE id = $1 AND tenant_id = $2", id, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// candidate, id'nin kendisi veya alt hesaplarından biri mi (döngü kontrolü)
+func isInAccountSubtreeRepo(db *sql.DB, id, candidate int) (bool, error) {
+	var found bool
+	err := db.QueryRow(`
+		WITH RECURSIVE sub AS (
+			SELECT id FROM accounts WHERE id = $1
+			UNION ALL
+			SELECT a.id FROM accounts a JOIN sub ON a.parent_id = sub.id
+		) SELECT EXISTS (SELECT 1 FROM sub WHERE id = $2)`, id, candidate).Scan(&found)
+	return found, err
+}
+
+func getAccountPeopleRepo(db *sql.DB, tenantID, accountID int) ([]AccountPerson, error) {
+	rows, err := db.Query(`
+		SELECT c.id, c.name, c.email, c.phone, c.owner_id, c.region, c.postal_code, c.lead_source, c.tenant_id, c.custom_fields,
+			ap.account_id, ap.job_title, ap.is_primary
+		FROM account_people ap JOIN customers c ON c.id = ap.customer_id
+		WHERE ap.account_id = $1 AND ap.tenant_id = $2
+		ORDER BY ap.is_primary DESC, c.name, c.id`, accountID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := []AccountPerson{}
+	for rows.Next() {
+		var p AccountPerson
+		if err := customer.ScanCustomer(rows, &p.Customer, &p.AccountID, &p.JobTitle, &p.IsPrimary); err != nil {
+			return nil, err
+		}
+		people = append(people, p)
+	}
+	return people, rows.Err()
+}
+
+// Kişiyi hesaba bağlar veya unvanını günceller. Birincil kişi işaretlenirse hesabın diğer birincil kişisi kaldırılır.
+func upsertAccountPersonRepo(db *sql.DB, tenantID, accountID, customerID int, jobTitle string, isPrimary bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if isPrimary {
+		if _, err := tx.Exec("UPDATE account_people SET is_primary = FALSE WHERE account_id = $1 AND customer_id <> $2", accountID, customerID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO account_people (account_id, customer_id, job_title, is_primary, tenant_id) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id, customer_id) DO UPDATE SET job_title = EXCLUDED.job_title, is_primary = EXCLUDED.is_primary`,
+		accountID, customerID, jobTitle, isPrimary, tenantID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteAccountPersonRepo(db *sql.DB, tenantID, accountID, customerID int) (bool, error) {
+	res, err := db.Exec("DELETE FROM account_people WHERE account_id = $1 AND customer_id = $2 AND tenant_id = $3", accountID, customerID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Hesap ağacındaki tüm kişilerin iletişim kayıtları ve fırsatları üzerinden özet
+func getAccountSummaryRepo(db *sql.DB, tenantID, accountID int) (AccountSummary, error) {
+	s := AccountSummary{AccountID: accountID, ContactsByType: map[string]int{}, Deals: []AccountDealRollup{}}
+	people := customer.AccountPeopleSubquery("$1", "$2")
+
+	err := db.QueryRow(`
+		WITH RECURSIVE sub AS (
+			SELECT id FROM accounts WHERE id = $1 AND tenant_id = $2
+			UNION ALL
+			SELECT a.id FROM accounts a JOIN sub ON a.parent_id = sub.id
+		)
+		SELECT (SELECT COUNT(*) FROM sub),
+			(SELECT COUNT(DISTINCT customer_id) FROM account_people WHERE tenant_id = $2 AND account_id IN (SELECT id FROM sub))`,
+		accountID, tenantID).Scan(&s.AccountCount, &s.PeopleCount)
+	if err != nil {
+		return s, err
+	}
+
+	rows, err := db.Query(`
+		SELECT type, COUNT(*), MAX(occurred_at) FROM contacts
+		WHERE customer_id IN (`+people+`)
+		GROUP BY type`, accountID, tenantID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var typ string
+		var count int
+		var last sql.NullTime
+		if err := rows.Scan(&typ, &count, &last); err != nil {
+			return s, err
+		}
+		s.ContactsByType[typ] = count
+		s.ContactCount += count
+		if last.Valid && (s.LastContactAt == nil || last.Time.After(*s.LastContactAt)) {
+			t := last.Time
+			s.LastContactAt = &t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	dealRows, err := db.Query(`
+		SELECT d.currency,
+			COUNT(*) FILTER (WHERE NOT s.is_won AND NOT s.is_lost), COALESCE(SUM(d.amount) FILTER (WHERE NOT s.is_won AND NOT s.is_lost), 0),
+			COUNT(*) FILTER (WHERE s.is_won), COALESCE(SUM(d.amount) FILTER (WHERE s.is_won), 0),
+			COUNT(*) FILTER (WHERE s.is_lost), SUM(d.amount)
+		FROM deals d JOIN pipeline_stages s ON s.id = d.stage_id
+		WHERE d.tenant_id = $2 AND d.customer_id IN (`+people+`)
+		GROUP BY d.currency ORDER BY d.currency`, accountID, tenantID)
+	if err != nil {
+		return s, err
+	}
+	defer dealRows.Close()
+	for dealRows.Next() {
+		var r AccountDealRollup
+		if err := dealRows.Scan(&r.Currency, &r.OpenCount, &r.OpenAmount, &r.WonCount, &r.WonAmount, &r.LostCount, &r.TotalAmount); err != nil {
+			return s, err
+		}
+		s.Deals = append(s.Deals, r)
+	}
+	return s, dealRows.Err()
+}
+
+func customerExistsRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	var found bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND tenant_id = $2)", id, tenantID).Scan(&found)
+	return found, err
+}
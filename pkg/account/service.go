@@ -0,0 +1,173 @@
+package account
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrAccountNotFound       = errors.New("Hesap bulunamadı")
+	ErrAccountPersonNotFound = errors.New("Kişi bu hesaba bağlı değil")
+	ErrManagerOnly           = errors.New("Bu işlem sadece yöneticiler içindir")
+)
+
+type AccountListParams struct {
+	TenantID  int
+	Search    string // İsim veya alan adı araması
+	ParentID  int    // Sadece bu hesabın doğrudan alt hesapları
+	RootsOnly bool   // Sadece üst hesabı olmayanlar
+	Page      int
+	PageSize  int
+}
+
+type AccountListResult struct {
+	Accounts []Account
+	Total    int
+	Page     int
+	PageSize int
+}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// Hesap listeleme (pagination + filtreleme)
+func GetAccounts(db *sql.DB, params AccountListParams) (AccountListResult, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+	return getAccountsRepo(db, params)
+}
+
+// Oturum kullanıcısının tenant'ındaki hesap (başka tenant'ın hesabı bulunamadı sayılır)
+func GetAccount(db *sql.DB, user common.AuthUser, id int) (Account, error) {
+	a, err := getAccountRepo(db, tenantOf(user), id)
+	if err == sql.ErrNoRows {
+		return Account{}, ErrAccountNotFound
+	}
+	return a, err
+}
+
+// Hesap ekleme. Sorumlu verilmezse hesabı ekleyen kullanıcı sorumlu olur.
+func CreateAccount(db *sql.DB, user common.AuthUser, a *Account) error {
+	a.TenantID = tenantOf(user)
+	if a.OwnerID == 0 {
+		a.OwnerID = user.ID
+	}
+	if err := ValidateAccount(*a); err != nil {
+		return err
+	}
+	if a.ParentID > 0 {
+		if _, err := GetAccount(db, user, a.ParentID); err != nil {
+			return err
+		}
+	}
+	return createAccountRepo(db, a)
+}
+
+// Hesap güncelleme; üst hesap değişirse hiyerarşide döngü oluşmaması kontrol edilir
+func UpdateAccount(db *sql.DB, user common.AuthUser, a *Account) error {
+	a.TenantID = tenantOf(user)
+	if err := ValidateAccount(*a); err != nil {
+		return err
+	}
+	if a.ParentID > 0 {
+		if _, err := GetAccount(db, user, a.ParentID); err != nil {
+			return err
+		}
+		cycle, err := isInAccountSubtreeRepo(db, a.ID, a.ParentID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return errors.New("Hesap kendi alt hesabının altına taşınamaz")
+		}
+	}
+	err := updateAccountRepo(db, a)
+	if err == sql.ErrNoRows {
+		return ErrAccountNotFound
+	}
+	return err
+}
+
+// Hesap silme (sadece yönetici), alt hesaplar kök hesap olur
+func DeleteAccount(db *sql.DB, user common.AuthUser, id int) error {
+	if !user.IsManager() {
+		return ErrManagerOnly
+	}
+	found, err := deleteAccountRepo(db, tenantOf(user), id)
+	if err == nil && !found {
+		return ErrAccountNotFound
+	}
+	return err
+}
+
+func GetAccountPeople(db *sql.DB, user common.AuthUser, accountID int) ([]AccountPerson, error) {
+	return getAccountPeopleRepo(db, tenantOf(user), accountID)
+}
+
+// Kişiyi hesaba unvanıyla bağlar; bağlantı varsa unvan ve birincil kişi bilgisi güncellenir.
+// Hesap ve kişi oturum kullanıcısının tenant'ında olmalı.
+func LinkAccountPerson(db *sql.DB, user common.AuthUser, accountID, customerID int, jobTitle string, isPrimary bool) error {
+	if accountID <= 0 || customerID <= 0 {
+		return errors.New("Geçersiz hesap veya müşteri ID")
+	}
+	jobTitle = strings.TrimSpace(jobTitle)
+	if utf8.RuneCountInString(jobTitle) > 100 {
+		return errors.New("Unvan en fazla 100 karakter olabilir")
+	}
+	if _, err := GetAccount(db, user, accountID); err != nil {
+		return err
+	}
+	found, err := customerExistsRepo(db, tenantOf(user), customerID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return customer.ErrCustomerNotFound
+	}
+	return upsertAccountPersonRepo(db, tenantOf(user), accountID, customerID, jobTitle, isPrimary)
+}
+
+func UnlinkAccountPerson(db *sql.DB, user common.AuthUser, accountID, customerID int) error {
+	found, err := deleteAccountPersonRepo(db, tenantOf(user), accountID, customerID)
+	if err == nil && !found {
+		return ErrAccountPersonNotFound
+	}
+	return err
+}
+
+// Hesap ve alt hesaplarındaki tüm kişiler üzerinden iletişim ve fırsat özeti
+func GetAccountSummary(db *sql.DB, user common.AuthUser, accountID int) (AccountSummary, error) {
+	if _, err := GetAccount(db, user, accountID); err != nil {
+		return AccountSummary{}, err
+	}
+	return getAccountSummaryRepo(db, tenantOf(user), accountID)
+}
+
+// --- Validasyon Fonksiyonları ---
+func ValidateAccount(a Account) error {
+	if utf8.RuneCountInString(strings.TrimSpace(a.Name)) < 2 {
+		return errors.New("Hesap adı en az 2 karakter olmalı")
+	}
+	if a.Domain != "" && (strings.ContainsAny(a.Domain, " /@") || !strings.Contains(a.Domain, ".")) {
+		return errors.New("Geçersiz alan adı")
+	}
+	if a.ParentID < 0 || a.OwnerID < 0 {
+		return errors.New("Geçersiz üst hesap veya sorumlu ID")
+	}
+	if a.ID > 0 && a.ParentID == a.ID {
+		return errors.New("Hesap kendi üst hesabı olamaz")
+	}
+	return nil
+}
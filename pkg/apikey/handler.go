@@ -0,0 +1,139 @@
+package apikey
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// API anahtarlarının yönetimi sadece adminlere açıktır
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Anahtar listeleme (GET /api/api-keys?include_revoked=true)
+func (h *Handler) GetKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	keys, err := GetKeys(h.DBReplica, user, r.URL.Query().Get("include_revoked") == "true")
+	if err != nil {
+		writeKeyError(w, "API anahtarları alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// Geçerli kapsamlar (GET /api/api-keys/scopes)
+func (h *Handler) GetScopesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Scopes())
+}
+
+// Anahtar oluşturma (POST /api/api-keys); yanıttaki key bir daha gösterilmez
+func (h *Handler) CreateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var k APIKey
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateKey(h.DBPrimary, user, &k); err != nil {
+		writeKeyError(w, "API anahtarı oluşturulamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, k)
+}
+
+// Anahtar detayı (GET /api/api-keys/{id})
+func (h *Handler) GetKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := idFromPath(r.URL.Path, "/api/api-keys/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz anahtar ID", err)
+		return
+	}
+	k, err := GetKey(h.DBReplica, user, id)
+	if err != nil {
+		writeKeyError(w, "API anahtarı alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, k)
+}
+
+// Anahtar yenileme (POST /api/api-keys/{id}/rotate), gövde isteğe bağlı: {"grace_period_hours": 24}
+func (h *Handler) RotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := idFromPath(r.URL.Path, "/api/api-keys/", "/rotate")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz anahtar ID", err)
+		return
+	}
+	var req RotateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+			return
+		}
+	}
+	k, err := RotateKey(h.DBPrimary, user, id, req)
+	if err != nil {
+		writeKeyError(w, "API anahtarı yenilenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, k)
+}
+
+// Anahtar iptali (DELETE /api/api-keys/{id})
+func (h *Handler) RevokeKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := idFromPath(r.URL.Path, "/api/api-keys/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz anahtar ID", err)
+		return
+	}
+	if err := RevokeKey(h.DBPrimary, user, id); err != nil {
+		writeKeyError(w, "API anahtarı iptal edilemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeKeyError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrAdminOnly), errors.Is(err, ErrManagedByAPIKeys):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrKeyNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrKeyRevoked):
+		common.WriteError(w, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, ErrNameRequired), errors.Is(err, ErrScopesRequired), errors.Is(err, ErrInvalidScope),
+		errors.Is(err, ErrInvalidIP), errors.Is(err, ErrInvalidExpiry), errors.Is(err, ErrInvalidGrace),
+		errors.Is(err, ErrUserNotInTenant):
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusInternalServerError, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// /api/api-keys/12/rotate gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
@@ -0,0 +1,34 @@
+package apikey
+
+import "time"
+
+// Sunucudan sunucuya entegrasyonlar için API anahtarı. Anahtar, sahibi olan kullanıcı adına ve onun
+// güncel rolüyle çalışır; kapsamlar (scopes) erişilebilen kaynakları ayrıca daraltır.
+// Anahtarın kendisi saklanmaz, sadece SHA-256 özeti tutulur; Key alanı yalnızca oluşturma ve
+// yenileme yanıtında dolu döner.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Anahtarın başı (crm_<prefix>_...), loglarda ve listede tanımak için
+	UserID     int        `json:"user_id"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"` // Boşsa her adresten; IP veya CIDR
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy int        `json:"replaced_by,omitempty"` // Yenilemeyle yerine gelen anahtar
+	CreatedBy  int        `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Key        string     `json:"key,omitempty"`
+	TenantID   int        `json:"-"`
+	hash       string
+	userEmail  string
+	userRole   string
+	userTenant int
+}
+
+// Anahtar yenileme isteği; eski anahtar grace_period_hours kadar daha geçerli kalır (0: hemen iptal)
+type RotateRequest struct {
+	GracePeriodHours int `json:"grace_period_hours"`
+}
@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const keyColumns = `k.id, k.name, k.prefix, k.user_id, k.scopes, k.allowed_ips, k.expires_at, k.last_used_at,
+	k.last_used_ip, k.revoked_at, k.replaced_by, k.created_by, k.created_at, k.tenant_id, k.key_hash`
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanKey(row scanner, k *APIKey, extra ...interface{}) error {
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	var replacedBy, createdBy sql.NullInt64
+	dest := []interface{}{&k.ID, &k.Name, &k.Prefix, &k.UserID, pq.Array(&k.Scopes), pq.Array(&k.AllowedIPs),
+		&expiresAt, &lastUsedAt, &k.LastUsedIP, &revokedAt, &replacedBy, &createdBy, &k.CreatedAt, &k.TenantID, &k.hash}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	k.ReplacedBy = int(replacedBy.Int64)
+	k.CreatedBy = int(createdBy.Int64)
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	if k.AllowedIPs == nil {
+		k.AllowedIPs = []string{}
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func createKeyRepo(db queryRower, k *APIKey) error {
+	return scanKey(db.QueryRow(`
+		WITH k AS (
+			INSERT INTO api_keys (tenant_id, name, prefix, key_hash, user_id, scopes, allowed_ips, expires_at, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0))
+			RETURNING *
+		)
+		SELECT `+keyColumns+" FROM k",
+		k.TenantID, k.Name, k.Prefix, k.hash, k.UserID, pq.Array(k.Scopes), pq.Array(k.AllowedIPs), k.ExpiresAt, k.CreatedBy), k)
+}
+
+func getKeyRepo(db *sql.DB, id int) (APIKey, error) {
+	var k APIKey
+	err := scanKey(db.QueryRow("SELECT "+keyColumns+" FROM api_keys k WHERE k.id = $1", id), &k)
+	return k, err
+}
+
+func getKeysRepo(db *sql.DB, tenantID int, includeRevoked bool) ([]APIKey, error) {
+	rows, err := db.Query(`SELECT `+keyColumns+` FROM api_keys k
+		WHERE k.tenant_id = $1 AND ($2 OR k.revoked_at IS NULL) ORDER BY k.id`, tenantID, includeRevoked)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := scanKey(rows, &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Doğrulama için anahtarı, sahibinin güncel e-posta ve rolüyle birlikte getirir
+func getKeyByPrefixRepo(db *sql.DB, prefix string) (APIKey, error) {
+	var k APIKey
+	err := scanKey(db.QueryRow(`SELECT `+keyColumns+`, u.email, u.role, u.tenant_id
+		FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.prefix = $1`, prefix),
+		&k, &k.userEmail, &k.userRole, &k.userTenant)
+	return k, err
+}
+
+// Kullanıcının tenant'ı; anahtar başka tenant'ın kullanıcısına verilemez
+func userTenantRepo(db *sql.DB, userID int) (int, error) {
+	var tenantID int
+	err := db.QueryRow("SELECT tenant_id FROM users WHERE id = $1", userID).Scan(&tenantID)
+	return tenantID, err
+}
+
+func revokeKeyRepo(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL", id)
+	return err
+}
+
+// Yeni anahtarı aynı ayarlarla ekler; eski anahtar graceUntil'e kadar (nil ise hemen) geçerliliğini yitirir
+func rotateKeyRepo(db *sql.DB, old APIKey, k *APIKey, graceUntil *time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Aynı anahtar eşzamanlı iki kez yenilenmesin
+	var replacedBy sql.NullInt64
+	var revoked bool
+	if err := tx.QueryRow("SELECT replaced_by, revoked_at IS NOT NULL FROM api_keys WHERE id = $1 FOR UPDATE", old.ID).
+		Scan(&replacedBy, &revoked); err != nil {
+		return err
+	}
+	if revoked || replacedBy.Valid {
+		return ErrKeyRevoked
+	}
+	if err := createKeyRepo(tx, k); err != nil {
+		return err
+	}
+	if graceUntil == nil {
+		_, err = tx.Exec("UPDATE api_keys SET revoked_at = now(), replaced_by = $2 WHERE id = $1", old.ID, k.ID)
+	} else {
+		_, err = tx.Exec(`UPDATE api_keys SET replaced_by = $2,
+			expires_at = CASE WHEN expires_at IS NULL OR expires_at > $3 THEN $3 ELSE expires_at END
+			WHERE id = $1`, old.ID, k.ID, *graceUntil)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Son kullanım bilgisi her istekte yazılmaz, dakikada en fazla bir kez güncellenir
+func touchKeyRepo(db *sql.DB, id int, ip string) error {
+	_, err := db.Exec(`UPDATE api_keys SET last_used_at = now(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute' OR last_used_ip <> $2)`, id, ip)
+	return err
+}
@@ -0,0 +1,373 @@
+package apikey
+
+import (
+	"Go-CRM/pkg/common"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAdminOnly        = errors.New("Bu işlem sadece adminler içindir")
+	ErrKeyNotFound      = errors.New("API anahtarı bulunamadı")
+	ErrInvalidKey       = errors.New("Geçersiz API anahtarı")
+	ErrKeyRevoked       = errors.New("API anahtarı iptal edilmiş")
+	ErrKeyExpired       = errors.New("API anahtarının süresi dolmuş")
+	ErrIPNotAllowed     = errors.New("Bu IP adresinden API anahtarı kullanılamaz")
+	ErrScopeDenied      = errors.New("API anahtarının bu işlem için yetkisi yok")
+	ErrNameRequired     = errors.New("Anahtar adı zorunludur")
+	ErrScopesRequired   = errors.New("En az bir kapsam seçilmeli")
+	ErrInvalidScope     = errors.New("Geçersiz kapsam")
+	ErrInvalidIP        = errors.New("Geçersiz IP adresi veya CIDR")
+	ErrInvalidExpiry    = errors.New("Bitiş zamanı gelecekte olmalı")
+	ErrInvalidGrace     = fmt.Errorf("Geçiş süresi 0 ile %d saat arasında olmalı", maxGraceHours)
+	ErrUserNotInTenant  = errors.New("Anahtar sahibi kullanıcı bulunamadı")
+	ErrManagedByAPIKeys = errors.New("API anahtarları API anahtarıyla yönetilemez")
+)
+
+const (
+	keyPrefix     = "crm_"
+	maxGraceHours = 7 * 24
+)
+
+// Kapsamlar "<kaynak>:read" veya "<kaynak>:write" biçimindedir; write, read'i de kapsar.
+// Kaynak, /api/ altındaki ilk yol parçasından belirlenir. Listede olmayan uç noktalara
+// (ör. /api/api-keys, bildirim tercihleri) API anahtarıyla erişilemez.
+var resourcePaths = map[string][]string{
+	"customers": {"customers", "accounts", "tags", "segments", "customer-fields", "assignment-rules", "search", "attachments", "exports"},
+	"contacts":  {"contacts"},
+	"deals":     {"deals", "pipelines"},
+	"tasks":     {"tasks"},
+	"events":    {"events"},
+	"webhooks":  {"webhooks"},
+}
+
+// Geçerli kapsamların sıralı listesi
+func Scopes() []string {
+	scopes := []string{}
+	for resource := range resourcePaths {
+		scopes = append(scopes, resource+":read", resource+":write")
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// İsteğin gerektirdiği kapsam; API anahtarına kapalı uç noktalarda false döner
+func RequiredScope(method, path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return "", false
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	for resource, segments := range resourcePaths {
+		for _, s := range segments {
+			if s != segment {
+				continue
+			}
+			if method == http.MethodGet || method == http.MethodHead {
+				return resource + ":read", true
+			}
+			return resource + ":write", true
+		}
+	}
+	return "", false
+}
+
+// Anahtarın kapsamı isteğe izin veriyor mu
+func (k APIKey) Allows(scope string) bool {
+	resource, access, _ := strings.Cut(scope, ":")
+	for _, s := range k.Scopes {
+		if s == scope || (access == "read" && s == resource+":write") {
+			return true
+		}
+	}
+	return false
+}
+
+// IP kısıtı yoksa veya adres listedeki bir IP/CIDR'a uyuyorsa true
+func (k APIKey) AllowsIP(ip string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range k.AllowedIPs {
+		if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// Ad, kapsam, IP listesi ve bitiş zamanını doğrular; IP'ler CIDR biçimine çevrilir
+func ValidateKey(k *APIKey, now time.Time) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return ErrNameRequired
+	}
+	if len(k.Scopes) == 0 {
+		return ErrScopesRequired
+	}
+	valid := map[string]bool{}
+	for _, s := range Scopes() {
+		valid[s] = true
+	}
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, s := range k.Scopes {
+		s = strings.TrimSpace(s)
+		if !valid[s] {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	sort.Strings(scopes)
+	k.Scopes = scopes
+
+	ips := []string{}
+	for _, entry := range k.AllowedIPs {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("%w: %q", ErrInvalidIP, entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidIP, entry)
+		}
+		ips = append(ips, network.String())
+	}
+	k.AllowedIPs = ips
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+// crm_<12 hex önek>_<43 karakter gizli kısım>; önek veritabanında açık tutulur ve aramada kullanılır
+func generateKey() (key, prefix, hash string, err error) {
+	p := make([]byte, 6)
+	s := make([]byte, 32)
+	if _, err = rand.Read(p); err != nil {
+		return
+	}
+	if _, err = rand.Read(s); err != nil {
+		return
+	}
+	prefix = hex.EncodeToString(p)
+	key = keyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(s)
+	return key, prefix, hashKey(key), nil
+}
+
+// Anahtarlar rastgele ve uzun olduğu için bcrypt yerine SHA-256 yeterlidir (her istekte doğrulanır)
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authorization: Bearer crm_... veya X-API-Key başlığındaki anahtar; yoksa boş döner
+func KeyFromRequest(r *http.Request) string {
+	if k := strings.TrimSpace(r.Header.Get("X-API-Key")); k != "" {
+		return k
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(token, keyPrefix) {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// IP kısıtı için istemci adresi. X-Real-IP sadece istek iç ağdaki yük dengeleyiciden (nginx) geldiyse
+// dikkate alınır; doğrudan gelen isteklerde istemci bu başlığı taklit edebilir.
+func RequestIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); real != "" && remote != nil &&
+		(remote.IsLoopback() || remote.IsPrivate()) && net.ParseIP(real) != nil {
+		return real
+	}
+	return host
+}
+
+// Anahtarı doğrular ve sahibi adına oturum kullanıcısını döner. Rol her istekte kullanıcının
+// güncel rolünden okunur; kullanıcının yetkisi düşürülürse anahtarınki de düşer.
+func Authenticate(db *sql.DB, raw, ip string, now time.Time) (common.AuthUser, APIKey, error) {
+	rest, ok := strings.CutPrefix(raw, keyPrefix)
+	prefix, _, found := strings.Cut(rest, "_")
+	if !ok || !found || len(prefix) != 12 {
+		return common.AuthUser{}, APIKey{}, ErrInvalidKey
+	}
+	k, err := getKeyByPrefixRepo(db, prefix)
+	if err == sql.ErrNoRows {
+		return common.AuthUser{}, APIKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return common.AuthUser{}, APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(raw)), []byte(k.hash)) != 1 {
+		return common.AuthUser{}, APIKey{}, ErrInvalidKey
+	}
+	if k.RevokedAt != nil {
+		return common.AuthUser{}, k, ErrKeyRevoked
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return common.AuthUser{}, k, ErrKeyExpired
+	}
+	// Kullanıcı sonradan başka tenant'a taşındıysa anahtar geçersizdir
+	if k.userTenant != k.TenantID {
+		return common.AuthUser{}, k, ErrInvalidKey
+	}
+	if !k.AllowsIP(ip) {
+		return common.AuthUser{}, k, ErrIPNotAllowed
+	}
+	go func() {
+		if err := touchKeyRepo(db, k.ID, ip); err != nil {
+			log.Printf("API anahtarı %s son kullanım bilgisi yazılamadı: %v", k.Prefix, err)
+		}
+	}()
+	user := common.AuthUser{ID: k.UserID, Email: k.userEmail, Role: k.userRole, TenantID: k.TenantID, APIKeyID: k.ID}
+	return user, k, nil
+}
+
+// Anahtar yönetimi sadece JWT ile oturum açmış adminlere açıktır
+func requireAdmin(user common.AuthUser) error {
+	if user.APIKeyID != 0 {
+		return ErrManagedByAPIKeys
+	}
+	if !user.IsAdmin() {
+		return ErrAdminOnly
+	}
+	return nil
+}
+
+func GetKeys(db *sql.DB, user common.AuthUser, includeRevoked bool) ([]APIKey, error) {
+	if err := requireAdmin(user); err != nil {
+		return nil, err
+	}
+	return getKeysRepo(db, tenantOf(user), includeRevoked)
+}
+
+func GetKey(db *sql.DB, user common.AuthUser, id int) (APIKey, error) {
+	if err := requireAdmin(user); err != nil {
+		return APIKey{}, err
+	}
+	k, err := getKeyRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && k.TenantID != tenantOf(user)) {
+		return APIKey{}, ErrKeyNotFound
+	}
+	return k, err
+}
+
+// Yeni anahtar; user_id verilmezse anahtar oluşturan admin adına çalışır
+func CreateKey(db *sql.DB, user common.AuthUser, k *APIKey) error {
+	if err := requireAdmin(user); err != nil {
+		return err
+	}
+	if err := ValidateKey(k, time.Now()); err != nil {
+		return err
+	}
+	if k.UserID == 0 {
+		k.UserID = user.ID
+	}
+	tenantID, err := userTenantRepo(db, k.UserID)
+	if err == sql.ErrNoRows || (err == nil && tenantID != tenantOf(user)) {
+		return ErrUserNotInTenant
+	}
+	if err != nil {
+		return err
+	}
+	key, prefix, hash, err := generateKey()
+	if err != nil {
+		return err
+	}
+	k.Prefix, k.hash = prefix, hash
+	k.TenantID = tenantOf(user)
+	k.CreatedBy = user.ID
+	if err := createKeyRepo(db, k); err != nil {
+		return err
+	}
+	k.Key = key
+	return nil
+}
+
+// Aynı ad, sahip, kapsam, IP ve bitiş zamanıyla yeni anahtar üretir. Eski anahtar geçiş süresi
+// boyunca çalışmaya devam eder, böylece entegrasyonlar kesintisiz güncellenebilir.
+func RotateKey(db *sql.DB, user common.AuthUser, id int, req RotateRequest) (APIKey, error) {
+	old, err := GetKey(db, user, id)
+	if err != nil {
+		return APIKey{}, err
+	}
+	if req.GracePeriodHours < 0 || req.GracePeriodHours > maxGraceHours {
+		return APIKey{}, ErrInvalidGrace
+	}
+	now := time.Now()
+	if old.RevokedAt != nil || old.ReplacedBy != 0 || (old.ExpiresAt != nil && !now.Before(*old.ExpiresAt)) {
+		return APIKey{}, ErrKeyRevoked
+	}
+	key, prefix, hash, err := generateKey()
+	if err != nil {
+		return APIKey{}, err
+	}
+	k := APIKey{
+		Name:       old.Name,
+		Prefix:     prefix,
+		hash:       hash,
+		UserID:     old.UserID,
+		Scopes:     old.Scopes,
+		AllowedIPs: old.AllowedIPs,
+		ExpiresAt:  old.ExpiresAt,
+		TenantID:   old.TenantID,
+		CreatedBy:  user.ID,
+	}
+	var graceUntil *time.Time
+	if req.GracePeriodHours > 0 {
+		t := now.Add(time.Duration(req.GracePeriodHours) * time.Hour)
+		graceUntil = &t
+	}
+	if err := rotateKeyRepo(db, old, &k, graceUntil); err != nil {
+		return APIKey{}, err
+	}
+	k.Key = key
+	return k, nil
+}
+
+// Anahtar silinmez, iptal edilir; kayıt denetim için kalır
+func RevokeKey(db *sql.DB, user common.AuthUser, id int) error {
+	if _, err := GetKey(db, user, id); err != nil {
+		return err
+	}
+	return revokeKeyRepo(db, id)
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+)
+
+// Kullanıcı rolleri
+const (
+	RoleRep     = "rep"
+	RoleManager = "manager"
+	RoleAdmin   = "admin"
+)
+
+// Tenant bilgisi olmayan eski token'lar ve kayıtlar için varsayılan tenant
+const DefaultTenantID = 1
+
+// JWT'den veya API anahtarından çözülen oturum sahibi kullanıcı bilgisi
+type AuthUser struct {
+	ID       int
+	Email    string
+	Role     string
+	TenantID int
+	APIKeyID int // İstek API anahtarıyla geldiyse anahtarın ID'si, JWT ile geldiyse 0
+}
+
+// Yönetici yetkisi (admin de yönetici işlemlerini yapabilir)
+func (u AuthUser) IsManager() bool {
+	return u.Role == RoleManager || u.Role == RoleAdmin
+}
+
+func (u AuthUser) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+type authUserKey struct{}
+
+// Kullanıcıyı context'e ekler (auth middleware tarafından çağrılır)
+func ContextWithUser(ctx context.Context, u AuthUser) context.Context {
+	return context.WithValue(ctx, authUserKey{}, u)
+}
+
+// Context'teki oturum kullanıcısını döner
+func UserFromContext(ctx context.Context) (AuthUser, bool) {
+	u, ok := ctx.Value(authUserKey{}).(AuthUser)
+	return u, ok && u.ID > 0
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// vCard (RFC 6350) ve iCalendar (RFC 5545) ortak satır biçimi
+
+// Metin değerindeki özel karakterleri kaçışlar (\ , ; ve satır sonu)
+func EscapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// Satırı CRLF ile yazar; 75 bayttan uzun satırlar katlanır, çok baytlı UTF-8 karakterler bölünmez
+func WriteContentLine(b *strings.Builder, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // Devam satırındaki boşluk da sayılır
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Listelerde next_cursor/prev_cursor olarak dönen imleçler.
+// İçerik base64 JSON'dur ve HMAC-SHA256 ile imzalanır; istemci imleci
+// değiştiremez ve başka bir sorgunun (farklı filtre/sıralama) imlecini kullanamaz.
+
+var ErrInvalidCursor = &QueryError{Msg: "Geçersiz cursor"}
+
+var (
+	cursorKey     []byte
+	cursorKeyOnce sync.Once
+)
+
+// İmza anahtarını ayarlar (api servisi JWT secret'ından türetir).
+// Ayarlanmazsa süreç başına rastgele anahtar üretilir.
+func SetCursorKey(key []byte) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("list-cursor"))
+	cursorKey = mac.Sum(nil)
+}
+
+func getCursorKey() []byte {
+	cursorKeyOnce.Do(func() {
+		if cursorKey == nil {
+			cursorKey = make([]byte, 32)
+			rand.Read(cursorKey)
+		}
+	})
+	return cursorKey
+}
+
+// Sayfa sınırındaki satırın sıralama anahtarı
+type Cursor struct {
+	Values   []string `json:"v"`
+	Backward bool     `json:"b,omitempty"` // Önceki sayfa için
+	Scope    string   `json:"s"`           // Sorgu özeti
+}
+
+// İmleci scope'a (liste adı + filtre + sıralama) bağlayarak imzalar
+func EncodeCursor(scope string, c Cursor) string {
+	c.Scope = scopeDigest(scope)
+	payload, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(signCursor(body))
+}
+
+// İmzayı ve scope'u doğrular; boş token için nil döner
+func DecodeCursor(token, scope string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, signCursor(body)) {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Scope != scopeDigest(scope) {
+		return nil, &QueryError{Msg: "Cursor bu sorgu için geçersiz, filtre veya sıralama değişmiş"}
+	}
+	return &c, nil
+}
+
+// Sorgu sırasıyla okunan (en fazla limit+1) satırın anahtarlarından sayfa imleçlerini üretir.
+// Dönen n kadar satır tutulmalı; cur geri yönlüyse satırlar ayrıca ters çevrilmelidir.
+func PageCursors(scope string, cur *Cursor, keys [][]string, limit int) (n int, next, prev string) {
+	hasMore := len(keys) > limit
+	n = len(keys)
+	if hasMore {
+		n = limit
+	}
+	if n == 0 {
+		return 0, "", ""
+	}
+	first, last := keys[0], keys[n-1]
+	backward := cur != nil && cur.Backward
+	if backward {
+		first, last = last, first
+	}
+	// İleri giderken sonraki sayfa fazladan satır varsa, geri giderken her zaman vardır
+	if (!backward && hasMore) || backward {
+		next = EncodeCursor(scope, Cursor{Values: last})
+	}
+	if (backward && hasMore) || (!backward && cur != nil) {
+		prev = EncodeCursor(scope, Cursor{Values: first, Backward: true})
+	}
+	return n, next, prev
+}
+
+func signCursor(body string) []byte {
+	mac := hmac.New(sha256.New, getCursorKey())
+	mac.Write([]byte(body))
+	return mac.Sum(nil)[:16]
+}
+
+func scopeDigest(scope string) string {
+	sum := sha256.Sum256([]byte(scope))
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
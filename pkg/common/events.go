@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Varsayılan topic'e (crm-events) yazılan alan olayı zarfı; webhook servisi ve diğer tüketiciler bunu okur.
+// Type örnekleri: customer.created, customer.updated, deal.won
+type DomainEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	TenantID   int             `json:"tenant_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func NewDomainEvent(eventType string, tenantID int, data interface{}) (DomainEvent, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return DomainEvent{}, err
+	}
+	return DomainEvent{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		TenantID:   tenantID,
+		OccurredAt: time.Now().UTC(),
+		Data:       body,
+	}, nil
+}
+
+// Olayı arka planda yayınlar; Kafka hatası isteği etkilemez, sadece loglanır
+func PublishDomainEvent(key, eventType string, tenantID int, data interface{}) {
+	e, err := NewDomainEvent(eventType, tenantID, data)
+	if err != nil {
+		log.Printf("Olay oluşturulamadı (%s): %v", eventType, err)
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Olay oluşturulamadı (%s): %v", eventType, err)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := PublishEvent(ctx, key, string(payload)); err != nil {
+			log.Printf("Olay yayınlanamadı (%s): %v", eventType, err)
+		}
+	}()
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"os"
 	"time"
 
@@ -10,6 +11,11 @@ import (
 
 var kafkaWriter *kafka.Writer
 
+var errKafkaNotInitialized = errors.New("Kafka başlatılmadı")
+
+// Mesaj bazında topic seçilebilen writer (örn. notification.command)
+var kafkaTopicWriter *kafka.Writer
+
 // Kafka bağlantısını başlatır (singleton)
 func InitKafka() error {
 	addr := os.Getenv("KAFKA_ADDR")
@@ -25,14 +31,32 @@ func InitKafka() error {
 		Topic:    topic,
 		Balancer: &kafka.LeastBytes{},
 	}
+	kafkaTopicWriter = &kafka.Writer{
+		Addr:                   kafka.TCP(addr),
+		Balancer:               &kafka.LeastBytes{},
+		AllowAutoTopicCreation: true,
+	}
 	return nil
 }
 
 // Kafka'ya event publish eder
 func PublishEvent(ctx context.Context, key, value string) error {
+	if kafkaWriter == nil {
+		return errKafkaNotInitialized
+	}
 	return kafkaWriter.WriteMessages(ctx, kafka.Message{
 		Key:   []byte(key),
 		Value: []byte(value),
 		Time:  time.Now(),
 	})
 }
+
+// Varsayılan topic dışındaki bir topic'e mesaj yazar
+func PublishToTopic(ctx context.Context, topic, key, value string) error {
+	return kafkaTopicWriter.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: []byte(value),
+		Time:  time.Now(),
+	})
+}
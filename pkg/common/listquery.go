@@ -0,0 +1,448 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Liste uç noktalarının ortak filtre ve sıralama dili.
+//
+//	filter=created_at>=2025-01-01,phone:exists,tag:in:vip|gold,name:contains:ali
+//	sort=-created_at,name
+//
+// Koşullar virgülle ayrılır ve hepsi birlikte (VE) uygulanır. Değer içindeki
+// virgül ve | karakterleri \ ile kaçırılabilir. Alan isimleri her uç noktanın
+// beyaz listesinden (ListFields) gelir; değerler her zaman parametre olarak bağlanır.
+
+// Filtre operatörleri
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpIn       = "in"
+	OpNotIn    = "nin"
+	OpContains = "contains"
+	OpExists   = "exists"
+	OpMissing  = "missing"
+)
+
+// Alan tipleri (değerlerin nasıl çözüleceğini belirler)
+const (
+	ListString = "string"
+	ListNumber = "number"
+	ListTime   = "time" // YYYY-MM-DD veya RFC3339
+)
+
+// Karşılaştırma operatörlerinin kısa yazımları, uzun olanlar önce denenir
+var comparisonOps = []struct{ symbol, op string }{
+	{">=", OpGte}, {"<=", OpLte}, {"!=", OpNe}, {">", OpGt}, {"<", OpLt}, {"=", OpEq},
+}
+
+var sqlOps = map[string]string{OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+
+const (
+	maxFilterConditions = 20
+	maxSortKeys         = 3
+)
+
+// Filtre veya sıralama ifadesindeki kullanıcı hatası (HTTP 400)
+type QueryError struct {
+	Msg string
+}
+
+func (e *QueryError) Error() string { return e.Msg }
+
+func queryErrorf(format string, args ...interface{}) error {
+	return &QueryError{Msg: fmt.Sprintf(format, args...)}
+}
+
+type FilterCondition struct {
+	Field  string
+	Op     string
+	Values []string
+}
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Filtrelenebilir/sıralanabilir alan tanımı
+type ListField struct {
+	Column   string // SQL ifadesi, ör. "ct.created_at"
+	Type     string
+	Sortable bool
+	Nullable bool // Sıralamada NULL yerine tipin sıfır değeri kullanılır
+	// Standart karşılaştırma yerine özel SQL üreten alanlar için (ör. etiketler).
+	// Verildiğinde sadece Ops içindeki operatörler kabul edilir.
+	Build func(c FilterCondition, arg func(interface{}) string) string
+	Ops   []string
+}
+
+// Uç noktanın beyaz listesi: istekteki alan adı -> tanım
+type ListFields map[string]ListField
+
+// filter parametresini koşullara ayırır (alan adları henüz doğrulanmaz)
+func ParseFilter(s string) ([]FilterCondition, error) {
+	var conds []FilterCondition
+	for _, part := range splitEscaped(s, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		c, err := parseCondition(part)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+	if len(conds) > maxFilterConditions {
+		return nil, queryErrorf("En fazla %d filtre koşulu verilebilir", maxFilterConditions)
+	}
+	return conds, nil
+}
+
+func parseCondition(part string) (FilterCondition, error) {
+	// Uzun yazım: alan:operatör[:değer]
+	if i := strings.IndexByte(part, ':'); i > 0 && !strings.ContainsAny(part[:i], "<>=!") {
+		field := part[:i]
+		rest := part[i+1:]
+		op, value, hasValue := strings.Cut(rest, ":")
+		c := FilterCondition{Field: field, Op: op}
+		switch op {
+		case OpExists, OpMissing:
+			if hasValue {
+				return c, queryErrorf("%s operatörü değer almaz: %s", op, part)
+			}
+			return c, nil
+		case OpIn, OpNotIn:
+			for _, v := range splitEscaped(value, '|') {
+				c.Values = append(c.Values, unescape(v))
+			}
+		case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpContains:
+			c.Values = []string{unescape(value)}
+		default:
+			return c, queryErrorf("Bilinmeyen filtre operatörü: %s", op)
+		}
+		if !hasValue || value == "" {
+			return c, queryErrorf("Filtre değeri eksik: %s", part)
+		}
+		return c, nil
+	}
+	// Kısa yazım: alan>=değer
+	for _, o := range comparisonOps {
+		if i := strings.Index(part, o.symbol); i > 0 {
+			value := unescape(part[i+len(o.symbol):])
+			if value == "" {
+				return FilterCondition{}, queryErrorf("Filtre değeri eksik: %s", part)
+			}
+			return FilterCondition{Field: strings.TrimSpace(part[:i]), Op: o.op, Values: []string{value}}, nil
+		}
+	}
+	return FilterCondition{}, queryErrorf("Geçersiz filtre ifadesi: %s", part)
+}
+
+// sort parametresini ayırır; - öneki azalan sıralamadır
+func ParseSort(s string) ([]SortKey, error) {
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k := SortKey{Field: strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+"), Desc: strings.HasPrefix(part, "-")}
+		if seen[k.Field] {
+			return nil, queryErrorf("Sıralama alanı tekrarlandı: %s", k.Field)
+		}
+		seen[k.Field] = true
+		keys = append(keys, k)
+	}
+	if len(keys) > maxSortKeys {
+		return nil, queryErrorf("En fazla %d sıralama alanı verilebilir", maxSortKeys)
+	}
+	return keys, nil
+}
+
+// Koşulları beyaz listeye göre parametreli WHERE parçalarına çevirir
+func (f ListFields) Where(conds []FilterCondition, arg func(interface{}) string) ([]string, error) {
+	var where []string
+	for _, c := range conds {
+		field, ok := f[c.Field]
+		if !ok {
+			return nil, queryErrorf("Filtrelenemeyen alan: %s (izin verilenler: %s)", c.Field, f.names(false))
+		}
+		if field.Build != nil {
+			if !containsOp(field.Ops, c.Op) {
+				return nil, queryErrorf("%s alanı için geçersiz operatör: %s", c.Field, c.Op)
+			}
+			where = append(where, field.Build(c, arg))
+			continue
+		}
+		clause, err := field.compare(c, arg)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, clause)
+	}
+	return where, nil
+}
+
+func (field ListField) compare(c FilterCondition, arg func(interface{}) string) (string, error) {
+	col := field.Column
+	switch c.Op {
+	case OpExists, OpMissing:
+		clause := col + " IS NOT NULL"
+		if field.Type == ListString {
+			clause = "(" + col + " IS NOT NULL AND " + col + " <> '')"
+		}
+		if c.Op == OpMissing {
+			clause = "NOT " + clause
+		}
+		return clause, nil
+	case OpContains:
+		if field.Type != ListString {
+			return "", queryErrorf("contains sadece metin alanlarında kullanılabilir: %s", c.Field)
+		}
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(c.Values[0]))
+		return "LOWER(" + col + ") LIKE " + arg("%"+escaped+"%"), nil
+	case OpIn, OpNotIn:
+		var in []string
+		for _, raw := range c.Values {
+			v, err := field.parse(c.Field, raw)
+			if err != nil {
+				return "", err
+			}
+			in = append(in, arg(v))
+		}
+		op := " IN "
+		if c.Op == OpNotIn {
+			op = " NOT IN "
+		}
+		return col + op + "(" + strings.Join(in, ", ") + ")", nil
+	default:
+		if (c.Op != OpEq && c.Op != OpNe) && field.Type == ListString {
+			return "", queryErrorf("%s metin alanı için büyüklük karşılaştırması yapılamaz", c.Field)
+		}
+		v, err := field.parse(c.Field, c.Values[0])
+		if err != nil {
+			return "", err
+		}
+		return col + " " + sqlOps[c.Op] + " " + arg(v), nil
+	}
+}
+
+func (field ListField) parse(name, raw string) (interface{}, error) {
+	switch field.Type {
+	case ListNumber:
+		// Tamsayı sütunlarıyla karşılaştırma için mümkünse tamsayı bağlanır
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n, nil
+		}
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, queryErrorf("%s sayı olmalı: %s", name, raw)
+		}
+		return n, nil
+	case ListTime:
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, queryErrorf("%s YYYY-MM-DD veya RFC3339 biçiminde olmalı: %s", name, raw)
+		}
+		return t, nil
+	}
+	return raw, nil
+}
+
+// Sıralama anahtarlarını ORDER BY ifadesine çevirir. Anahtar yoksa def kullanılır;
+// sıralamanın kararlı olması için sonuna her zaman tiebreaker (ör. "id") eklenir.
+func (f ListFields) OrderBy(keys []SortKey, def []SortKey, tiebreaker string) (string, error) {
+	ks, err := f.Keyset(keys, def, tiebreaker)
+	if err != nil {
+		return "", err
+	}
+	return ks.OrderBy(false), nil
+}
+
+// Keyset sayfalama için çözülmüş sıralama: her anahtarın SQL ifadesi ve yönü
+type Keyset struct {
+	exprs []string
+	desc  []bool
+}
+
+// Sıralama anahtarlarını doğrular, tiebreaker'ı son anahtarın yönüyle ekler
+func (f ListFields) Keyset(keys []SortKey, def []SortKey, tiebreaker string) (*Keyset, error) {
+	if len(keys) == 0 {
+		keys = def
+	}
+	ks := &Keyset{}
+	hasTiebreaker := false
+	for _, k := range keys {
+		field, ok := f[k.Field]
+		if !ok || !field.Sortable {
+			return nil, queryErrorf("Sıralanamayan alan: %s (izin verilenler: %s)", k.Field, f.names(true))
+		}
+		ks.exprs = append(ks.exprs, field.sortExpr())
+		ks.desc = append(ks.desc, k.Desc)
+		if k.Field == tiebreaker {
+			hasTiebreaker = true
+		}
+	}
+	if !hasTiebreaker {
+		ks.exprs = append(ks.exprs, f[tiebreaker].sortExpr())
+		ks.desc = append(ks.desc, len(ks.desc) > 0 && ks.desc[len(ks.desc)-1])
+	}
+	return ks, nil
+}
+
+// NULL değerler keyset karşılaştırmasını bozacağından tipin sıfır değeriyle değiştirilir
+func (field ListField) sortExpr() string {
+	if !field.Nullable {
+		return field.Column
+	}
+	switch field.Type {
+	case ListNumber:
+		return "COALESCE(" + field.Column + ", 0)"
+	case ListTime:
+		return "COALESCE(" + field.Column + ", 'epoch'::timestamptz)"
+	}
+	return "COALESCE(" + field.Column + ", '')"
+}
+
+// ORDER BY ifadesi; backward önceki sayfa için sıralamayı tersine çevirir
+func (k *Keyset) OrderBy(backward bool) string {
+	parts := make([]string, len(k.exprs))
+	for i, expr := range k.exprs {
+		dir := " ASC"
+		if k.desc[i] != backward {
+			dir = " DESC"
+		}
+		parts[i] = expr + dir
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Satırın anahtar değerlerini okumak için SELECT listesine eklenecek ifadeler
+func (k *Keyset) Columns() string {
+	return strings.Join(k.exprs, ", ")
+}
+
+// Anahtar sütunlarını taramak için hedefler (Scan'e extra olarak verilir)
+func (k *Keyset) Dest() []interface{} {
+	dest := make([]interface{}, len(k.exprs))
+	for i := range dest {
+		dest[i] = new(interface{})
+	}
+	return dest
+}
+
+// Dest ile taranan değerleri imlece yazılacak metinlere çevirir
+func (k *Keyset) Values(dest []interface{}) []string {
+	values := make([]string, len(dest))
+	for i, d := range dest {
+		switch v := (*d.(*interface{})).(type) {
+		case time.Time:
+			values[i] = v.Format(time.RFC3339Nano)
+		case []byte:
+			values[i] = string(v)
+		default:
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
+
+// İmleçteki satırdan sonra (backward ise önce) gelen satırlar için WHERE parçası.
+// Tüm anahtarlar aynı yöndeyse index dostu satır karşılaştırması kullanılır.
+func (k *Keyset) After(c *Cursor, arg func(interface{}) string) (string, error) {
+	if len(c.Values) != len(k.exprs) {
+		return "", ErrInvalidCursor
+	}
+	params := make([]string, len(c.Values))
+	for i, v := range c.Values {
+		params[i] = arg(v)
+	}
+	op := func(i int) string {
+		if k.desc[i] != c.Backward {
+			return " < "
+		}
+		return " > "
+	}
+	sameDir := true
+	for i := range k.desc {
+		sameDir = sameDir && k.desc[i] == k.desc[0]
+	}
+	if sameDir {
+		return "(" + strings.Join(k.exprs, ", ") + ")" + op(0) + "(" + strings.Join(params, ", ") + ")", nil
+	}
+	// (a > $1) OR (a = $1 AND b < $2) OR ...
+	var ors []string
+	for i := range k.exprs {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, k.exprs[j]+" = "+params[j])
+		}
+		ands = append(ands, k.exprs[i]+op(i)+params[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", nil
+}
+
+func (f ListFields) names(sortable bool) string {
+	var names []string
+	for name, field := range f {
+		if !sortable || field.Sortable {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func containsOp(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// sep ile ayırır, \ ile kaçırılmış ayırıcıları bölmez (kaçış karakterleri korunur)
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Oturum gerektirmeyen, süreli indirme bağlantıları (ör. dışa aktarma dosyaları).
+// Bağlantı yolu ve son geçerlilik zamanı HMAC-SHA256 ile imzalanır.
+
+var (
+	ErrLinkExpired = errors.New("Bağlantının süresi dolmuş")
+	ErrLinkInvalid = errors.New("Geçersiz bağlantı imzası")
+)
+
+var (
+	urlKey     []byte
+	urlKeyOnce sync.Once
+)
+
+// İmza anahtarını ayarlar (api servisi JWT secret'ından türetir).
+// Ayarlanmazsa süreç başına rastgele anahtar üretilir; yeniden başlatmada bağlantılar geçersiz olur.
+func SetURLSigningKey(key []byte) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("signed-url"))
+	urlKey = mac.Sum(nil)
+}
+
+func getURLKey() []byte {
+	urlKeyOnce.Do(func() {
+		if urlKey == nil {
+			urlKey = make([]byte, 32)
+			rand.Read(urlKey)
+		}
+	})
+	return urlKey
+}
+
+// Yola expires ve signature parametrelerini ekler
+func SignURL(path string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return fmt.Sprintf("%s?expires=%s&signature=%s", path, exp, signPath(path, exp))
+}
+
+// İmzayı ve süreyi doğrular; path sorgu parametreleri olmadan istek yoludur
+func VerifySignedURL(path string, q url.Values) error {
+	exp := q.Get("expires")
+	got, err := base64.RawURLEncoding.DecodeString(q.Get("signature"))
+	if err != nil || exp == "" {
+		return ErrLinkInvalid
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(signPath(path, exp))
+	if !hmac.Equal(got, want) {
+		return ErrLinkInvalid
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrLinkInvalid
+	}
+	if time.Now().Unix() > unix {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+func signPath(path, expires string) string {
+	mac := hmac.New(sha256.New, getURLKey())
+	mac.Write([]byte(path + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,196 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler bu hatayı (sarmalanmış olarak) dönerse mesaj kalıcı olarak reddedilir (554);
+// diğer hatalarda gönderen sunucu daha sonra tekrar dener (451)
+var ErrMessageRejected = errors.New("mesaj reddedildi")
+
+// Sadece mesaj almak için minimal SMTP sunucusu (RFC 5321): HELO/EHLO, MAIL, RCPT, DATA, RSET, NOOP, QUIT.
+// Röle yapmaz; kimlik doğrulama ve TLS önündeki MTA'ya bırakılır.
+type SMTPServer struct {
+	Addr     string
+	Hostname string
+	MaxSize  int64 // Bayt; 0 ise 25MB
+	// Kabul edilecek alıcılar; nil ise her alıcı kabul edilir
+	AcceptRcpt func(addr string) bool
+	Handler    func(from string, to []string, data []byte) error
+}
+
+const (
+	smtpMaxRecipients  = 100
+	smtpCommandTimeout = 5 * time.Minute
+)
+
+func (s *SMTPServer) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Bağlantıları l kapatılana kadar kabul eder
+func (s *SMTPServer) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *SMTPServer) maxSize() int64 {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return 25 << 20
+}
+
+func (s *SMTPServer) serveConn(c net.Conn) {
+	defer c.Close()
+	tp := textproto.NewConn(c)
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	reply := func(format string, args ...interface{}) {
+		tp.PrintfLine(format, args...)
+	}
+	var (
+		from string
+		to   []string
+		mail bool
+	)
+	reset := func() {
+		from, to, mail = "", nil, false
+	}
+	reply("220 %s ESMTP Go-CRM", hostname)
+	for {
+		c.SetDeadline(time.Now().Add(smtpCommandTimeout))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reset()
+			reply("250 %s", hostname)
+		case "EHLO":
+			reset()
+			reply("250-%s", hostname)
+			reply("250-SIZE %d", s.maxSize())
+			reply("250-8BITMIME")
+			reply("250 PIPELINING")
+		case "MAIL":
+			addr, params, ok := smtpPath(arg, "FROM:")
+			if !ok {
+				reply("501 5.5.4 MAIL FROM:<adres> bekleniyor")
+				continue
+			}
+			if size, err := strconv.ParseInt(params["SIZE"], 10, 64); err == nil && size > s.maxSize() {
+				reply("552 5.3.4 Mesaj çok büyük")
+				continue
+			}
+			reset()
+			from, mail = addr, true
+			reply("250 2.1.0 Tamam")
+		case "RCPT":
+			if !mail {
+				reply("503 5.5.1 Önce MAIL gerekli")
+				continue
+			}
+			addr, _, ok := smtpPath(arg, "TO:")
+			if !ok || addr == "" {
+				reply("501 5.5.4 RCPT TO:<adres> bekleniyor")
+				continue
+			}
+			if len(to) >= smtpMaxRecipients {
+				reply("452 4.5.3 Çok fazla alıcı")
+				continue
+			}
+			if s.AcceptRcpt != nil && !s.AcceptRcpt(addr) {
+				reply("550 5.1.1 Alıcı kabul edilmiyor")
+				continue
+			}
+			to = append(to, addr)
+			reply("250 2.1.5 Tamam")
+		case "DATA":
+			if len(to) == 0 {
+				reply("503 5.5.1 Önce RCPT gerekli")
+				continue
+			}
+			reply("354 Mesajı gönderin, <CRLF>.<CRLF> ile bitirin")
+			dr := tp.DotReader()
+			data, err := io.ReadAll(io.LimitReader(dr, s.maxSize()+1))
+			if err != nil {
+				return
+			}
+			if int64(len(data)) > s.maxSize() {
+				io.Copy(io.Discard, dr)
+				reset()
+				reply("552 5.3.4 Mesaj çok büyük")
+				continue
+			}
+			err = s.Handler(from, to, data)
+			reset()
+			switch {
+			case err == nil:
+				reply("250 2.0.0 Mesaj alındı")
+			case errors.Is(err, ErrMessageRejected):
+				reply("554 5.6.0 %s", oneLine(err.Error()))
+			default:
+				log.Printf("SMTP mesajı işlenemedi: %v", err)
+				reply("451 4.3.0 Geçici hata, daha sonra tekrar deneyin")
+			}
+		case "RSET":
+			reset()
+			reply("250 2.0.0 Tamam")
+		case "NOOP":
+			reply("250 2.0.0 Tamam")
+		case "VRFY":
+			reply("252 2.1.5 Doğrulanamıyor")
+		case "QUIT":
+			reply("221 2.0.0 Güle güle")
+			return
+		default:
+			reply("502 5.5.2 Desteklenmeyen komut")
+		}
+	}
+}
+
+// "FROM:<a@b.com> SIZE=123" -> ("a@b.com", {"SIZE": "123"})
+func smtpPath(arg, prefix string) (string, map[string]string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+	params := map[string]string{}
+	for _, p := range strings.Fields(rest[end+1:]) {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = v
+	}
+	return rest[1:end], params, true
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
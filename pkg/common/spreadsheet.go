@@ -0,0 +1,513 @@
+package common
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// İçe/dışa aktarılan tablo biçimleri
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Tabloyu satır satır okur; tüm dosyayı belleğe almaz. Okuma bitince io.EOF döner.
+type RowReader interface {
+	Read() ([]string, error)
+	Close() error
+}
+
+// Dosya adının uzantısından biçimi bulur
+func SpreadsheetFormat(filename string) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	return "", errors.New("Desteklenmeyen dosya biçimi, CSV veya XLSX yükleyin")
+}
+
+// Diskteki CSV veya XLSX dosyasını açar. XLSX'te ilk çalışma sayfası okunur.
+func OpenSpreadsheet(filename, format string) (RowReader, error) {
+	switch format {
+	case FormatCSV:
+		return openCSV(filename)
+	case FormatXLSX:
+		return openXLSX(filename)
+	}
+	return nil, fmt.Errorf("desteklenmeyen biçim: %s", format)
+}
+
+type csvRowReader struct {
+	f *os.File
+	r *csv.Reader
+}
+
+func (c *csvRowReader) Read() ([]string, error) { return c.r.Read() }
+func (c *csvRowReader) Close() error            { return c.f.Close() }
+
+// Excel'in Türkçe yerel ayarı CSV'yi ; ile kaydettiğinden ayırıcı ilk satırdan tahmin edilir
+func openCSV(filename string) (RowReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+	first, _ := br.Peek(4096)
+	if i := bytes.IndexByte(first, '\n'); i >= 0 {
+		first = first[:i]
+	}
+	r := csv.NewReader(br)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	if bytes.Count(first, []byte{';'}) > bytes.Count(first, []byte{','}) {
+		r.Comma = ';'
+	}
+	return &csvRowReader{f: f, r: r}, nil
+}
+
+// XLSX okuyucu: sharedStrings ve stiller belleğe alınır, sayfa XML'i akış halinde okunur
+type xlsxRowReader struct {
+	zr      *zip.ReadCloser
+	sheet   io.ReadCloser
+	dec     *xml.Decoder
+	strings []string
+	dateFmt map[int]bool // Tarih biçimli stil indeksleri
+	nextRow int          // Boş satırların atlanmaması için beklenen satır numarası
+	pending []string     // Araya boş satırlar eklendikten sonra dönülecek satır
+	pendNum int
+}
+
+// Düz (t) veya zengin metin parçalı (r) hücre metni
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.R {
+		s += r.T
+	}
+	return s
+}
+
+func openXLSX(filename string) (RowReader, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, errors.New("Geçersiz XLSX dosyası")
+	}
+	x := &xlsxRowReader{zr: zr, nextRow: 1}
+	if err := x.load(); err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxRowReader) load() error {
+	files := map[string]*zip.File{}
+	for _, f := range x.zr.File {
+		files[f.Name] = f
+	}
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return err
+	}
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return errors.New("XLSX dosyasında çalışma sayfası bulunamadı")
+	}
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if x.strings, err = readSharedStrings(f); err != nil {
+			return err
+		}
+	}
+	if f, ok := files["xl/styles.xml"]; ok {
+		if x.dateFmt, err = readDateStyles(f); err != nil {
+			return err
+		}
+	}
+	if x.sheet, err = sheet.Open(); err != nil {
+		return err
+	}
+	x.dec = xml.NewDecoder(x.sheet)
+	return nil
+}
+
+// workbook.xml'deki ilk sayfanın dosya yolu
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var wb struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &wb); err != nil || len(wb.Sheets) == 0 {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	for _, r := range rels.Rels {
+		if r.ID == wb.Sheets[0].RID {
+			if strings.HasPrefix(r.Target, "/") {
+				return strings.TrimPrefix(r.Target, "/"), nil
+			}
+			return path.Join("xl", r.Target), nil
+		}
+	}
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return errors.New("dosya yok")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := decodeZipXML(f, &sst); err != nil {
+		return nil, err
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		out[i] = si.String()
+	}
+	return out, nil
+}
+
+// Excel'in yerleşik tarih biçimleri (14-22, 45-47) ve tarih içeren özel biçimler
+func readDateStyles(f *zip.File) (map[int]bool, error) {
+	var st struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipXML(f, &st); err != nil {
+		return nil, err
+	}
+	custom := map[int]bool{}
+	for _, n := range st.NumFmts {
+		code := strings.ToLower(n.Code)
+		custom[n.ID] = strings.Contains(code, "yy") || (strings.Contains(code, "d") && strings.Contains(code, "m"))
+	}
+	dates := map[int]bool{}
+	for i, xf := range st.Xfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || custom[id] {
+			dates[i] = true
+		}
+	}
+	return dates, nil
+}
+
+func (x *xlsxRowReader) Read() ([]string, error) {
+	if x.pending != nil {
+		x.nextRow++
+		if x.nextRow < x.pendNum {
+			return []string{}, nil
+		}
+		row := x.pending
+		x.pending = nil
+		x.nextRow++
+		return row, nil
+	}
+	for {
+		tok, err := x.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "row" {
+			continue
+		}
+		row, err := x.readRow()
+		if err != nil {
+			return nil, err
+		}
+		// Satır numarası atlanmışsa arada boş satırlar vardır, satır numaraları korunur
+		if n, err := strconv.Atoi(attr(se, "r")); err == nil && n > x.nextRow {
+			x.pending, x.pendNum = row, n
+			return []string{}, nil
+		}
+		x.nextRow++
+		return row, nil
+	}
+}
+
+// <row> içindeki hücreleri sütun harfine göre yerleştirir
+func (x *xlsxRowReader) readRow() ([]string, error) {
+	var row []string
+	for {
+		tok, err := x.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			var cell struct {
+				V  string   `xml:"v"`
+				IS xlsxText `xml:"is"`
+			}
+			if err := x.dec.DecodeElement(&cell, &t); err != nil {
+				return nil, err
+			}
+			col := len(row)
+			if ref := attr(t, "r"); ref != "" {
+				col = columnIndex(ref)
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			row[col] = x.cellValue(attr(t, "t"), attr(t, "s"), cell.V, cell.IS)
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return row, nil
+			}
+		}
+	}
+}
+
+func (x *xlsxRowReader) cellValue(typ, style, v string, inline xlsxText) string {
+	switch typ {
+	case "s":
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= len(x.strings) {
+			return ""
+		}
+		return x.strings[i]
+	case "inlineStr":
+		return inline.String()
+	case "b":
+		if v == "1" {
+			return "true"
+		}
+		return "false"
+	case "", "n":
+		if s, err := strconv.Atoi(style); err == nil && x.dateFmt[s] {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return excelDate(f)
+			}
+		}
+	}
+	return v
+}
+
+// Excel seri tarihini (1900 sistemi) YYYY-MM-DD'ye çevirir
+func excelDate(serial float64) string {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	days := math.Floor(serial)
+	t := base.AddDate(0, 0, int(days))
+	if frac := serial - days; frac > 0 {
+		t = t.Add(time.Duration(math.Round(frac*86400)) * time.Second)
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return t.Format("2006-01-02")
+}
+
+// "AB12" -> 27
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
+
+func attr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (x *xlsxRowReader) Close() error {
+	if x.sheet != nil {
+		x.sheet.Close()
+	}
+	return x.zr.Close()
+}
+
+// Tabloyu satır satır yazar; satırlar hedefe akış halinde gönderilir. Close bitirir (XLSX'te zip kapanır).
+type RowWriter interface {
+	Write(row []string) error
+	Close() error
+}
+
+// CSV veya XLSX yazıcı oluşturur
+func NewSpreadsheetWriter(w io.Writer, format string) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		// Excel'in Türkçe karakterleri doğru açması için UTF-8 BOM
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{w: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("desteklenmeyen biçim: %s", format)
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) Write(row []string) error {
+	safe := make([]string, len(row))
+	for i, v := range row {
+		safe[i] = csvSafeCell(v)
+	}
+	return c.w.Write(safe)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Tablo programlarında formül olarak çalışabilecek hücrelerin başına ' eklenir.
+// "+90 532 ..." gibi telefon ve "-12,5" gibi sayılar olduğu gibi bırakılır.
+func csvSafeCell(v string) string {
+	if v == "" {
+		return v
+	}
+	switch v[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + v
+	case '+', '-':
+		if strings.Trim(v[1:], "0123456789 .,()") != "" {
+			return "'" + v
+		}
+	}
+	return v
+}
+
+// Excel'in sayfa başına satır sınırı
+const maxXLSXRows = 1048576
+
+// Tek sayfalı XLSX yazıcı: hücreler satır içi metin (inlineStr) olarak yazılır,
+// bu sayede paylaşılan metin tablosu için tüm veriyi bellekte tutmak gerekmez
+type xlsxRowWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func newXLSXWriter(w io.Writer) (RowWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, p := range xlsxStaticParts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+	// Sayfa en son yazılır; zip girdileri sırayla yazıldığından açık kalabilir
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxRowWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxRowWriter) Write(row []string) error {
+	if x.rows >= maxXLSXRows {
+		return fmt.Errorf("XLSX en fazla %d satır içerebilir, CSV veya JSONL kullanın", maxXLSXRows)
+	}
+	x.rows++
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<row r="%d">`, x.rows)
+	for i, v := range row {
+		if v == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, columnName(i), x.rows)
+		if err := xml.EscapeText(&b, []byte(v)); err != nil {
+			return err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	_, err := x.sheet.Write(b.Bytes())
+	return err
+}
+
+func (x *xlsxRowWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// 27 -> "AB" (columnIndex'in tersi)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,270 @@
+package customer
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Müşteri ve iletişim kaydı ekleri (sözleşme, teklif vb.). Dosya içeriği depolama sürücüsünde,
+// üst verisi attachments tablosunda tutulur.
+
+const (
+	MaxAttachmentSize = 20 << 20
+	attachmentLinkTTL = 5 * time.Minute
+	maxFilenameLength = 255
+
+	ScanClean   = "clean"
+	ScanSkipped = "skipped"
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("Dosya bulunamadı")
+	ErrAttachmentForbidden = errors.New("Dosyayı sadece yükleyen veya bir yönetici silebilir")
+	ErrAttachmentType      = errors.New("Bu dosya türüne izin verilmiyor")
+	ErrAttachmentTooLarge  = errors.New("Dosya en fazla 20MB olabilir")
+	ErrStorageDisabled     = errors.New("Dosya depolama yapılandırılmamış")
+)
+
+// Eski Office (doc, xls, ppt) ve Outlook (msg) dosyalarının ortak kapsayıcısı; http.DetectContentType tanımaz
+const oleContentType = "application/x-ole-storage"
+
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// İzin verilen uzantılar: içeriğin koklanan türü (sniffed) uzantıyla uyuşmalıdır, saklanan tür contentType'tır
+var attachmentTypes = map[string]struct{ sniffed, contentType string }{
+	".pdf":  {"application/pdf", "application/pdf"},
+	".png":  {"image/png", "image/png"},
+	".jpg":  {"image/jpeg", "image/jpeg"},
+	".jpeg": {"image/jpeg", "image/jpeg"},
+	".gif":  {"image/gif", "image/gif"},
+	".webp": {"image/webp", "image/webp"},
+	".txt":  {"text/plain", "text/plain; charset=utf-8"},
+	".csv":  {"text/plain", "text/csv; charset=utf-8"},
+	".eml":  {"text/plain", "message/rfc822"},
+	".zip":  {"application/zip", "application/zip"},
+	".docx": {"application/zip", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	".xlsx": {"application/zip", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	".pptx": {"application/zip", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+	".odt":  {"application/zip", "application/vnd.oasis.opendocument.text"},
+	".ods":  {"application/zip", "application/vnd.oasis.opendocument.spreadsheet"},
+	".doc":  {oleContentType, "application/msword"},
+	".xls":  {oleContentType, "application/vnd.ms-excel"},
+	".ppt":  {oleContentType, "application/vnd.ms-powerpoint"},
+	".msg":  {oleContentType, "application/vnd.ms-outlook"},
+}
+
+// Dosya adı ve ilk baytlara göre saklanacak içerik türü. Uzantı listede olmalı ve içerik
+// uzantıyla uyuşmalıdır; ör. .pdf uzantılı bir HTML veya çalıştırılabilir dosya reddedilir.
+func DetectAttachmentType(filename string, head []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	t, ok := attachmentTypes[ext]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrAttachmentType, ext)
+	}
+	sniffed := http.DetectContentType(head)
+	if bytes.HasPrefix(head, oleMagic) {
+		sniffed = oleContentType
+	}
+	if base, _, _ := strings.Cut(sniffed, ";"); base != t.sniffed {
+		return "", fmt.Errorf("%w: dosya içeriği (%s) %s uzantısıyla uyuşmuyor", ErrAttachmentType, base, ext)
+	}
+	return t.contentType, nil
+}
+
+// Yol bölümleri ve kontrol karakterleri atılır; indirmede Content-Disposition'a güvenle yazılabilir
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if r := []rune(name); len(r) > maxFilenameLength {
+		ext := []rune(filepath.Ext(name))
+		name = string(r[:maxFilenameLength-len(ext)]) + string(ext)
+	}
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+type AttachmentUpload struct {
+	CustomerID int
+	ContactID  int // İsteğe bağlı; kayıt aynı müşteriye ait olmalı
+	Filename   string
+	Body       io.Reader
+}
+
+// Dosyayı doğrular, tarar ve depoya yazar. İçerik önce geçici dosyaya alınır (boyut sınırı, özet,
+// tür koklama ve tarama için); üst veri kaydı başarısız olursa depodaki dosya silinir.
+func UploadAttachment(ctx context.Context, db *sql.DB, store storage.Storage, scanner storage.Scanner,
+	user common.AuthUser, up AttachmentUpload) (Attachment, error) {
+	if store == nil {
+		return Attachment{}, ErrStorageDisabled
+	}
+	a := Attachment{CustomerID: up.CustomerID, ContactID: up.ContactID, Filename: cleanFilename(up.Filename),
+		UploadedBy: user.ID, TenantID: tenantOf(user), ScanStatus: ScanSkipped}
+	if a.Filename == "" {
+		return Attachment{}, errors.New("Dosya adı gerekli")
+	}
+	if _, ok := attachmentTypes[strings.ToLower(filepath.Ext(a.Filename))]; !ok {
+		return Attachment{}, fmt.Errorf("%w: %q", ErrAttachmentType, filepath.Ext(a.Filename))
+	}
+	if err := checkAttachmentTarget(db, user, a.CustomerID, a.ContactID); err != nil {
+		return Attachment{}, err
+	}
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(up.Body, MaxAttachmentSize+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if n > MaxAttachmentSize {
+		return Attachment{}, ErrAttachmentTooLarge
+	}
+	if n == 0 {
+		return Attachment{}, errors.New("Dosya boş")
+	}
+	a.Size, a.SHA256 = n, hex.EncodeToString(hash.Sum(nil))
+
+	head := make([]byte, 512)
+	m, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return Attachment{}, err
+	}
+	if a.ContentType, err = DetectAttachmentType(a.Filename, head[:m]); err != nil {
+		return Attachment{}, err
+	}
+	if scanner != nil {
+		if err := scanner.Scan(ctx, io.NewSectionReader(tmp, 0, n)); err != nil {
+			return Attachment{}, err
+		}
+		a.ScanStatus = ScanClean
+	}
+
+	a.StorageKey = fmt.Sprintf("tenants/%d/customers/%d/%s", a.TenantID, a.CustomerID, uuid.NewString())
+	if err := store.Put(ctx, a.StorageKey, io.NewSectionReader(tmp, 0, n), n, a.ContentType); err != nil {
+		return Attachment{}, err
+	}
+	if err := createAttachmentRepo(db, &a); err != nil {
+		if derr := store.Delete(context.Background(), a.StorageKey); derr != nil {
+			log.Printf("Kaydedilemeyen ekin dosyası silinemedi (%s): %v", a.StorageKey, derr)
+		}
+		return Attachment{}, err
+	}
+	signAttachment(&a)
+	return a, nil
+}
+
+// Müşteri oturumun tenant'ında olmalı; iletişim kaydı verildiyse bu müşteriye ait olmalı
+func checkAttachmentTarget(db *sql.DB, user common.AuthUser, customerID, contactID int) error {
+	c, err := getCustomerRepo(db, customerID)
+	if err == sql.ErrNoRows || (err == nil && c.TenantID != tenantOf(user)) {
+		return ErrCustomerNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if contactID > 0 {
+		owner, err := contactCustomerRepo(db, contactID)
+		if err == sql.ErrNoRows || (err == nil && owner != customerID) {
+			return ErrContactNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Müşterinin ekleri, en yeniden eskiye; contactID verilirse sadece o kaydın ekleri
+func GetAttachments(db *sql.DB, user common.AuthUser, customerID, contactID int) ([]Attachment, error) {
+	if err := checkAttachmentTarget(db, user, customerID, contactID); err != nil {
+		return nil, err
+	}
+	attachments, err := getAttachmentsRepo(db, customerID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		signAttachment(&attachments[i])
+	}
+	return attachments, nil
+}
+
+func GetAttachment(db *sql.DB, user common.AuthUser, id int) (Attachment, error) {
+	a, err := getAttachmentRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && a.TenantID != tenantOf(user)) {
+		return Attachment{}, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return Attachment{}, err
+	}
+	signAttachment(&a)
+	return a, nil
+}
+
+// Eki sadece yükleyen veya bir yönetici silebilir; kayıt silindikten sonra dosya depodan kaldırılır
+func DeleteAttachment(ctx context.Context, db *sql.DB, store storage.Storage, user common.AuthUser, id int) error {
+	a, err := GetAttachment(db, user, id)
+	if err != nil {
+		return err
+	}
+	if a.UploadedBy != user.ID && !user.IsManager() {
+		return ErrAttachmentForbidden
+	}
+	if err := deleteAttachmentRepo(db, id); err != nil {
+		return err
+	}
+	if store != nil {
+		if err := store.Delete(ctx, a.StorageKey); err != nil {
+			log.Printf("Ek dosyası depodan silinemedi (%s): %v", a.StorageKey, err)
+		}
+	}
+	return nil
+}
+
+func attachmentDownloadPath(id int) string {
+	return fmt.Sprintf("/api/attachments/%d/download", id)
+}
+
+func signAttachment(a *Attachment) {
+	a.DownloadURL = common.SignURL(attachmentDownloadPath(a.ID), time.Now().Add(attachmentLinkTTL))
+}
+
+// İmzalı bağlantıyla istenen ekin üst verisi; bağlantı oturum gerektirmez
+func GetAttachmentDownload(db *sql.DB, id int, q url.Values) (Attachment, error) {
+	if err := common.VerifySignedURL(attachmentDownloadPath(id), q); err != nil {
+		return Attachment{}, err
+	}
+	a, err := getAttachmentRepo(db, id)
+	if err == sql.ErrNoRows {
+		return Attachment{}, ErrAttachmentNotFound
+	}
+	return a, err
+}
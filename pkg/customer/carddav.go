@@ -0,0 +1,387 @@
+package customer
+
+import (
+	"Go-CRM/pkg/common"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Salt okunur CardDAV (RFC 6352) adres defteri. Kullanıcının sorumlu olduğu müşteriler
+// /carddav/addressbooks/customers/ altında vCard 3.0 olarak sunulur.
+//
+//	/carddav/                              principal
+//	/carddav/addressbooks/                 adres defteri evi
+//	/carddav/addressbooks/customers/       adres defteri
+//	/carddav/addressbooks/customers/{id}.vcf
+
+const (
+	davPrefix        = "/carddav"
+	davHomePath      = davPrefix + "/addressbooks/"
+	davBookPath      = davHomePath + "customers/"
+	davNS            = "DAV:"
+	cardDAVNS        = "urn:ietf:params:xml:ns:carddav"
+	calendarServerNS = "http://calendarserver.org/ns/"
+)
+
+var davPrefixes = map[string]string{davNS: "D", cardDAVNS: "C", calendarServerNS: "CS"}
+
+type davResource int
+
+const (
+	davPrincipal davResource = iota
+	davHome
+	davBook
+	davCard
+)
+
+// Adres defterindeki kart ve içeriğinin özeti (ETag)
+type addressCard struct {
+	customer Customer
+	data     string
+	etag     string
+}
+
+func newAddressCard(c Customer) addressCard {
+	data := MarshalVCard(c, VCard3)
+	sum := sha256.Sum256([]byte(data))
+	return addressCard{customer: c, data: data, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+}
+
+func cardHref(id int) string {
+	return davBookPath + strconv.Itoa(id) + ".vcf"
+}
+
+// Adres defterinin tüm kartları; CTag kartların ETag'lerinden türetilir, herhangi bir değişiklikte değişir
+func (h *Handler) addressBook(user common.AuthUser) ([]addressCard, string, error) {
+	var cards []addressCard
+	ctag := sha256.New()
+	where := []string{"tenant_id = $1", "owner_id = $2"}
+	err := eachCustomerRepo(h.DBReplica, where, []interface{}{tenantOf(user), user.ID}, "id", func(c Customer, _ sql.NullTime) error {
+		card := newAddressCard(c)
+		cards = append(cards, card)
+		fmt.Fprintf(ctag, "%d%s", c.ID, card.etag)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return cards, `"` + hex.EncodeToString(ctag.Sum(nil)[:8]) + `"`, nil
+}
+
+// CardDAV istekleri (/carddav/...); kimlik doğrulama cmd/api'deki middleware'de yapılır
+func (h *Handler) CardDAVHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Go-CRM"`)
+		http.Error(w, "Yetkilendirme gerekli", http.StatusUnauthorized)
+		return
+	}
+	res, id, ok := parseDAVPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("DAV", "1, 3, addressbook")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		h.davGet(w, r, user, res, id)
+	case "PROPFIND":
+		h.davPropfind(w, r, user, res, id)
+	case "REPORT":
+		h.davReport(w, r, user, res)
+	default:
+		// Adres defteri şimdilik salt okunur
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		http.Error(w, "Adres defteri salt okunur", http.StatusMethodNotAllowed)
+	}
+}
+
+func parseDAVPath(p string) (davResource, int, bool) {
+	p = strings.TrimPrefix(p, davPrefix)
+	switch strings.TrimSuffix(p, "/") {
+	case "":
+		return davPrincipal, 0, true
+	case "/addressbooks":
+		return davHome, 0, true
+	case "/addressbooks/customers":
+		return davBook, 0, true
+	}
+	name, ok := strings.CutPrefix(p, "/addressbooks/customers/")
+	if !ok || !strings.HasSuffix(name, ".vcf") {
+		return 0, 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSuffix(name, ".vcf"))
+	if err != nil || id <= 0 {
+		return 0, 0, false
+	}
+	return davCard, id, true
+}
+
+func (h *Handler) davGet(w http.ResponseWriter, r *http.Request, user common.AuthUser, res davResource, id int) {
+	if res != davCard {
+		http.Error(w, "Koleksiyon içeriği PROPFIND ile listelenir", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := GetCustomer(h.DBReplica, user, id)
+	if err != nil || c.OwnerID != user.ID {
+		http.NotFound(w, r)
+		return
+	}
+	card := newAddressCard(c)
+	w.Header().Set("ETag", card.etag)
+	if r.Header.Get("If-None-Match") == card.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+	if r.Method == http.MethodGet {
+		io.WriteString(w, card.data)
+	}
+}
+
+// PROPFIND gövdesinde istenen özellikler; boş gövde veya allprop'ta nil döner
+func requestedProps(r *http.Request) ([]xml.Name, []string, error) {
+	var req struct {
+		Prop *struct {
+			Any []struct {
+				XMLName xml.Name
+			} `xml:",any"`
+		} `xml:"prop"`
+		Hrefs []string `xml:"href"`
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.TrimSpace(string(body)) == "" {
+		return nil, nil, nil
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return nil, nil, err
+	}
+	if req.Prop == nil {
+		return nil, req.Hrefs, nil
+	}
+	names := make([]xml.Name, len(req.Prop.Any))
+	for i, p := range req.Prop.Any {
+		names[i] = p.XMLName
+	}
+	return names, req.Hrefs, nil
+}
+
+// Bir kaynağın yanıtı: özellik adı -> XML içeriği
+type davResponse struct {
+	href  string
+	props map[xml.Name]string
+}
+
+func davName(space, local string) xml.Name {
+	return xml.Name{Space: space, Local: local}
+}
+
+func href(p string) string {
+	return "<D:href>" + xmlEscape(p) + "</D:href>"
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (h *Handler) principalProps(user common.AuthUser) map[xml.Name]string {
+	return map[xml.Name]string{
+		davName(davNS, "resourcetype"):                 "<D:collection/><D:principal/>",
+		davName(davNS, "displayname"):                  xmlEscape(user.Email),
+		davName(davNS, "current-user-principal"):       href(davPrefix + "/"),
+		davName(davNS, "principal-URL"):                href(davPrefix + "/"),
+		davName(cardDAVNS, "addressbook-home-set"):     href(davHomePath),
+		davName(davNS, "current-user-privilege-set"):   "<D:privilege><D:read/></D:privilege>",
+		davName(davNS, "principal-collection-set"):     href(davPrefix + "/"),
+		davName(calendarServerNS, "email-address-set"): "<CS:email-address>" + xmlEscape(user.Email) + "</CS:email-address>",
+	}
+}
+
+func homeProps() map[xml.Name]string {
+	return map[xml.Name]string{
+		davName(davNS, "resourcetype"):               "<D:collection/>",
+		davName(davNS, "displayname"):                "Adres defterleri",
+		davName(davNS, "current-user-principal"):     href(davPrefix + "/"),
+		davName(davNS, "current-user-privilege-set"): "<D:privilege><D:read/></D:privilege>",
+	}
+}
+
+func bookProps(ctag string) map[xml.Name]string {
+	return map[xml.Name]string{
+		davName(davNS, "resourcetype"):                "<D:collection/><C:addressbook/>",
+		davName(davNS, "displayname"):                 "CRM Müşterileri",
+		davName(cardDAVNS, "addressbook-description"): "Sorumlu olduğunuz müşteriler (salt okunur)",
+		davName(davNS, "current-user-principal"):      href(davPrefix + "/"),
+		davName(davNS, "current-user-privilege-set"):  "<D:privilege><D:read/></D:privilege>",
+		davName(calendarServerNS, "getctag"):          xmlEscape(ctag),
+		davName(davNS, "getetag"):                     xmlEscape(ctag),
+		davName(cardDAVNS, "supported-address-data"):  `<C:address-data-type content-type="text/vcard" version="3.0"/>`,
+		davName(cardDAVNS, "max-resource-size"):       "102400",
+		davName(davNS, "supported-report-set"): "<D:supported-report><D:report><C:addressbook-multiget/></D:report></D:supported-report>" +
+			"<D:supported-report><D:report><C:addressbook-query/></D:report></D:supported-report>",
+	}
+}
+
+func cardProps(card addressCard, withData bool) map[xml.Name]string {
+	props := map[xml.Name]string{
+		davName(davNS, "resourcetype"):   "",
+		davName(davNS, "getetag"):        xmlEscape(card.etag),
+		davName(davNS, "getcontenttype"): "text/vcard; charset=utf-8",
+		davName(davNS, "displayname"):    xmlEscape(card.customer.Name),
+	}
+	if withData {
+		props[davName(cardDAVNS, "address-data")] = xmlEscape(card.data)
+	}
+	return props
+}
+
+func (h *Handler) davPropfind(w http.ResponseWriter, r *http.Request, user common.AuthUser, res davResource, id int) {
+	names, _, err := requestedProps(r)
+	if err != nil {
+		http.Error(w, "Geçersiz PROPFIND gövdesi", http.StatusBadRequest)
+		return
+	}
+	depth := r.Header.Get("Depth")
+	children := depth == "1" || strings.EqualFold(depth, "infinity")
+
+	var responses []davResponse
+	switch res {
+	case davPrincipal:
+		responses = append(responses, davResponse{davPrefix + "/", h.principalProps(user)})
+		if children {
+			responses = append(responses, davResponse{davHomePath, homeProps()})
+		}
+	case davHome:
+		responses = append(responses, davResponse{davHomePath, homeProps()})
+		if children {
+			_, ctag, err := h.addressBook(user)
+			if err != nil {
+				common.WriteError(w, http.StatusInternalServerError, "Adres defteri alınamadı", err)
+				return
+			}
+			responses = append(responses, davResponse{davBookPath, bookProps(ctag)})
+		}
+	case davBook:
+		cards, ctag, err := h.addressBook(user)
+		if err != nil {
+			common.WriteError(w, http.StatusInternalServerError, "Adres defteri alınamadı", err)
+			return
+		}
+		responses = append(responses, davResponse{davBookPath, bookProps(ctag)})
+		if children {
+			for _, c := range cards {
+				responses = append(responses, davResponse{cardHref(c.customer.ID), cardProps(c, false)})
+			}
+		}
+	case davCard:
+		c, err := GetCustomer(h.DBReplica, user, id)
+		if err != nil || c.OwnerID != user.ID {
+			http.NotFound(w, r)
+			return
+		}
+		responses = append(responses, davResponse{cardHref(id), cardProps(newAddressCard(c), false)})
+	}
+	writeMultistatus(w, responses, names, nil)
+}
+
+// addressbook-multiget istenen kartları, addressbook-query (filtreler desteklenmez) tüm kartları döner
+func (h *Handler) davReport(w http.ResponseWriter, r *http.Request, user common.AuthUser, res davResource) {
+	if res != davBook {
+		http.Error(w, "REPORT sadece adres defterinde desteklenir", http.StatusForbidden)
+		return
+	}
+	names, hrefs, err := requestedProps(r)
+	if err != nil {
+		http.Error(w, "Geçersiz REPORT gövdesi", http.StatusBadRequest)
+		return
+	}
+	cards, _, err := h.addressBook(user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Adres defteri alınamadı", err)
+		return
+	}
+	byHref := map[string]addressCard{}
+	for _, c := range cards {
+		byHref[cardHref(c.customer.ID)] = c
+	}
+	var (
+		responses []davResponse
+		missing   []string
+	)
+	if len(hrefs) == 0 {
+		for _, c := range cards {
+			responses = append(responses, davResponse{cardHref(c.customer.ID), cardProps(c, true)})
+		}
+	}
+	for _, hr := range hrefs {
+		// İstemciler tam URL veya yüzde kodlanmış yol gönderebilir
+		p := hr
+		if u, err := url.Parse(hr); err == nil {
+			p = u.Path
+		}
+		if c, ok := byHref[p]; ok {
+			responses = append(responses, davResponse{p, cardProps(c, true)})
+		} else {
+			missing = append(missing, hr)
+		}
+	}
+	writeMultistatus(w, responses, names, missing)
+}
+
+// 207 Multi-Status yazar; istenen ama bilinmeyen özellikler 404 propstat'ında döner
+func writeMultistatus(w http.ResponseWriter, responses []davResponse, names []xml.Name, missing []string) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav" xmlns:CS="http://calendarserver.org/ns/">`)
+	for _, res := range responses {
+		b.WriteString("<D:response>" + href(res.href))
+		var found, notFound strings.Builder
+		want := names
+		if want == nil {
+			// allprop: address-data gibi büyük özellikler sadece açıkça istenirse döner
+			for n := range res.props {
+				if n.Local != "address-data" {
+					want = append(want, n)
+				}
+			}
+		}
+		for _, n := range want {
+			v, ok := res.props[n]
+			if prefix, known := davPrefixes[n.Space]; ok && known {
+				fmt.Fprintf(&found, "<%s:%s>%s</%s:%s>", prefix, n.Local, v, prefix, n.Local)
+			} else {
+				fmt.Fprintf(&notFound, `<x:%s xmlns:x="%s"/>`, n.Local, xmlEscape(n.Space))
+			}
+		}
+		if found.Len() > 0 {
+			b.WriteString("<D:propstat><D:prop>" + found.String() + "</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>")
+		}
+		if notFound.Len() > 0 {
+			b.WriteString("<D:propstat><D:prop>" + notFound.String() + "</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>")
+		}
+		b.WriteString("</D:response>")
+	}
+	for _, m := range missing {
+		b.WriteString("<D:response>" + href(m) + "<D:status>HTTP/1.1 404 Not Found</D:status></D:response>")
+	}
+	b.WriteString("</D:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,450 @@
+package customer
+
+import (
+	"Go-CRM/pkg/common"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Gelen ham e-postaların (RFC 822) iletişim kaydına dönüştürülmesi.
+// Mesaj, adreslerindeki müşterilerin her biri için ayrı bir email tipi kayıt olarak loglanır.
+
+const (
+	MaxEmailSize = 25 << 20
+	// Kayıt içeriğine alınan gövde uzunluğu (karakter)
+	maxEmailContent = 100000
+	maxMIMEDepth    = 10
+)
+
+var ErrInvalidEmail = errors.New("E-posta ayrıştırılamadı")
+
+// mime.WordDecoder ve gövdeler için karakter kümesi dönüştürücü (ISO-8859-9, windows-1254 vb.)
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("desteklenmeyen karakter kümesi: %s", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+var headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func decodeHeader(s string) string {
+	if d, err := headerDecoder.DecodeHeader(s); err == nil {
+		return d
+	}
+	return s
+}
+
+// Ham mesajı ayrıştırır. Metin gövdesi text/plain'den (yoksa HTML'den) alınır, ekler ayrılır.
+func ParseEmail(r io.Reader) (InboundEmail, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return InboundEmail{}, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	e := InboundEmail{Subject: strings.TrimSpace(decodeHeader(m.Header.Get("Subject")))}
+	from := parseAddressList(m.Header.Get("From"))
+	if len(from) == 0 {
+		return InboundEmail{}, fmt.Errorf("%w: gönderen (From) yok", ErrInvalidEmail)
+	}
+	e.From = from[0]
+	e.To = parseAddressList(m.Header.Get("To"))
+	e.Cc = parseAddressList(m.Header.Get("Cc"))
+	if ids := messageIDs(m.Header.Get("Message-Id")); len(ids) > 0 {
+		e.MessageID = ids[0]
+	}
+	if ids := messageIDs(m.Header.Get("In-Reply-To")); len(ids) > 0 {
+		e.InReplyTo = ids[0]
+	}
+	e.References = messageIDs(m.Header.Get("References"))
+	if d, err := m.Header.Date(); err == nil {
+		e.Date = d
+	}
+
+	var htmlBody string
+	if err := walkMIME(textproto.MIMEHeader(m.Header), m.Body, 0, &e, &htmlBody); err != nil {
+		return InboundEmail{}, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	if strings.TrimSpace(e.Text) == "" && htmlBody != "" {
+		e.Text = htmlToText(htmlBody)
+	}
+	e.Text = strings.TrimSpace(strings.ReplaceAll(e.Text, "\r\n", "\n"))
+	return e, nil
+}
+
+// Geçersiz adresler atlanır; istemcilerin bozuk başlıkları tüm mesajı reddettirmesin
+func parseAddressList(s string) []mail.Address {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parser := mail.AddressParser{WordDecoder: headerDecoder}
+	if list, err := parser.ParseList(s); err == nil {
+		out := make([]mail.Address, len(list))
+		for i, a := range list {
+			out[i] = *a
+		}
+		return out
+	}
+	var out []mail.Address
+	for _, part := range strings.Split(s, ",") {
+		if a, err := parser.Parse(part); err == nil {
+			out = append(out, *a)
+		}
+	}
+	return out
+}
+
+var messageIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+
+// "<a@b> <c@d>" -> ["<a@b>", "<c@d>"]; açılı parantezsiz tek kimlik de kabul edilir
+func messageIDs(s string) []string {
+	ids := messageIDPattern.FindAllString(s, -1)
+	if len(ids) == 0 {
+		if s = strings.TrimSpace(s); s != "" && !strings.ContainsAny(s, " \t") {
+			ids = []string{"<" + s + ">"}
+		}
+	}
+	return ids
+}
+
+// MIME ağacını gezer: ilk text/plain gövdeye, ilk text/html htmlBody'ye yazılır, geri kalanı ek sayılır
+func walkMIME(header textproto.MIMEHeader, body io.Reader, depth int, e *InboundEmail, htmlBody *string) error {
+	if depth > maxMIMEDepth {
+		return errors.New("MIME yapısı çok derin")
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkMIME(part.Header, part, depth+1, e, htmlBody); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	data, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if isText && disposition != "attachment" && filename == "" {
+		text := decodeCharset(params["charset"], data)
+		if mediaType == "text/plain" && e.Text == "" {
+			e.Text = text
+			return nil
+		}
+		if mediaType == "text/html" && *htmlBody == "" {
+			*htmlBody = text
+			return nil
+		}
+	}
+	if filename == "" {
+		filename = "ek"
+		if mediaType == "message/rfc822" {
+			filename = "ileti.eml"
+		}
+	}
+	e.Attachments = append(e.Attachments, EmailAttachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Size:        len(data),
+		Data:        data,
+	})
+	return nil
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// Bilinmeyen karakter kümesinde veri olduğu gibi (UTF-8 varsayılarak) döner
+func decodeCharset(charset string, data []byte) string {
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return string(data)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+var (
+	htmlDropPattern  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlBreakPattern = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankLines       = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+)
+
+// Sadece HTML gövdesi olan mesajlar için kaba metin dönüşümü
+func htmlToText(s string) string {
+	s = htmlDropPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	return strings.TrimSpace(blankLines.ReplaceAllString(s, "\n\n"))
+}
+
+// Mesaj kimliği yoksa içerikten türetilir; aynı mesajın tekrar yüklenmesi yine tekrar sayılır
+func syntheticMessageID(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "<" + hex.EncodeToString(sum[:16]) + "@go-crm>"
+}
+
+// Konuşmanın kök mesajı: References'ın ilki, yoksa yanıtlanan, yoksa mesajın kendisi
+func threadRoot(e InboundEmail) string {
+	if len(e.References) > 0 {
+		return e.References[0]
+	}
+	if e.InReplyTo != "" {
+		return e.InReplyTo
+	}
+	return e.MessageID
+}
+
+func formatAddress(a mail.Address) string {
+	if a.Name == "" {
+		return a.Address
+	}
+	return a.Name + " <" + a.Address + ">"
+}
+
+// Kayıt içeriği: konu ve metin gövdesi
+func emailContent(e InboundEmail) string {
+	content := "Konu: " + e.Subject
+	if e.Subject == "" {
+		content = "Konu: (konu yok)"
+	}
+	if e.Text != "" {
+		content += "\n\n" + e.Text
+	}
+	if r := []rune(content); len(r) > maxEmailContent {
+		content = string(r[:maxEmailContent]) + "…"
+	}
+	return content
+}
+
+// SMTP ile alımda kabul edilen alıcı adresleri ve tenant'ları ("crm@firma.com" veya "@inbox.firma.com" -> tenant).
+// Mesaj başlıkları sahte olabileceğinden tenant sadece zarftaki alıcıdan (RCPT TO) belirlenir.
+type InboundRecipients map[string]int
+
+// "crm@acme.com=2,@inbox.globex.com=3" biçimindeki listeyi ayrıştırır; her adres için tenant zorunludur
+func ParseInboundRecipients(s string) (InboundRecipients, error) {
+	recipients := InboundRecipients{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		addr, tenant, ok := strings.Cut(entry, "=")
+		addr = strings.ToLower(strings.TrimSpace(addr))
+		tenantID, err := strconv.Atoi(strings.TrimSpace(tenant))
+		if !ok || err != nil || tenantID <= 0 {
+			return nil, fmt.Errorf("alıcı için tenant verilmeli (ör. crm@firma.com=1): %s", entry)
+		}
+		if !strings.Contains(addr, "@") || strings.HasSuffix(addr, "@") {
+			return nil, fmt.Errorf("geçersiz alıcı adresi: %s", entry)
+		}
+		recipients[addr] = tenantID
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("en az bir alıcı adresi tanımlanmalı")
+	}
+	return recipients, nil
+}
+
+// Alıcının tenant'ı; tam adres eşleşmesi alan adı eşleşmesinden önceliklidir
+func (r InboundRecipients) TenantOf(rcpt string) (int, bool) {
+	rcpt = strings.ToLower(strings.TrimSpace(rcpt))
+	if tenantID, ok := r[rcpt]; ok {
+		return tenantID, true
+	}
+	if at := strings.LastIndex(rcpt, "@"); at > 0 {
+		tenantID, ok := r[rcpt[at:]]
+		return tenantID, ok
+	}
+	return 0, false
+}
+
+// Ham e-postayı ayrıştırıp tenant'ın adreslerdeki müşterileri için email tipi iletişim kayıtları oluşturur.
+// Gönderen tenant'ın bir kullanıcısıysa yön outbound, değilse inbound'dur. Başlıklar sahte olabileceğinden
+// yazar mesajdan çıkarılmaz, authorID kullanılır (SMTP ile alımda 0: yazarsız).
+// Aynı Message-ID aynı müşteriye ikinci kez loglanmaz.
+func IngestEmail(db *sql.DB, tenantID, authorID int, raw []byte) (EmailIngestResult, error) {
+	e, err := ParseEmail(bytes.NewReader(raw))
+	if err != nil {
+		return EmailIngestResult{}, err
+	}
+	if e.MessageID == "" {
+		e.MessageID = syntheticMessageID(raw)
+	}
+
+	addresses := append([]mail.Address{e.From}, append(e.To, e.Cc...)...)
+	var lowered []string
+	for _, a := range addresses {
+		lowered = append(lowered, strings.ToLower(a.Address))
+	}
+	users, err := findUsersByEmailRepo(db, tenantID, lowered)
+	if err != nil {
+		return EmailIngestResult{}, err
+	}
+
+	result := EmailIngestResult{MessageID: e.MessageID, Direction: DirectionInbound, Contacts: []Contact{}, Unmatched: []string{}}
+	if _, ok := users[lowered[0]]; ok {
+		result.Direction = DirectionOutbound
+	}
+
+	// Müşteri adresleri: CRM kullanıcısı olmayan tüm taraflar
+	var external []string
+	seen := map[string]bool{}
+	for _, addr := range lowered {
+		if _, internal := users[addr]; internal || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		external = append(external, addr)
+	}
+	normalized := make([]string, len(external))
+	for i, addr := range external {
+		normalized[i] = NormalizeEmail(addr)
+	}
+	customers, err := findCustomersByEmailsRepo(db, tenantID, normalized)
+	if err != nil {
+		return EmailIngestResult{}, err
+	}
+
+	thread, err := findEmailThreadRepo(db, tenantID, append(append([]string{}, e.References...), e.InReplyTo))
+	if err != nil {
+		return EmailIngestResult{}, err
+	}
+	if thread == "" {
+		thread = threadRoot(e)
+	}
+	result.ThreadID = thread
+
+	occurredAt := e.Date
+	if occurredAt.IsZero() || occurredAt.After(time.Now()) {
+		occurredAt = time.Now()
+	}
+	var participants []string
+	for _, a := range addresses {
+		participants = append(participants, formatAddress(a))
+	}
+	details := ContactEmail{
+		MessageID:   e.MessageID,
+		InReplyTo:   e.InReplyTo,
+		ThreadID:    thread,
+		From:        formatAddress(e.From),
+		To:          formatAddresses(e.To),
+		Cc:          formatAddresses(e.Cc),
+		Subject:     e.Subject,
+		Attachments: e.Attachments,
+	}
+	if details.Attachments == nil {
+		details.Attachments = []EmailAttachment{}
+	}
+
+	logged := map[int]bool{}
+	for i, addr := range external {
+		customerID, ok := customers[normalized[i]]
+		if !ok {
+			result.Unmatched = append(result.Unmatched, addr)
+			continue
+		}
+		if logged[customerID] {
+			continue
+		}
+		logged[customerID] = true
+		contact := Contact{
+			CustomerID:   customerID,
+			AuthorID:     authorID,
+			Type:         ContactTypeEmail,
+			Direction:    result.Direction,
+			Participants: participants,
+			Content:      emailContent(e),
+			OccurredAt:   occurredAt,
+		}
+		if err := validateContact(contact); err != nil {
+			return result, err
+		}
+		created, err := createEmailContactRepo(db, tenantID, &contact, details)
+		if err != nil {
+			return result, err
+		}
+		if !created {
+			result.Duplicates = append(result.Duplicates, customerID)
+			continue
+		}
+		result.Contacts = append(result.Contacts, contact)
+	}
+	if len(result.Contacts) == 0 && len(result.Duplicates) == 0 {
+		log.Printf("E-posta hiçbir müşteriyle eşleşmedi (message-id=%s)", e.MessageID)
+	}
+	return result, nil
+}
+
+func formatAddresses(list []mail.Address) []string {
+	out := make([]string, len(list))
+	for i, a := range list {
+		out[i] = formatAddress(a)
+	}
+	return out
+}
+
+// İletişim kaydının ait olduğu e-posta konuşması (aynı müşteri, aynı thread), eskiden yeniye
+func GetEmailThread(db *sql.DB, user common.AuthUser, contactID int) ([]EmailThreadEntry, error) {
+	entries, err := getEmailThreadRepo(db, tenantOf(user), contactID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrContactNotFound
+	}
+	return entries, nil
+}
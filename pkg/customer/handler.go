@@ -2,11 +2,20 @@ package customer
 
 import (
 	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/storage"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Handler fonksiyonları sade tutulur, iş mantığı service katmanında
@@ -14,70 +23,819 @@ import (
 type Handler struct {
 	DBPrimary *sql.DB
 	DBReplica *sql.DB
+	// Ek dosyaları; Storage nil ise ek yükleme kapalıdır, Scanner nil ise tarama yapılmaz
+	Storage storage.Storage
+	Scanner storage.Scanner
 }
 
-// Müşteri listeleme (GET /api/customers?page=1&pageSize=10&search=ali)
+// Müşteri listeleme (GET /api/customers?pageSize=10&cursor=...&include_total=true&search=ali&owner_id=3&account_id=2&tag_id=4&cf.industry=retail&filter=created_at>=2025-01-01,tag:in:vip|gold&sort=-created_at,name)
 func (h *Handler) GetCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := customerListParams(r)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	result, err := GetCustomers(h.DBReplica, params)
+	if err != nil {
+		writeListError(w, "Müşteri listesi alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Müşteri listesi ve dışa aktarmada ortak sorgu parametreleri
+func customerListParams(r *http.Request) (CustomerListParams, error) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	ownerID, _ := strconv.Atoi(q.Get("owner_id"))
+	accountID, _ := strconv.Atoi(q.Get("account_id"))
+	tagID, _ := strconv.Atoi(q.Get("tag_id"))
+
+	filter, sort, err := parseListQuery(q.Get("filter"), q.Get("sort"))
+	if err != nil {
+		return CustomerListParams{}, err
+	}
+
+	params := CustomerListParams{
+		Page:      page,
+		PageSize:  pageSize,
+		Search:    strings.TrimSpace(q.Get("search")),
+		OwnerID:   ownerID,
+		AccountID: accountID,
+		TagID:     tagID,
+		Filter:    filter,
+		Sort:      sort,
+		Cursor:    q.Get("cursor"),
+	}
+	params.IncludeTotal, _ = strconv.ParseBool(q.Get("include_total"))
+	if user, ok := common.UserFromContext(r.Context()); ok {
+		params.TenantID = tenantOf(user)
+	}
+	// Özel alan filtreleri "cf." önekiyle verilir
+	for name, values := range q {
+		if key, ok := strings.CutPrefix(name, "cf."); ok && len(values) > 0 {
+			if params.CustomFields == nil {
+				params.CustomFields = map[string]string{}
+			}
+			params.CustomFields[key] = values[0]
+		}
+	}
+	return params, nil
+}
+
+// Müşteri ekleme (POST /api/customers)
+func (h *Handler) CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var c Customer
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if !common.IsEmailValid(c.Email) {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz e-posta adresi", nil)
+		return
+	}
+	if !common.IsPhoneValid(c.Phone) {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz telefon numarası", nil)
+		return
+	}
+	if err := CreateCustomer(h.DBPrimary, user, &c); err != nil {
+		if errors.Is(err, ErrOwnerNotFound) {
+			common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		common.WriteError(w, http.StatusBadRequest, "Müşteri eklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// Tek müşteri (GET /api/customers/{id})
+func (h *Handler) GetCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	c, err := GetCustomer(h.DBReplica, user, id)
+	if err != nil {
+		writeCustomerError(w, "Müşteri alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// Müşteri güncelleme (PUT /api/customers/{id})
+func (h *Handler) UpdateCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	var c Customer
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if c.Email != "" && !common.IsEmailValid(c.Email) {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz e-posta adresi", nil)
+		return
+	}
+	c.ID = id
+	if err := UpdateCustomer(h.DBPrimary, user, &c); err != nil {
+		writeCustomerError(w, "Müşteri güncellenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// Olası mükerrer müşteri çiftleri (GET /api/customers/duplicates?page=1&pageSize=20)
+func (h *Handler) GetDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
-	search := strings.TrimSpace(r.URL.Query().Get("search"))
+	report, err := GetDuplicateReport(h.DBReplica, user, page, pageSize)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Mükerrer kayıt raporu alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// İki müşteriyi birleştirme (POST /api/customers/merge)
+func (h *Handler) MergeCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var req struct {
+		SurvivorID int `json:"survivor_id"`
+		MergedID   int `json:"merged_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	result, err := MergeCustomers(h.DBPrimary, user, req.SurvivorID, req.MergedID)
+	if err != nil {
+		writeCustomerError(w, "Müşteriler birleştirilemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// CSV/XLSX müşteri içe aktarma (POST /api/customers/imports, multipart: file, mapping, mode, dry_run)
+func (h *Handler) CreateImportHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Dosya okunamadı veya 20MB sınırını aşıyor", err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "file alanında dosya gönderilmeli", err)
+		return
+	}
+	defer file.Close()
+	if header.Size > MaxImportFileSize {
+		common.WriteError(w, http.StatusBadRequest, "Dosya en fazla 20MB olabilir", nil)
+		return
+	}
+	job := CustomerImport{Filename: header.Filename, Mode: r.FormValue("mode")}
+	if v := r.FormValue("dry_run"); v != "" {
+		if job.DryRun, err = strconv.ParseBool(v); err != nil {
+			common.WriteError(w, http.StatusBadRequest, "Geçersiz dry_run değeri", err)
+			return
+		}
+	}
+	if v := r.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &job.Mapping); err != nil {
+			common.WriteError(w, http.StatusBadRequest, "Geçersiz sütun eşlemesi", err)
+			return
+		}
+	}
+
+	// İşlem arka planda süreceği için yükleme geçici dosyaya alınır
+	tmp, err := os.CreateTemp("", "customer-import-*"+filepath.Ext(header.Filename))
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Dosya kaydedilemedi", err)
+		return
+	}
+	_, err = io.Copy(tmp, file)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		common.WriteError(w, http.StatusInternalServerError, "Dosya kaydedilemedi", err)
+		return
+	}
+	if err := StartCustomerImport(h.DBPrimary, user, &job, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		writeCustomerError(w, err.Error(), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// İçe aktarma durumu ve sayaçlar (GET /api/customers/imports/{id})
+func (h *Handler) GetImportHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/imports/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz içe aktarma ID", err)
+		return
+	}
+	// İlerleme sık değiştiğinden replica gecikmesine takılmamak için primary'den okunur
+	job, err := GetCustomerImport(h.DBPrimary, user, id)
+	if err != nil {
+		writeCustomerError(w, "İçe aktarma alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// Hatalı satırlar CSV olarak (GET /api/customers/imports/{id}/errors)
+func (h *Handler) GetImportErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/imports/", "/errors")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz içe aktarma ID", err)
+		return
+	}
+	if _, err := GetCustomerImport(h.DBPrimary, user, id); err != nil {
+		writeCustomerError(w, "İçe aktarma alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%d-errors.csv"`, id))
+	if err := WriteImportErrors(h.DBPrimary, user, id, w); err != nil {
+		// Başlıklar gönderildiğinden yanıt değiştirilemez, sadece loglanır
+		log.Printf("İçe aktarma %d hata raporu yazılamadı: %v", id, err)
+	}
+}
+
+// İlk yazmaya kadar hata yanıtı dönülebilmesi için yazılan bayt sayısını tutar
+type countingWriter struct {
+	w http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+var exportContentTypes = map[string]string{
+	common.FormatCSV:  "text/csv; charset=utf-8",
+	common.FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportJSONL:       "application/x-ndjson",
+	ExportVCF:         "text/vcard; charset=utf-8",
+}
+
+// Dışa aktarmayı yanıta akış halinde yazar; akış başladıktan sonraki hatalar sadece loglanır
+func streamExport(w http.ResponseWriter, name, format string, write func(io.Writer) (int, error)) {
+	if format == "" {
+		format = common.FormatCSV
+	}
+	if err := checkExportFormat(format); err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format("20060102"), format))
+	cw := &countingWriter{w: w}
+	if _, err := write(cw); err != nil {
+		if cw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			writeListError(w, "Dışa aktarma yapılamadı", err)
+			return
+		}
+		log.Printf("Dışa aktarma yarıda kesildi: %v", err)
+	}
+}
+
+// Müşterileri dosya olarak indirir (GET /api/customers/export?format=csv|xlsx|jsonl&<GET /api/customers filtreleri>)
+func (h *Handler) ExportCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := common.UserFromContext(r.Context()); !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	params, err := customerListParams(r)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	streamExport(w, "customers", format, func(out io.Writer) (int, error) {
+		return WriteCustomersExport(h.DBReplica, params, format, out)
+	})
+}
+
+// İletişim kayıtları dışa aktarma parametreleri (customer_id, type, filter, sort)
+func contactExportParams(r *http.Request) (ContactListParams, error) {
+	q := r.URL.Query()
+	filter, sort, err := parseListQuery(q.Get("filter"), q.Get("sort"))
+	if err != nil {
+		return ContactListParams{}, err
+	}
+	customerID, _ := strconv.Atoi(q.Get("customer_id"))
+	return ContactListParams{CustomerID: customerID, Type: q.Get("type"), Filter: filter, Sort: sort}, nil
+}
+
+// İletişim kayıtlarını dosya olarak indirir (GET /api/contacts/export?format=csv&customer_id=5&type=call&filter=...&sort=...)
+func (h *Handler) ExportContactsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	params, err := contactExportParams(r)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if err := checkContactExportFormat(format); err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	streamExport(w, "contacts", format, func(out io.Writer) (int, error) {
+		return WriteContactsExport(h.DBReplica, tenantOf(user), params, format, out)
+	})
+}
+
+// Arka planda dışa aktarma başlatır (POST /api/exports?type=customers|contacts&format=csv&<ilgili liste filtreleri>)
+func (h *Handler) CreateExportHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	p := ExportParams{Type: q.Get("type"), Format: q.Get("format"), Query: r.URL.RawQuery}
+	var err error
+	switch p.Type {
+	case ExportCustomers:
+		p.Customers, err = customerListParams(r)
+	case ExportContacts:
+		p.Contacts, err = contactExportParams(r)
+	}
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	job, err := StartExport(h.DBPrimary, h.DBReplica, user, p)
+	if err != nil {
+		writeListError(w, "Dışa aktarma başlatılamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// Dışa aktarma durumu ve indirme bağlantısı (GET /api/exports/{id})
+func (h *Handler) GetExportHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/exports/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz dışa aktarma ID", err)
+		return
+	}
+	job, err := GetExport(h.DBPrimary, user, id)
+	if errors.Is(err, ErrExportNotFound) {
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+		return
+	}
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Dışa aktarma alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// İmzalı bağlantıyla dosya indirme (GET /api/exports/{id}/download?expires=...&signature=...), JWT gerektirmez
+func (h *Handler) DownloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r.URL.Path, "/api/exports/", "/download")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz dışa aktarma ID", err)
+		return
+	}
+	job, f, err := OpenExportDownload(h.DBPrimary, id, r.URL.Query())
+	switch {
+	case errors.Is(err, common.ErrLinkInvalid):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+		return
+	case errors.Is(err, common.ErrLinkExpired), errors.Is(err, ErrExportExpired):
+		common.WriteError(w, http.StatusGone, err.Error(), err)
+		return
+	case errors.Is(err, ErrExportNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+		return
+	case err != nil:
+		common.WriteError(w, http.StatusInternalServerError, "Dosya açılamadı", err)
+		return
+	}
+	defer f.Close()
+	name := fmt.Sprintf("%s-export-%d.%s", job.Type, job.ID, job.Format)
+	w.Header().Set("Content-Type", exportContentTypes[job.Format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	var modTime time.Time
+	if job.FinishedAt != nil {
+		modTime = *job.FinishedAt
+	}
+	http.ServeContent(w, r, name, modTime, f)
+}
+
+// Tenant'ın müşteri özel alan tanımları (GET /api/customer-fields)
+func (h *Handler) GetFieldDefinitionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	defs, err := GetFieldDefinitions(h.DBReplica, user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Özel alanlar alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(defs)
+}
+
+// Özel alan tanımı ekleme (POST /api/customer-fields)
+func (h *Handler) CreateFieldDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var d FieldDefinition
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateFieldDefinition(h.DBPrimary, user, &d); err != nil {
+		writeCustomerError(w, "Özel alan eklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(d)
+}
+
+// Özel alan tanımı güncelleme (PUT /api/customer-fields/{id})
+func (h *Handler) UpdateFieldDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customer-fields/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz alan ID", err)
+		return
+	}
+	var d FieldDefinition
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	d.ID = id
+	if err := UpdateFieldDefinition(h.DBPrimary, user, &d); err != nil {
+		writeCustomerError(w, "Özel alan güncellenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// Özel alan tanımı silme (DELETE /api/customer-fields/{id})
+func (h *Handler) DeleteFieldDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customer-fields/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz alan ID", err)
+		return
+	}
+	if err := DeleteFieldDefinition(h.DBPrimary, user, id); err != nil {
+		writeCustomerError(w, "Özel alan silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// filter ve sort sorgu parametrelerini ayrıştırır
+func parseListQuery(filter, sort string) ([]common.FilterCondition, []common.SortKey, error) {
+	conds, err := common.ParseFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := common.ParseSort(sort)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conds, keys, nil
+}
+
+// Filtre/sıralama hataları 400, diğerleri 500 döner
+func writeListError(w http.ResponseWriter, msg string, err error) {
+	var qe *common.QueryError
+	if errors.As(err, &qe) {
+		common.WriteError(w, http.StatusBadRequest, qe.Msg, err)
+		return
+	}
+	if errors.Is(err, ErrCustomerNotFound) {
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+		return
+	}
+	common.WriteError(w, http.StatusInternalServerError, msg, err)
+}
+
+func writeCustomerError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrCustomerNotFound), errors.Is(err, ErrFieldNotFound), errors.Is(err, ErrImportNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrAdminOnly), errors.Is(err, ErrManagerOnly), errors.Is(err, ErrMergeForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+// Müşteri sorumlusunu değiştirme (POST /api/customers/{id}/owner)
+func (h *Handler) ReassignCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/", "/owner")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	var req struct {
+		OwnerID int    `json:"owner_id"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := ReassignCustomer(h.DBPrimary, user, id, req.OwnerID, req.Reason); err != nil {
+		writeOwnershipError(w, "Sorumlu değiştirilemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Müşteri sorumlu geçmişi (GET /api/customers/{id}/owner-history)
+func (h *Handler) GetOwnerHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/", "/owner-history")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	history, err := GetOwnerHistory(h.DBReplica, user, id)
+	if err != nil {
+		writeOwnershipError(w, "Sorumlu geçmişi alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// Toplu yeniden atama, ör. temsilci ayrıldığında (POST /api/customers/reassign)
+func (h *Handler) BulkReassignHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var req struct {
+		FromOwnerID int     `json:"from_owner_id"`
+		ToOwnerIDs  []int64 `json:"to_owner_ids"`
+		Reason      string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	moved, err := BulkReassign(h.DBPrimary, user, req.FromOwnerID, req.ToOwnerIDs, req.Reason)
+	if err != nil {
+		writeOwnershipError(w, "Toplu atama yapılamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"moved": moved})
+}
+
+// Atama kuralları (GET /api/assignment-rules)
+func (h *Handler) GetAssignmentRulesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	rules, err := GetAssignmentRules(h.DBReplica, user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Atama kuralları alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// Atama kuralı ekleme (POST /api/assignment-rules)
+func (h *Handler) CreateAssignmentRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	rule := AssignmentRule{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateAssignmentRule(h.DBPrimary, user, &rule); err != nil {
+		writeOwnershipError(w, "Atama kuralı eklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
 
-	params := CustomerListParams{
-		Page:     page,
-		PageSize: pageSize,
-		Search:   search,
+// Atama kuralı güncelleme (PUT /api/assignment-rules/{id})
+func (h *Handler) UpdateAssignmentRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
 	}
-	result, err := GetCustomers(h.DBReplica, params)
+	id, err := idFromPath(r.URL.Path, "/api/assignment-rules/", "")
 	if err != nil {
-		http.Error(w, "Müşteri listesi alınamadı: "+err.Error(), http.StatusInternalServerError)
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz kural ID", err)
+		return
+	}
+	var rule AssignmentRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	rule.ID = id
+	if err := UpdateAssignmentRule(h.DBPrimary, user, &rule); err != nil {
+		writeOwnershipError(w, "Atama kuralı güncellenemedi", err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(rule)
 }
 
-// Müşteri ekleme (POST /api/customers)
-func (h *Handler) CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
-	var c Customer
-	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
-		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+// Atama kuralı silme (DELETE /api/assignment-rules/{id})
+func (h *Handler) DeleteAssignmentRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
 		return
 	}
-	if !common.IsEmailValid(c.Email) {
-		common.WriteError(w, http.StatusBadRequest, "Geçersiz e-posta adresi", nil)
+	id, err := idFromPath(r.URL.Path, "/api/assignment-rules/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz kural ID", err)
 		return
 	}
-	if !common.IsPhoneValid(c.Phone) {
-		common.WriteError(w, http.StatusBadRequest, "Geçersiz telefon numarası", nil)
+	if err := DeleteAssignmentRule(h.DBPrimary, user, id); err != nil {
+		writeOwnershipError(w, "Atama kuralı silinemedi", err)
 		return
 	}
-	if err := CreateCustomer(h.DBPrimary, &c); err != nil {
-		common.WriteError(w, http.StatusBadRequest, "Müşteri eklenemedi", err)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeOwnershipError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrCustomerNotFound), errors.Is(err, ErrRuleNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrOwnerForbidden), errors.Is(err, ErrManagerOnly):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrOwnerNotFound):
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+// Müşteri ve iletişim kayıtlarında tam metin arama (GET /api/search?q=ali yılmaz&type=contacts&limit=10)
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	params := SearchParams{
+		Query: strings.TrimSpace(q.Get("q")),
+		Type:  q.Get("type"),
+	}
+	params.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if user, ok := common.UserFromContext(r.Context()); ok {
+		params.TenantID = tenantOf(user)
+	}
+	result, err := Search(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Arama yapılamadı", err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(c)
+	json.NewEncoder(w).Encode(result)
 }
 
-// Belirli bir müşterinin iletişim kayıtları (GET /api/contacts/{customerId})
+// Belirli bir müşterinin iletişim kayıtları (GET /api/contacts/{customerId}?pageSize=20&cursor=...&include_total=true&filter=type:in:call|meeting&sort=-occurred_at)
 func (h *Handler) GetContactsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
 	customerIDStr := strings.TrimPrefix(r.URL.Path, "/api/contacts/")
 	customerID, err := strconv.Atoi(customerIDStr)
 	if err != nil || customerID <= 0 {
 		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", nil)
 		return
 	}
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	filter, sort, err := parseListQuery(q.Get("filter"), q.Get("sort"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
 	params := ContactListParams{
 		CustomerID: customerID,
+		Type:       q.Get("type"),
+		Filter:     filter,
+		Sort:       sort,
+		Cursor:     q.Get("cursor"),
 		Page:       page,
 		PageSize:   pageSize,
 	}
-	result, err := GetContactsByCustomerID(h.DBReplica, params)
+	params.IncludeTotal, _ = strconv.ParseBool(q.Get("include_total"))
+	result, err := GetContactsByCustomerID(h.DBReplica, user, params)
 	if err != nil {
-		common.WriteError(w, http.StatusInternalServerError, "İletişim kayıtları alınamadı", err)
+		writeListError(w, "İletişim kayıtları alınamadı", err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -86,6 +844,11 @@ func (h *Handler) GetContactsHandler(w http.ResponseWriter, r *http.Request) {
 
 // İletişim kaydı ekleme (POST /api/contacts)
 func (h *Handler) CreateContactHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
 	var contact Contact
 	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
 		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
@@ -99,11 +862,506 @@ func (h *Handler) CreateContactHandler(w http.ResponseWriter, r *http.Request) {
 		common.WriteError(w, http.StatusBadRequest, "İletişim içeriği çok kısa", nil)
 		return
 	}
-	if err := CreateContact(h.DBPrimary, &contact); err != nil {
-		common.WriteError(w, http.StatusBadRequest, "İletişim kaydı eklenemedi", err)
+	if contact.Type != "" && !isContactType(contact.Type) {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz iletişim tipi", nil)
+		return
+	}
+	contact.AuthorID = user.ID
+	if err := CreateContact(h.DBPrimary, user, &contact); err != nil {
+		writeCustomerError(w, "İletişim kaydı eklenemedi", err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(contact)
 }
+
+// Ekip aktivite akışı (GET /api/contacts?from=2025-01-01&to=2025-01-31&author_id=3&customer_id=5&account_id=2&type=call&filter=outcome:eq:won&cursor=...&limit=20)
+func (h *Handler) GetContactFeedHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var params ContactFeedParams
+	var err error
+	if user, ok := common.UserFromContext(r.Context()); ok {
+		params.TenantID = tenantOf(user)
+	}
+	if params.From, err = parseDateParam(q.Get("from"), false); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz başlangıç tarihi", err)
+		return
+	}
+	if params.To, err = parseDateParam(q.Get("to"), true); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz bitiş tarihi", err)
+		return
+	}
+	for name, dst := range map[string]*int{"author_id": &params.AuthorID, "customer_id": &params.CustomerID, "account_id": &params.AccountID} {
+		if v := q.Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil || *dst <= 0 {
+				common.WriteError(w, http.StatusBadRequest, "Geçersiz "+name, err)
+				return
+			}
+		}
+	}
+	params.Limit, _ = strconv.Atoi(q.Get("limit"))
+	params.Type = q.Get("type")
+	params.Cursor = q.Get("cursor")
+	// Akış imleçle sayfalandığı için sıralama sabittir
+	if q.Get("sort") != "" {
+		common.WriteError(w, http.StatusBadRequest, "Aktivite akışında sort desteklenmez", nil)
+		return
+	}
+	if params.Filter, err = common.ParseFilter(q.Get("filter")); err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	result, err := GetContactFeed(h.DBReplica, params)
+	if err != nil {
+		var qe *common.QueryError
+		if errors.As(err, &qe) {
+			common.WriteError(w, http.StatusBadRequest, qe.Msg, err)
+			return
+		}
+		common.WriteError(w, http.StatusBadRequest, "Aktivite akışı alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// İletişim kaydı düzenleme (PUT /api/contacts/{id})
+func (h *Handler) UpdateContactHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/contacts/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz iletişim ID", err)
+		return
+	}
+	var contact Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	contact.ID = id
+	if err := UpdateContact(h.DBPrimary, user, &contact); err != nil {
+		writeContactError(w, "İletişim kaydı güncellenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
+// İletişim kaydı silme (DELETE /api/contacts/{id})
+func (h *Handler) DeleteContactHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/contacts/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz iletişim ID", err)
+		return
+	}
+	if err := DeleteContact(h.DBPrimary, user, id); err != nil {
+		writeContactError(w, "İletişim kaydı silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// İletişim kaydının revizyonları (GET /api/contacts/{id}/revisions)
+func (h *Handler) GetContactRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/contacts/", "/revisions")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz iletişim ID", err)
+		return
+	}
+	revisions, err := GetContactRevisions(h.DBReplica, user, id)
+	if err != nil {
+		writeContactError(w, "Revizyonlar alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// Servis hatasını uygun HTTP durum koduna çevirir
+func writeContactError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrContactNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrContactForbidden), errors.Is(err, ErrRevisionForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+// /api/contacts/12/revisions gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
+
+// Tip bazında iletişim sayıları (GET /api/contacts/stats?from=2025-01-01&to=2025-01-31&author_id=3)
+func (h *Handler) GetContactStatsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	params := ContactStatsParams{TenantID: tenantOf(user)}
+	var err error
+	if params.From, err = parseDateParam(q.Get("from"), false); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz başlangıç tarihi", err)
+		return
+	}
+	if params.To, err = parseDateParam(q.Get("to"), true); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz bitiş tarihi", err)
+		return
+	}
+	if v := q.Get("author_id"); v != "" {
+		if params.AuthorID, err = strconv.Atoi(v); err != nil || params.AuthorID <= 0 {
+			common.WriteError(w, http.StatusBadRequest, "Geçersiz author_id", err)
+			return
+		}
+	}
+	stats, err := GetContactStats(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "İletişim istatistikleri alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// Tarih parametresini çözer: "2006-01-02" veya RFC3339.
+// Sadece gün verilmiş bir bitiş tarihi o günü de kapsayacak şekilde ertesi güne çekilir.
+func parseDateParam(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// Müşterinin vCard'ı (GET /api/customers/{id}/vcard?version=3.0|4.0)
+func (h *Handler) GetCustomerVCardHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/customers/", "/vcard")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	version, err := vcardVersion(r.URL.Query().Get("version"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	c, err := GetCustomer(h.DBReplica, user, id)
+	if err != nil {
+		writeCustomerError(w, "Müşteri alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="customer-%d.vcf"`, c.ID))
+	io.WriteString(w, MarshalVCard(c, version))
+}
+
+// vCard dosyasından müşteri oluşturur (POST /api/customers/vcard, gövde text/vcard)
+func (h *Handler) ImportVCardHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxVCardImportSize)
+	cards, err := ParseVCards(r.Body)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	result, err := ImportVCards(h.DBPrimary, user, cards)
+	if err != nil {
+		writeCustomerError(w, "vCard içe aktarılamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Ham e-postayı iletişim kaydına alır (POST /api/contacts/emails). Gövde message/rfc822 (.eml)
+// veya multipart "file" alanı olabilir. Kayıt oluşmadıysa (tekrar veya eşleşme yok) 200 döner.
+func (h *Handler) IngestEmailHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxEmailSize+1<<20)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(8 << 20); err != nil {
+			common.WriteError(w, http.StatusBadRequest, "Dosya okunamadı veya 25MB sınırını aşıyor", err)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, "file alanında .eml dosyası gönderilmeli", err)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	raw, err := io.ReadAll(io.LimitReader(body, MaxEmailSize+1))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "E-posta okunamadı veya 25MB sınırını aşıyor", err)
+		return
+	}
+	if len(raw) > MaxEmailSize {
+		common.WriteError(w, http.StatusBadRequest, "E-posta en fazla 25MB olabilir", nil)
+		return
+	}
+	result, err := IngestEmail(h.DBPrimary, tenantOf(user), user.ID, raw)
+	if err != nil {
+		writeContactError(w, "E-posta kaydedilemedi", err)
+		return
+	}
+	status := http.StatusOK
+	if len(result.Contacts) > 0 {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// E-posta kaydının konuşma zinciri (GET /api/contacts/{id}/thread)
+func (h *Handler) GetEmailThreadHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/contacts/", "/thread")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz iletişim ID", err)
+		return
+	}
+	entries, err := GetEmailThread(h.DBReplica, user, id)
+	if err != nil {
+		writeContactError(w, "Konuşma alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// Müşteriye dosya ekler (POST /api/customers/{id}/attachments, multipart: file, contact_id)
+func (h *Handler) UploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	customerID, err := idFromPath(r.URL.Path, "/api/customers/", "/attachments")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	if h.Storage == nil {
+		writeAttachmentError(w, "Dosya yüklenemedi", ErrStorageDisabled)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAttachmentSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		common.WriteError(w, http.StatusRequestEntityTooLarge, "Dosya okunamadı veya 20MB sınırını aşıyor", err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "file alanında dosya gönderilmeli", err)
+		return
+	}
+	defer file.Close()
+	up := AttachmentUpload{CustomerID: customerID, Filename: header.Filename, Body: file}
+	if v := r.FormValue("contact_id"); v != "" {
+		if up.ContactID, err = strconv.Atoi(v); err != nil || up.ContactID <= 0 {
+			common.WriteError(w, http.StatusBadRequest, "Geçersiz iletişim ID", err)
+			return
+		}
+	}
+	a, err := UploadAttachment(r.Context(), h.DBPrimary, h.Storage, h.Scanner, user, up)
+	if err != nil {
+		writeAttachmentError(w, "Dosya yüklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+// Müşterinin ekleri (GET /api/customers/{id}/attachments?contact_id=4)
+func (h *Handler) GetAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	customerID, err := idFromPath(r.URL.Path, "/api/customers/", "/attachments")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz müşteri ID", err)
+		return
+	}
+	contactID, _ := strconv.Atoi(r.URL.Query().Get("contact_id"))
+	attachments, err := GetAttachments(h.DBReplica, user, customerID, contactID)
+	if err != nil {
+		writeAttachmentError(w, "Dosyalar alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// Ek bilgisi ve yeni indirme bağlantısı (GET /api/attachments/{id})
+func (h *Handler) GetAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/attachments/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz dosya ID", err)
+		return
+	}
+	a, err := GetAttachment(h.DBReplica, user, id)
+	if err != nil {
+		writeAttachmentError(w, "Dosya alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// Ek silme (DELETE /api/attachments/{id})
+func (h *Handler) DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/attachments/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz dosya ID", err)
+		return
+	}
+	if err := DeleteAttachment(r.Context(), h.DBPrimary, h.Storage, user, id); err != nil {
+		writeAttachmentError(w, "Dosya silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// İmzalı bağlantıyla ek indirme (GET /api/attachments/{id}/download?expires=...&signature=..., JWT gerektirmez).
+// Depo süreli bağlantı üretebiliyorsa (S3) oraya yönlendirilir, aksi halde dosya api üzerinden akıtılır.
+func (h *Handler) DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r.URL.Path, "/api/attachments/", "/download")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz dosya ID", err)
+		return
+	}
+	a, err := GetAttachmentDownload(h.DBReplica, id, r.URL.Query())
+	switch {
+	case errors.Is(err, common.ErrLinkInvalid):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+		return
+	case errors.Is(err, common.ErrLinkExpired):
+		common.WriteError(w, http.StatusGone, err.Error(), err)
+		return
+	case err != nil:
+		writeAttachmentError(w, "Dosya alınamadı", err)
+		return
+	}
+	if h.Storage == nil {
+		writeAttachmentError(w, "Dosya alınamadı", ErrStorageDisabled)
+		return
+	}
+	if p, ok := h.Storage.(storage.Presigner); ok {
+		link, err := p.PresignGet(a.StorageKey, attachmentLinkTTL, a.Filename, a.ContentType)
+		if err != nil {
+			common.WriteError(w, http.StatusInternalServerError, "İndirme bağlantısı oluşturulamadı", err)
+			return
+		}
+		http.Redirect(w, r, link, http.StatusFound)
+		return
+	}
+	body, err := h.Storage.Get(r.Context(), a.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			err = ErrAttachmentNotFound
+		}
+		writeAttachmentError(w, "Dosya açılamadı", err)
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Ek %d gönderilemedi: %v", a.ID, err)
+	}
+}
+
+// Servis hatasını uygun HTTP durum koduna çevirir
+func writeAttachmentError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrAttachmentNotFound), errors.Is(err, ErrCustomerNotFound), errors.Is(err, ErrContactNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrAttachmentForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrAttachmentTooLarge):
+		common.WriteError(w, http.StatusRequestEntityTooLarge, err.Error(), err)
+	case errors.Is(err, ErrAttachmentType):
+		common.WriteError(w, http.StatusUnsupportedMediaType, err.Error(), err)
+	case errors.Is(err, storage.ErrInfected):
+		common.WriteError(w, http.StatusUnprocessableEntity, err.Error(), err)
+	case errors.Is(err, ErrStorageDisabled):
+		common.WriteError(w, http.StatusServiceUnavailable, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
@@ -1,18 +1,334 @@
 package customer
 
+import (
+	"net/mail"
+	"time"
+)
+
 // Customer veri modeli
 // Veritabanı ve API için ortak kullanılacak
 
 type Customer struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Phone string `json:"phone"`
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	OwnerID    int    `json:"owner_id,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	LeadSource string `json:"lead_source,omitempty"`
+	TenantID   int    `json:"-"`
+	// Tenant'ın tanımladığı özel alanların değerleri (anahtar -> değer)
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	Tags         []string               `json:"tags,omitempty"`
+	// Sadece müşteri eklenirken dolar; kayıt yine de oluşturulur
+	PossibleDuplicates []DuplicateMatch `json:"possible_duplicates,omitempty"`
+}
+
+// Özel alan tipleri
+const (
+	FieldText        = "text"
+	FieldNumber      = "number"
+	FieldDate        = "date" // YYYY-MM-DD
+	FieldEnum        = "enum"
+	FieldMultiSelect = "multi_select"
+)
+
+// Yöneticinin tenant için tanımladığı müşteri özel alanı (ör. vergi numarası, sözleşme yenileme tarihi)
+type FieldDefinition struct {
+	ID       int         `json:"id"`
+	Key      string      `json:"key"` // custom_fields içindeki anahtar, ör. "tax_number"
+	Label    string      `json:"label"`
+	Type     string      `json:"type"`
+	Required bool        `json:"required"`
+	Options  []string    `json:"options,omitempty"` // enum ve multi_select için izin verilen değerler
+	Default  interface{} `json:"default,omitempty"`
+	Position int         `json:"position"`
+}
+
+// Müşteri sorumlusu değişikliği
+type OwnerChange struct {
+	ID          int    `json:"id"`
+	CustomerID  int    `json:"customer_id"`
+	FromOwnerID int    `json:"from_owner_id,omitempty"`
+	ToOwnerID   int    `json:"to_owner_id"`
+	ChangedBy   int    `json:"changed_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	ChangedAt   string `json:"changed_at"`
 }
 
+// Atama kuralı tipleri
+const (
+	RuleRoundRobin = "round_robin" // Her müşteriyle eşleşir
+	RuleRegion     = "region"      // Bölge birebir (büyük/küçük harf duyarsız)
+	RulePostalCode = "postal_code" // Posta kodu öneki, ör. "34" İstanbul
+	RuleLeadSource = "lead_source" // Kaynak birebir, ör. "web", "fuar"
+)
+
+// Yeni müşteriye otomatik sorumlu atayan kural.
+// Kurallar Position sırasıyla denenir, ilk eşleşen kural atamayı yapar;
+// birden fazla atanan varsa aralarında sırayla (round-robin) dağıtılır.
+type AssignmentRule struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Position    int     `json:"position"`
+	Type        string  `json:"type"`
+	MatchValue  string  `json:"match_value,omitempty"`
+	AssigneeIDs []int64 `json:"assignee_ids"`
+	Active      bool    `json:"active"`
+}
+
+// İletişim (etkileşim) tipleri
+const (
+	ContactTypeNote    = "note"
+	ContactTypeCall    = "call"
+	ContactTypeEmail   = "email"
+	ContactTypeMeeting = "meeting"
+)
+
+// Arama ve e-postalar için yön
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
 type Contact struct {
-	ID         int    `json:"id"`
-	CustomerID int    `json:"customer_id"`
-	Content    string `json:"content"`
-	CreatedAt  string `json:"created_at"`
+	ID              int       `json:"id"`
+	CustomerID      int       `json:"customer_id"`
+	CustomerName    string    `json:"customer_name,omitempty"` // Sadece aktivite akışında doldurulur
+	AuthorID        int       `json:"author_id,omitempty"`
+	Type            string    `json:"type"`                       // note, call, email, meeting
+	Direction       string    `json:"direction,omitempty"`        // inbound, outbound (call ve email)
+	DurationSeconds int       `json:"duration_seconds,omitempty"` // call ve meeting
+	Outcome         string    `json:"outcome,omitempty"`
+	Participants    []string  `json:"participants,omitempty"`
+	Content         string    `json:"content"`
+	OccurredAt      time.Time `json:"occurred_at"` // Etkileşimin gerçekleştiği zaman (boşsa kayıt zamanı)
+	CreatedAt       string    `json:"created_at"`
+}
+
+// İletişim kaydının düzenleme/silme öncesi hali
+type ContactRevision struct {
+	ID        int     `json:"id"`
+	ContactID int     `json:"contact_id"`
+	Revision  int     `json:"revision"`
+	Action    string  `json:"action"` // update, delete
+	EditedBy  int     `json:"edited_by,omitempty"`
+	Snapshot  Contact `json:"snapshot"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// Tip bazında iletişim sayıları (raporlama)
+type ContactTypeStat struct {
+	Type                 string `json:"type"`
+	Count                int    `json:"count"`
+	TotalDurationSeconds int    `json:"total_duration_seconds"`
+}
+
+// Ayrıştırılmış gelen e-posta (RFC 822)
+type InboundEmail struct {
+	MessageID   string
+	InReplyTo   string
+	References  []string
+	From        mail.Address
+	To          []mail.Address
+	Cc          []mail.Address
+	Subject     string
+	Date        time.Time
+	Text        string // text/plain gövde; yoksa HTML'den türetilir
+	Attachments []EmailAttachment
+}
+
+// E-posta eki; içerik sadece ayrıştırma sırasında tutulur, kayıtta üst veri saklanır
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Data        []byte `json:"-"`
+}
+
+// email tipi iletişim kaydının mesaj bilgileri (konuşma zinciri için)
+type ContactEmail struct {
+	ContactID   int               `json:"contact_id"`
+	MessageID   string            `json:"message_id"`
+	InReplyTo   string            `json:"in_reply_to,omitempty"`
+	ThreadID    string            `json:"thread_id"`
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Subject     string            `json:"subject"`
+	Attachments []EmailAttachment `json:"attachments"`
+}
+
+// Konuşmadaki bir mesaj
+type EmailThreadEntry struct {
+	Contact
+	Email ContactEmail `json:"email"`
+}
+
+// E-posta alımının sonucu: oluşturulan kayıtlar, zaten loglanmış müşteriler ve eşleşmeyen adresler
+type EmailIngestResult struct {
+	MessageID  string    `json:"message_id"`
+	ThreadID   string    `json:"thread_id"`
+	Direction  string    `json:"direction"`
+	Contacts   []Contact `json:"contacts"`
+	Duplicates []int     `json:"duplicate_customer_ids,omitempty"`
+	Unmatched  []string  `json:"unmatched_addresses"`
+}
+
+// Müşteriye (ve isteğe bağlı olarak bir iletişim kaydına) bağlı dosya; içerik depolama sürücüsündedir
+type Attachment struct {
+	ID          int       `json:"id"`
+	CustomerID  int       `json:"customer_id"`
+	ContactID   int       `json:"contact_id,omitempty"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	ScanStatus  string    `json:"scan_status"` // clean, skipped (tarayıcı yapılandırılmamış)
+	UploadedBy  int       `json:"uploaded_by,omitempty"`
+	TenantID    int       `json:"-"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	DownloadURL string    `json:"download_url,omitempty"` // Süreli, oturum gerektirmeyen indirme bağlantısı
+}
+
+// Arama sonucu tipleri
+const (
+	SearchCustomers = "customers"
+	SearchContacts  = "contacts"
+)
+
+// Tam metin arama eşleşmesi. Snippet'te eşleşen kelimeler <mark> ile işaretlenir,
+// geri kalan metin HTML olarak kaçırılmıştır.
+type SearchHit struct {
+	ID         int        `json:"id"`
+	CustomerID int        `json:"customer_id"`
+	Title      string     `json:"title"`
+	Snippet    string     `json:"snippet"`
+	Rank       float64    `json:"rank"`
+	OccurredAt *time.Time `json:"occurred_at,omitempty"` // Sadece iletişim kayıtlarında
+}
+
+type SearchResult struct {
+	Query     string      `json:"query"`
+	Customers []SearchHit `json:"customers"`
+	Contacts  []SearchHit `json:"contacts"`
+}
+
+// Mükerrer kayıt eşleşme nedenleri
+const (
+	DuplicateEmail = "email" // Normalize e-posta aynı
+	DuplicatePhone = "phone" // Telefonun son 10 hanesi aynı
+	DuplicateName  = "name"  // İsimler trigram olarak benzer
+)
+
+// Müşteri eklenirken uyarı olarak dönen olası mükerrer kayıt
+type DuplicateMatch struct {
+	CustomerID int      `json:"customer_id"`
+	Name       string   `json:"name"`
+	Email      string   `json:"email"`
+	Phone      string   `json:"phone,omitempty"`
+	Reasons    []string `json:"reasons"`
+	Similarity float64  `json:"similarity"` // İsim benzerliği (0-1)
+}
+
+// Mükerrer kayıt raporundaki çift, eski kayıt önce
+type DuplicatePair struct {
+	Customers  []Customer `json:"customers"`
+	Reasons    []string   `json:"reasons"`
+	Similarity float64    `json:"similarity"`
+}
+
+// Birleştirme sonucu: güncellenmiş kalan kayıt ve taşınan kayıt sayıları
+type MergeResult struct {
+	Customer Customer       `json:"customer"`
+	MergedID int            `json:"merged_id"`
+	Moved    map[string]int `json:"moved"` // contacts, deals, tasks, tags, accounts, emails, attachments, events
+}
+
+// Toplu içe aktarma modları; eşleşme normalize e-posta ile yapılır
+const (
+	ImportCreate         = "create"          // E-postası kayıtlı satır hata sayılır
+	ImportUpsert         = "upsert"          // E-postası kayıtlı müşteri güncellenir
+	ImportSkipDuplicates = "skip_duplicates" // E-postası kayıtlı satır atlanır
+)
+
+// İçe aktarma işinin durumları
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// Arka planda işlenen CSV/XLSX içe aktarma işi
+type CustomerImport struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	Mode     string `json:"mode"`
+	DryRun   bool   `json:"dry_run"`
+	// Sütun başlığı -> müşteri alanı (name, email, phone, region, postal_code, lead_source, owner_id, cf.<anahtar>)
+	Mapping       map[string]string `json:"mapping"`
+	Headers       []string          `json:"headers"`
+	Status        string            `json:"status"`
+	TotalRows     int               `json:"total_rows"`
+	ProcessedRows int               `json:"processed_rows"`
+	CreatedCount  int               `json:"created_count"`
+	UpdatedCount  int               `json:"updated_count"`
+	SkippedCount  int               `json:"skipped_count"`
+	ErrorCount    int               `json:"error_count"`
+	Error         string            `json:"error,omitempty"`
+	CreatedBy     int               `json:"created_by"`
+	TenantID      int               `json:"-"`
+	CreatedAt     time.Time         `json:"created_at"`
+	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+}
+
+// İçe aktarmada hatalı satır; Values dosyadaki ham değerlerdir
+type ImportRowError struct {
+	Row     int      `json:"row"`
+	Message string   `json:"message"`
+	Values  []string `json:"values"`
+}
+
+// Dışa aktarma kapsamları
+const (
+	ExportCustomers = "customers"
+	ExportContacts  = "contacts"
+)
+
+// Satır başına bir JSON nesnesi; csv ve xlsx için common.FormatCSV/FormatXLSX kullanılır
+const ExportJSONL = "jsonl"
+
+// Müşteri başına bir vCard 3.0 kaydı (sadece müşteri dışa aktarmada)
+const ExportVCF = "vcf"
+
+// Arka plan dışa aktarma durumları
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+	ExportExpired   = "expired" // Dosya saklama süresi dolduğu için silindi
+)
+
+// Büyük tenant'lar için arka planda dosyaya yazılan dışa aktarma
+type ExportJob struct {
+	ID         int        `json:"id"`
+	Type       string     `json:"type"`
+	Format     string     `json:"format"`
+	Query      string     `json:"query,omitempty"` // İstekteki filtre parametreleri
+	Status     string     `json:"status"`
+	RowCount   int        `json:"row_count"`
+	Error      string     `json:"error,omitempty"`
+	CreatedBy  int        `json:"created_by"`
+	TenantID   int        `json:"-"`
+	FilePath   string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Dosyanın silineceği zaman
+	// İmzalı ve süreli indirme bağlantısı; sadece tamamlanmış işte, her sorguda yeniden üretilir
+	DownloadURL string `json:"download_url,omitempty"`
 }
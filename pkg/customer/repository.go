@@ -1,58 +1,1381 @@
 package customer
 
 import (
+	"Go-CRM/pkg/common"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// İletişim sorgularında ortak seçilen sütunlar (scanContact ile aynı sırada)
+const contactColumns = "ct.id, ct.customer_id, ct.author_id, ct.type, ct.direction, ct.duration_seconds, ct.outcome, ct.participants, ct.content, ct.occurred_at, ct.created_at"
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Satırı Contact'a çevirir; ek sütunlar (örn. müşteri adı) extra ile sona eklenir
+func scanContact(rows scanner, contact *Contact, extra ...interface{}) (createdAt sql.NullTime, err error) {
+	var authorID sql.NullInt64
+	dest := []interface{}{
+		&contact.ID, &contact.CustomerID, &authorID, &contact.Type, &contact.Direction,
+		&contact.DurationSeconds, &contact.Outcome, pq.Array(&contact.Participants),
+		&contact.Content, &contact.OccurredAt, &createdAt,
+	}
+	if err = rows.Scan(append(dest, extra...)...); err != nil {
+		return createdAt, err
+	}
+	contact.AuthorID = int(authorID.Int64)
+	if createdAt.Valid {
+		contact.CreatedAt = createdAt.Time.Format("02-01-2006 15:04")
+	}
+	return createdAt, nil
+}
+
+// Müşteri listelerinde ortak seçilen sütunlar (scanCustomer ile aynı sırada)
+const customerColumns = "id, name, email, phone, owner_id, region, postal_code, lead_source, tenant_id, custom_fields"
+
+// extra, customerColumns'tan sonra seçilen ek sütunlar içindir
+func scanCustomer(row scanner, c *Customer, extra ...interface{}) error {
+	var ownerID sql.NullInt64
+	var customFields []byte
+	dest := append([]interface{}{&c.ID, &c.Name, &c.Email, &c.Phone, &ownerID, &c.Region, &c.PostalCode, &c.LeadSource,
+		&c.TenantID, &customFields}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	c.OwnerID = int(ownerID.Int64)
+	c.CustomFields = nil
+	if len(customFields) > 0 {
+		if err := json.Unmarshal(customFields, &c.CustomFields); err != nil {
+			return err
+		}
+		if len(c.CustomFields) == 0 {
+			c.CustomFields = nil
+		}
+	}
+	return nil
+}
+
+// Müşteri sütunlarını customerColumns sırasıyla seçen diğer paketlerin (ör. hesap kişileri) sorguları için
+func ScanCustomer(rows *sql.Rows, c *Customer, extra ...interface{}) error {
+	return scanCustomer(rows, c, extra...)
+}
+
 // Yeni müşteri ekler
 func createCustomerRepo(db *sql.DB, c *Customer) error {
+	customFields, err := marshalCustomFields(c.CustomFields)
+	if err != nil {
+		return err
+	}
 	return db.QueryRow(
-		"INSERT INTO customers (name, email, phone) VALUES ($1, $2, $3) RETURNING id",
-		c.Name, c.Email, c.Phone,
+		"INSERT INTO customers (name, email, phone, owner_id, region, postal_code, lead_source, tenant_id, custom_fields) VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9) RETURNING id",
+		c.Name, c.Email, c.Phone, c.OwnerID, c.Region, c.PostalCode, c.LeadSource, c.TenantID, customFields,
 	).Scan(&c.ID)
 }
 
+func getCustomerRepo(db *sql.DB, id int) (Customer, error) {
+	var c Customer
+	err := scanCustomer(db.QueryRow("SELECT "+customerColumns+" FROM customers WHERE id = $1", id), &c)
+	return c, err
+}
+
+// Müşteri bilgilerini günceller (sorumlu değişikliği ayrı uç noktadan yapılır)
+func updateCustomerRepo(db *sql.DB, c *Customer) error {
+	customFields, err := marshalCustomFields(c.CustomFields)
+	if err != nil {
+		return err
+	}
+	return scanCustomer(db.QueryRow(
+		"UPDATE customers SET name = $1, email = $2, phone = $3, region = $4, postal_code = $5, lead_source = $6, custom_fields = $7 WHERE id = $8 RETURNING "+customerColumns,
+		c.Name, c.Email, c.Phone, c.Region, c.PostalCode, c.LeadSource, customFields, c.ID,
+	), c)
+}
+
+func marshalCustomFields(fields map[string]interface{}) (string, error) {
+	if fields == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(fields)
+	return string(b), err
+}
+
+const fieldDefinitionColumns = "id, key, label, type, required, options, default_value, position"
+
+func scanFieldDefinition(row scanner, d *FieldDefinition) error {
+	var def []byte
+	if err := row.Scan(&d.ID, &d.Key, &d.Label, &d.Type, &d.Required, pq.Array(&d.Options), &def, &d.Position); err != nil {
+		return err
+	}
+	d.Default = nil
+	if len(def) > 0 {
+		return json.Unmarshal(def, &d.Default)
+	}
+	return nil
+}
+
+// Tenant'ın özel alan tanımlarını sırasıyla getirir
+func getFieldDefinitionsRepo(db *sql.DB, tenantID int) ([]FieldDefinition, error) {
+	rows, err := db.Query("SELECT "+fieldDefinitionColumns+" FROM custom_field_definitions WHERE tenant_id = $1 ORDER BY position, id", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := []FieldDefinition{}
+	for rows.Next() {
+		var d FieldDefinition
+		if err := scanFieldDefinition(rows, &d); err != nil {
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+	return defs, rows.Err()
+}
+
+// Varsayılan değer yoksa NULL yazılır
+func fieldDefaultValue(d FieldDefinition) (interface{}, error) {
+	if d.Default == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(d.Default)
+	return string(b), err
+}
+
+func createFieldDefinitionRepo(db *sql.DB, tenantID int, d *FieldDefinition) error {
+	def, err := fieldDefaultValue(*d)
+	if err != nil {
+		return err
+	}
+	return scanFieldDefinition(db.QueryRow(`
+		INSERT INTO custom_field_definitions (tenant_id, key, label, type, required, options, default_value, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+fieldDefinitionColumns,
+		tenantID, d.Key, d.Label, d.Type, d.Required, pq.Array(d.Options), def, d.Position,
+	), d)
+}
+
+// Anahtar ve tip değiştirilemez; mevcut değerlerin anlamı bozulmasın diye sadece etiket, zorunluluk, seçenekler, varsayılan ve sıra güncellenir
+func updateFieldDefinitionRepo(db *sql.DB, tenantID int, d *FieldDefinition) error {
+	def, err := fieldDefaultValue(*d)
+	if err != nil {
+		return err
+	}
+	return scanFieldDefinition(db.QueryRow(`
+		UPDATE custom_field_definitions SET label = $1, required = $2, options = $3, default_value = $4, position = $5
+		WHERE id = $6 AND tenant_id = $7 RETURNING `+fieldDefinitionColumns,
+		d.Label, d.Required, pq.Array(d.Options), def, d.Position, d.ID, tenantID,
+	), d)
+}
+
+func getFieldDefinitionRepo(db *sql.DB, tenantID, id int) (FieldDefinition, error) {
+	var d FieldDefinition
+	err := scanFieldDefinition(db.QueryRow("SELECT "+fieldDefinitionColumns+" FROM custom_field_definitions WHERE id = $1 AND tenant_id = $2", id, tenantID), &d)
+	return d, err
+}
+
+// Tanımı siler; müşterilerdeki değerler de temizlenir
+func deleteFieldDefinitionRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var key string
+	err = tx.QueryRow("DELETE FROM custom_field_definitions WHERE id = $1 AND tenant_id = $2 RETURNING key", id, tenantID).Scan(&key)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE customers SET custom_fields = custom_fields - $1 WHERE tenant_id = $2 AND custom_fields ? $1", key, tenantID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// exec için *sql.DB ve *sql.Tx ortak arayüzü
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// *sql.DB ve *sql.Tx için tek satırlık sorgu arayüzü
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// ids içinden tenant'ta bulunan kullanıcı sayısı
+func countTenantUsersRepo(db *sql.DB, tenantID int, ids []int64) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE tenant_id = $1 AND id = ANY($2)", tenantID, pq.Array(ids)).Scan(&n)
+	return n, err
+}
+
+func insertOwnerChangeRepo(db execer, customerID, from, to, changedBy int, reason string) error {
+	_, err := db.Exec(
+		"INSERT INTO customer_owner_history (customer_id, from_owner_id, to_owner_id, changed_by, reason) VALUES ($1, NULLIF($2, 0), $3, NULLIF($4, 0), $5)",
+		customerID, from, to, changedBy, reason,
+	)
+	return err
+}
+
+// Müşterinin sorumlusunu değiştirir ve geçmişe yazar, önceki sorumluyu döner
+func reassignCustomerRepo(db *sql.DB, customerID, to, changedBy int, reason string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var from sql.NullInt64
+	if err := tx.QueryRow("SELECT owner_id FROM customers WHERE id = $1 FOR UPDATE", customerID).Scan(&from); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE customers SET owner_id = $1 WHERE id = $2", to, customerID); err != nil {
+		return 0, err
+	}
+	if err := insertOwnerChangeRepo(tx, customerID, int(from.Int64), to, changedBy, reason); err != nil {
+		return 0, err
+	}
+	return int(from.Int64), tx.Commit()
+}
+
+// Bir sorumlunun tenant'taki tüm müşterilerini hedef sorumlular arasında sırayla dağıtır, taşınan müşteri sayısını döner
+func bulkReassignRepo(db *sql.DB, tenantID, from int, to []int64, changedBy int, reason string) (int, error) {
+	res, err := db.Exec(`
+		WITH moved AS (
+			SELECT id, ($2::int[])[((row_number() OVER (ORDER BY id)) - 1) % array_length($2::int[], 1) + 1] AS to_owner
+			FROM customers WHERE owner_id = $1 AND tenant_id = $5
+		), upd AS (
+			UPDATE customers c SET owner_id = m.to_owner FROM moved m WHERE c.id = m.id
+			RETURNING c.id, m.to_owner
+		)
+		INSERT INTO customer_owner_history (customer_id, from_owner_id, to_owner_id, changed_by, reason)
+		SELECT id, $1, to_owner, NULLIF($3, 0), $4 FROM upd`,
+		from, pq.Array(to), changedBy, reason, tenantID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func getOwnerHistoryRepo(db *sql.DB, customerID int) ([]OwnerChange, error) {
+	rows, err := db.Query(`
+		SELECT id, customer_id, from_owner_id, to_owner_id, changed_by, reason, changed_at
+		FROM customer_owner_history WHERE customer_id = $1 ORDER BY changed_at DESC, id DESC`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []OwnerChange{}
+	for rows.Next() {
+		var c OwnerChange
+		var from, changedBy sql.NullInt64
+		var changedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.CustomerID, &from, &c.ToOwnerID, &changedBy, &c.Reason, &changedAt); err != nil {
+			return nil, err
+		}
+		c.FromOwnerID = int(from.Int64)
+		c.ChangedBy = int(changedBy.Int64)
+		if changedAt.Valid {
+			c.ChangedAt = changedAt.Time.Format("02-01-2006 15:04")
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+const ruleColumns = "id, name, position, type, match_value, assignee_ids, active"
+
+func scanRule(row scanner, r *AssignmentRule) error {
+	return row.Scan(&r.ID, &r.Name, &r.Position, &r.Type, &r.MatchValue, pq.Array(&r.AssigneeIDs), &r.Active)
+}
+
+// Tenant'ın atama kurallarını sırasıyla getirir
+func getAssignmentRulesRepo(db *sql.DB, tenantID int, onlyActive bool) ([]AssignmentRule, error) {
+	query := "SELECT " + ruleColumns + " FROM assignment_rules WHERE tenant_id = $1"
+	if onlyActive {
+		query += " AND active"
+	}
+	rows, err := db.Query(query+" ORDER BY position, id", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []AssignmentRule{}
+	for rows.Next() {
+		var r AssignmentRule
+		if err := scanRule(rows, &r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func createAssignmentRuleRepo(db *sql.DB, tenantID int, r *AssignmentRule) error {
+	return scanRule(db.QueryRow(
+		"INSERT INTO assignment_rules (tenant_id, name, position, type, match_value, assignee_ids, active) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING "+ruleColumns,
+		tenantID, r.Name, r.Position, r.Type, r.MatchValue, pq.Array(r.AssigneeIDs), r.Active,
+	), r)
+}
+
+func updateAssignmentRuleRepo(db *sql.DB, tenantID int, r *AssignmentRule) error {
+	return scanRule(db.QueryRow(
+		"UPDATE assignment_rules SET name = $1, position = $2, type = $3, match_value = $4, assignee_ids = $5, active = $6 WHERE id = $7 AND tenant_id = $8 RETURNING "+ruleColumns,
+		r.Name, r.Position, r.Type, r.MatchValue, pq.Array(r.AssigneeIDs), r.Active, r.ID, tenantID,
+	), r)
+}
+
+func deleteAssignmentRuleRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	res, err := db.Exec("DELETE FROM assignment_rules WHERE id = $1 AND tenant_id = $2", id, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Kuralın round-robin sayacını atomik olarak artırır, artıştan önceki değeri döner
+func nextRoundRobinRepo(db *sql.DB, ruleID int) (int, error) {
+	var n int
+	err := db.QueryRow("UPDATE assignment_rules SET rr_cursor = rr_cursor + 1 WHERE id = $1 RETURNING rr_cursor - 1", ruleID).Scan(&n)
+	return n, err
+}
+
 // Belirli bir müşterinin iletişim kayıtlarını getirir (pagination)
 func getContactsByCustomerIDRepo(db *sql.DB, params ContactListParams) (ContactListResult, error) {
-	query := "SELECT id, customer_id, content, created_at FROM contacts WHERE customer_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
-	rows, err := db.Query(query, params.CustomerID, params.PageSize, (params.Page-1)*params.PageSize)
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{"ct.customer_id = " + arg(params.CustomerID)}
+	if params.Type != "" {
+		where = append(where, "ct.type = "+arg(params.Type))
+	}
+	filterWhere, err := ContactListFields.Where(params.Filter, arg)
+	if err != nil {
+		return ContactListResult{}, err
+	}
+	where = append(where, filterWhere...)
+	keyset, err := ContactListFields.Keyset(params.Sort, defaultContactSort, "id")
+	if err != nil {
+		return ContactListResult{}, err
+	}
+	filter := strings.Join(where, " AND ")
+	filterArgs := len(args)
+	scope := fmt.Sprintf("contacts|%s|%v|%s", filter, args, keyset.OrderBy(false))
+	cur, err := common.DecodeCursor(params.Cursor, scope)
+	if err != nil {
+		return ContactListResult{}, err
+	}
+	if cur != nil {
+		after, err := keyset.After(cur, arg)
+		if err != nil {
+			return ContactListResult{}, err
+		}
+		where = append(where, after)
+	}
+
+	query := "SELECT " + contactColumns + ", " + keyset.Columns() + " FROM contacts ct WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY " + keyset.OrderBy(cur != nil && cur.Backward) + " LIMIT " + arg(params.PageSize+1)
+	if params.Page > 1 {
+		query += " OFFSET " + arg((params.Page-1)*params.PageSize)
+	}
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return ContactListResult{}, err
 	}
 	defer rows.Close()
 
-	var contacts []Contact
+	var (
+		contacts []Contact
+		keys     [][]string
+	)
 	for rows.Next() {
 		var contact Contact
-		var createdAt sql.NullTime
-		if err := rows.Scan(&contact.ID, &contact.CustomerID, &contact.Content, &createdAt); err != nil {
+		dest := keyset.Dest()
+		if _, err := scanContact(rows, &contact, dest...); err != nil {
 			return ContactListResult{}, err
 		}
-		if createdAt.Valid {
-			contact.CreatedAt = createdAt.Time.Format("02-01-2006 15:04")
-		}
 		contacts = append(contacts, contact)
+		keys = append(keys, keyset.Values(dest))
 	}
-
-	// Toplam kayıt sayısı
-	var total int
-	countQ := "SELECT COUNT(*) FROM contacts WHERE customer_id = $1"
-	if err := db.QueryRow(countQ, params.CustomerID).Scan(&total); err != nil {
+	if err := rows.Err(); err != nil {
 		return ContactListResult{}, err
 	}
 
-	return ContactListResult{
-		Contacts: contacts,
-		Total:    total,
-		Page:     params.Page,
-		PageSize: params.PageSize,
-	}, nil
+	result := ContactListResult{PageSize: params.PageSize}
+	var n int
+	n, result.NextCursor, result.PrevCursor = common.PageCursors(scope, cur, keys, params.PageSize)
+	result.Contacts = contacts[:n]
+	if cur != nil && cur.Backward {
+		slices.Reverse(result.Contacts)
+	}
+	if params.Cursor == "" {
+		result.Page = params.Page
+	}
+
+	// Toplam kayıt sayısı (isteğe bağlı)
+	if params.IncludeTotal {
+		var total int
+		countQ := "SELECT COUNT(*) FROM contacts ct WHERE " + filter
+		if err := db.QueryRow(countQ, args[:filterArgs]...).Scan(&total); err != nil {
+			return ContactListResult{}, err
+		}
+		result.Total = &total
+	}
+	return result, nil
 }
 
 // Yeni iletişim kaydı ekler
-func createContactRepo(db *sql.DB, contact *Contact) error {
+func createContactRepo(db queryRower, contact *Contact) error {
 	return db.QueryRow(
-		"INSERT INTO contacts (customer_id, content) VALUES ($1, $2) RETURNING id, created_at",
-		contact.CustomerID, contact.Content,
+		`INSERT INTO contacts (customer_id, author_id, type, direction, duration_seconds, outcome, participants, content, occurred_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`,
+		contact.CustomerID, contact.AuthorID, contact.Type, contact.Direction, contact.DurationSeconds,
+		contact.Outcome, pq.Array(contact.Participants), contact.Content, contact.OccurredAt,
 	).Scan(&contact.ID, &contact.CreatedAt)
 }
+
+// Düzenleme/silme için iletişim kaydını kilitleyerek getirir
+func getContactForUpdateRepo(tx *sql.Tx, id int) (Contact, error) {
+	var contact Contact
+	row := tx.QueryRow("SELECT "+contactColumns+" FROM contacts ct WHERE ct.id = $1 FOR UPDATE", id)
+	_, err := scanContact(row, &contact)
+	return contact, err
+}
+
+// Kaydın mevcut halini bir sonraki revizyon numarasıyla saklar
+func insertContactRevisionRepo(tx *sql.Tx, old Contact, action string, editedBy int) error {
+	snapshot, err := json.Marshal(old)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO contact_revisions (contact_id, revision, action, edited_by, snapshot)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, NULLIF($3, 0), $4 FROM contact_revisions WHERE contact_id = $1`,
+		old.ID, action, editedBy, snapshot,
+	)
+	return err
+}
+
+// İletişim kaydının düzenlenebilir alanlarını günceller
+func updateContactRepo(tx *sql.Tx, contact *Contact) error {
+	_, err := tx.Exec(`
+		UPDATE contacts SET type = $1, direction = $2, duration_seconds = $3, outcome = $4,
+			participants = $5, content = $6, occurred_at = $7, updated_at = now()
+		WHERE id = $8`,
+		contact.Type, contact.Direction, contact.DurationSeconds, contact.Outcome,
+		pq.Array(contact.Participants), contact.Content, contact.OccurredAt, contact.ID,
+	)
+	return err
+}
+
+func deleteContactRepo(tx *sql.Tx, id int) error {
+	_, err := tx.Exec("DELETE FROM contacts WHERE id = $1", id)
+	return err
+}
+
+// Kaydın müşterisi ve yazarı (yazar silinmişse 0)
+func contactAuthorRepo(db *sql.DB, contactID int) (customerID, authorID int, err error) {
+	var author sql.NullInt64
+	err = db.QueryRow("SELECT customer_id, author_id FROM contacts WHERE id = $1", contactID).Scan(&customerID, &author)
+	return customerID, int(author.Int64), err
+}
+
+// İletişim kaydının revizyonlarını en yeniden eskiye listeler
+func getContactRevisionsRepo(db *sql.DB, contactID int) ([]ContactRevision, error) {
+	rows, err := db.Query(`
+		SELECT id, contact_id, revision, action, edited_by, snapshot, created_at
+		FROM contact_revisions WHERE contact_id = $1 ORDER BY revision DESC`, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []ContactRevision{}
+	for rows.Next() {
+		var rev ContactRevision
+		var editedBy sql.NullInt64
+		var snapshot []byte
+		var createdAt sql.NullTime
+		if err := rows.Scan(&rev.ID, &rev.ContactID, &rev.Revision, &rev.Action, &editedBy, &snapshot, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(snapshot, &rev.Snapshot); err != nil {
+			return nil, err
+		}
+		rev.EditedBy = int(editedBy.Int64)
+		if createdAt.Valid {
+			rev.CreatedAt = createdAt.Time.Format("02-01-2006 15:04")
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// Tüm müşterilerin iletişim kayıtlarını filtreli olarak getirir (keyset pagination).
+// Sıralama (created_at DESC, id DESC); bir fazla kayıt çekilerek sonraki sayfanın varlığı anlaşılır.
+// İmleç filtreye bağlı olarak imzalanır, başka bir filtreyle kullanılamaz.
+func getContactFeedRepo(db *sql.DB, params ContactFeedParams) (ContactFeedResult, error) {
+	var (
+		args  []interface{}
+		where []string
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	tenant := arg(params.TenantID)
+	where = append(where, "c.tenant_id = "+tenant)
+	if !params.From.IsZero() {
+		where = append(where, "ct.created_at >= "+arg(params.From))
+	}
+	if !params.To.IsZero() {
+		where = append(where, "ct.created_at < "+arg(params.To))
+	}
+	if params.AuthorID > 0 {
+		where = append(where, "ct.author_id = "+arg(params.AuthorID))
+	}
+	if params.CustomerID > 0 {
+		where = append(where, "ct.customer_id = "+arg(params.CustomerID))
+	}
+	if params.AccountID > 0 {
+		where = append(where, "ct.customer_id IN ("+AccountPeopleSubquery(arg(params.AccountID), tenant)+")")
+	}
+	if params.Type != "" {
+		where = append(where, "ct.type = "+arg(params.Type))
+	}
+	filterWhere, err := ContactListFields.Where(params.Filter, arg)
+	if err != nil {
+		return ContactFeedResult{}, err
+	}
+	where = append(where, filterWhere...)
+	scope := fmt.Sprintf("contact-feed|%s|%v", strings.Join(where, " AND "), args)
+	cur, err := common.DecodeCursor(params.Cursor, scope)
+	if err != nil {
+		return ContactFeedResult{}, err
+	}
+	if cur != nil {
+		// Akış sadece ileri sayfalanır; anahtar (created_at, id)
+		if cur.Backward || len(cur.Values) != 2 {
+			return ContactFeedResult{}, common.ErrInvalidCursor
+		}
+		createdAt, err1 := time.Parse(time.RFC3339Nano, cur.Values[0])
+		id, err2 := strconv.Atoi(cur.Values[1])
+		if err1 != nil || err2 != nil {
+			return ContactFeedResult{}, common.ErrInvalidCursor
+		}
+		where = append(where, fmt.Sprintf("(ct.created_at, ct.id) < (%s, %s)", arg(createdAt), arg(id)))
+	}
+
+	query := "SELECT " + contactColumns + ", c.name FROM contacts ct JOIN customers c ON c.id = ct.customer_id WHERE " +
+		strings.Join(where, " AND ") + " ORDER BY ct.created_at DESC, ct.id DESC LIMIT " + arg(params.Limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return ContactFeedResult{}, err
+	}
+	defer rows.Close()
+
+	var (
+		contacts []Contact
+		keys     []time.Time
+	)
+	for rows.Next() {
+		var contact Contact
+		createdAt, err := scanContact(rows, &contact, &contact.CustomerName)
+		if err != nil {
+			return ContactFeedResult{}, err
+		}
+		contacts = append(contacts, contact)
+		keys = append(keys, createdAt.Time)
+	}
+	if err := rows.Err(); err != nil {
+		return ContactFeedResult{}, err
+	}
+
+	result := ContactFeedResult{Contacts: contacts}
+	if len(contacts) > params.Limit {
+		last := contacts[params.Limit-1]
+		result.Contacts = contacts[:params.Limit]
+		result.NextCursor = common.EncodeCursor(scope, common.Cursor{
+			Values: []string{keys[params.Limit-1].Format(time.RFC3339Nano), strconv.Itoa(last.ID)},
+		})
+	}
+	return result, nil
+}
+
+// Tarih aralığında tip bazında iletişim sayıları ve toplam süreler
+func getContactStatsRepo(db *sql.DB, params ContactStatsParams) ([]ContactTypeStat, error) {
+	var (
+		args  []interface{}
+		where []string
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where = append(where, "c.tenant_id = "+arg(params.TenantID))
+	if !params.From.IsZero() {
+		where = append(where, "ct.occurred_at >= "+arg(params.From))
+	}
+	if !params.To.IsZero() {
+		where = append(where, "ct.occurred_at < "+arg(params.To))
+	}
+	if params.AuthorID > 0 {
+		where = append(where, "ct.author_id = "+arg(params.AuthorID))
+	}
+	query := "SELECT ct.type, COUNT(*), COALESCE(SUM(ct.duration_seconds), 0) FROM contacts ct JOIN customers c ON c.id = ct.customer_id" +
+		" WHERE " + strings.Join(where, " AND ") + " GROUP BY ct.type ORDER BY ct.type"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []ContactTypeStat{}
+	for rows.Next() {
+		var s ContactTypeStat
+		if err := rows.Scan(&s.Type, &s.Count, &s.TotalDurationSeconds); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// Hesap ve tüm alt hesaplarına bağlı kişilerin ID'lerini veren alt sorgu.
+// param ve tenantParam, hesap ve tenant ID'lerinin yer tutucularıdır (ör. "$3", "$1");
+// fırsat gibi başka paketler de filtrelemede kullanır.
+func AccountPeopleSubquery(param, tenantParam string) string {
+	return `SELECT ap.customer_id FROM account_people ap WHERE ap.tenant_id = ` + tenantParam + ` AND ap.account_id IN (
+		WITH RECURSIVE sub AS (
+			SELECT id FROM accounts WHERE id = ` + param + ` AND tenant_id = ` + tenantParam + `
+			UNION ALL
+			SELECT a.id FROM accounts a JOIN sub ON a.parent_id = sub.id
+		) SELECT id FROM sub)`
+}
+
+// Müşterilerin etiket isimlerini toplu getirir (müşteri ID -> etiketler)
+func getCustomerTagsRepo(db *sql.DB, customerIDs []int64) (map[int][]string, error) {
+	tags := map[int][]string{}
+	if len(customerIDs) == 0 {
+		return tags, nil
+	}
+	rows, err := db.Query(`
+		SELECT ctg.customer_id, t.name FROM customer_tags ctg JOIN tags t ON t.id = ctg.tag_id
+		WHERE ctg.customer_id = ANY($1) ORDER BY t.name`, pq.Array(customerIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], name)
+	}
+	return tags, rows.Err()
+}
+
+// Tam metin arama yapılandırması (Türkçe kök bulma + unaccent), şema güncellemelerinde oluşturulur
+const searchConfig = "turkish_unaccent"
+
+// ts_headline'a verilmeden önce metni HTML olarak kaçırır; sadece <mark> etiketleri ham kalır
+func htmlEscapeSQL(expr string) string {
+	return "replace(replace(replace(" + expr + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
+
+func scanSearchHits(rows *sql.Rows, withTime bool) ([]SearchHit, error) {
+	defer rows.Close()
+	hits := []SearchHit{}
+	for rows.Next() {
+		var h SearchHit
+		dest := []interface{}{&h.ID, &h.CustomerID, &h.Title, &h.Snippet, &h.Rank}
+		var occurredAt time.Time
+		if withTime {
+			dest = append(dest, &occurredAt)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if withTime {
+			h.OccurredAt = &occurredAt
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+func searchCustomersRepo(db *sql.DB, tenantID int, tsq string, limit int) ([]SearchHit, error) {
+	rows, err := db.Query(`
+		SELECT id, id, name,
+			ts_headline('`+searchConfig+`', `+htmlEscapeSQL("name || ' ' || COALESCE(email, '')")+`, q, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+			ts_rank_cd(search_vector, q)
+		FROM customers CROSS JOIN to_tsquery('`+searchConfig+`', $1) q
+		WHERE tenant_id = $2 AND search_vector @@ q
+		ORDER BY 5 DESC, id DESC
+		LIMIT $3`, tsq, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSearchHits(rows, false)
+}
+
+func searchContactsRepo(db *sql.DB, tenantID int, tsq string, limit int) ([]SearchHit, error) {
+	rows, err := db.Query(`
+		SELECT ct.id, ct.customer_id, c.name,
+			ts_headline('`+searchConfig+`', `+htmlEscapeSQL("ct.content")+`, q,
+				'MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" ... ", StartSel=<mark>, StopSel=</mark>'),
+			ts_rank_cd(ct.search_vector, q), ct.occurred_at
+		FROM contacts ct
+		JOIN customers c ON c.id = ct.customer_id
+		CROSS JOIN to_tsquery('`+searchConfig+`', $1) q
+		WHERE c.tenant_id = $2 AND ct.search_vector @@ q
+		ORDER BY 5 DESC, ct.occurred_at DESC
+		LIMIT $3`, tsq, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSearchHits(rows, true)
+}
+
+// Yeni veya mevcut bir kayda benzeyen müşteriler; excludeID kaydın kendisidir
+func findDuplicatesRepo(db *sql.DB, tenantID, excludeID int, email, phone, name string, threshold float64) ([]DuplicateMatch, error) {
+	rows, err := db.Query(`
+		SELECT id, name, email, COALESCE(phone, ''),
+			$2::text <> '' AND email_normalized = $2::text,
+			$3::text <> '' AND phone_normalized = $3::text,
+			similarity(name, $4::text)
+		FROM customers
+		WHERE tenant_id = $1 AND id <> $5 AND (
+			($2::text <> '' AND email_normalized = $2::text) OR
+			($3::text <> '' AND phone_normalized = $3::text) OR
+			(name % $4::text AND similarity(name, $4::text) >= $6)
+		)
+		ORDER BY 5 DESC, 6 DESC, 7 DESC, id
+		LIMIT 10`, tenantID, email, phone, name, excludeID, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []DuplicateMatch
+	for rows.Next() {
+		var m DuplicateMatch
+		var emailMatch, phoneMatch bool
+		if err := rows.Scan(&m.CustomerID, &m.Name, &m.Email, &m.Phone, &emailMatch, &phoneMatch, &m.Similarity); err != nil {
+			return nil, err
+		}
+		if emailMatch {
+			m.Reasons = append(m.Reasons, DuplicateEmail)
+		}
+		if phoneMatch {
+			m.Reasons = append(m.Reasons, DuplicatePhone)
+		}
+		if m.Similarity >= threshold {
+			m.Reasons = append(m.Reasons, DuplicateName)
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Tenant'taki tüm mükerrer çiftler; her eşleşme tipi kendi index'ini kullanır
+func getDuplicatePairsRepo(db *sql.DB, tenantID int, threshold float64, page, pageSize int) ([]DuplicatePair, int, error) {
+	rows, err := db.Query(`
+		WITH pairs AS (
+			SELECT a.id AS a_id, b.id AS b_id, 'email' AS reason
+			FROM customers a
+			JOIN customers b ON b.tenant_id = a.tenant_id AND b.email_normalized = a.email_normalized AND b.id > a.id
+			WHERE a.tenant_id = $1 AND a.email_normalized <> ''
+			UNION ALL
+			SELECT a.id, b.id, 'phone'
+			FROM customers a
+			JOIN customers b ON b.tenant_id = a.tenant_id AND b.phone_normalized = a.phone_normalized AND b.id > a.id
+			WHERE a.tenant_id = $1 AND length(a.phone_normalized) >= 7
+			UNION ALL
+			SELECT a.id, b.id, 'name'
+			FROM customers a
+			JOIN customers b ON b.tenant_id = a.tenant_id AND b.name % a.name AND b.id > a.id
+			WHERE a.tenant_id = $1 AND similarity(a.name, b.name) >= $2
+		)
+		SELECT p.a_id, p.b_id, array_agg(DISTINCT p.reason ORDER BY p.reason), similarity(ca.name, cb.name), COUNT(*) OVER ()
+		FROM pairs p
+		JOIN customers ca ON ca.id = p.a_id
+		JOIN customers cb ON cb.id = p.b_id
+		GROUP BY p.a_id, p.b_id, ca.name, cb.name
+		ORDER BY COUNT(*) DESC, 4 DESC, p.a_id, p.b_id
+		LIMIT $3 OFFSET $4`, tenantID, threshold, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		pairs []DuplicatePair
+		ids   []int64
+		total int
+	)
+	for rows.Next() {
+		var a, b int64
+		var p DuplicatePair
+		if err := rows.Scan(&a, &b, pq.Array(&p.Reasons), &p.Similarity, &total); err != nil {
+			return nil, 0, err
+		}
+		p.Customers = []Customer{{ID: int(a)}, {ID: int(b)}}
+		pairs = append(pairs, p)
+		ids = append(ids, a, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return pairs, total, nil
+	}
+
+	// Çiftlerdeki müşterileri tek sorguda doldur
+	customerRows, err := db.Query("SELECT "+customerColumns+" FROM customers WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer customerRows.Close()
+	byID := map[int]Customer{}
+	for customerRows.Next() {
+		var c Customer
+		if err := scanCustomer(customerRows, &c); err != nil {
+			return nil, 0, err
+		}
+		byID[c.ID] = c
+	}
+	for i := range pairs {
+		for j, c := range pairs[i].Customers {
+			pairs[i].Customers[j] = byID[c.ID]
+		}
+	}
+	return pairs, total, customerRows.Err()
+}
+
+// İki müşteriyi tek işlemde birleştirir: bağlı kayıtları kalan kayda taşır, boş alanlarını
+// silinen kayıttan doldurur, birleştirmeyi loglar ve diğer kaydı siler
+func mergeCustomersRepo(db *sql.DB, tenantID int, survivorID int, merged Customer, mergedBy int) (map[string]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Kilitler id sırasıyla alınır ki eşzamanlı ters yönlü birleştirmeler kilitlenmesin
+	var locked int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT id FROM customers WHERE id IN ($1, $2) AND tenant_id = $3 ORDER BY id FOR UPDATE
+		) l`, survivorID, merged.ID, tenantID).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked != 2 {
+		return nil, ErrCustomerNotFound
+	}
+
+	moved := map[string]int{}
+	for _, m := range []struct{ key, query string }{
+		{"contacts", "UPDATE contacts SET customer_id = $1 WHERE customer_id = $2"},
+		{"deals", "UPDATE deals SET customer_id = $1 WHERE customer_id = $2"},
+		{"tasks", "UPDATE tasks SET customer_id = $1 WHERE customer_id = $2"},
+		{"tags", "UPDATE customer_tags SET customer_id = $1 WHERE customer_id = $2 AND tag_id NOT IN (SELECT tag_id FROM customer_tags WHERE customer_id = $1)"},
+		{"accounts", "UPDATE account_people SET customer_id = $1 WHERE customer_id = $2 AND account_id NOT IN (SELECT account_id FROM account_people WHERE customer_id = $1)"},
+		{"emails", "UPDATE contact_emails SET customer_id = $1 WHERE customer_id = $2 AND message_id NOT IN (SELECT message_id FROM contact_emails WHERE customer_id = $1)"},
+		{"attachments", "UPDATE attachments SET customer_id = $1 WHERE customer_id = $2"},
+		{"events", "UPDATE events SET customer_id = $1 WHERE customer_id = $2"},
+	} {
+		res, err := tx.Exec(m.query, survivorID, merged.ID)
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		moved[m.key] = int(n)
+	}
+
+	// Kalan kaydın değerleri korunur, sadece boş alanlar doldurulur
+	if _, err := tx.Exec(`
+		UPDATE customers s SET
+			phone = COALESCE(NULLIF(s.phone, ''), m.phone),
+			region = COALESCE(NULLIF(s.region, ''), m.region),
+			postal_code = COALESCE(NULLIF(s.postal_code, ''), m.postal_code),
+			lead_source = COALESCE(NULLIF(s.lead_source, ''), m.lead_source),
+			owner_id = COALESCE(s.owner_id, m.owner_id),
+			custom_fields = m.custom_fields || s.custom_fields
+		FROM customers m
+		WHERE s.id = $1 AND m.id = $2`, survivorID, merged.ID); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	movedJSON, _ := json.Marshal(moved)
+	if _, err := tx.Exec(`
+		INSERT INTO customer_merges (tenant_id, survivor_id, merged_id, merged_snapshot, moved, merged_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))`,
+		tenantID, survivorID, merged.ID, string(snapshot), string(movedJSON), mergedBy); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM customers WHERE id = $1", merged.ID); err != nil {
+		return nil, err
+	}
+	return moved, tx.Commit()
+}
+
+const importColumns = `id, filename, format, mode, dry_run, mapping, headers, status, total_rows, processed_rows,
+	created_count, updated_count, skipped_count, error_count, error, created_by, tenant_id, created_at, finished_at`
+
+func scanImport(row scanner, j *CustomerImport) error {
+	var mapping []byte
+	var createdBy sql.NullInt64
+	var finishedAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.Filename, &j.Format, &j.Mode, &j.DryRun, &mapping, pq.Array(&j.Headers), &j.Status,
+		&j.TotalRows, &j.ProcessedRows, &j.CreatedCount, &j.UpdatedCount, &j.SkippedCount, &j.ErrorCount, &j.Error,
+		&createdBy, &j.TenantID, &j.CreatedAt, &finishedAt); err != nil {
+		return err
+	}
+	j.CreatedBy = int(createdBy.Int64)
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+	return json.Unmarshal(mapping, &j.Mapping)
+}
+
+func createImportRepo(db *sql.DB, j *CustomerImport) error {
+	mapping, err := json.Marshal(j.Mapping)
+	if err != nil {
+		return err
+	}
+	return db.QueryRow(`
+		INSERT INTO customer_imports (tenant_id, filename, format, mode, dry_run, mapping, headers, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0))
+		RETURNING id, created_at`,
+		j.TenantID, j.Filename, j.Format, j.Mode, j.DryRun, string(mapping), pq.Array(j.Headers), j.Status, j.CreatedBy,
+	).Scan(&j.ID, &j.CreatedAt)
+}
+
+func getImportRepo(db *sql.DB, id int) (CustomerImport, error) {
+	var j CustomerImport
+	err := scanImport(db.QueryRow("SELECT "+importColumns+" FROM customer_imports WHERE id = $1", id), &j)
+	return j, err
+}
+
+// İşin durumunu ve sayaçlarını yazar; bitmiş durumlarda finished_at da set edilir
+func updateImportProgressRepo(db *sql.DB, j *CustomerImport) error {
+	_, err := db.Exec(`
+		UPDATE customer_imports SET status = $1, total_rows = $2, processed_rows = $3, created_count = $4,
+			updated_count = $5, skipped_count = $6, error_count = $7, error = $8,
+			finished_at = CASE WHEN $1 IN ('completed', 'failed') THEN CURRENT_TIMESTAMP END
+		WHERE id = $9`,
+		j.Status, j.TotalRows, j.ProcessedRows, j.CreatedCount, j.UpdatedCount, j.SkippedCount, j.ErrorCount, j.Error, j.ID)
+	return err
+}
+
+func insertImportErrorRepo(db *sql.DB, importID int, e ImportRowError) error {
+	_, err := db.Exec("INSERT INTO customer_import_errors (import_id, row_number, message, row_values) VALUES ($1, $2, $3, $4)",
+		importID, e.Row, e.Message, pq.Array(e.Values))
+	return err
+}
+
+// Hata raporunu satır sırasıyla okur, her satır için fn çağrılır (rapor belleğe alınmaz)
+func eachImportErrorRepo(db *sql.DB, importID int, fn func(ImportRowError) error) error {
+	rows, err := db.Query("SELECT row_number, message, row_values FROM customer_import_errors WHERE import_id = $1 ORDER BY row_number, id", importID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e ImportRowError
+		if err := rows.Scan(&e.Row, &e.Message, pq.Array(&e.Values)); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Sunucu yeniden başladığında yarıda kalan işleri başarısız işaretler
+func failInterruptedImportsRepo(db *sql.DB) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE customer_imports SET status = 'failed', error = 'Sunucu yeniden başlatıldığı için işlem yarıda kaldı',
+			finished_at = CURRENT_TIMESTAMP
+		WHERE status IN ('pending', 'running')`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Tenant'ta normalize e-postası eşleşen müşteri, yoksa 0
+func findCustomerByEmailRepo(db *sql.DB, tenantID int, email string) (int, error) {
+	var id int
+	err := db.QueryRow("SELECT id FROM customers WHERE tenant_id = $1 AND email_normalized = $2 ORDER BY id LIMIT 1", tenantID, email).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// Müşterileri sırayla fn'e verir; sonuç kümesi belleğe alınmaz (dışa aktarma için)
+func eachCustomerRepo(db *sql.DB, where []string, args []interface{}, orderBy string, fn func(Customer, sql.NullTime) error) error {
+	query := "SELECT " + customerColumns + `, created_at,
+		ARRAY(SELECT t.name FROM customer_tags ctg JOIN tags t ON t.id = ctg.tag_id WHERE ctg.customer_id = customers.id ORDER BY t.name)
+		FROM customers`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := db.Query(query+" ORDER BY "+orderBy, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c Customer
+		var createdAt sql.NullTime
+		if err := scanCustomer(rows, &c, &createdAt, pq.Array(&c.Tags)); err != nil {
+			return err
+		}
+		if err := fn(c, createdAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Tenant'ın iletişim kayıtlarını müşteri adıyla sırayla fn'e verir (dışa aktarma için)
+func eachContactRepo(db *sql.DB, tenantID int, params ContactListParams, fn func(Contact, sql.NullTime) error) error {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{"c.tenant_id = " + arg(tenantID)}
+	if params.CustomerID > 0 {
+		where = append(where, "ct.customer_id = "+arg(params.CustomerID))
+	}
+	if params.Type != "" {
+		where = append(where, "ct.type = "+arg(params.Type))
+	}
+	filterWhere, err := ContactListFields.Where(params.Filter, arg)
+	if err != nil {
+		return err
+	}
+	where = append(where, filterWhere...)
+	keyset, err := ContactListFields.Keyset(params.Sort, defaultContactSort, "id")
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query("SELECT "+contactColumns+", c.name FROM contacts ct JOIN customers c ON c.id = ct.customer_id WHERE "+
+		strings.Join(where, " AND ")+" ORDER BY "+keyset.OrderBy(false), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var contact Contact
+		createdAt, err := scanContact(rows, &contact, &contact.CustomerName)
+		if err != nil {
+			return err
+		}
+		if err := fn(contact, createdAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+const exportColumns = "id, type, format, query, status, row_count, error, created_by, tenant_id, file_path, created_at, finished_at, expires_at"
+
+func scanExport(row scanner, j *ExportJob) error {
+	var createdBy sql.NullInt64
+	var finishedAt, expiresAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.Type, &j.Format, &j.Query, &j.Status, &j.RowCount, &j.Error, &createdBy, &j.TenantID,
+		&j.FilePath, &j.CreatedAt, &finishedAt, &expiresAt); err != nil {
+		return err
+	}
+	j.CreatedBy = int(createdBy.Int64)
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+	if expiresAt.Valid {
+		j.ExpiresAt = &expiresAt.Time
+	}
+	return nil
+}
+
+func createExportRepo(db *sql.DB, j *ExportJob) error {
+	return db.QueryRow(`
+		INSERT INTO customer_exports (tenant_id, type, format, query, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+		RETURNING id, created_at`,
+		j.TenantID, j.Type, j.Format, j.Query, j.Status, j.CreatedBy,
+	).Scan(&j.ID, &j.CreatedAt)
+}
+
+func getExportRepo(db *sql.DB, id int) (ExportJob, error) {
+	var j ExportJob
+	err := scanExport(db.QueryRow("SELECT "+exportColumns+" FROM customer_exports WHERE id = $1", id), &j)
+	return j, err
+}
+
+func updateExportRepo(db *sql.DB, j *ExportJob) error {
+	_, err := db.Exec(`
+		UPDATE customer_exports SET status = $1, row_count = $2, error = $3, file_path = $4, expires_at = $5,
+			finished_at = CASE WHEN $1 IN ('completed', 'failed') THEN CURRENT_TIMESTAMP END
+		WHERE id = $6`,
+		j.Status, j.RowCount, j.Error, j.FilePath, j.ExpiresAt, j.ID)
+	return err
+}
+
+// Saklama süresi dolan dosyaları expired işaretler, silinecek dosya yollarını döner
+func expireExportsRepo(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`
+		UPDATE customer_exports SET status = 'expired'
+		WHERE status = 'completed' AND expires_at < CURRENT_TIMESTAMP
+		RETURNING file_path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+func failInterruptedExportsRepo(db *sql.DB) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE customer_exports SET status = 'failed', error = 'Sunucu yeniden başlatıldığı için işlem yarıda kaldı',
+			finished_at = CURRENT_TIMESTAMP
+		WHERE status IN ('pending', 'running')`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Adreslere karşılık gelen tenant kullanıcıları (küçük harfli e-posta -> kullanıcı)
+func findUsersByEmailRepo(db *sql.DB, tenantID int, emails []string) (map[string]common.AuthUser, error) {
+	rows, err := db.Query(`
+		SELECT id, lower(email), tenant_id FROM users
+		WHERE lower(email) = ANY($1) AND tenant_id = $2
+		ORDER BY id`, pq.Array(emails), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := map[string]common.AuthUser{}
+	for rows.Next() {
+		var u common.AuthUser
+		if err := rows.Scan(&u.ID, &u.Email, &u.TenantID); err != nil {
+			return nil, err
+		}
+		if _, ok := users[u.Email]; !ok {
+			users[u.Email] = u
+		}
+	}
+	return users, rows.Err()
+}
+
+// Normalize edilmiş e-postalara karşılık gelen müşteriler (aynı adreste birden fazla müşteri varsa en eskisi)
+func findCustomersByEmailsRepo(db *sql.DB, tenantID int, emails []string) (map[string]int, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT ON (email_normalized) email_normalized, id FROM customers
+		WHERE tenant_id = $1 AND email_normalized = ANY($2)
+		ORDER BY email_normalized, id`, tenantID, pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := map[string]int{}
+	for rows.Next() {
+		var email string
+		var id int
+		if err := rows.Scan(&email, &id); err != nil {
+			return nil, err
+		}
+		customers[email] = id
+	}
+	return customers, rows.Err()
+}
+
+// Yanıtlanan/atıf yapılan mesajlardan birinin daha önce kaydedilmiş konuşması (yoksa "")
+func findEmailThreadRepo(db *sql.DB, tenantID int, messageIDs []string) (string, error) {
+	var thread string
+	err := db.QueryRow(`
+		SELECT thread_id FROM contact_emails
+		WHERE tenant_id = $1 AND message_id = ANY($2)
+		ORDER BY contact_id LIMIT 1`, tenantID, pq.Array(messageIDs)).Scan(&thread)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return thread, err
+}
+
+// İletişim kaydını ve e-posta bilgisini tek işlemde ekler. Mesaj bu müşteri için zaten kayıtlıysa
+// hiçbir şey eklenmez ve false döner.
+func createEmailContactRepo(db *sql.DB, tenantID int, contact *Contact, email ContactEmail) (bool, error) {
+	attachments, err := json.Marshal(email.Attachments)
+	if err != nil {
+		return false, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Aynı mesajın eşzamanlı alımları müşteri satırında sıraya girer
+	if _, err := tx.Exec("SELECT 1 FROM customers WHERE id = $1 FOR UPDATE", contact.CustomerID); err != nil {
+		return false, err
+	}
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM contact_emails WHERE customer_id = $1 AND message_id = $2)",
+		contact.CustomerID, email.MessageID).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	if err := createContactRepo(tx, contact); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO contact_emails (contact_id, tenant_id, customer_id, message_id, in_reply_to, thread_id,
+			from_address, to_addresses, cc_addresses, subject, attachments)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		contact.ID, tenantID, contact.CustomerID, email.MessageID, email.InReplyTo, email.ThreadID,
+		email.From, pq.Array(email.To), pq.Array(email.Cc), email.Subject, attachments); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Kaydın ait olduğu konuşmadaki mesajlar (aynı müşteri), eskiden yeniye
+func getEmailThreadRepo(db *sql.DB, tenantID, contactID int) ([]EmailThreadEntry, error) {
+	rows, err := db.Query(`
+		SELECT `+contactColumns+`, ce.contact_id, ce.message_id, ce.in_reply_to, ce.thread_id, ce.from_address,
+			ce.to_addresses, ce.cc_addresses, ce.subject, ce.attachments
+		FROM contact_emails base
+		JOIN contact_emails ce ON ce.customer_id = base.customer_id AND ce.thread_id = base.thread_id
+		JOIN contacts ct ON ct.id = ce.contact_id
+		WHERE base.contact_id = $1 AND base.tenant_id = $2
+		ORDER BY ct.occurred_at, ct.id`, contactID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []EmailThreadEntry{}
+	for rows.Next() {
+		var e EmailThreadEntry
+		var attachments []byte
+		if _, err := scanContact(rows, &e.Contact, &e.Email.ContactID, &e.Email.MessageID, &e.Email.InReplyTo,
+			&e.Email.ThreadID, &e.Email.From, pq.Array(&e.Email.To), pq.Array(&e.Email.Cc), &e.Email.Subject,
+			&attachments); err != nil {
+			return nil, err
+		}
+		e.Email.Attachments = []EmailAttachment{}
+		if err := json.Unmarshal(attachments, &e.Email.Attachments); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func contactCustomerRepo(db *sql.DB, contactID int) (int, error) {
+	var customerID int
+	err := db.QueryRow("SELECT customer_id FROM contacts WHERE id = $1", contactID).Scan(&customerID)
+	return customerID, err
+}
+
+// Ek sorgularında ortak seçilen sütunlar (scanAttachment ile aynı sırada)
+const attachmentColumns = "id, customer_id, contact_id, filename, content_type, size, sha256, scan_status, uploaded_by, tenant_id, storage_key, created_at"
+
+func scanAttachment(row scanner, a *Attachment) error {
+	var contactID, uploadedBy sql.NullInt64
+	if err := row.Scan(&a.ID, &a.CustomerID, &contactID, &a.Filename, &a.ContentType, &a.Size, &a.SHA256,
+		&a.ScanStatus, &uploadedBy, &a.TenantID, &a.StorageKey, &a.CreatedAt); err != nil {
+		return err
+	}
+	a.ContactID = int(contactID.Int64)
+	a.UploadedBy = int(uploadedBy.Int64)
+	return nil
+}
+
+func createAttachmentRepo(db *sql.DB, a *Attachment) error {
+	return db.QueryRow(`
+		INSERT INTO attachments (tenant_id, customer_id, contact_id, filename, content_type, size, sha256, scan_status,
+			uploaded_by, storage_key)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, $8, NULLIF($9, 0), $10) RETURNING id, created_at`,
+		a.TenantID, a.CustomerID, a.ContactID, a.Filename, a.ContentType, a.Size, a.SHA256, a.ScanStatus,
+		a.UploadedBy, a.StorageKey,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func getAttachmentRepo(db *sql.DB, id int) (Attachment, error) {
+	var a Attachment
+	err := scanAttachment(db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = $1", id), &a)
+	return a, err
+}
+
+func getAttachmentsRepo(db *sql.DB, customerID, contactID int) ([]Attachment, error) {
+	rows, err := db.Query("SELECT "+attachmentColumns+` FROM attachments
+		WHERE customer_id = $1 AND ($2 = 0 OR contact_id = $2)
+		ORDER BY created_at DESC, id DESC`, customerID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func deleteAttachmentRepo(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM attachments WHERE id = $1", id)
+	return err
+}
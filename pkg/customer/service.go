@@ -2,41 +2,192 @@ package customer
 
 import (
 	"Go-CRM/pkg/common"
+	"bufio"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
+
+	"github.com/lib/pq"
 )
 
 // Pagination ve filtreleme için parametreler
 type CustomerListParams struct {
-	Page     int
-	PageSize int
-	Search   string // İsim veya e-posta araması
+	Page      int
+	PageSize  int
+	Search    string // İsim veya e-posta araması
+	OwnerID   int    // 0 ise tüm sorumlular
+	AccountID int    // Hesap ve alt hesaplarına bağlı kişiler
+	TenantID  int    // 0 ise tüm tenant'lar
+	// Özel alan filtreleri (anahtar -> değer); multi_select alanlarda değeri içerenler eşleşir
+	CustomFields map[string]string
+	TagID        int
+	Filter       []common.FilterCondition // filter= ifadesi (CustomerListFields beyaz listesi)
+	Sort         []common.SortKey         // Boşsa en yeni müşteri önce
+	Cursor       string                   // Önceki yanıttaki next_cursor/prev_cursor; verilirse Page yok sayılır
+	IncludeTotal bool                     // Toplam sayı için ayrıca COUNT(*) çalıştırılır
+
+	// Ek WHERE koşulu (ör. segment filtresi); customers tablosunun sütunlarıyla yazılır
+	Where func(arg func(interface{}) string) (string, error)
+}
+
+// GetCustomers için filtrelenebilir ve sıralanabilir alanlar
+var CustomerListFields = common.ListFields{
+	"id":          {Column: "id", Type: common.ListNumber, Sortable: true},
+	"name":        {Column: "name", Type: common.ListString, Sortable: true},
+	"email":       {Column: "email", Type: common.ListString, Sortable: true},
+	"phone":       {Column: "phone", Type: common.ListString, Nullable: true},
+	"region":      {Column: "region", Type: common.ListString, Sortable: true},
+	"postal_code": {Column: "postal_code", Type: common.ListString, Sortable: true},
+	"lead_source": {Column: "lead_source", Type: common.ListString, Sortable: true},
+	"owner_id":    {Column: "owner_id", Type: common.ListNumber, Sortable: true, Nullable: true},
+	"created_at":  {Column: "created_at", Type: common.ListTime, Sortable: true, Nullable: true},
+	"tag": {
+		Build: tagFilterSQL,
+		Ops:   []string{common.OpEq, common.OpNe, common.OpIn, common.OpNotIn, common.OpExists, common.OpMissing},
+	},
+}
+
+var defaultCustomerSort = []common.SortKey{{Field: "id", Desc: true}}
+
+// tag:in:vip|gold gibi koşullar etiket adına göre (büyük/küçük harf duyarsız) eşleşir
+func tagFilterSQL(c common.FilterCondition, arg func(interface{}) string) string {
+	var sub string
+	switch c.Op {
+	case common.OpExists, common.OpMissing:
+		sub = "SELECT customer_id FROM customer_tags"
+	default:
+		names := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			names[i] = strings.ToLower(v)
+		}
+		sub = "SELECT ctg.customer_id FROM customer_tags ctg JOIN tags t ON t.id = ctg.tag_id WHERE LOWER(t.name) = ANY(" + arg(pq.Array(names)) + ")"
+	}
+	switch c.Op {
+	case common.OpNe, common.OpNotIn, common.OpMissing:
+		return "id NOT IN (" + sub + ")"
+	}
+	return "id IN (" + sub + ")"
 }
 
 type CustomerListResult struct {
-	Customers []Customer
-	Total     int
-	Page      int
-	PageSize  int
+	Customers  []Customer
+	Total      *int `json:",omitempty"` // Sadece IncludeTotal ile
+	Page       int  `json:",omitempty"` // Sadece sayfa numarasıyla (cursor olmadan) istenirse
+	PageSize   int
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Liste parametrelerinden WHERE koşullarını üretir (listeleme ve dışa aktarmada ortak)
+func customerWhere(db *sql.DB, params CustomerListParams, arg func(interface{}) string) ([]string, error) {
+	// Özel alan filtreleri tenant'ın alan tanımlarına göre tiplenir
+	var fieldFilter string
+	if len(params.CustomFields) > 0 {
+		if params.TenantID == 0 {
+			params.TenantID = common.DefaultTenantID
+		}
+		defs, err := getFieldDefinitionsRepo(db, params.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		if fieldFilter, err = CustomFieldFilter(defs, params.CustomFields); err != nil {
+			return nil, err
+		}
+	}
+
+	var where []string
+	if tsq := SearchQuery(params.Search); tsq != "" {
+		where = append(where, "search_vector @@ to_tsquery('"+searchConfig+"', "+arg(tsq)+")")
+	}
+	if params.OwnerID > 0 {
+		where = append(where, "owner_id = "+arg(params.OwnerID))
+	}
+	if params.AccountID > 0 {
+		tenantID := params.TenantID
+		if tenantID == 0 {
+			tenantID = common.DefaultTenantID
+		}
+		where = append(where, "id IN ("+AccountPeopleSubquery(arg(params.AccountID), arg(tenantID))+")")
+	}
+	if params.TenantID > 0 {
+		where = append(where, "tenant_id = "+arg(params.TenantID))
+	}
+	if fieldFilter != "" {
+		where = append(where, "custom_fields @> "+arg(fieldFilter)+"::jsonb")
+	}
+	if params.TagID > 0 {
+		where = append(where, "id IN (SELECT customer_id FROM customer_tags WHERE tag_id = "+arg(params.TagID)+")")
+	}
+	if params.Where != nil {
+		clause, err := params.Where(arg)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, clause)
+	}
+	filterWhere, err := CustomerListFields.Where(params.Filter, arg)
+	if err != nil {
+		return nil, err
+	}
+	return append(where, filterWhere...), nil
 }
 
-// Müşteri listeleme (pagination + filtreleme)
+// Müşteri listeleme (keyset pagination + filtreleme).
+// Sayfalar sıralama anahtarına göre imleçle ilerler; eşzamanlı eklemeler sayfaları kaydırmaz.
+// Page > 1 geriye dönük uyumluluk için OFFSET ile çalışır.
 func GetCustomers(db *sql.DB, params CustomerListParams) (CustomerListResult, error) {
-	if params.Page < 1 {
+	if params.Page < 1 || params.Cursor != "" {
 		params.Page = 1
 	}
 	if params.PageSize < 1 || params.PageSize > 100 {
 		params.PageSize = 10
 	}
 
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where, err := customerWhere(db, params, arg)
+	if err != nil {
+		return CustomerListResult{}, err
+	}
+	keyset, err := CustomerListFields.Keyset(params.Sort, defaultCustomerSort, "id")
+	if err != nil {
+		return CustomerListResult{}, err
+	}
+	filter := strings.Join(where, " AND ")
+	filterArgs := len(args)
+	// İmleç sadece aynı filtre ve sıralamayla kullanılabilir
+	scope := fmt.Sprintf("customers|%s|%v|%s", filter, args, keyset.OrderBy(false))
+	cur, err := common.DecodeCursor(params.Cursor, scope)
+	if err != nil {
+		return CustomerListResult{}, err
+	}
+	if cur != nil {
+		after, err := keyset.After(cur, arg)
+		if err != nil {
+			return CustomerListResult{}, err
+		}
+		where = append(where, after)
+	}
+
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("customers:%s:%d:%d", params.Search, params.Page, params.PageSize)
+	cacheKey := fmt.Sprintf("customers:%s:%d:%s:%d:%t", scope, params.Page, params.Cursor, params.PageSize, params.IncludeTotal)
 	if cached, err := common.RedisGet(ctx, cacheKey); err == nil && cached != "" {
 		var result CustomerListResult
 		if err := json.Unmarshal([]byte(cached), &result); err == nil {
@@ -44,22 +195,15 @@ func GetCustomers(db *sql.DB, params CustomerListParams) (CustomerListResult, er
 		}
 	}
 
-	var (
-		args   []interface{}
-		where  []string
-		query  = "SELECT id, name, email, phone FROM customers"
-		countQ = "SELECT COUNT(*) FROM customers"
-	)
-	if params.Search != "" {
-		where = append(where, "(LOWER(name) LIKE $1 OR LOWER(email) LIKE $1)")
-		args = append(args, "%"+strings.ToLower(params.Search)+"%")
-	}
+	query := "SELECT " + customerColumns + ", " + keyset.Columns() + " FROM customers"
 	if len(where) > 0 {
 		query += " WHERE " + strings.Join(where, " AND ")
-		countQ += " WHERE " + strings.Join(where, " AND ")
 	}
-	query += " ORDER BY id DESC LIMIT $2 OFFSET $3"
-	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+	// Sonraki sayfanın varlığını anlamak için bir fazla satır okunur
+	query += " ORDER BY " + keyset.OrderBy(cur != nil && cur.Backward) + " LIMIT " + arg(params.PageSize+1)
+	if params.Page > 1 {
+		query += " OFFSET " + arg((params.Page-1)*params.PageSize)
+	}
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -67,27 +211,52 @@ func GetCustomers(db *sql.DB, params CustomerListParams) (CustomerListResult, er
 	}
 	defer rows.Close()
 
-	var customers []Customer
+	var (
+		customers []Customer
+		keys      [][]string
+	)
 	for rows.Next() {
 		var c Customer
-		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Phone); err != nil {
+		dest := keyset.Dest()
+		if err := scanCustomer(rows, &c, dest...); err != nil {
 			return CustomerListResult{}, err
 		}
 		customers = append(customers, c)
+		keys = append(keys, keyset.Values(dest))
 	}
 
-	// Toplam kayıt sayısı
-	total := 0
-	if err := db.QueryRow(countQ, args[:len(args)-2]...).Scan(&total); err != nil {
+	if err := rows.Err(); err != nil {
 		return CustomerListResult{}, err
 	}
 
-	result := CustomerListResult{
-		Customers: customers,
-		Total:     total,
-		Page:      params.Page,
-		PageSize:  params.PageSize,
+	result := CustomerListResult{PageSize: params.PageSize}
+	var n int
+	n, result.NextCursor, result.PrevCursor = common.PageCursors(scope, cur, keys, params.PageSize)
+	customers = customers[:n]
+	if cur != nil && cur.Backward {
+		slices.Reverse(customers)
+	}
+	if params.Cursor == "" {
+		result.Page = params.Page
+	}
+
+	// Toplam kayıt sayısı (büyük tablolarda pahalı olduğundan isteğe bağlı)
+	if params.IncludeTotal {
+		countQ := "SELECT COUNT(*) FROM customers"
+		if filter != "" {
+			countQ += " WHERE " + filter
+		}
+		var total int
+		if err := db.QueryRow(countQ, args[:filterArgs]...).Scan(&total); err != nil {
+			return CustomerListResult{}, err
+		}
+		result.Total = &total
+	}
+
+	if err := attachTags(db, customers); err != nil {
+		return CustomerListResult{}, err
 	}
+	result.Customers = customers
 
 	// Sonucu cache'e yaz
 	if b, err := json.Marshal(result); err == nil {
@@ -97,70 +266,1935 @@ func GetCustomers(db *sql.DB, params CustomerListParams) (CustomerListResult, er
 	return result, nil
 }
 
-// Müşteri ekleme (validasyon ve güvenlik)
-func CreateCustomer(db *sql.DB, c *Customer) error {
-	if err := validateCustomer(*c); err != nil {
+// Müşteri ekleme (validasyon ve güvenlik).
+// Sorumlu verilmemişse atama kuralları uygulanır, hiçbiri eşleşmezse müşteri ekleyene atanır.
+func CreateCustomer(db *sql.DB, user common.AuthUser, c *Customer) error {
+	c.TenantID = tenantOf(user)
+	defs, err := getFieldDefinitionsRepo(db, c.TenantID)
+	if err != nil {
+		return err
+	}
+	c.CustomFields = applyFieldDefaults(defs, c.CustomFields)
+	if err := validateCustomer(*c, defs); err != nil {
+		return err
+	}
+	reason := "manual"
+	if c.OwnerID > 0 {
+		if err := checkOwners(db, c.TenantID, int64(c.OwnerID)); err != nil {
+			return err
+		}
+	} else {
+		ownerID, rule, err := applyAssignmentRules(db, *c)
+		if err != nil {
+			return err
+		}
+		c.OwnerID = ownerID
+		reason = "rule: " + rule
+		if ownerID == 0 {
+			c.OwnerID = user.ID
+			reason = "creator"
+		}
+	}
+	// Olası mükerrer kayıtlar engellemez, yanıtta uyarı olarak döner
+	if c.PossibleDuplicates, err = FindDuplicates(db, c.TenantID, *c); err != nil {
 		return err
 	}
 	if err := createCustomerRepo(db, c); err != nil {
 		return err
 	}
+	if c.OwnerID > 0 {
+		if err := insertOwnerChangeRepo(db, c.ID, 0, c.OwnerID, user.ID, reason); err != nil {
+			return err
+		}
+	}
 	// Kafka event publish
-	go func(cust Customer) {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		payload, _ := json.Marshal(cust)
-		_ = common.PublishEvent(ctx, fmt.Sprintf("customer-%d", cust.ID), string(payload))
-	}(*c)
+	common.PublishDomainEvent(fmt.Sprintf("customer-%d", c.ID), "customer.created", c.TenantID, *c)
 	return nil
 }
 
-// İletişim kayıtlarını getir (pagination)
-func GetContactsByCustomerID(db *sql.DB, params ContactListParams) (ContactListResult, error) {
-	if params.CustomerID <= 0 {
-		return ContactListResult{}, errors.New("Geçersiz müşteri ID")
+// Oturum kullanıcısının müşterisi (başka tenant'ın müşterisi bulunamadı sayılır)
+func GetCustomer(db *sql.DB, user common.AuthUser, id int) (Customer, error) {
+	c, err := getCustomerRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && c.TenantID != tenantOf(user)) {
+		return Customer{}, ErrCustomerNotFound
 	}
-	if params.Page < 1 {
-		params.Page = 1
+	if err != nil {
+		return Customer{}, err
 	}
-	if params.PageSize < 1 || params.PageSize > 100 {
-		params.PageSize = 10
+	customers := []Customer{c}
+	err = attachTags(db, customers)
+	return customers[0], err
+}
+
+// Müşterilerin etiketlerini tek sorguda doldurur
+func attachTags(db *sql.DB, customers []Customer) error {
+	ids := make([]int64, len(customers))
+	for i, c := range customers {
+		ids[i] = int64(c.ID)
 	}
-	return getContactsByCustomerIDRepo(db, params)
+	tags, err := getCustomerTagsRepo(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range customers {
+		customers[i].Tags = tags[customers[i].ID]
+	}
+	return nil
 }
 
-// İletişim kaydı ekleme (validasyon)
-func CreateContact(db *sql.DB, contact *Contact) error {
-	if contact.CustomerID <= 0 || utf8.RuneCountInString(contact.Content) < 3 {
-		return errors.New("Geçersiz iletişim kaydı")
+// Müşteri güncelleme; gönderilmeyen özel alanlar silinir, varsayılanı olanlar varsayılana döner
+func UpdateCustomer(db *sql.DB, user common.AuthUser, c *Customer) error {
+	current, err := GetCustomer(db, user, c.ID)
+	if err != nil {
+		return err
 	}
-	return createContactRepo(db, contact)
+	c.TenantID = current.TenantID
+	defs, err := getFieldDefinitionsRepo(db, c.TenantID)
+	if err != nil {
+		return err
+	}
+	c.CustomFields = applyFieldDefaults(defs, c.CustomFields)
+	if err := validateCustomer(*c, defs); err != nil {
+		return err
+	}
+	if err := updateCustomerRepo(db, c); err != nil {
+		return err
+	}
+	common.PublishDomainEvent(fmt.Sprintf("customer-%d", c.ID), "customer.updated", c.TenantID, *c)
+	return nil
 }
 
-// Pagination ve filtreleme için parametreler
-type ContactListParams struct {
-	CustomerID int
-	Page       int
-	PageSize   int
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
 }
 
-type ContactListResult struct {
-	Contacts []Contact
+// Sorumlu olarak verilen kullanıcıların hepsi tenant'ta olmalı; yoksa veya başka tenant'taysa ErrOwnerNotFound
+func checkOwners(db *sql.DB, tenantID int, ids ...int64) error {
+	unique := map[int64]bool{}
+	for _, id := range ids {
+		unique[id] = true
+	}
+	n, err := countTenantUsersRepo(db, tenantID, ids)
+	if err != nil {
+		return err
+	}
+	if n != len(unique) {
+		return ErrOwnerNotFound
+	}
+	return nil
+}
+
+var (
+	ErrAdminOnly     = errors.New("Bu işlem sadece adminler içindir")
+	ErrFieldNotFound = errors.New("Özel alan bulunamadı")
+)
+
+// Oturum kullanıcısının tenant'ındaki özel alan tanımları
+func GetFieldDefinitions(db *sql.DB, user common.AuthUser) ([]FieldDefinition, error) {
+	return getFieldDefinitionsRepo(db, tenantOf(user))
+}
+
+func CreateFieldDefinition(db *sql.DB, user common.AuthUser, d *FieldDefinition) error {
+	if !user.IsAdmin() {
+		return ErrAdminOnly
+	}
+	if err := ValidateFieldDefinition(*d); err != nil {
+		return err
+	}
+	return createFieldDefinitionRepo(db, tenantOf(user), d)
+}
+
+// Tanım güncelleme; anahtar ve tip değiştirilemez
+func UpdateFieldDefinition(db *sql.DB, user common.AuthUser, d *FieldDefinition) error {
+	if !user.IsAdmin() {
+		return ErrAdminOnly
+	}
+	current, err := getFieldDefinitionRepo(db, tenantOf(user), d.ID)
+	if err == sql.ErrNoRows {
+		return ErrFieldNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if (d.Key != "" && d.Key != current.Key) || (d.Type != "" && d.Type != current.Type) {
+		return errors.New("Özel alanın anahtarı ve tipi değiştirilemez")
+	}
+	d.Key, d.Type = current.Key, current.Type
+	if err := ValidateFieldDefinition(*d); err != nil {
+		return err
+	}
+	return updateFieldDefinitionRepo(db, tenantOf(user), d)
+}
+
+// Tanımı ve müşterilerdeki değerlerini siler
+func DeleteFieldDefinition(db *sql.DB, user common.AuthUser, id int) error {
+	if !user.IsAdmin() {
+		return ErrAdminOnly
+	}
+	found, err := deleteFieldDefinitionRepo(db, tenantOf(user), id)
+	if err == nil && !found {
+		return ErrFieldNotFound
+	}
+	return err
+}
+
+// Boş (null) değerleri atar, değeri verilmemiş alanlara varsayılanı yazar
+func applyFieldDefaults(defs []FieldDefinition, fields map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range fields {
+		if v != nil {
+			out[k] = v
+		}
+	}
+	for _, d := range defs {
+		if _, ok := out[d.Key]; !ok && d.Default != nil {
+			out[d.Key] = d.Default
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// Sorgu parametresi filtrelerini jsonb içerme (@>) sorgusu için JSON nesnesine çevirir
+func CustomFieldFilter(defs []FieldDefinition, filters map[string]string) (string, error) {
+	byKey := map[string]FieldDefinition{}
+	for _, d := range defs {
+		byKey[d.Key] = d
+	}
+	doc := map[string]interface{}{}
+	for key, raw := range filters {
+		d, ok := byKey[key]
+		if !ok {
+			return "", fmt.Errorf("Bilinmeyen özel alan: %s", key)
+		}
+		var v interface{} = raw
+		switch d.Type {
+		case FieldNumber:
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return "", fmt.Errorf("%s sayı olmalı", key)
+			}
+			v = n
+		case FieldMultiSelect:
+			v = []string{raw}
+		}
+		if err := validateFieldValue(d, v); err != nil {
+			return "", err
+		}
+		doc[key] = v
+	}
+	b, err := json.Marshal(doc)
+	return string(b), err
+}
+
+var (
+	ErrCustomerNotFound = errors.New("Müşteri bulunamadı")
+	ErrOwnerForbidden   = errors.New("Sorumluyu sadece mevcut sorumlu veya bir yönetici değiştirebilir")
+	ErrOwnerNotFound    = errors.New("Sorumlu bu tenant'ta bulunamadı")
+	ErrManagerOnly      = errors.New("Bu işlem sadece yöneticiler içindir")
+	ErrRuleNotFound     = errors.New("Atama kuralı bulunamadı")
+	ErrMergeForbidden   = errors.New("Müşterileri sadece ikisinin de sorumlusu veya bir yönetici birleştirebilir")
+	ErrImportNotFound   = errors.New("İçe aktarma bulunamadı")
+)
+
+// Bu benzerliğin (pg_trgm similarity) üzerindeki isimler olası mükerrer sayılır
+const nameSimilarityThreshold = 0.6
+
+// E-postayı karşılaştırma için normalize eder: küçük harf, boşluksuz ve +etiket olmadan.
+// customers.email_normalized sütunuyla aynı kuralı uygular.
+func NormalizeEmail(email string) string {
+	e := strings.ToLower(strings.TrimSpace(email))
+	local, domain, ok := strings.Cut(e, "@")
+	if !ok {
+		return e
+	}
+	if i := strings.IndexByte(local, '+'); i >= 0 {
+		local = local[:i]
+	}
+	return local + "@" + domain
+}
+
+// Telefonun sadece rakamlarını ve son 10 hanesini alır (+90 / 0 önekleri eşitlenir).
+// customers.phone_normalized sütunuyla aynı kuralı uygular.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
+}
+
+// Kayda benzeyen müşteriler (normalize e-posta, telefon veya benzer isim)
+func FindDuplicates(db *sql.DB, tenantID int, c Customer) ([]DuplicateMatch, error) {
+	phone := NormalizePhone(c.Phone)
+	if len(phone) < 7 {
+		phone = "" // Kısa numaralar yanlış eşleşme üretir
+	}
+	email := NormalizeEmail(c.Email)
+	if email == "" && phone == "" && strings.TrimSpace(c.Name) == "" {
+		return nil, nil
+	}
+	return findDuplicatesRepo(db, tenantID, c.ID, email, phone, strings.TrimSpace(c.Name), nameSimilarityThreshold)
+}
+
+type DuplicateReport struct {
+	Pairs    []DuplicatePair
 	Total    int
 	Page     int
 	PageSize int
 }
 
-// --- Validasyon Fonksiyonları ---
-func validateCustomer(c Customer) error {
-	if utf8.RuneCountInString(c.Name) < 2 {
-		return errors.New("İsim en az 2 karakter olmalı")
+// Tenant'taki olası mükerrer müşteri çiftleri, en çok nedenle eşleşenler önce
+func GetDuplicateReport(db *sql.DB, user common.AuthUser, page, pageSize int) (DuplicateReport, error) {
+	if page < 1 {
+		page = 1
 	}
-	if c.Email != "" && !strings.Contains(c.Email, "@") {
-		return errors.New("Geçersiz e-posta adresi")
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
 	}
-	if c.Phone != "" && len(c.Phone) < 7 {
-		return errors.New("Telefon numarası çok kısa")
+	pairs, total, err := getDuplicatePairsRepo(db, tenantOf(user), nameSimilarityThreshold, page, pageSize)
+	if err != nil {
+		return DuplicateReport{}, err
+	}
+	if pairs == nil {
+		pairs = []DuplicatePair{}
+	}
+	return DuplicateReport{Pairs: pairs, Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+// mergedID kaydını survivorID kaydına birleştirir. İletişim kayıtları, fırsatlar, görevler,
+// etiketler ve hesap bağlantıları taşınır; birleştirme customer_merges tablosuna ve audit
+// akışına yazılır. Sadece iki kaydın da sorumlusu veya yönetici birleştirebilir.
+func MergeCustomers(db *sql.DB, user common.AuthUser, survivorID, mergedID int) (MergeResult, error) {
+	if survivorID <= 0 || mergedID <= 0 || survivorID == mergedID {
+		return MergeResult{}, errors.New("Birleştirilecek iki farklı müşteri seçilmeli")
+	}
+	survivor, err := GetCustomer(db, user, survivorID)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	merged, err := GetCustomer(db, user, mergedID)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	if !user.IsManager() && (survivor.OwnerID != user.ID || merged.OwnerID != user.ID) {
+		return MergeResult{}, ErrMergeForbidden
+	}
+	moved, err := mergeCustomersRepo(db, tenantOf(user), survivorID, merged, user.ID)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	if survivor, err = GetCustomer(db, user, survivorID); err != nil {
+		return MergeResult{}, err
+	}
+
+	// Audit kaydı (audit-svc audit.raw topic'ini immudb'ye yazar)
+	go func(event map[string]interface{}) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		payload, _ := json.Marshal(event)
+		_ = common.PublishToTopic(ctx, "audit.raw", fmt.Sprintf("customer-%d", survivorID), string(payload))
+	}(map[string]interface{}{
+		"event":       "customer.merged",
+		"tenant_id":   tenantOf(user),
+		"survivor_id": survivorID,
+		"merged":      merged,
+		"moved":       moved,
+		"merged_by":   user.ID,
+		"at":          time.Now().UTC(),
+	})
+	result := MergeResult{Customer: survivor, MergedID: mergedID, Moved: moved}
+	common.PublishDomainEvent(fmt.Sprintf("customer-%d", survivorID), "customer.merged", tenantOf(user), result)
+	return result, nil
+}
+
+// Müşterinin sorumlusunu değiştirir (mevcut sorumlu veya yönetici)
+func ReassignCustomer(db *sql.DB, user common.AuthUser, customerID, ownerID int, reason string) error {
+	if ownerID <= 0 {
+		return errors.New("Geçersiz sorumlu ID")
+	}
+	c, err := GetCustomer(db, user, customerID)
+	if err != nil {
+		return err
+	}
+	if c.OwnerID != user.ID && !user.IsManager() {
+		return ErrOwnerForbidden
+	}
+	if c.OwnerID == ownerID {
+		return nil
+	}
+	if err := checkOwners(db, tenantOf(user), int64(ownerID)); err != nil {
+		return err
+	}
+	_, err = reassignCustomerRepo(db, customerID, ownerID, user.ID, reason)
+	if err == sql.ErrNoRows {
+		return ErrCustomerNotFound
+	}
+	return err
+}
+
+// Ayrılan temsilcinin tüm müşterilerini hedef temsilciler arasında sırayla dağıtır (sadece yönetici)
+func BulkReassign(db *sql.DB, user common.AuthUser, fromOwnerID int, toOwnerIDs []int64, reason string) (int, error) {
+	if !user.IsManager() {
+		return 0, ErrManagerOnly
+	}
+	if fromOwnerID <= 0 {
+		return 0, errors.New("Geçersiz kaynak sorumlu ID")
+	}
+	if len(toOwnerIDs) == 0 {
+		return 0, errors.New("En az bir hedef sorumlu gerekli")
+	}
+	for _, id := range toOwnerIDs {
+		if id <= 0 || int(id) == fromOwnerID {
+			return 0, fmt.Errorf("Geçersiz hedef sorumlu: %d", id)
+		}
+	}
+	if err := checkOwners(db, tenantOf(user), toOwnerIDs...); err != nil {
+		return 0, err
+	}
+	if reason == "" {
+		reason = "bulk reassignment"
+	}
+	return bulkReassignRepo(db, tenantOf(user), fromOwnerID, toOwnerIDs, user.ID, reason)
+}
+
+func GetOwnerHistory(db *sql.DB, user common.AuthUser, customerID int) ([]OwnerChange, error) {
+	if _, err := GetCustomer(db, user, customerID); err != nil {
+		return nil, err
+	}
+	return getOwnerHistoryRepo(db, customerID)
+}
+
+// Atama kuralı müşteriyle eşleşiyor mu
+func MatchRule(r AssignmentRule, c Customer) bool {
+	if !r.Active || len(r.AssigneeIDs) == 0 {
+		return false
+	}
+	value := strings.TrimSpace(r.MatchValue)
+	switch r.Type {
+	case RuleRoundRobin:
+		return true
+	case RuleRegion:
+		return value != "" && strings.EqualFold(value, strings.TrimSpace(c.Region))
+	case RulePostalCode:
+		return value != "" && strings.HasPrefix(strings.ReplaceAll(c.PostalCode, " ", ""), value)
+	case RuleLeadSource:
+		return value != "" && strings.EqualFold(value, strings.TrimSpace(c.LeadSource))
+	}
+	return false
+}
+
+// İlk eşleşen aktif kurala göre sorumluyu seçer, eşleşme yoksa 0 döner
+func applyAssignmentRules(db *sql.DB, c Customer) (int, string, error) {
+	rules, err := getAssignmentRulesRepo(db, c.TenantID, true)
+	if err != nil {
+		return 0, "", err
+	}
+	for _, r := range rules {
+		if !MatchRule(r, c) {
+			continue
+		}
+		n := 0
+		if len(r.AssigneeIDs) > 1 {
+			if n, err = nextRoundRobinRepo(db, r.ID); err != nil {
+				return 0, "", err
+			}
+		}
+		return int(r.AssigneeIDs[n%len(r.AssigneeIDs)]), r.Name, nil
+	}
+	return 0, "", nil
+}
+
+func GetAssignmentRules(db *sql.DB, user common.AuthUser) ([]AssignmentRule, error) {
+	return getAssignmentRulesRepo(db, tenantOf(user), false)
+}
+
+// Kurallar oturumun tenant'ına kaydedilir; atanacak kullanıcılar da aynı tenant'ta olmalı
+func CreateAssignmentRule(db *sql.DB, user common.AuthUser, r *AssignmentRule) error {
+	if !user.IsManager() {
+		return ErrManagerOnly
+	}
+	if err := ValidateAssignmentRule(*r); err != nil {
+		return err
+	}
+	if err := checkOwners(db, tenantOf(user), r.AssigneeIDs...); err != nil {
+		return err
+	}
+	return createAssignmentRuleRepo(db, tenantOf(user), r)
+}
+
+func UpdateAssignmentRule(db *sql.DB, user common.AuthUser, r *AssignmentRule) error {
+	if !user.IsManager() {
+		return ErrManagerOnly
+	}
+	if err := ValidateAssignmentRule(*r); err != nil {
+		return err
+	}
+	if err := checkOwners(db, tenantOf(user), r.AssigneeIDs...); err != nil {
+		return err
+	}
+	err := updateAssignmentRuleRepo(db, tenantOf(user), r)
+	if err == sql.ErrNoRows {
+		return ErrRuleNotFound
+	}
+	return err
+}
+
+func DeleteAssignmentRule(db *sql.DB, user common.AuthUser, id int) error {
+	if !user.IsManager() {
+		return ErrManagerOnly
+	}
+	found, err := deleteAssignmentRuleRepo(db, tenantOf(user), id)
+	if err == nil && !found {
+		return ErrRuleNotFound
+	}
+	return err
+}
+
+// İletişim kayıtlarını getir (pagination); müşteri oturumun tenant'ında olmalı
+func GetContactsByCustomerID(db *sql.DB, user common.AuthUser, params ContactListParams) (ContactListResult, error) {
+	if params.CustomerID <= 0 {
+		return ContactListResult{}, errors.New("Geçersiz müşteri ID")
+	}
+	if params.Page < 1 || params.Cursor != "" {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+	if params.Type != "" && !isContactType(params.Type) {
+		return ContactListResult{}, errors.New("Geçersiz iletişim tipi")
+	}
+	if _, err := GetCustomer(db, user, params.CustomerID); err != nil {
+		return ContactListResult{}, err
+	}
+	return getContactsByCustomerIDRepo(db, params)
+}
+
+// İletişim kaydı ekleme (validasyon); müşteri oturumun tenant'ında olmalı
+func CreateContact(db *sql.DB, user common.AuthUser, contact *Contact) error {
+	if contact.Type == "" {
+		contact.Type = ContactTypeNote
+	}
+	if contact.OccurredAt.IsZero() {
+		contact.OccurredAt = time.Now()
+	}
+	if err := validateContact(*contact); err != nil {
+		return err
+	}
+	if _, err := GetCustomer(db, user, contact.CustomerID); err != nil {
+		return err
+	}
+	return createContactRepo(db, contact)
+}
+
+var (
+	ErrContactNotFound   = errors.New("İletişim kaydı bulunamadı")
+	ErrContactForbidden  = errors.New("Bu kaydı sadece yazarı veya bir yönetici değiştirebilir")
+	ErrRevisionForbidden = errors.New("Revizyonları sadece kaydın yazarı veya bir yönetici görebilir")
+)
+
+// İletişim kaydını günceller; önceki hali contact_revisions'a yazılır.
+// Müşteri ve yazar değiştirilemez, sadece kaydın yazarı veya yönetici düzenleyebilir.
+func UpdateContact(db *sql.DB, user common.AuthUser, contact *Contact) error {
+	if contact.Type == "" {
+		contact.Type = ContactTypeNote
+	}
+	return withContactTx(db, user, contact.ID, "update", func(tx *sql.Tx, old Contact) error {
+		contact.CustomerID = old.CustomerID
+		contact.AuthorID = old.AuthorID
+		contact.CreatedAt = old.CreatedAt
+		if contact.OccurredAt.IsZero() {
+			contact.OccurredAt = old.OccurredAt
+		}
+		if err := validateContact(*contact); err != nil {
+			return err
+		}
+		return updateContactRepo(tx, contact)
+	})
+}
+
+// İletişim kaydını siler; son hali contact_revisions'ta kalır
+func DeleteContact(db *sql.DB, user common.AuthUser, id int) error {
+	return withContactTx(db, user, id, "delete", func(tx *sql.Tx, old Contact) error {
+		return deleteContactRepo(tx, id)
+	})
+}
+
+// İletişim kaydının revizyon geçmişi; düzenleme kuralı gibi sadece yazarı veya yönetici görebilir.
+// Silinmiş kayıtlar için müşteri ve yazar son revizyondan okunur; başka tenant'ın kaydı bulunamadı sayılır.
+func GetContactRevisions(db *sql.DB, user common.AuthUser, contactID int) ([]ContactRevision, error) {
+	if contactID <= 0 {
+		return nil, errors.New("Geçersiz iletişim ID")
+	}
+	revisions, err := getContactRevisionsRepo(db, contactID)
+	if err != nil {
+		return nil, err
+	}
+	customerID, authorID, err := contactAuthorRepo(db, contactID)
+	if err == sql.ErrNoRows && len(revisions) > 0 {
+		customerID, authorID, err = revisions[0].Snapshot.CustomerID, revisions[0].Snapshot.AuthorID, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, ErrContactNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := GetCustomer(db, user, customerID); err != nil {
+		if errors.Is(err, ErrCustomerNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	if authorID != user.ID && !user.IsManager() {
+		return nil, ErrRevisionForbidden
+	}
+	return revisions, nil
+}
+
+// Kaydı kilitler, yetkiyi kontrol eder, revizyonu yazar ve fn'i aynı transaction'da çalıştırır
+func withContactTx(db *sql.DB, user common.AuthUser, id int, action string, fn func(tx *sql.Tx, old Contact) error) error {
+	if id <= 0 {
+		return errors.New("Geçersiz iletişim ID")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := getContactForUpdateRepo(tx, id)
+	if err == sql.ErrNoRows {
+		return ErrContactNotFound
+	}
+	if err != nil {
+		return err
+	}
+	c, err := getCustomerRepo(db, old.CustomerID)
+	if err == sql.ErrNoRows || (err == nil && c.TenantID != tenantOf(user)) {
+		return ErrContactNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if old.AuthorID != user.ID && !user.IsManager() {
+		return ErrContactForbidden
+	}
+	if err := insertContactRevisionRepo(tx, old, action, user.ID); err != nil {
+		return err
+	}
+	if err := fn(tx, old); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Tip bazında iletişim sayıları için filtreler
+type ContactStatsParams struct {
+	TenantID int       // 0 ise varsayılan tenant
+	From     time.Time // occurred_at, dahil
+	To       time.Time // occurred_at, hariç
+	AuthorID int
+}
+
+// Arama hacmi, toplantı sayısı gibi raporlar için tip bazında özet
+func GetContactStats(db *sql.DB, params ContactStatsParams) ([]ContactTypeStat, error) {
+	if !params.From.IsZero() && !params.To.IsZero() && !params.From.Before(params.To) {
+		return nil, errors.New("Başlangıç tarihi bitiş tarihinden önce olmalı")
+	}
+	if params.TenantID == 0 {
+		params.TenantID = common.DefaultTenantID
+	}
+	return getContactStatsRepo(db, params)
+}
+
+// Pagination ve filtreleme için parametreler
+type ContactListParams struct {
+	CustomerID int
+	Type       string // Boşsa tüm tipler
+	Filter     []common.FilterCondition
+	Sort       []common.SortKey // Boşsa en yeni kayıt önce
+	Cursor     string           // Önceki yanıttaki next_cursor/prev_cursor; verilirse Page yok sayılır
+	Page       int
+	PageSize   int
+
+	IncludeTotal bool
+}
+
+// İletişim kaydı listeleri için filtrelenebilir ve sıralanabilir alanlar
+var ContactListFields = common.ListFields{
+	"id":               {Column: "ct.id", Type: common.ListNumber, Sortable: true},
+	"customer_id":      {Column: "ct.customer_id", Type: common.ListNumber, Sortable: true},
+	"author_id":        {Column: "ct.author_id", Type: common.ListNumber, Sortable: true, Nullable: true},
+	"type":             {Column: "ct.type", Type: common.ListString, Sortable: true},
+	"direction":        {Column: "ct.direction", Type: common.ListString, Sortable: true},
+	"outcome":          {Column: "ct.outcome", Type: common.ListString, Sortable: true},
+	"duration_seconds": {Column: "ct.duration_seconds", Type: common.ListNumber, Sortable: true},
+	"content":          {Column: "ct.content", Type: common.ListString},
+	"occurred_at":      {Column: "ct.occurred_at", Type: common.ListTime, Sortable: true},
+	"created_at":       {Column: "ct.created_at", Type: common.ListTime, Sortable: true, Nullable: true},
+}
+
+var defaultContactSort = []common.SortKey{{Field: "created_at", Desc: true}}
+
+type ContactListResult struct {
+	Contacts   []Contact
+	Total      *int `json:",omitempty"` // Sadece IncludeTotal ile
+	Page       int  `json:",omitempty"`
+	PageSize   int
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Aktivite akışı (tüm müşterilerin iletişim kayıtları) için filtreler
+type ContactFeedParams struct {
+	TenantID   int       // 0 ise varsayılan tenant
+	From       time.Time // Dahil
+	To         time.Time // Hariç
+	AuthorID   int
+	CustomerID int
+	AccountID  int // Hesap ve alt hesaplarındaki tüm kişiler
+	Type       string
+	Filter     []common.FilterCondition // Sıralama sabit olduğundan sadece filtre desteklenir
+	Cursor     string                   // Önceki sayfanın NextCursor değeri
+	Limit      int
+}
+
+type ContactFeedResult struct {
+	Contacts   []Contact
+	NextCursor string // Boşsa başka sayfa yok
+}
+
+// Ekip genelindeki son iletişim kayıtlarını getirir (en yeniden eskiye)
+func GetContactFeed(db *sql.DB, params ContactFeedParams) (ContactFeedResult, error) {
+	if params.Limit < 1 || params.Limit > 100 {
+		params.Limit = 20
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && !params.From.Before(params.To) {
+		return ContactFeedResult{}, errors.New("Başlangıç tarihi bitiş tarihinden önce olmalı")
+	}
+	if params.Type != "" && !isContactType(params.Type) {
+		return ContactFeedResult{}, errors.New("Geçersiz iletişim tipi")
+	}
+	if params.TenantID == 0 {
+		params.TenantID = common.DefaultTenantID
+	}
+	return getContactFeedRepo(db, params)
+}
+
+// Tam metin arama parametreleri
+type SearchParams struct {
+	Query    string
+	Type     string // SearchCustomers, SearchContacts veya boş (ikisi de)
+	Limit    int    // Her tip için en fazla sonuç
+	TenantID int
+}
+
+const (
+	maxSearchTerms = 8
+	maxSearchLimit = 50
+)
+
+// Arama metnini önek eşleşmeli tsquery'ye çevirir: "ali yıl" -> "ali:* & yıl:*".
+// Harf, rakam ve birleşik işaretler dışındaki her karakter ayırıcıdır; böylece
+// tsquery operatörleri kullanıcıdan gelemez. Büyük/küçük harf ve İ/ı farkı
+// veritabanındaki turkish_unaccent yapılandırmasıyla giderilir.
+func SearchQuery(s string) string {
+	terms := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	for i, t := range terms {
+		terms[i] = t + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Müşterilerde ve iletişim kayıtlarında sıralı (rank) arama
+func Search(db *sql.DB, params SearchParams) (SearchResult, error) {
+	tsq := SearchQuery(params.Query)
+	if utf8.RuneCountInString(strings.TrimSpace(params.Query)) < 2 || tsq == "" {
+		return SearchResult{}, errors.New("Arama metni en az 2 karakter olmalı")
+	}
+	if params.Type != "" && params.Type != SearchCustomers && params.Type != SearchContacts {
+		return SearchResult{}, errors.New("Geçersiz arama tipi")
+	}
+	if params.Limit < 1 || params.Limit > maxSearchLimit {
+		params.Limit = 10
+	}
+	if params.TenantID == 0 {
+		params.TenantID = common.DefaultTenantID
+	}
+
+	result := SearchResult{Query: params.Query, Customers: []SearchHit{}, Contacts: []SearchHit{}}
+	var err error
+	if params.Type == "" || params.Type == SearchCustomers {
+		if result.Customers, err = searchCustomersRepo(db, params.TenantID, tsq, params.Limit); err != nil {
+			return SearchResult{}, err
+		}
+	}
+	if params.Type == "" || params.Type == SearchContacts {
+		if result.Contacts, err = searchContactsRepo(db, params.TenantID, tsq, params.Limit); err != nil {
+			return SearchResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// Toplu içe aktarma sınırları
+const (
+	MaxImportFileSize   = 20 << 20
+	maxImportRows       = 50000
+	importProgressEvery = 100 // Bu kadar satırda bir ilerleme kaydedilir
+)
+
+// Sütunların eşlenebileceği müşteri alanları; özel alanlar "cf.<anahtar>" ile eşlenir
+var importFields = []string{"name", "email", "phone", "region", "postal_code", "lead_source", "owner_id"}
+
+// Eşleme verilmemişse başlıklar alan adı, özel alan anahtarı veya etiketiyle
+// (büyük/küçük harf duyarsız) eşlenir; eşleşmeyen sütunlar yok sayılır
+func DefaultImportMapping(headers []string, defs []FieldDefinition) map[string]string {
+	mapping := map[string]string{}
+	for _, h := range headers {
+		name := strings.TrimSpace(h)
+		for _, f := range importFields {
+			if strings.EqualFold(name, f) {
+				mapping[h] = f
+			}
+		}
+		for _, d := range defs {
+			if strings.EqualFold(name, d.Key) || strings.EqualFold(name, "cf."+d.Key) || strings.EqualFold(name, d.Label) {
+				mapping[h] = "cf." + d.Key
+			}
+		}
+	}
+	return mapping
+}
+
+type importColumn struct {
+	index int
+	field string
+}
+
+// Eşlemeyi dosya başlıklarına ve özel alan tanımlarına göre doğrular
+func resolveImportMapping(headers []string, mapping map[string]string, defs []FieldDefinition, mode string) ([]importColumn, error) {
+	byKey := map[string]bool{}
+	for _, d := range defs {
+		byKey[d.Key] = true
+	}
+	used := map[string]bool{}
+	var cols []importColumn
+	for i, h := range headers {
+		field, ok := mapping[h]
+		if !ok || field == "" {
+			continue
+		}
+		if key, isCustom := strings.CutPrefix(field, "cf."); isCustom {
+			if !byKey[key] {
+				return nil, fmt.Errorf("Bilinmeyen özel alan: %s", key)
+			}
+		} else if !containsString(importFields, field) {
+			return nil, fmt.Errorf("Geçersiz alan: %s", field)
+		}
+		if used[field] {
+			return nil, fmt.Errorf("%s alanı birden fazla sütuna eşlenmiş", field)
+		}
+		used[field] = true
+		cols = append(cols, importColumn{index: i, field: field})
+	}
+	for h := range mapping {
+		if !containsString(headers, h) {
+			return nil, fmt.Errorf("Dosyada %q sütunu yok", h)
+		}
+	}
+	if !used["name"] {
+		return nil, errors.New("name alanı bir sütuna eşlenmeli")
+	}
+	if mode != ImportCreate && !used["email"] {
+		return nil, errors.New("Bu modda email alanı bir sütuna eşlenmeli")
+	}
+	return cols, nil
+}
+
+var importDateLayouts = []string{"2006-01-02", "02.01.2006", "02/01/2006", "2006-01-02 15:04:05"}
+
+// Hücre metnini özel alan tipine çevirir (sayılarda ondalık virgül, tarihlerde GG.AA.YYYY kabul edilir)
+func ParseImportValue(d FieldDefinition, raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	switch d.Type {
+	case FieldNumber:
+		s := raw
+		if !strings.Contains(s, ".") {
+			s = strings.Replace(s, ",", ".", 1)
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s sayı olmalı", d.Label)
+		}
+		return f, nil
+	case FieldDate:
+		for _, layout := range importDateLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t.Format("2006-01-02"), nil
+			}
+		}
+		return nil, fmt.Errorf("%s YYYY-MM-DD biçiminde tarih olmalı", d.Label)
+	case FieldEnum:
+		return importOption(d, raw), nil
+	case FieldMultiSelect:
+		values := []string{}
+		for _, s := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == ',' }) {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, importOption(d, s))
+			}
+		}
+		return values, nil
+	}
+	return raw, nil
+}
+
+// Seçeneği büyük/küçük harf duyarsız eşleyip tanımdaki yazımıyla döner
+func importOption(d FieldDefinition, s string) string {
+	for _, o := range d.Options {
+		if strings.EqualFold(o, s) {
+			return o
+		}
+	}
+	return s
+}
+
+// Telefon biçimlendirmesini (boşluk, tire, parantez, nokta) temizler
+func cleanImportPhone(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '(' || r == ')' || r == '.' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func blankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Satırı müşteriye çevirir; set, dosyada değeri dolu olan alanlardır
+func parseImportRow(row []string, cols []importColumn, defs map[string]FieldDefinition) (Customer, map[string]bool, error) {
+	var c Customer
+	set := map[string]bool{}
+	for _, col := range cols {
+		if col.index >= len(row) {
+			continue
+		}
+		v := strings.TrimSpace(row[col.index])
+		if v == "" {
+			continue
+		}
+		set[col.field] = true
+		switch col.field {
+		case "name":
+			c.Name = v
+		case "email":
+			c.Email = v
+		case "phone":
+			c.Phone = cleanImportPhone(v)
+		case "region":
+			c.Region = v
+		case "postal_code":
+			c.PostalCode = v
+		case "lead_source":
+			c.LeadSource = v
+		case "owner_id":
+			id, err := strconv.Atoi(v)
+			if err != nil || id <= 0 {
+				return c, nil, fmt.Errorf("Geçersiz sorumlu ID: %s", v)
+			}
+			c.OwnerID = id
+		default:
+			key := strings.TrimPrefix(col.field, "cf.")
+			val, err := ParseImportValue(defs[key], v)
+			if err != nil {
+				return c, nil, err
+			}
+			if c.CustomFields == nil {
+				c.CustomFields = map[string]interface{}{}
+			}
+			c.CustomFields[key] = val
+		}
+	}
+	return c, set, nil
+}
+
+// Dosyayı doğrular, işi kaydeder ve arka planda işlemeye başlar.
+// path geçici dosyadır; başarılı olursa işlem bitince silinir, hata dönerse silmek çağırana kalır.
+func StartCustomerImport(db *sql.DB, user common.AuthUser, job *CustomerImport, path string) error {
+	if job.Mode == "" {
+		job.Mode = ImportCreate
+	}
+	if job.Mode != ImportCreate && job.Mode != ImportUpsert && job.Mode != ImportSkipDuplicates {
+		return errors.New("Geçersiz içe aktarma modu (create, upsert, skip_duplicates)")
+	}
+	format, err := common.SpreadsheetFormat(job.Filename)
+	if err != nil {
+		return err
+	}
+	job.Format = format
+	if job.Headers, err = readImportHeaders(path, format); err != nil {
+		return err
+	}
+	job.TenantID = tenantOf(user)
+	job.CreatedBy = user.ID
+	defs, err := getFieldDefinitionsRepo(db, job.TenantID)
+	if err != nil {
+		return err
+	}
+	if len(job.Mapping) == 0 {
+		job.Mapping = DefaultImportMapping(job.Headers, defs)
+	}
+	cols, err := resolveImportMapping(job.Headers, job.Mapping, defs, job.Mode)
+	if err != nil {
+		return err
+	}
+	job.Status = ImportPending
+	if err := createImportRepo(db, job); err != nil {
+		return err
+	}
+	run := &importRun{db: db, job: *job, cols: cols, defs: defs, seen: map[string]int{}}
+	go run.start(path)
+	return nil
+}
+
+func readImportHeaders(path, format string) ([]string, error) {
+	rr, err := common.OpenSpreadsheet(path, format)
+	if err != nil {
+		return nil, err
+	}
+	defer rr.Close()
+	headers, err := rr.Read()
+	if err != nil || blankRow(headers) {
+		return nil, errors.New("Dosyada başlık satırı yok")
+	}
+	for i, h := range headers {
+		headers[i] = strings.TrimSpace(h)
+	}
+	return headers, nil
+}
+
+// Arka planda çalışan tek bir içe aktarma
+type importRun struct {
+	db   *sql.DB
+	job  CustomerImport
+	cols []importColumn
+	defs []FieldDefinition
+	seen map[string]int // Dosyada geçen normalize e-posta -> müşteri ID (dry-run'da 0)
+}
+
+// Satırdaki veri hatası; rapora yazılır ve işleme devam edilir
+type importRowErr struct{ error }
+
+func (r *importRun) start(path string) {
+	defer os.Remove(path)
+	r.job.Status = ImportRunning
+	if err := updateImportProgressRepo(r.db, &r.job); err != nil {
+		log.Printf("İçe aktarma %d başlatılamadı: %v", r.job.ID, err)
+		return
+	}
+	r.job.Status = ImportCompleted
+	if err := r.process(path); err != nil {
+		r.job.Status = ImportFailed
+		r.job.Error = err.Error()
+	}
+	if err := updateImportProgressRepo(r.db, &r.job); err != nil {
+		log.Printf("İçe aktarma %d durumu kaydedilemedi: %v", r.job.ID, err)
+	}
+}
+
+func (r *importRun) process(path string) error {
+	total, err := countImportRows(path, r.job.Format)
+	if err != nil {
+		return err
+	}
+	if total > maxImportRows {
+		return fmt.Errorf("Dosya en fazla %d satır içerebilir", maxImportRows)
+	}
+	r.job.TotalRows = total
+	if err := updateImportProgressRepo(r.db, &r.job); err != nil {
+		return err
+	}
+
+	byKey := map[string]FieldDefinition{}
+	for _, d := range r.defs {
+		byKey[d.Key] = d
+	}
+	rr, err := common.OpenSpreadsheet(path, r.job.Format)
+	if err != nil {
+		return err
+	}
+	defer rr.Close()
+	if _, err := rr.Read(); err != nil {
+		return err
+	}
+	line := 1
+	for {
+		row, err := rr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line++
+		if err != nil {
+			return fmt.Errorf("Dosya okunamadı (satır %d): %v", line, err)
+		}
+		if blankRow(row) {
+			continue
+		}
+		err = r.processRow(row, byKey)
+		var rowErr importRowErr
+		if errors.As(err, &rowErr) {
+			r.job.ErrorCount++
+			err = insertImportErrorRepo(r.db, r.job.ID, ImportRowError{Row: line, Message: rowErr.Error(), Values: row})
+		}
+		if err != nil {
+			return err
+		}
+		r.job.ProcessedRows++
+		if r.job.ProcessedRows%importProgressEvery == 0 {
+			if err := updateImportProgressRepo(r.db, &r.job); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Toplam satır sayısı (başlık ve boş satırlar hariç), ilerleme yüzdesi için
+func countImportRows(path, format string) (int, error) {
+	rr, err := common.OpenSpreadsheet(path, format)
+	if err != nil {
+		return 0, err
+	}
+	defer rr.Close()
+	n := -1
+	for {
+		row, err := rr.Read()
+		if err == io.EOF {
+			return max(n, 0), nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("Dosya okunamadı: %v", err)
+		}
+		if !blankRow(row) {
+			n++
+		}
+	}
+}
+
+// Satırı moda göre ekler, günceller veya atlar. Dry-run'da veritabanına yazılmaz.
+func (r *importRun) processRow(row []string, defs map[string]FieldDefinition) error {
+	c, set, err := parseImportRow(row, r.cols, defs)
+	if err != nil {
+		return importRowErr{err}
+	}
+	c.TenantID = r.job.TenantID
+
+	email := NormalizeEmail(c.Email)
+	existingID, inFile := 0, false
+	if email != "" {
+		if existingID, inFile = r.seen[email]; !inFile {
+			if existingID, err = findCustomerByEmailRepo(r.db, c.TenantID, email); err != nil {
+				return err
+			}
+		}
+	}
+	if existingID == 0 && !inFile {
+		return r.create(c, email)
+	}
+	switch r.job.Mode {
+	case ImportSkipDuplicates:
+		r.job.SkippedCount++
+		return nil
+	case ImportUpsert:
+		return r.update(existingID, c, set, email)
+	}
+	if inFile {
+		return importRowErr{errors.New("Bu e-posta dosyada daha önce geçiyor")}
+	}
+	return importRowErr{fmt.Errorf("Bu e-posta ile kayıtlı müşteri var (ID %d)", existingID)}
+}
+
+func (r *importRun) create(c Customer, email string) error {
+	c.CustomFields = applyFieldDefaults(r.defs, c.CustomFields)
+	if err := validateCustomer(c, r.defs); err != nil {
+		return importRowErr{err}
+	}
+	if c.OwnerID > 0 {
+		err := checkOwners(r.db, r.job.TenantID, int64(c.OwnerID))
+		if errors.Is(err, ErrOwnerNotFound) {
+			return importRowErr{err}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !r.job.DryRun {
+		reason := "import"
+		if c.OwnerID == 0 {
+			ownerID, rule, err := applyAssignmentRules(r.db, c)
+			if err != nil {
+				return err
+			}
+			c.OwnerID = r.job.CreatedBy
+			if ownerID > 0 {
+				c.OwnerID = ownerID
+				reason = "import, rule: " + rule
+			}
+		}
+		if err := createCustomerRepo(r.db, &c); err != nil {
+			return err
+		}
+		if c.OwnerID > 0 {
+			if err := insertOwnerChangeRepo(r.db, c.ID, 0, c.OwnerID, r.job.CreatedBy, reason); err != nil {
+				return err
+			}
+		}
+	}
+	if email != "" {
+		r.seen[email] = c.ID
+	}
+	r.job.CreatedCount++
+	return nil
+}
+
+// Dosyada dolu olan alanları mevcut müşterinin üzerine yazar; boş hücreler değeri silmez,
+// özel alanlar birleştirilir. Sorumlu değişikliği içe aktarmayla yapılmaz.
+func (r *importRun) update(id int, c Customer, set map[string]bool, email string) error {
+	current := Customer{TenantID: r.job.TenantID}
+	if id > 0 {
+		var err error
+		if current, err = getCustomerRepo(r.db, id); err != nil {
+			return err
+		}
+	}
+	if set["name"] {
+		current.Name = c.Name
+	}
+	if set["email"] {
+		current.Email = c.Email
+	}
+	if set["phone"] {
+		current.Phone = c.Phone
+	}
+	if set["region"] {
+		current.Region = c.Region
+	}
+	if set["postal_code"] {
+		current.PostalCode = c.PostalCode
+	}
+	if set["lead_source"] {
+		current.LeadSource = c.LeadSource
+	}
+	if len(c.CustomFields) > 0 && current.CustomFields == nil {
+		current.CustomFields = map[string]interface{}{}
+	}
+	for k, v := range c.CustomFields {
+		current.CustomFields[k] = v
+	}
+	current.CustomFields = applyFieldDefaults(r.defs, current.CustomFields)
+	if err := validateCustomer(current, r.defs); err != nil {
+		return importRowErr{err}
+	}
+	if !r.job.DryRun && id > 0 {
+		if err := updateCustomerRepo(r.db, &current); err != nil {
+			return err
+		}
+	}
+	r.seen[email] = id
+	r.job.UpdatedCount++
+	return nil
+}
+
+// İçe aktarma işini sadece başlatan kullanıcı veya tenant'taki bir yönetici görebilir
+func GetCustomerImport(db *sql.DB, user common.AuthUser, id int) (CustomerImport, error) {
+	job, err := getImportRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && (job.TenantID != tenantOf(user) || (job.CreatedBy != user.ID && !user.IsManager()))) {
+		return CustomerImport{}, ErrImportNotFound
+	}
+	return job, err
+}
+
+// Hatalı satırları CSV olarak yazar: satır numarası, hata ve dosyadaki orijinal sütunlar
+func WriteImportErrors(db *sql.DB, user common.AuthUser, id int, w io.Writer) error {
+	job, err := GetCustomerImport(db, user, id)
+	if err != nil {
+		return err
+	}
+	cw, err := common.NewSpreadsheetWriter(w, common.FormatCSV)
+	if err != nil {
+		return err
+	}
+	if err := cw.Write(append([]string{"Satır", "Hata"}, job.Headers...)); err != nil {
+		return err
+	}
+	err = eachImportErrorRepo(db, id, func(e ImportRowError) error {
+		return cw.Write(append([]string{strconv.Itoa(e.Row), e.Message}, e.Values...))
+	})
+	if err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// Sunucu başlarken önceki süreçte yarıda kalan içe aktarmaları başarısız işaretler
+func FailInterruptedImports(db *sql.DB) {
+	n, err := failInterruptedImportsRepo(db)
+	if err != nil {
+		log.Printf("Yarıda kalan içe aktarmalar işaretlenemedi: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("%d içe aktarma yarıda kaldığı için başarısız işaretlendi", n)
+	}
+}
+
+// Dışa aktarma dosyaları bu süre saklanır, indirme bağlantıları ise en fazla exportLinkTTL geçerlidir
+const (
+	exportFileTTL = 24 * time.Hour
+	exportLinkTTL = time.Hour
+)
+
+var (
+	ErrExportNotFound = errors.New("Dışa aktarma bulunamadı")
+	ErrExportExpired  = errors.New("Dışa aktarma dosyasının süresi dolmuş, yeniden oluşturun")
+)
+
+// Müşteri sütunları içe aktarmadaki alan adlarıyla aynıdır; özel alanlar "cf.<anahtar>" olarak eklenir
+var customerExportColumns = []string{"id", "name", "email", "phone", "owner_id", "region", "postal_code", "lead_source", "created_at", "tags"}
+
+var contactExportColumns = []string{"id", "customer_id", "customer_name", "author_id", "type", "direction",
+	"duration_seconds", "outcome", "participants", "content", "occurred_at", "created_at"}
+
+func checkExportFormat(format string) error {
+	if format != common.FormatCSV && format != common.FormatXLSX && format != ExportJSONL && format != ExportVCF {
+		return errors.New("Geçersiz biçim (csv, xlsx, jsonl, vcf)")
+	}
+	return nil
+}
+
+// vCard sadece müşteriler için üretilir
+func checkContactExportFormat(format string) error {
+	if format == ExportVCF {
+		return errors.New("İletişim kayıtları vcf olarak dışa aktarılamaz")
+	}
+	return checkExportFormat(format)
+}
+
+// Başlık ilk satırla (veya boş sonuçta kapanışta) yazılır; sorgu hata verirse hedefe hiçbir şey yazılmamış olur
+type exportWriter struct {
+	w      io.Writer
+	format string
+	header []string
+	rows   common.RowWriter
+	enc    *json.Encoder
+	n      int
+}
+
+func (e *exportWriter) start() error {
+	if e.rows != nil || e.enc != nil {
+		return nil
+	}
+	if e.format == ExportJSONL {
+		e.enc = json.NewEncoder(e.w)
+		return nil
+	}
+	var err error
+	if e.rows, err = common.NewSpreadsheetWriter(e.w, e.format); err != nil {
+		return err
+	}
+	return e.rows.Write(e.header)
+}
+
+// JSONL'de obj, tablo biçimlerinde row yazılır
+func (e *exportWriter) write(row []string, obj interface{}) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.n++
+	if e.enc != nil {
+		return e.enc.Encode(obj)
+	}
+	return e.rows.Write(row)
+}
+
+func (e *exportWriter) close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if e.rows != nil {
+		return e.rows.Close()
+	}
+	return nil
+}
+
+func exportTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+func exportInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// Özel alan değerini içe aktarmanın geri okuyabileceği metne çevirir
+func exportFieldValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(x))
+		for i, p := range x {
+			parts[i] = fmt.Sprint(p)
+		}
+		return strings.Join(parts, "; ")
+	}
+	return fmt.Sprint(v)
+}
+
+// GetCustomers ile aynı filtre ve sıralamadaki tüm müşterileri akış halinde yazar, satır sayısını döner.
+// Sayfalama parametreleri yok sayılır; bellek kullanımı müşteri sayısından bağımsızdır.
+func WriteCustomersExport(db *sql.DB, params CustomerListParams, format string, w io.Writer) (int, error) {
+	if err := checkExportFormat(format); err != nil {
+		return 0, err
+	}
+	if params.TenantID == 0 {
+		params.TenantID = common.DefaultTenantID
+	}
+	defs, err := getFieldDefinitionsRepo(db, params.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where, err := customerWhere(db, params, arg)
+	if err != nil {
+		return 0, err
+	}
+	keyset, err := CustomerListFields.Keyset(params.Sort, defaultCustomerSort, "id")
+	if err != nil {
+		return 0, err
+	}
+	header := slices.Clone(customerExportColumns)
+	for _, d := range defs {
+		header = append(header, "cf."+d.Key)
+	}
+	out := &exportWriter{w: w, format: format, header: header}
+	err = eachCustomerRepo(db, where, args, keyset.OrderBy(false), func(c Customer, createdAt sql.NullTime) error {
+		if format == ExportVCF {
+			out.n++
+			_, err := io.WriteString(w, MarshalVCard(c, VCard3))
+			return err
+		}
+		if format == ExportJSONL {
+			var at *time.Time
+			if createdAt.Valid {
+				at = &createdAt.Time
+			}
+			return out.write(nil, struct {
+				Customer
+				CreatedAt *time.Time `json:"created_at,omitempty"`
+			}{c, at})
+		}
+		row := []string{strconv.Itoa(c.ID), c.Name, c.Email, c.Phone, exportInt(c.OwnerID), c.Region, c.PostalCode,
+			c.LeadSource, exportTime(createdAt), strings.Join(c.Tags, "; ")}
+		for _, d := range defs {
+			row = append(row, exportFieldValue(c.CustomFields[d.Key]))
+		}
+		return out.write(row, nil)
+	})
+	if err != nil || format == ExportVCF {
+		return out.n, err
+	}
+	return out.n, out.close()
+}
+
+// Tenant'ın iletişim kayıtlarını (customer_id verilirse sadece o müşterinin) akış halinde yazar
+func WriteContactsExport(db *sql.DB, tenantID int, params ContactListParams, format string, w io.Writer) (int, error) {
+	if err := checkContactExportFormat(format); err != nil {
+		return 0, err
+	}
+	if params.Type != "" && !isContactType(params.Type) {
+		return 0, errors.New("Geçersiz iletişim tipi")
+	}
+	if tenantID == 0 {
+		tenantID = common.DefaultTenantID
+	}
+	out := &exportWriter{w: w, format: format, header: contactExportColumns}
+	err := eachContactRepo(db, tenantID, params, func(c Contact, createdAt sql.NullTime) error {
+		if format == ExportJSONL {
+			return out.write(nil, c)
+		}
+		return out.write([]string{strconv.Itoa(c.ID), strconv.Itoa(c.CustomerID), c.CustomerName, exportInt(c.AuthorID),
+			c.Type, c.Direction, exportInt(c.DurationSeconds), c.Outcome, strings.Join(c.Participants, "; "), c.Content,
+			c.OccurredAt.UTC().Format(time.RFC3339), exportTime(createdAt)}, nil)
+	})
+	if err != nil {
+		return out.n, err
+	}
+	return out.n, out.close()
+}
+
+// Arka plan dışa aktarmasının kapsamı; Type'a göre Customers veya Contacts kullanılır
+type ExportParams struct {
+	Type      string
+	Format    string
+	Query     string // İş kaydında saklanan ham sorgu parametreleri
+	Customers CustomerListParams
+	Contacts  ContactListParams
+}
+
+// Dışa aktarmayı arka planda başlatır. Kayıtlar replica'dan okunur, iş durumu primary'ye yazılır.
+func StartExport(primary, replica *sql.DB, user common.AuthUser, p ExportParams) (ExportJob, error) {
+	if p.Format == "" {
+		p.Format = common.FormatCSV
+	}
+	if err := checkExportFormat(p.Format); err != nil {
+		return ExportJob{}, err
+	}
+	// Filtre ve sıralama hataları iş oluşturulmadan bildirilir
+	noArg := func(interface{}) string { return "$0" }
+	switch p.Type {
+	case ExportCustomers:
+		if _, err := CustomerListFields.Where(p.Customers.Filter, noArg); err != nil {
+			return ExportJob{}, err
+		}
+		if _, err := CustomerListFields.Keyset(p.Customers.Sort, defaultCustomerSort, "id"); err != nil {
+			return ExportJob{}, err
+		}
+	case ExportContacts:
+		if err := checkContactExportFormat(p.Format); err != nil {
+			return ExportJob{}, err
+		}
+		if _, err := ContactListFields.Where(p.Contacts.Filter, noArg); err != nil {
+			return ExportJob{}, err
+		}
+		if _, err := ContactListFields.Keyset(p.Contacts.Sort, defaultContactSort, "id"); err != nil {
+			return ExportJob{}, err
+		}
+	default:
+		return ExportJob{}, errors.New("Geçersiz dışa aktarma tipi (customers, contacts)")
+	}
+	p.Customers.TenantID = tenantOf(user)
+	job := ExportJob{Type: p.Type, Format: p.Format, Query: p.Query, Status: ExportPending, CreatedBy: user.ID, TenantID: tenantOf(user)}
+	if err := createExportRepo(primary, &job); err != nil {
+		return ExportJob{}, err
+	}
+	go runExport(primary, replica, job, p)
+	return job, nil
+}
+
+func runExport(primary, replica *sql.DB, job ExportJob, p ExportParams) {
+	job.Status = ExportRunning
+	if err := updateExportRepo(primary, &job); err != nil {
+		log.Printf("Dışa aktarma %d başlatılamadı: %v", job.ID, err)
+		return
+	}
+	job.Status = ExportCompleted
+	if err := writeExportFile(replica, &job, p); err != nil {
+		job.Status = ExportFailed
+		job.Error = err.Error()
+	}
+	if err := updateExportRepo(primary, &job); err != nil {
+		log.Printf("Dışa aktarma %d durumu kaydedilemedi: %v", job.ID, err)
+	}
+}
+
+// EXPORT_DIR verilmezse geçici dizin kullanılır; birden fazla api kopyasında ortak bir dizin olmalıdır
+func exportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "crm-exports")
+}
+
+func writeExportFile(db *sql.DB, job *ExportJob, p ExportParams) error {
+	dir := exportDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, fmt.Sprintf("export-%d-*.%s", job.ID, job.Format))
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriterSize(f, 64<<10)
+	var n int
+	if p.Type == ExportContacts {
+		n, err = WriteContactsExport(db, job.TenantID, p.Contacts, job.Format, bw)
+	} else {
+		n, err = WriteCustomersExport(db, p.Customers, job.Format, bw)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	expires := time.Now().Add(exportFileTTL)
+	job.FilePath, job.RowCount, job.ExpiresAt = f.Name(), n, &expires
+	return nil
+}
+
+func exportDownloadPath(id int) string {
+	return fmt.Sprintf("/api/exports/%d/download", id)
+}
+
+// Dışa aktarma işini sadece başlatan kullanıcı veya tenant'taki bir yönetici görebilir.
+// Tamamlanmış işlerde süreli indirme bağlantısı üretilir.
+func GetExport(db *sql.DB, user common.AuthUser, id int) (ExportJob, error) {
+	job, err := getExportRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && (job.TenantID != tenantOf(user) || (job.CreatedBy != user.ID && !user.IsManager()))) {
+		return ExportJob{}, ErrExportNotFound
+	}
+	if err != nil {
+		return ExportJob{}, err
+	}
+	if job.Status == ExportCompleted && job.ExpiresAt != nil && time.Now().Before(*job.ExpiresAt) {
+		expires := time.Now().Add(exportLinkTTL)
+		if job.ExpiresAt.Before(expires) {
+			expires = *job.ExpiresAt
+		}
+		job.DownloadURL = common.SignURL(exportDownloadPath(job.ID), expires)
+	}
+	return job, nil
+}
+
+// İmzalı bağlantıyla istenen dosyayı açar; bağlantı oturum gerektirmez
+func OpenExportDownload(db *sql.DB, id int, q url.Values) (ExportJob, *os.File, error) {
+	if err := common.VerifySignedURL(exportDownloadPath(id), q); err != nil {
+		return ExportJob{}, nil, err
+	}
+	job, err := getExportRepo(db, id)
+	if err == sql.ErrNoRows {
+		return ExportJob{}, nil, ErrExportNotFound
+	}
+	if err != nil {
+		return ExportJob{}, nil, err
+	}
+	if job.Status != ExportCompleted || job.ExpiresAt == nil || time.Now().After(*job.ExpiresAt) {
+		return ExportJob{}, nil, ErrExportExpired
+	}
+	f, err := os.Open(job.FilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ExportJob{}, nil, ErrExportExpired
+	}
+	return job, f, err
+}
+
+// Saklama süresi dolan dışa aktarma dosyalarını interval aralıklarla siler
+func RunExportCleanup(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			paths, err := expireExportsRepo(db)
+			if err != nil {
+				log.Printf("Dışa aktarma temizliği hatası: %v", err)
+				continue
+			}
+			for _, p := range paths {
+				if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+					log.Printf("Dışa aktarma dosyası silinemedi: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Sunucu başlarken önceki süreçte yarıda kalan dışa aktarmaları başarısız işaretler
+func FailInterruptedExports(db *sql.DB) {
+	n, err := failInterruptedExportsRepo(db)
+	if err != nil {
+		log.Printf("Yarıda kalan dışa aktarmalar işaretlenemedi: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("%d dışa aktarma yarıda kaldığı için başarısız işaretlendi", n)
+	}
+}
+
+// --- Validasyon Fonksiyonları ---
+func validateCustomer(c Customer, defs []FieldDefinition) error {
+	if utf8.RuneCountInString(c.Name) < 2 {
+		return errors.New("İsim en az 2 karakter olmalı")
+	}
+	if c.Email != "" && !strings.Contains(c.Email, "@") {
+		return errors.New("Geçersiz e-posta adresi")
+	}
+	if c.Phone != "" && len(c.Phone) < 7 {
+		return errors.New("Telefon numarası çok kısa")
+	}
+	return ValidateCustomFields(defs, c.CustomFields)
+}
+
+// Özel alan değerlerini tenant'ın tanımlarına göre doğrular
+func ValidateCustomFields(defs []FieldDefinition, fields map[string]interface{}) error {
+	known := map[string]bool{}
+	for _, d := range defs {
+		known[d.Key] = true
+		v, ok := fields[d.Key]
+		if !ok || v == nil {
+			if d.Required {
+				return fmt.Errorf("%s alanı zorunlu", d.Label)
+			}
+			continue
+		}
+		if err := validateFieldValue(d, v); err != nil {
+			return err
+		}
+	}
+	for k := range fields {
+		if !known[k] {
+			return fmt.Errorf("Bilinmeyen özel alan: %s", k)
+		}
+	}
+	return nil
+}
+
+// JSON'dan çözülen değerin alan tipine uygunluğu
+func validateFieldValue(d FieldDefinition, v interface{}) error {
+	switch d.Type {
+	case FieldText:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s metin olmalı", d.Label)
+		}
+		if d.Required && strings.TrimSpace(s) == "" {
+			return fmt.Errorf("%s alanı zorunlu", d.Label)
+		}
+		if utf8.RuneCountInString(s) > 1000 {
+			return fmt.Errorf("%s en fazla 1000 karakter olabilir", d.Label)
+		}
+	case FieldNumber:
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s sayı olmalı", d.Label)
+		}
+	case FieldDate:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s YYYY-MM-DD biçiminde tarih olmalı", d.Label)
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return fmt.Errorf("%s YYYY-MM-DD biçiminde tarih olmalı", d.Label)
+		}
+	case FieldEnum:
+		s, ok := v.(string)
+		if !ok || !containsString(d.Options, s) {
+			return fmt.Errorf("%s için geçersiz seçenek", d.Label)
+		}
+	case FieldMultiSelect:
+		var values []string
+		switch vs := v.(type) {
+		case []string:
+			values = vs
+		case []interface{}:
+			for _, x := range vs {
+				s, ok := x.(string)
+				if !ok {
+					return fmt.Errorf("%s için geçersiz seçenek", d.Label)
+				}
+				values = append(values, s)
+			}
+		default:
+			return fmt.Errorf("%s seçenek listesi olmalı", d.Label)
+		}
+		if d.Required && len(values) == 0 {
+			return fmt.Errorf("%s alanı zorunlu", d.Label)
+		}
+		for _, s := range values {
+			if !containsString(d.Options, s) {
+				return fmt.Errorf("%s için geçersiz seçenek: %s", d.Label, s)
+			}
+		}
+	default:
+		return fmt.Errorf("Geçersiz alan tipi: %s", d.Type)
+	}
+	return nil
+}
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+func ValidateFieldDefinition(d FieldDefinition) error {
+	if !fieldKeyPattern.MatchString(d.Key) {
+		return errors.New("Alan anahtarı küçük harfle başlamalı, sadece a-z, 0-9 ve _ içermeli (en fazla 40 karakter)")
+	}
+	if utf8.RuneCountInString(strings.TrimSpace(d.Label)) < 2 {
+		return errors.New("Alan etiketi en az 2 karakter olmalı")
+	}
+	switch d.Type {
+	case FieldEnum, FieldMultiSelect:
+		if len(d.Options) == 0 {
+			return errors.New("Seçenekli alanlar için en az bir seçenek gerekli")
+		}
+		seen := map[string]bool{}
+		for _, o := range d.Options {
+			if strings.TrimSpace(o) == "" || seen[o] {
+				return errors.New("Seçenekler boş veya tekrarlı olamaz")
+			}
+			seen[o] = true
+		}
+	case FieldText, FieldNumber, FieldDate:
+		if len(d.Options) > 0 {
+			return errors.New("Seçenekler sadece enum ve multi_select alanlar için verilebilir")
+		}
+	default:
+		return fmt.Errorf("Geçersiz alan tipi: %s", d.Type)
+	}
+	if d.Default != nil {
+		if err := validateFieldValue(d, d.Default); err != nil {
+			return fmt.Errorf("Geçersiz varsayılan değer: %v", err)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateAssignmentRule(r AssignmentRule) error {
+	if utf8.RuneCountInString(strings.TrimSpace(r.Name)) < 2 {
+		return errors.New("Kural adı en az 2 karakter olmalı")
+	}
+	switch r.Type {
+	case RuleRoundRobin:
+		if r.MatchValue != "" {
+			return errors.New("Round-robin kuralı eşleşme değeri almaz")
+		}
+	case RuleRegion, RulePostalCode, RuleLeadSource:
+		if strings.TrimSpace(r.MatchValue) == "" {
+			return errors.New("Eşleşme değeri zorunlu")
+		}
+	default:
+		return fmt.Errorf("Geçersiz kural tipi: %s", r.Type)
+	}
+	if len(r.AssigneeIDs) == 0 {
+		return errors.New("En az bir atanacak kullanıcı gerekli")
+	}
+	for _, id := range r.AssigneeIDs {
+		if id <= 0 {
+			return errors.New("Geçersiz atanacak kullanıcı ID")
+		}
+	}
+	return nil
+}
+
+// Tipe göre izin verilen sonuç (outcome) değerleri
+var contactOutcomes = map[string][]string{
+	ContactTypeCall:    {"connected", "no_answer", "voicemail", "busy", "wrong_number"},
+	ContactTypeMeeting: {"held", "no_show", "rescheduled", "cancelled"},
+}
+
+func isContactType(t string) bool {
+	switch t {
+	case ContactTypeNote, ContactTypeCall, ContactTypeEmail, ContactTypeMeeting:
+		return true
+	}
+	return false
+}
+
+func validateContact(c Contact) error {
+	if c.CustomerID <= 0 || utf8.RuneCountInString(c.Content) < 3 {
+		return errors.New("Geçersiz iletişim kaydı")
+	}
+	if !isContactType(c.Type) {
+		return errors.New("Geçersiz iletişim tipi")
+	}
+	if c.OccurredAt.After(time.Now().Add(time.Minute)) {
+		return errors.New("Etkileşim zamanı gelecekte olamaz")
+	}
+	if c.DurationSeconds < 0 {
+		return errors.New("Süre negatif olamaz")
+	}
+
+	// Yön: arama ve e-postada zorunlu, not ve toplantıda verilemez
+	switch c.Type {
+	case ContactTypeCall, ContactTypeEmail:
+		if c.Direction != DirectionInbound && c.Direction != DirectionOutbound {
+			return errors.New("Arama ve e-posta için yön inbound veya outbound olmalı")
+		}
+	default:
+		if c.Direction != "" {
+			return errors.New("Yön sadece arama ve e-posta için verilebilir")
+		}
+	}
+
+	// Süre sadece arama ve toplantıda anlamlı
+	if c.DurationSeconds > 0 && c.Type != ContactTypeCall && c.Type != ContactTypeMeeting {
+		return errors.New("Süre sadece arama ve toplantı için verilebilir")
+	}
+
+	if c.Outcome != "" {
+		allowed, ok := contactOutcomes[c.Type]
+		if !ok {
+			return errors.New("Sonuç sadece arama ve toplantı için verilebilir")
+		}
+		valid := false
+		for _, o := range allowed {
+			if o == c.Outcome {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("Geçersiz sonuç: %s", c.Outcome)
+		}
+	}
+
+	if c.Type == ContactTypeMeeting && len(c.Participants) == 0 {
+		return errors.New("Toplantı için en az bir katılımcı gerekli")
+	}
+	for _, p := range c.Participants {
+		if strings.TrimSpace(p) == "" {
+			return errors.New("Katılımcı adı boş olamaz")
+		}
 	}
 	return nil
 }
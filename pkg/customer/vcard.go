@@ -0,0 +1,380 @@
+package customer
+
+import (
+	"Go-CRM/pkg/common"
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Desteklenen vCard sürümleri (RFC 2426 ve RFC 6350)
+const (
+	VCard3 = "3.0"
+	VCard4 = "4.0"
+)
+
+// Adres defterlerinde kaydı tanıyan UID; aynı müşteri her dışa aktarmada aynı kimliği taşır
+func vcardUID(id int) string {
+	return fmt.Sprintf("go-crm-customer-%d", id)
+}
+
+// Müşteriyi vCard'a çevirir. Bölge ve posta kodu iş adresine, etiketler CATEGORIES'e,
+// kaynak ve özel alanlar X-CRM-* özelliklerine yazılır. Satırlar CRLF ile biter.
+func MarshalVCard(c Customer, version string) string {
+	if version != VCard4 {
+		version = VCard3
+	}
+	var b strings.Builder
+	line := func(name, value string) {
+		common.WriteContentLine(&b, name+":"+value)
+	}
+	line("BEGIN", "VCARD")
+	line("VERSION", version)
+	if c.ID > 0 {
+		line("UID", vcardUID(c.ID))
+	}
+	line("FN", common.EscapeText(c.Name))
+	given, family := splitName(c.Name)
+	line("N", common.EscapeText(family)+";"+common.EscapeText(given)+";;;")
+	if c.Email != "" {
+		if version == VCard3 {
+			line("EMAIL;TYPE=INTERNET", common.EscapeText(c.Email))
+		} else {
+			line("EMAIL", common.EscapeText(c.Email))
+		}
+	}
+	if c.Phone != "" {
+		if version == VCard3 {
+			line("TEL;TYPE=CELL", common.EscapeText(c.Phone))
+		} else {
+			line("TEL;TYPE=cell", common.EscapeText(c.Phone))
+		}
+	}
+	if c.Region != "" || c.PostalCode != "" {
+		line("ADR;TYPE="+map[string]string{VCard3: "WORK", VCard4: "work"}[version],
+			";;;;"+common.EscapeText(c.Region)+";"+common.EscapeText(c.PostalCode)+";")
+	}
+	if len(c.Tags) > 0 {
+		tags := make([]string, len(c.Tags))
+		for i, t := range c.Tags {
+			tags[i] = common.EscapeText(t)
+		}
+		line("CATEGORIES", strings.Join(tags, ","))
+	}
+	if c.LeadSource != "" {
+		line("X-CRM-LEAD-SOURCE", common.EscapeText(c.LeadSource))
+	}
+	keys := make([]string, 0, len(c.CustomFields))
+	for k := range c.CustomFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v := exportFieldValue(c.CustomFields[k]); v != "" {
+			line("X-CRM-FIELD;X-KEY="+k, common.EscapeText(v))
+		}
+	}
+	line("END", "VCARD")
+	return b.String()
+}
+
+// "Ali Veli Yılmaz" -> ("Ali Veli", "Yılmaz"); tek kelimelik isim soyadı boş bırakır
+func splitName(name string) (given, family string) {
+	name = strings.TrimSpace(name)
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name, ""
+	}
+	return strings.TrimSpace(name[:i]), name[i+1:]
+}
+
+func unescapeVCard(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// vCard özelliği: GRUP.AD;PARAMETRELER:değer
+type vcardProp struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+func parseVCardLine(line string) (vcardProp, bool) {
+	// Değer tırnak içindeki parametrelerden sonra ilk ':' ile başlar
+	quoted, colon := false, -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return vcardProp{}, false
+	}
+	parts := strings.Split(line[:colon], ";")
+	name := strings.ToUpper(parts[0])
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	p := vcardProp{name: name, params: map[string][]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			// vCard 2.1 tarzı "TEL;CELL"
+			k, v = "TYPE", param
+		}
+		k = strings.ToUpper(k)
+		for _, x := range strings.Split(v, ",") {
+			p.params[k] = append(p.params[k], strings.Trim(x, `"`))
+		}
+	}
+	return p, true
+}
+
+func (p vcardProp) hasType(t string) bool {
+	for _, x := range p.params["TYPE"] {
+		if strings.EqualFold(x, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ; ile ayrılmış yapılı değeri (N, ADR) bileşenlerine ayırır
+func splitVCardValue(s string, sep byte) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			cur.WriteByte(s[i])
+			cur.WriteByte(s[i+1])
+			i++
+		case s[i] == sep:
+			parts = append(parts, unescapeVCard(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	return append(parts, unescapeVCard(cur.String()))
+}
+
+// Tek istekte içe aktarılabilecek kart sayısı ve gövde boyutu
+const (
+	maxVCardImport     = 1000
+	MaxVCardImportSize = 5 << 20
+)
+
+// Bir veya daha fazla vCard'ı (3.0/4.0) müşterilere çevirir. Özel alan değerleri metin olarak gelir;
+// tipine çevirmek çağırana kalır. Tanınmayan özellikler yok sayılır.
+func ParseVCards(r io.Reader) ([]Customer, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var (
+		lines []string
+		cards []Customer
+	)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		// Katlanmış satırlar boşluk veya sekmeyle devam eder
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		cur    *Customer
+		phones []vcardProp
+	)
+	for _, l := range lines {
+		p, ok := parseVCardLine(l)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCARD"):
+			cur, phones = &Customer{}, nil
+		case cur == nil:
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VCARD"):
+			cur.Phone = preferredPhone(phones)
+			cards = append(cards, *cur)
+			cur = nil
+			if len(cards) > maxVCardImport {
+				return nil, fmt.Errorf("Tek seferde en fazla %d kart içe aktarılabilir", maxVCardImport)
+			}
+		case p.name == "VERSION":
+			if p.value != VCard3 && p.value != VCard4 {
+				return nil, fmt.Errorf("Desteklenmeyen vCard sürümü: %s (3.0 veya 4.0 olmalı)", p.value)
+			}
+		case p.name == "FN":
+			cur.Name = strings.TrimSpace(unescapeVCard(p.value))
+		case p.name == "N":
+			if cur.Name == "" {
+				n := splitVCardValue(p.value, ';')
+				parts := []string{}
+				for _, i := range []int{3, 1, 2, 0} { // Önek, ad, ikinci ad, soyad
+					if i < len(n) && strings.TrimSpace(n[i]) != "" {
+						parts = append(parts, strings.TrimSpace(n[i]))
+					}
+				}
+				cur.Name = strings.Join(parts, " ")
+			}
+		case p.name == "EMAIL":
+			if cur.Email == "" || p.hasType("PREF") || p.params["PREF"] != nil {
+				cur.Email = strings.TrimSpace(unescapeVCard(p.value))
+			}
+		case p.name == "TEL":
+			phones = append(phones, p)
+		case p.name == "ADR":
+			if cur.Region == "" && cur.PostalCode == "" {
+				adr := splitVCardValue(p.value, ';')
+				if len(adr) > 4 {
+					cur.Region = strings.TrimSpace(adr[4])
+				}
+				if len(adr) > 5 {
+					cur.PostalCode = strings.TrimSpace(adr[5])
+				}
+			}
+		case p.name == "CATEGORIES":
+			for _, t := range splitVCardValue(p.value, ',') {
+				if t = strings.TrimSpace(t); t != "" {
+					cur.Tags = append(cur.Tags, t)
+				}
+			}
+		case p.name == "X-CRM-LEAD-SOURCE":
+			cur.LeadSource = unescapeVCard(p.value)
+		case p.name == "X-CRM-FIELD":
+			if keys := p.params["X-KEY"]; len(keys) > 0 && keys[0] != "" {
+				if cur.CustomFields == nil {
+					cur.CustomFields = map[string]interface{}{}
+				}
+				cur.CustomFields[keys[0]] = unescapeVCard(p.value)
+			}
+		}
+	}
+	if len(cards) == 0 {
+		return nil, errors.New("Dosyada vCard bulunamadı")
+	}
+	return cards, nil
+}
+
+// Cep telefonu varsa o, yoksa tercih edilen, yoksa ilk numara; "tel:" URI öneki atılır
+func preferredPhone(phones []vcardProp) string {
+	if len(phones) == 0 {
+		return ""
+	}
+	best := phones[0]
+	for _, p := range phones {
+		if p.hasType("CELL") {
+			best = p
+			break
+		}
+		if p.hasType("PREF") || p.params["PREF"] != nil {
+			best = p
+		}
+	}
+	v := strings.TrimPrefix(strings.TrimSpace(unescapeVCard(best.value)), "tel:")
+	return cleanImportPhone(v)
+}
+
+// vCard içe aktarma sonucu; Index dosyadaki kart sırasıdır (0'dan başlar)
+type VCardImportResult struct {
+	Created []int              `json:"created"`
+	Skipped []VCardImportIssue `json:"skipped"`
+	Errors  []VCardImportIssue `json:"errors"`
+}
+
+type VCardImportIssue struct {
+	Index      int    `json:"index"`
+	Name       string `json:"name,omitempty"`
+	CustomerID int    `json:"customer_id,omitempty"` // Atlananlarda e-postası eşleşen müşteri
+	Message    string `json:"message"`
+}
+
+// Kartları müşteri olarak ekler. E-postası tenant'ta kayıtlı kartlar atlanır;
+// özel alan değerleri tanımlarına göre çevrilir, tanımsız alanlar yok sayılır.
+func ImportVCards(db *sql.DB, user common.AuthUser, cards []Customer) (VCardImportResult, error) {
+	result := VCardImportResult{Created: []int{}, Skipped: []VCardImportIssue{}, Errors: []VCardImportIssue{}}
+	defs, err := getFieldDefinitionsRepo(db, tenantOf(user))
+	if err != nil {
+		return result, err
+	}
+	byKey := map[string]FieldDefinition{}
+	for _, d := range defs {
+		byKey[d.Key] = d
+	}
+	for i, c := range cards {
+		issue := VCardImportIssue{Index: i, Name: c.Name}
+		fields := map[string]interface{}{}
+		var fieldErr error
+		for k, v := range c.CustomFields {
+			d, ok := byKey[k]
+			if !ok {
+				continue
+			}
+			if fields[k], fieldErr = ParseImportValue(d, fmt.Sprint(v)); fieldErr != nil {
+				break
+			}
+		}
+		if fieldErr != nil {
+			issue.Message = fieldErr.Error()
+			result.Errors = append(result.Errors, issue)
+			continue
+		}
+		c.CustomFields, c.Tags, c.ID, c.OwnerID = fields, nil, 0, 0
+		if email := NormalizeEmail(c.Email); email != "" {
+			id, err := findCustomerByEmailRepo(db, tenantOf(user), email)
+			if err != nil {
+				return result, err
+			}
+			if id > 0 {
+				issue.CustomerID, issue.Message = id, "Bu e-posta ile kayıtlı müşteri var"
+				result.Skipped = append(result.Skipped, issue)
+				continue
+			}
+		}
+		if err := CreateCustomer(db, user, &c); err != nil {
+			issue.Message = err.Error()
+			result.Errors = append(result.Errors, issue)
+			continue
+		}
+		result.Created = append(result.Created, c.ID)
+	}
+	return result, nil
+}
+
+func vcardVersion(s string) (string, error) {
+	switch s {
+	case "", "3", VCard3:
+		return VCard3, nil
+	case "4", VCard4:
+		return VCard4, nil
+	}
+	return "", fmt.Errorf("Geçersiz vCard sürümü: %s (3.0 veya 4.0 olmalı)", s)
+}
@@ -0,0 +1,277 @@
+package deal
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler fonksiyonları sade tutulur, iş mantığı service katmanında
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Süreç listeleme (GET /api/pipelines)
+func (h *Handler) GetPipelinesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	pipelines, err := GetPipelines(h.DBReplica, user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Süreçler alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pipelines)
+}
+
+// Süreç ekleme (POST /api/pipelines)
+func (h *Handler) CreatePipelineHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var p Pipeline
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreatePipeline(h.DBPrimary, user, &p); err != nil {
+		writeDealError(w, "Süreç eklenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// Sürece aşama ekleme (POST /api/pipelines/{id}/stages)
+func (h *Handler) CreateStageHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	pipelineID, err := idFromPath(r.URL.Path, "/api/pipelines/", "/stages")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz süreç ID", err)
+		return
+	}
+	var s Stage
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	s.PipelineID = pipelineID
+	if err := CreateStage(h.DBPrimary, user, &s); err != nil {
+		writeDealError(w, "Aşama eklenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s)
+}
+
+// Fırsat listeleme (GET /api/deals?owner_id=1&customer_id=2&account_id=4&pipeline_id=1&stage_id=3&page=1&pageSize=10)
+func (h *Handler) GetDealsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	params := DealListParams{TenantID: tenantOf(user)}
+	params.OwnerID, _ = strconv.Atoi(q.Get("owner_id"))
+	params.CustomerID, _ = strconv.Atoi(q.Get("customer_id"))
+	params.AccountID, _ = strconv.Atoi(q.Get("account_id"))
+	params.PipelineID, _ = strconv.Atoi(q.Get("pipeline_id"))
+	params.StageID, _ = strconv.Atoi(q.Get("stage_id"))
+	params.Page, _ = strconv.Atoi(q.Get("page"))
+	params.PageSize, _ = strconv.Atoi(q.Get("pageSize"))
+
+	result, err := GetDeals(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Fırsat listesi alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Fırsat ekleme (POST /api/deals)
+func (h *Handler) CreateDealHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var d Deal
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateDeal(h.DBPrimary, user, &d); err != nil {
+		writeDealError(w, "Fırsat eklenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, d)
+}
+
+// Tek fırsat (GET /api/deals/{id})
+func (h *Handler) GetDealHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/deals/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz fırsat ID", err)
+		return
+	}
+	d, err := GetDeal(h.DBReplica, user, id)
+	if err != nil {
+		writeDealError(w, "Fırsat alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Fırsat güncelleme (PUT /api/deals/{id})
+func (h *Handler) UpdateDealHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/deals/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz fırsat ID", err)
+		return
+	}
+	var patch DealPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	d, err := UpdateDeal(h.DBPrimary, user, id, patch)
+	if err != nil {
+		writeDealError(w, "Fırsat güncellenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Aşama geçişi (POST /api/deals/{id}/stage, gövde: {"stage_id": 3})
+func (h *Handler) MoveDealHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/deals/", "/stage")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz fırsat ID", err)
+		return
+	}
+	var body struct {
+		StageID int `json:"stage_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.StageID <= 0 {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	d, err := MoveDealToStage(h.DBPrimary, user, id, body.StageID)
+	if err != nil {
+		writeDealError(w, "Aşama değiştirilemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Aşama geçmişi (GET /api/deals/{id}/history)
+func (h *Handler) GetStageHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/deals/", "/history")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz fırsat ID", err)
+		return
+	}
+	history, err := GetStageHistory(h.DBReplica, user, id)
+	if err != nil {
+		writeDealError(w, "Aşama geçmişi alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// Ağırlıklı tahmin (GET /api/deals/forecast?from=2025-01&to=2025-06&owner_id=3)
+// to ayı dahildir.
+func (h *Handler) GetForecastHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	params := ForecastParams{TenantID: tenantOf(user)}
+	from, err := time.Parse("2006-01", q.Get("from"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "from YYYY-MM biçiminde olmalı", err)
+		return
+	}
+	to, err := time.Parse("2006-01", q.Get("to"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "to YYYY-MM biçiminde olmalı", err)
+		return
+	}
+	params.From = from
+	params.To = to.AddDate(0, 1, 0)
+	params.OwnerID, _ = strconv.Atoi(q.Get("owner_id"))
+
+	forecast, err := GetForecast(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Tahmin hesaplanamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, forecast)
+}
+
+// Servis hatasını uygun HTTP durum koduna çevirir
+func writeDealError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrDealNotFound), errors.Is(err, ErrStageNotFound), errors.Is(err, ErrPipelineNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// /api/deals/12/stage gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
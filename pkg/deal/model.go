@@ -0,0 +1,96 @@
+package deal
+
+// Satış fırsatı (deal) veri modelleri
+// Veritabanı ve API için ortak kullanılacak
+
+// Satış süreci (ör. "Yeni satış", "Yenileme")
+type Pipeline struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	IsDefault bool    `json:"is_default"`
+	Stages    []Stage `json:"stages"`
+}
+
+// Süreç aşaması. Probability, bu aşamadaki fırsatlar için varsayılan kazanma olasılığıdır (0-100).
+type Stage struct {
+	ID          int    `json:"id"`
+	PipelineID  int    `json:"pipeline_id"`
+	Name        string `json:"name"`
+	Position    int    `json:"position"`
+	Probability int    `json:"probability"`
+	IsWon       bool   `json:"is_won"`
+	IsLost      bool   `json:"is_lost"`
+}
+
+type Deal struct {
+	ID                int     `json:"id"`
+	CustomerID        int     `json:"customer_id"`
+	PipelineID        int     `json:"pipeline_id"`
+	StageID           int     `json:"stage_id"`
+	Title             string  `json:"title"`
+	Amount            float64 `json:"amount"`
+	Currency          string  `json:"currency"`            // ISO 4217, ör. TRY
+	ExpectedCloseDate string  `json:"expected_close_date"` // YYYY-MM-DD
+	OwnerID           int     `json:"owner_id"`
+	Probability       int     `json:"probability"` // 0 verilirse aşamanın olasılığı kullanılır
+	CreatedAt         string  `json:"created_at"`
+	UpdatedAt         string  `json:"updated_at"`
+	TenantID          int     `json:"-"`
+}
+
+// Kısmi fırsat güncellemesi (PUT /api/deals/{id}); nil alanlar mevcut değerini korur.
+// Sıfır değerler de geçerlidir: probability 0 yapılabilir, expected_close_date "" ile temizlenir.
+type DealPatch struct {
+	CustomerID        *int     `json:"customer_id"`
+	Title             *string  `json:"title"`
+	Amount            *float64 `json:"amount"`
+	Currency          *string  `json:"currency"`
+	ExpectedCloseDate *string  `json:"expected_close_date"`
+	OwnerID           *int     `json:"owner_id"`
+	Probability       *int     `json:"probability"`
+}
+
+// Gelen alanları fırsata uygular; süreç ve aşama sadece aşama geçişiyle değişir
+func (p DealPatch) Apply(d *Deal) {
+	if p.CustomerID != nil {
+		d.CustomerID = *p.CustomerID
+	}
+	if p.Title != nil {
+		d.Title = *p.Title
+	}
+	if p.Amount != nil {
+		d.Amount = *p.Amount
+	}
+	if p.Currency != nil {
+		d.Currency = *p.Currency
+	}
+	if p.ExpectedCloseDate != nil {
+		d.ExpectedCloseDate = *p.ExpectedCloseDate
+	}
+	if p.OwnerID != nil {
+		d.OwnerID = *p.OwnerID
+	}
+	if p.Probability != nil {
+		d.Probability = *p.Probability
+	}
+}
+
+// Aşama değişikliği geçmişi
+type StageChange struct {
+	ID          int    `json:"id"`
+	DealID      int    `json:"deal_id"`
+	FromStageID int    `json:"from_stage_id,omitempty"`
+	ToStageID   int    `json:"to_stage_id"`
+	ChangedBy   int    `json:"changed_by,omitempty"`
+	ChangedAt   string `json:"changed_at"`
+}
+
+// Sorumlu ve ay bazında ağırlıklı tahmin satırı
+type ForecastRow struct {
+	OwnerID        int     `json:"owner_id"`
+	Month          string  `json:"month"` // YYYY-MM
+	Currency       string  `json:"currency"`
+	DealCount      int     `json:"deal_count"`
+	TotalAmount    float64 `json:"total_amount"`
+	WeightedAmount float64 `json:"weighted_amount"`
+}
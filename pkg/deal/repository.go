@@ -0,0 +1,360 @@
+package deal
+
+import (
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dealColumns = "id, customer_id, pipeline_id, stage_id, title, amount, currency, expected_close_date, owner_id, probability, created_at, updated_at, tenant_id"
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDeal(row scanner, d *Deal) error {
+	var closeDate, createdAt, updatedAt sql.NullTime
+	var ownerID sql.NullInt64
+	if err := row.Scan(&d.ID, &d.CustomerID, &d.PipelineID, &d.StageID, &d.Title, &d.Amount, &d.Currency,
+		&closeDate, &ownerID, &d.Probability, &createdAt, &updatedAt, &d.TenantID); err != nil {
+		return err
+	}
+	d.OwnerID = int(ownerID.Int64)
+	if closeDate.Valid {
+		d.ExpectedCloseDate = closeDate.Time.Format("2006-01-02")
+	}
+	if createdAt.Valid {
+		d.CreatedAt = createdAt.Time.Format("02-01-2006 15:04")
+	}
+	if updatedAt.Valid {
+		d.UpdatedAt = updatedAt.Time.Format("02-01-2006 15:04")
+	}
+	return nil
+}
+
+// Tenant'ın süreçlerini aşamalarıyla birlikte getirir
+func getPipelinesRepo(db *sql.DB, tenantID int) ([]Pipeline, error) {
+	rows, err := db.Query(`
+		SELECT p.id, p.name, p.is_default, s.id, s.name, s.position, s.probability, s.is_won, s.is_lost
+		FROM pipelines p LEFT JOIN pipeline_stages s ON s.pipeline_id = p.id
+		WHERE p.tenant_id = $1
+		ORDER BY p.id, s.position`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pipelines := []Pipeline{}
+	for rows.Next() {
+		var p Pipeline
+		var stageID, position, probability sql.NullInt64
+		var stageName sql.NullString
+		var isWon, isLost sql.NullBool
+		if err := rows.Scan(&p.ID, &p.Name, &p.IsDefault, &stageID, &stageName, &position, &probability, &isWon, &isLost); err != nil {
+			return nil, err
+		}
+		if n := len(pipelines); n == 0 || pipelines[n-1].ID != p.ID {
+			p.Stages = []Stage{}
+			pipelines = append(pipelines, p)
+		}
+		if stageID.Valid {
+			last := &pipelines[len(pipelines)-1]
+			last.Stages = append(last.Stages, Stage{
+				ID:          int(stageID.Int64),
+				PipelineID:  p.ID,
+				Name:        stageName.String,
+				Position:    int(position.Int64),
+				Probability: int(probability.Int64),
+				IsWon:       isWon.Bool,
+				IsLost:      isLost.Bool,
+			})
+		}
+	}
+	return pipelines, rows.Err()
+}
+
+// Süreci aşamalarıyla birlikte tek transaction'da ekler
+func createPipelineRepo(db *sql.DB, tenantID int, p *Pipeline) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("INSERT INTO pipelines (name, tenant_id) VALUES ($1, $2) RETURNING id", p.Name, tenantID).Scan(&p.ID); err != nil {
+		return err
+	}
+	for i := range p.Stages {
+		p.Stages[i].PipelineID = p.ID
+		if err := insertStage(tx, &p.Stages[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func pipelineExistsRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	var found bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pipelines WHERE id = $1 AND tenant_id = $2)", id, tenantID).Scan(&found)
+	return found, err
+}
+
+// Tenant'ın hiç süreci yoksa varsayılan satış sürecini aşamalarıyla ekler
+func ensureDefaultPipelineRepo(db *sql.DB, tenantID int) error {
+	_, err := db.Exec(`
+		WITH p AS (
+			INSERT INTO pipelines (name, is_default, tenant_id)
+			SELECT 'Satış', TRUE, $1 WHERE NOT EXISTS (SELECT 1 FROM pipelines WHERE tenant_id = $1)
+			RETURNING id
+		)
+		INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost)
+		SELECT p.id, s.name, s.position, s.probability, s.is_won, s.is_lost FROM p, (VALUES
+			('Yeni', 1, 10, FALSE, FALSE),
+			('Nitelikli', 2, 25, FALSE, FALSE),
+			('Teklif', 3, 50, FALSE, FALSE),
+			('Müzakere', 4, 75, FALSE, FALSE),
+			('Kazanıldı', 5, 100, TRUE, FALSE),
+			('Kaybedildi', 6, 0, FALSE, TRUE)
+		) AS s(name, position, probability, is_won, is_lost)`, tenantID)
+	return err
+}
+
+// Mevcut sürecin sonuna yeni aşama ekler
+func createStageRepo(db *sql.DB, s *Stage) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT COALESCE(MAX(position), 0) + 1 FROM pipeline_stages WHERE pipeline_id = $1", s.PipelineID).Scan(&s.Position); err != nil {
+		return err
+	}
+	if err := insertStage(tx, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertStage(tx *sql.Tx, s *Stage) error {
+	return tx.QueryRow(
+		"INSERT INTO pipeline_stages (pipeline_id, name, position, probability, is_won, is_lost) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		s.PipelineID, s.Name, s.Position, s.Probability, s.IsWon, s.IsLost,
+	).Scan(&s.ID)
+}
+
+func getStageRepo(db *sql.DB, tenantID, id int) (Stage, error) {
+	var s Stage
+	err := db.QueryRow(`
+		SELECT s.id, s.pipeline_id, s.name, s.position, s.probability, s.is_won, s.is_lost
+		FROM pipeline_stages s JOIN pipelines p ON p.id = s.pipeline_id
+		WHERE s.id = $1 AND p.tenant_id = $2`, id, tenantID,
+	).Scan(&s.ID, &s.PipelineID, &s.Name, &s.Position, &s.Probability, &s.IsWon, &s.IsLost)
+	return s, err
+}
+
+// Tenant'ın varsayılan sürecinin ilk aşaması
+func getDefaultStageRepo(db *sql.DB, tenantID int) (Stage, error) {
+	var s Stage
+	err := db.QueryRow(`
+		SELECT s.id, s.pipeline_id, s.name, s.position, s.probability, s.is_won, s.is_lost
+		FROM pipeline_stages s JOIN pipelines p ON p.id = s.pipeline_id
+		WHERE p.is_default AND p.tenant_id = $1 ORDER BY s.position LIMIT 1`, tenantID,
+	).Scan(&s.ID, &s.PipelineID, &s.Name, &s.Position, &s.Probability, &s.IsWon, &s.IsLost)
+	return s, err
+}
+
+// Yeni fırsat ve ilk aşama geçmişi kaydını ekler
+func createDealRepo(db *sql.DB, d *Deal, changedBy int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(`
+		INSERT INTO deals (customer_id, pipeline_id, stage_id, title, amount, currency, expected_close_date, owner_id, probability, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::date, NULLIF($8, 0), $9, $10)
+		RETURNING `+dealColumns,
+		d.CustomerID, d.PipelineID, d.StageID, d.Title, d.Amount, d.Currency, d.ExpectedCloseDate, d.OwnerID, d.Probability, d.TenantID,
+	)
+	if err := scanDeal(row, d); err != nil {
+		return err
+	}
+	if err := insertStageChange(tx, d.ID, 0, d.StageID, changedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func getDealRepo(db *sql.DB, tenantID, id int) (Deal, error) {
+	var d Deal
+	err := scanDeal(db.QueryRow("SELECT "+dealColumns+" FROM deals WHERE id = $1 AND tenant_id = $2", id, tenantID), &d)
+	return d, err
+}
+
+// Müşterinin tenant'ı (fırsatın bağlanacağı müşteri kontrolü için)
+func customerTenantRepo(db *sql.DB, id int) (int, error) {
+	var tenantID int
+	err := db.QueryRow("SELECT tenant_id FROM customers WHERE id = $1", id).Scan(&tenantID)
+	return tenantID, err
+}
+
+// Fırsatın aşama dışındaki alanlarını günceller
+func updateDealRepo(db *sql.DB, d *Deal) error {
+	row := db.QueryRow(`
+		UPDATE deals SET customer_id = $1, title = $2, amount = $3, currency = $4,
+			expected_close_date = NULLIF($5, '')::date, owner_id = NULLIF($6, 0), probability = $7, updated_at = now()
+		WHERE id = $8 AND tenant_id = $9 RETURNING `+dealColumns,
+		d.CustomerID, d.Title, d.Amount, d.Currency, d.ExpectedCloseDate, d.OwnerID, d.Probability, d.ID, d.TenantID,
+	)
+	return scanDeal(row, d)
+}
+
+// Fırsatı yeni aşamaya taşır ve geçişi kaydeder
+func moveDealRepo(db *sql.DB, d *Deal, to Stage, changedBy int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var from int
+	if err := tx.QueryRow("SELECT stage_id FROM deals WHERE id = $1 AND tenant_id = $2 FOR UPDATE", d.ID, d.TenantID).Scan(&from); err != nil {
+		return err
+	}
+	row := tx.QueryRow(
+		"UPDATE deals SET stage_id = $1, probability = $2, updated_at = now() WHERE id = $3 AND tenant_id = $4 RETURNING "+dealColumns,
+		to.ID, to.Probability, d.ID, d.TenantID,
+	)
+	if err := scanDeal(row, d); err != nil {
+		return err
+	}
+	if err := insertStageChange(tx, d.ID, from, to.ID, changedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertStageChange(tx *sql.Tx, dealID, from, to, changedBy int) error {
+	_, err := tx.Exec(
+		"INSERT INTO deal_stage_history (deal_id, from_stage_id, to_stage_id, changed_by) VALUES ($1, NULLIF($2, 0), $3, NULLIF($4, 0))",
+		dealID, from, to, changedBy,
+	)
+	return err
+}
+
+func getStageHistoryRepo(db *sql.DB, dealID int) ([]StageChange, error) {
+	rows, err := db.Query(
+		"SELECT id, deal_id, from_stage_id, to_stage_id, changed_by, changed_at FROM deal_stage_history WHERE deal_id = $1 ORDER BY changed_at, id",
+		dealID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []StageChange{}
+	for rows.Next() {
+		var c StageChange
+		var from, changedBy sql.NullInt64
+		var changedAt time.Time
+		if err := rows.Scan(&c.ID, &c.DealID, &from, &c.ToStageID, &changedBy, &changedAt); err != nil {
+			return nil, err
+		}
+		c.FromStageID = int(from.Int64)
+		c.ChangedBy = int(changedBy.Int64)
+		c.ChangedAt = changedAt.Format("02-01-2006 15:04")
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+// Filtreli fırsat listesi (pagination)
+func getDealsRepo(db *sql.DB, params DealListParams) (DealListResult, error) {
+	var (
+		args  []interface{}
+		where []string
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	tenant := arg(params.TenantID)
+	where = append(where, "tenant_id = "+tenant)
+	if params.OwnerID > 0 {
+		where = append(where, "owner_id = "+arg(params.OwnerID))
+	}
+	if params.CustomerID > 0 {
+		where = append(where, "customer_id = "+arg(params.CustomerID))
+	}
+	if params.AccountID > 0 {
+		where = append(where, "customer_id IN ("+customer.AccountPeopleSubquery(arg(params.AccountID), tenant)+")")
+	}
+	if params.PipelineID > 0 {
+		where = append(where, "pipeline_id = "+arg(params.PipelineID))
+	}
+	if params.StageID > 0 {
+		where = append(where, "stage_id = "+arg(params.StageID))
+	}
+	filter := " WHERE " + strings.Join(where, " AND ")
+
+	// Toplam kayıt sayısı
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM deals"+filter, args...).Scan(&total); err != nil {
+		return DealListResult{}, err
+	}
+
+	query := "SELECT " + dealColumns + " FROM deals" + filter + " ORDER BY id DESC LIMIT " + arg(params.PageSize) + " OFFSET " + arg((params.Page-1)*params.PageSize)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return DealListResult{}, err
+	}
+	defer rows.Close()
+
+	deals := []Deal{}
+	for rows.Next() {
+		var d Deal
+		if err := scanDeal(rows, &d); err != nil {
+			return DealListResult{}, err
+		}
+		deals = append(deals, d)
+	}
+	return DealListResult{Deals: deals, Total: total, Page: params.Page, PageSize: params.PageSize}, rows.Err()
+}
+
+// Sorumlu, ay ve para birimi bazında ağırlıklı tahmin.
+// Kaybedilen fırsatlar hariç tutulur, kazanılanlar %100 olasılıkla sayılır.
+func getForecastRepo(db *sql.DB, params ForecastParams) ([]ForecastRow, error) {
+	args := []interface{}{params.From, params.To, params.TenantID}
+	query := `
+		SELECT COALESCE(d.owner_id, 0), to_char(date_trunc('month', d.expected_close_date), 'YYYY-MM'), d.currency,
+			COUNT(*), SUM(d.amount),
+			SUM(d.amount * CASE WHEN s.is_won THEN 100 ELSE d.probability END / 100.0)
+		FROM deals d JOIN pipeline_stages s ON s.id = d.stage_id
+		WHERE NOT s.is_lost AND d.expected_close_date >= $1 AND d.expected_close_date < $2 AND d.tenant_id = $3`
+	if params.OwnerID > 0 {
+		args = append(args, params.OwnerID)
+		query += " AND d.owner_id = $4"
+	}
+	query += " GROUP BY 1, 2, 3 ORDER BY 2, 1, 3"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	forecast := []ForecastRow{}
+	for rows.Next() {
+		var f ForecastRow
+		if err := rows.Scan(&f.OwnerID, &f.Month, &f.Currency, &f.DealCount, &f.TotalAmount, &f.WeightedAmount); err != nil {
+			return nil, err
+		}
+		forecast = append(forecast, f)
+	}
+	return forecast, rows.Err()
+}
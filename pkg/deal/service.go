@@ -0,0 +1,307 @@
+package deal
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrDealNotFound     = errors.New("Fırsat bulunamadı")
+	ErrStageNotFound    = errors.New("Aşama bulunamadı")
+	ErrPipelineNotFound = errors.New("Süreç bulunamadı")
+	ErrForbidden        = errors.New("Bu işlem için yetkiniz yok")
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Pagination ve filtreleme için parametreler
+type DealListParams struct {
+	TenantID   int
+	OwnerID    int
+	CustomerID int
+	AccountID  int // Hesap ve alt hesaplarındaki kişilerin fırsatları
+	PipelineID int
+	StageID    int
+	Page       int
+	PageSize   int
+}
+
+type DealListResult struct {
+	Deals    []Deal
+	Total    int
+	Page     int
+	PageSize int
+}
+
+// Tahmin aralığı (beklenen kapanış tarihine göre, From dahil To hariç)
+type ForecastParams struct {
+	From     time.Time
+	To       time.Time
+	OwnerID  int
+	TenantID int
+}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// Oturum kullanıcısının tenant'ındaki süreçleri aşamalarıyla listeler
+func GetPipelines(db *sql.DB, user common.AuthUser) ([]Pipeline, error) {
+	return getPipelinesRepo(db, tenantOf(user))
+}
+
+// Yeni süreç oluşturur (sadece yönetici)
+func CreatePipeline(db *sql.DB, user common.AuthUser, p *Pipeline) error {
+	if !user.IsManager() {
+		return ErrForbidden
+	}
+	if utf8.RuneCountInString(p.Name) < 2 {
+		return errors.New("Süreç adı en az 2 karakter olmalı")
+	}
+	if len(p.Stages) == 0 {
+		return errors.New("Süreç en az bir aşama içermeli")
+	}
+	for i := range p.Stages {
+		p.Stages[i].Position = i + 1
+		if err := validateStage(p.Stages[i]); err != nil {
+			return err
+		}
+	}
+	return createPipelineRepo(db, tenantOf(user), p)
+}
+
+// Sürecin sonuna aşama ekler (sadece yönetici)
+func CreateStage(db *sql.DB, user common.AuthUser, s *Stage) error {
+	if !user.IsManager() {
+		return ErrForbidden
+	}
+	if s.PipelineID <= 0 {
+		return errors.New("Geçersiz süreç ID")
+	}
+	if err := validateStage(*s); err != nil {
+		return err
+	}
+	found, err := pipelineExistsRepo(db, tenantOf(user), s.PipelineID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrPipelineNotFound
+	}
+	return createStageRepo(db, s)
+}
+
+// Fırsat listeleme (pagination + filtreleme)
+func GetDeals(db *sql.DB, params DealListParams) (DealListResult, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+	return getDealsRepo(db, params)
+}
+
+// Oturum kullanıcısının tenant'ındaki fırsat (başka tenant'ın fırsatı bulunamadı sayılır)
+func GetDeal(db *sql.DB, user common.AuthUser, id int) (Deal, error) {
+	d, err := getDealRepo(db, tenantOf(user), id)
+	if err == sql.ErrNoRows {
+		return Deal{}, ErrDealNotFound
+	}
+	return d, err
+}
+
+// Fırsat ekleme. Süreç/aşama verilmezse varsayılan sürecin ilk aşaması,
+// sorumlu verilmezse oturum kullanıcısı, olasılık verilmezse aşamanın olasılığı kullanılır.
+func CreateDeal(db *sql.DB, user common.AuthUser, d *Deal) error {
+	d.TenantID = tenantOf(user)
+	var stage Stage
+	var err error
+	if d.StageID > 0 {
+		stage, err = getStageRepo(db, d.TenantID, d.StageID)
+	} else {
+		// Yeni tenant'ın henüz süreci yoksa varsayılan süreç eklenir
+		if err := ensureDefaultPipelineRepo(db, d.TenantID); err != nil {
+			return err
+		}
+		stage, err = getDefaultStageRepo(db, d.TenantID)
+	}
+	if err == sql.ErrNoRows {
+		return ErrStageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if d.PipelineID > 0 && d.PipelineID != stage.PipelineID {
+		return errors.New("Aşama bu sürece ait değil")
+	}
+	d.PipelineID = stage.PipelineID
+	d.StageID = stage.ID
+	if d.OwnerID == 0 {
+		d.OwnerID = user.ID
+	}
+	if d.Probability == 0 {
+		d.Probability = stage.Probability
+	}
+	if d.Currency == "" {
+		d.Currency = "TRY"
+	}
+	if err := validateDeal(*d); err != nil {
+		return err
+	}
+	if err := checkCustomer(db, d.TenantID, d.CustomerID); err != nil {
+		return err
+	}
+	d.Amount = math.Round(d.Amount*100) / 100
+	if err := createDealRepo(db, d, user.ID); err != nil {
+		return err
+	}
+	publishDealEvent("deal.created", *d)
+	return nil
+}
+
+// Fırsatı günceller (aşama hariç), sadece gövdede gelen alanlar değişir. Sadece sorumlusu veya yönetici.
+func UpdateDeal(db *sql.DB, user common.AuthUser, id int, patch DealPatch) (Deal, error) {
+	d, err := GetDeal(db, user, id)
+	if err != nil {
+		return Deal{}, err
+	}
+	if d.OwnerID != user.ID && !user.IsManager() {
+		return Deal{}, ErrForbidden
+	}
+	patch.Apply(&d)
+	if err := validateDeal(d); err != nil {
+		return Deal{}, err
+	}
+	if patch.CustomerID != nil {
+		if err := checkCustomer(db, d.TenantID, d.CustomerID); err != nil {
+			return Deal{}, err
+		}
+	}
+	d.Amount = math.Round(d.Amount*100) / 100
+	if err := updateDealRepo(db, &d); err != nil {
+		return Deal{}, err
+	}
+	publishDealEvent("deal.updated", d)
+	return d, nil
+}
+
+// Fırsatı başka bir aşamaya taşır, olasılık yeni aşamanınkine çekilir ve geçiş kaydedilir
+func MoveDealToStage(db *sql.DB, user common.AuthUser, dealID, stageID int) (Deal, error) {
+	d, err := GetDeal(db, user, dealID)
+	if err != nil {
+		return Deal{}, err
+	}
+	if d.OwnerID != user.ID && !user.IsManager() {
+		return Deal{}, ErrForbidden
+	}
+	stage, err := getStageRepo(db, d.TenantID, stageID)
+	if err == sql.ErrNoRows {
+		return Deal{}, ErrStageNotFound
+	}
+	if err != nil {
+		return Deal{}, err
+	}
+	if stage.PipelineID != d.PipelineID {
+		return Deal{}, errors.New("Aşama fırsatın sürecine ait değil")
+	}
+	if stage.ID == d.StageID {
+		return d, nil
+	}
+	if err := moveDealRepo(db, &d, stage, user.ID); err != nil {
+		return Deal{}, err
+	}
+	event := "deal.stage_changed"
+	switch {
+	case stage.IsWon:
+		event = "deal.won"
+	case stage.IsLost:
+		event = "deal.lost"
+	}
+	publishDealEvent(event, d)
+	return d, nil
+}
+
+// Fırsatın aşama geçiş geçmişi
+func GetStageHistory(db *sql.DB, user common.AuthUser, dealID int) ([]StageChange, error) {
+	if dealID <= 0 {
+		return nil, errors.New("Geçersiz fırsat ID")
+	}
+	if _, err := GetDeal(db, user, dealID); err != nil {
+		return nil, err
+	}
+	return getStageHistoryRepo(db, dealID)
+}
+
+// Sorumlu ve ay bazında ağırlıklı tahmin
+func GetForecast(db *sql.DB, params ForecastParams) ([]ForecastRow, error) {
+	if params.From.IsZero() || params.To.IsZero() || !params.From.Before(params.To) {
+		return nil, errors.New("Geçerli bir tarih aralığı verilmeli")
+	}
+	if params.To.Sub(params.From) > 3*366*24*time.Hour {
+		return nil, errors.New("Tahmin aralığı en fazla 3 yıl olabilir")
+	}
+	return getForecastRepo(db, params)
+}
+
+// Fırsatın bağlanacağı müşteri aynı tenant'ta olmalı
+func checkCustomer(db *sql.DB, tenantID, customerID int) error {
+	customerTenant, err := customerTenantRepo(db, customerID)
+	if err == sql.ErrNoRows || (err == nil && customerTenant != tenantID) {
+		return customer.ErrCustomerNotFound
+	}
+	return err
+}
+
+// Fırsat değişikliklerini fırsatın tenant'ı ile Kafka'ya yayınlar
+func publishDealEvent(event string, d Deal) {
+	common.PublishDomainEvent(fmt.Sprintf("deal-%d", d.ID), event, d.TenantID, d)
+}
+
+// --- Validasyon Fonksiyonları ---
+func validateStage(s Stage) error {
+	if utf8.RuneCountInString(s.Name) < 2 {
+		return errors.New("Aşama adı en az 2 karakter olmalı")
+	}
+	if s.Probability < 0 || s.Probability > 100 {
+		return errors.New("Olasılık 0-100 arasında olmalı")
+	}
+	if s.IsWon && s.IsLost {
+		return errors.New("Aşama hem kazanıldı hem kaybedildi olamaz")
+	}
+	return nil
+}
+
+func validateDeal(d Deal) error {
+	if d.CustomerID <= 0 {
+		return errors.New("Geçersiz müşteri ID")
+	}
+	if utf8.RuneCountInString(d.Title) < 2 {
+		return errors.New("Başlık en az 2 karakter olmalı")
+	}
+	if d.Amount < 0 || math.IsNaN(d.Amount) || math.IsInf(d.Amount, 0) {
+		return errors.New("Tutar negatif olamaz")
+	}
+	if !currencyRe.MatchString(d.Currency) {
+		return errors.New("Para birimi 3 harfli ISO kodu olmalı (ör. TRY)")
+	}
+	if d.Probability < 0 || d.Probability > 100 {
+		return errors.New("Olasılık 0-100 arasında olmalı")
+	}
+	if d.ExpectedCloseDate != "" {
+		if _, err := time.Parse("2006-01-02", d.ExpectedCloseDate); err != nil {
+			return errors.New("Beklenen kapanış tarihi YYYY-MM-DD biçiminde olmalı")
+		}
+	}
+	return nil
+}
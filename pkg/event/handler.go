@@ -0,0 +1,235 @@
+package event
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler fonksiyonları sade tutulur, iş mantığı service katmanında
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Takvim (GET /api/events?from=2026-10-01T00:00:00Z&to=2026-11-01T00:00:00Z&customer_id=2&deal_id=3).
+// Varsayılan olarak oturum kullanıcısının etkinlikleri; yöneticiler user_id veya all=true verebilir.
+func (h *Handler) GetEventsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	params := EventListParams{TenantID: tenantOf(user), UserID: user.ID}
+	params.CustomerID, _ = strconv.Atoi(q.Get("customer_id"))
+	params.DealID, _ = strconv.Atoi(q.Get("deal_id"))
+	params.IncludeCancelled = q.Get("include_cancelled") == "true"
+	if userID, _ := strconv.Atoi(q.Get("user_id")); userID > 0 && userID != user.ID || q.Get("all") == "true" {
+		if !user.IsManager() {
+			common.WriteError(w, http.StatusForbidden, "Başka kullanıcıların takvimini sadece yöneticiler görebilir", nil)
+			return
+		}
+		params.UserID = userID
+	}
+	for name, dst := range map[string]*time.Time{"from": &params.From, "to": &params.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				common.WriteError(w, http.StatusBadRequest, name+" RFC3339 biçiminde olmalı", err)
+				return
+			}
+			*dst = t
+		}
+	}
+	occurrences, err := GetEvents(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Takvim alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, occurrences)
+}
+
+// Etkinlik ekleme (POST /api/events)
+func (h *Handler) CreateEventHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var e Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateEvent(h.DBPrimary, user, &e); err != nil {
+		writeEventError(w, "Etkinlik eklenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// Tek etkinlik (GET /api/events/{id})
+func (h *Handler) GetEventHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/events/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etkinlik ID", err)
+		return
+	}
+	e, err := GetEvent(h.DBReplica, user, id)
+	if err != nil {
+		writeEventError(w, "Etkinlik alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// Etkinliğin .ics dosyası (GET /api/events/{id}/ics)
+func (h *Handler) GetEventICSHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/events/", "/ics")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etkinlik ID", err)
+		return
+	}
+	e, err := GetEvent(h.DBReplica, user, id)
+	if err != nil {
+		writeEventError(w, "Etkinlik alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="event-`+strconv.Itoa(e.ID)+`.ics"`)
+	io.WriteString(w, MarshalCalendar([]Event{e}, "", ""))
+}
+
+// Etkinlik güncelleme (PUT /api/events/{id})
+func (h *Handler) UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/events/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etkinlik ID", err)
+		return
+	}
+	var e Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	e.ID = id
+	if err := UpdateEvent(h.DBPrimary, user, &e); err != nil {
+		writeEventError(w, "Etkinlik güncellenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// Etkinlik silme (DELETE /api/events/{id})
+func (h *Handler) DeleteEventHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/events/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etkinlik ID", err)
+		return
+	}
+	if err := DeleteEvent(h.DBPrimary, user, id); err != nil {
+		writeEventError(w, "Etkinlik silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Yeni gizli abonelik URL'si üretir (POST /api/events/feed); önceki URL geçersiz olur
+func (h *Handler) CreateFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	token, err := RotateFeedToken(h.DBPrimary, user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Takvim akışı oluşturulamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"url": FeedPath(token)})
+}
+
+// Abonelik URL'sini iptal eder (DELETE /api/events/feed)
+func (h *Handler) DeleteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	if err := RevokeFeedToken(h.DBPrimary, user); err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Takvim akışı silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// iCalendar abonelik akışı (GET /calendar/{token}.ics, JWT gerektirmez; anahtar URL'dedir)
+func (h *Handler) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/calendar/"), ".ics")
+	ics, err := CalendarFeed(h.DBReplica, token)
+	if err != nil {
+		writeEventError(w, "Takvim akışı alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	io.WriteString(w, ics)
+}
+
+// Servis hatasını uygun HTTP durum koduna çevirir
+func writeEventError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrEventNotFound), errors.Is(err, ErrFeedNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// /api/events/12 veya /api/events/12/ics gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix))
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
@@ -0,0 +1,107 @@
+package event
+
+import (
+	"Go-CRM/pkg/common"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) çıktısı: abonelik akışı ve e-posta davetleri (RFC 5546 iTIP)
+
+// iTIP yöntemleri; abonelik akışında yöntem yazılmaz
+const (
+	MethodRequest = "REQUEST"
+	MethodCancel  = "CANCEL"
+)
+
+const icalProdID = "-//Go-CRM//Takvim//TR"
+
+// Takvim istemcilerinin etkinliği tanıdığı UID; güncelleme ve iptal davetleri aynı UID'yi taşır
+func eventUID(id int) string {
+	return fmt.Sprintf("go-crm-event-%d@go-crm", id)
+}
+
+func icalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Etkinlikleri tek bir VCALENDAR'a yazar. method boşsa abonelik akışıdır, name takvim adı olarak gösterilir.
+func MarshalCalendar(events []Event, method, name string) string {
+	var b strings.Builder
+	line := func(s string) {
+		common.WriteContentLine(&b, s)
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + icalProdID)
+	line("CALSCALE:GREGORIAN")
+	if method != "" {
+		line("METHOD:" + method)
+	}
+	if name != "" {
+		line("X-WR-CALNAME:" + common.EscapeText(name))
+	}
+	for _, e := range events {
+		writeVEvent(line, e, method)
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+func writeVEvent(line func(string), e Event, method string) {
+	stamp := e.UpdatedAt
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	line("BEGIN:VEVENT")
+	line("UID:" + eventUID(e.ID))
+	line("DTSTAMP:" + icalTime(stamp))
+	if e.AllDay {
+		line("DTSTART;VALUE=DATE:" + e.StartAt.UTC().Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.EndAt.UTC().Format("20060102"))
+	} else {
+		line("DTSTART:" + icalTime(e.StartAt))
+		line("DTEND:" + icalTime(e.EndAt))
+	}
+	if e.RRule != "" {
+		line("RRULE:" + e.RRule)
+	}
+	line("SUMMARY:" + common.EscapeText(e.Title))
+	if e.Description != "" {
+		line("DESCRIPTION:" + common.EscapeText(e.Description))
+	}
+	if e.Location != "" {
+		line("LOCATION:" + common.EscapeText(e.Location))
+	}
+	status := e.Status
+	if method == MethodCancel {
+		status = StatusCancelled
+	}
+	line("STATUS:" + strings.ToUpper(status))
+	line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	if !e.CreatedAt.IsZero() {
+		line("CREATED:" + icalTime(e.CreatedAt))
+		line("LAST-MODIFIED:" + icalTime(stamp))
+	}
+	if e.OrganizerEmail != "" {
+		line("ORGANIZER:mailto:" + e.OrganizerEmail)
+	}
+	for _, a := range e.Attendees {
+		params := "ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=" + strings.ToUpper(a.Status)
+		if a.Status == AttendeeNeedsAction {
+			params += ";RSVP=TRUE"
+		}
+		if a.Name != "" {
+			params += `;CN="` + strings.NewReplacer(`"`, "'", "\n", " ").Replace(a.Name) + `"`
+		}
+		line(params + ":mailto:" + a.Email)
+	}
+	if e.CustomerID > 0 {
+		line(fmt.Sprintf("X-CRM-CUSTOMER-ID:%d", e.CustomerID))
+	}
+	if e.DealID > 0 {
+		line(fmt.Sprintf("X-CRM-DEAL-ID:%d", e.DealID))
+	}
+	line("END:VEVENT")
+}
@@ -0,0 +1,56 @@
+package event
+
+import "time"
+
+// Etkinlik durumları (iCalendar STATUS karşılıkları)
+const (
+	StatusConfirmed = "confirmed"
+	StatusTentative = "tentative"
+	StatusCancelled = "cancelled"
+)
+
+// Katılımcı yanıtları (iCalendar PARTSTAT karşılıkları)
+const (
+	AttendeeNeedsAction = "needs-action"
+	AttendeeAccepted    = "accepted"
+	AttendeeDeclined    = "declined"
+	AttendeeTentative   = "tentative"
+)
+
+// Müşteri veya fırsatla ilişkili toplantı/randevu
+type Event struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	AllDay      bool      `json:"all_day"` // Tüm gün etkinliklerinde saatler yok sayılır, end_at hariçtir
+	// RFC 5545 tekrar kuralı, ör. "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10" (boşsa tek seferlik)
+	RRule          string     `json:"rrule,omitempty"`
+	CustomerID     int        `json:"customer_id,omitempty"`
+	DealID         int        `json:"deal_id,omitempty"`
+	OrganizerID    int        `json:"organizer_id"`
+	OrganizerEmail string     `json:"organizer_email,omitempty"`
+	Status         string     `json:"status"` // confirmed, tentative, cancelled
+	Attendees      []Attendee `json:"attendees"`
+	Sequence       int        `json:"sequence"` // iCalendar SEQUENCE; her güncellemede artar
+	TenantID       int        `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Etkinlik katılımcısı; CRM kullanıcısı (user_id) veya sadece e-posta adresi (ör. müşteri)
+type Attendee struct {
+	UserID int    `json:"user_id,omitempty"`
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"` // needs-action, accepted, declined, tentative
+}
+
+// Tarih aralığındaki tek bir gerçekleşme; tekrarlayan etkinlikler her tekrar için ayrı döner
+type Occurrence struct {
+	Event
+	OccurrenceStart time.Time `json:"occurrence_start"`
+	OccurrenceEnd   time.Time `json:"occurrence_end"`
+}
@@ -0,0 +1,185 @@
+package event
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Organizatörün e-postası users tablosundan gelir (e ve u takma adları)
+const eventColumns = `e.id, e.title, e.description, e.location, e.start_at, e.end_at, e.all_day, e.rrule,
+	e.customer_id, e.deal_id, e.organizer_id, COALESCE(u.email, ''), e.status, e.attendees, e.sequence,
+	e.tenant_id, e.created_at, e.updated_at`
+
+const eventFrom = " FROM e LEFT JOIN users u ON u.id = e.organizer_id"
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEvent(row scanner, e *Event) error {
+	var customerID, dealID, organizerID sql.NullInt64
+	var attendees []byte
+	if err := row.Scan(&e.ID, &e.Title, &e.Description, &e.Location, &e.StartAt, &e.EndAt, &e.AllDay, &e.RRule,
+		&customerID, &dealID, &organizerID, &e.OrganizerEmail, &e.Status, &attendees, &e.Sequence,
+		&e.TenantID, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return err
+	}
+	e.CustomerID = int(customerID.Int64)
+	e.DealID = int(dealID.Int64)
+	e.OrganizerID = int(organizerID.Int64)
+	e.Attendees = []Attendee{}
+	return json.Unmarshal(attendees, &e.Attendees)
+}
+
+// Sonsuz tekrarlayan serilerde series_end NULL'dır
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func createEventRepo(db *sql.DB, e *Event, end time.Time) error {
+	attendees, err := json.Marshal(e.Attendees)
+	if err != nil {
+		return err
+	}
+	row := db.QueryRow(`
+		WITH e AS (
+			INSERT INTO events (tenant_id, title, description, location, start_at, end_at, all_day, rrule, series_end,
+				customer_id, deal_id, organizer_id, status, attendees)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0), NULLIF($11, 0), $12, $13, $14)
+			RETURNING *
+		)
+		SELECT `+eventColumns+eventFrom,
+		e.TenantID, e.Title, e.Description, e.Location, e.StartAt, e.EndAt, e.AllDay, e.RRule, nullTime(end),
+		e.CustomerID, e.DealID, e.OrganizerID, e.Status, attendees,
+	)
+	return scanEvent(row, e)
+}
+
+func getEventRepo(db *sql.DB, id int) (Event, error) {
+	var e Event
+	err := scanEvent(db.QueryRow("WITH e AS (SELECT * FROM events WHERE id = $1) SELECT "+eventColumns+eventFrom, id), &e)
+	return e, err
+}
+
+// Etkinliği günceller; davetlerin eskisinin yerine geçmesi için SEQUENCE artırılır
+func updateEventRepo(db *sql.DB, e *Event, end time.Time) error {
+	attendees, err := json.Marshal(e.Attendees)
+	if err != nil {
+		return err
+	}
+	row := db.QueryRow(`
+		WITH e AS (
+			UPDATE events SET title = $1, description = $2, location = $3, start_at = $4, end_at = $5, all_day = $6,
+				rrule = $7, series_end = $8, customer_id = NULLIF($9, 0), deal_id = NULLIF($10, 0), status = $11,
+				attendees = $12, sequence = sequence + 1, updated_at = now()
+			WHERE id = $13
+			RETURNING *
+		)
+		SELECT `+eventColumns+eventFrom,
+		e.Title, e.Description, e.Location, e.StartAt, e.EndAt, e.AllDay, e.RRule, nullTime(end),
+		e.CustomerID, e.DealID, e.Status, attendees, e.ID,
+	)
+	return scanEvent(row, e)
+}
+
+func deleteEventRepo(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM events WHERE id = $1", id)
+	return err
+}
+
+// Aralıkla kesişebilecek etkinlikler; tekrarlayanlar servis katmanında açılır
+func getEventsRepo(db *sql.DB, params EventListParams) ([]Event, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{
+		"tenant_id = " + arg(params.TenantID),
+		"start_at < " + arg(params.To),
+		"(series_end IS NULL OR series_end > " + arg(params.From) + ")",
+	}
+	if params.UserID > 0 {
+		u := arg(params.UserID)
+		where = append(where, "(organizer_id = "+u+" OR attendees @> jsonb_build_array(jsonb_build_object('user_id', "+u+"::int)))")
+	}
+	if params.CustomerID > 0 {
+		where = append(where, "customer_id = "+arg(params.CustomerID))
+	}
+	if params.DealID > 0 {
+		where = append(where, "deal_id = "+arg(params.DealID))
+	}
+	if !params.IncludeCancelled {
+		where = append(where, "status <> 'cancelled'")
+	}
+	rows, err := db.Query("WITH e AS (SELECT * FROM events WHERE "+strings.Join(where, " AND ")+
+		" ORDER BY start_at, id LIMIT "+arg(maxListedEvents)+") SELECT "+eventColumns+eventFrom+" ORDER BY e.start_at, e.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := scanEvent(rows, &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Katılımcı olarak eklenen kullanıcının e-postası ve tenant'ı
+func getUserRepo(db *sql.DB, id int) (email string, tenantID int, err error) {
+	err = db.QueryRow("SELECT email, tenant_id FROM users WHERE id = $1", id).Scan(&email, &tenantID)
+	return email, tenantID, err
+}
+
+// Tenant'ta bu e-postaya sahip kullanıcı (yoksa 0)
+func findUserByEmailRepo(db *sql.DB, tenantID int, email string) (int, error) {
+	var id int
+	err := db.QueryRow("SELECT id FROM users WHERE tenant_id = $1 AND lower(email) = lower($2)", tenantID, email).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+func customerTenantRepo(db *sql.DB, id int) (int, error) {
+	var tenantID int
+	err := db.QueryRow("SELECT tenant_id FROM customers WHERE id = $1", id).Scan(&tenantID)
+	return tenantID, err
+}
+
+func dealTenantRepo(db *sql.DB, id int) (int, error) {
+	var tenantID int
+	err := db.QueryRow("SELECT tenant_id FROM deals WHERE id = $1", id).Scan(&tenantID)
+	return tenantID, err
+}
+
+// Kullanıcının takvim akışı anahtarını (özeti) ekler veya değiştirir; eski URL geçersiz olur
+func upsertFeedTokenRepo(db *sql.DB, userID int, tokenHash string) error {
+	_, err := db.Exec(`
+		INSERT INTO calendar_feeds (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()`,
+		userID, tokenHash)
+	return err
+}
+
+func deleteFeedTokenRepo(db *sql.DB, userID int) error {
+	_, err := db.Exec("DELETE FROM calendar_feeds WHERE user_id = $1", userID)
+	return err
+}
+
+// Akış anahtarının sahibi
+func getFeedUserRepo(db *sql.DB, tokenHash string) (id, tenantID int, email string, err error) {
+	err = db.QueryRow(`
+		SELECT u.id, u.tenant_id, u.email FROM calendar_feeds f JOIN users u ON u.id = f.user_id
+		WHERE f.token_hash = $1`, tokenHash).Scan(&id, &tenantID, &email)
+	return id, tenantID, email, err
+}
@@ -0,0 +1,232 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RFC 5545 RRULE'un desteklenen alt kümesi: FREQ, INTERVAL, COUNT, UNTIL ve (haftalıkta) BYDAY.
+// Takvim istemcileri kuralı kendileri açar; API listelemesi için aynı açılım burada yapılır.
+type Recurrence struct {
+	Freq     string // DAILY, WEEKLY, MONTHLY, YEARLY
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+// Tek bir listelemede açılabilecek en fazla tekrar sayısı
+const maxOccurrences = 1000
+
+var rruleDays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10" biçimindeki kuralı çözümler ("RRULE:" öneki kabul edilir)
+func ParseRRule(s string) (Recurrence, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := Recurrence{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return Recurrence{}, fmt.Errorf("Geçersiz tekrar kuralı: %s", part)
+		}
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(v)
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 1000 {
+				return Recurrence{}, errors.New("INTERVAL 1-1000 arasında olmalı")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return Recurrence{}, errors.New("COUNT pozitif olmalı")
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(v)
+			if err != nil {
+				return Recurrence{}, errors.New("UNTIL 20261231 veya 20261231T235959Z biçiminde olmalı")
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(strings.ToUpper(v), ",") {
+				wd, ok := rruleDays[d]
+				if !ok {
+					return Recurrence{}, fmt.Errorf("Desteklenmeyen BYDAY değeri: %s", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "WKST":
+			// Hafta her zaman pazartesi başlar
+		default:
+			return Recurrence{}, fmt.Errorf("Desteklenmeyen tekrar kuralı parçası: %s (FREQ, INTERVAL, COUNT, UNTIL, BYDAY)", k)
+		}
+	}
+	switch r.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return Recurrence{}, errors.New("Tekrar kuralında FREQ zorunlu")
+	default:
+		return Recurrence{}, fmt.Errorf("Desteklenmeyen FREQ: %s (DAILY, WEEKLY, MONTHLY, YEARLY)", r.Freq)
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return Recurrence{}, errors.New("BYDAY sadece FREQ=WEEKLY ile desteklenir")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return Recurrence{}, errors.New("COUNT ve UNTIL birlikte kullanılamaz")
+	}
+	sort.Slice(r.ByDay, func(i, j int) bool { return mondayIndex(r.ByDay[i]) < mondayIndex(r.ByDay[j]) })
+	return r, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Sadece tarih verilmişse o günün sonuna kadar
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+// Kuralın standart yazımı (veritabanına ve .ics'e bu yazılır)
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range r.ByDay {
+			days = append(days, strings.ToUpper(d.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func mondayIndex(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+// Başlangıç zamanı start olan serinin tekrarlarını sırayla fn'e verir; fn false dönerse durur.
+// Aylık/yıllık tekrarlarda olmayan günler (ör. 31 Nisan) RFC 5545'teki gibi atlanır.
+func (r Recurrence) each(start time.Time, fn func(time.Time) bool) {
+	n := 0
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		n++
+		if !fn(t) {
+			return false
+		}
+		return r.Count == 0 || n < r.Count
+	}
+	// Kural hiç tekrar üretmese bile (ör. olmayan gün) döngü sınırlı kalır
+	for period := 0; period < 100000; period++ {
+		k := period * r.Interval
+		switch r.Freq {
+		case "DAILY":
+			if !emit(start.AddDate(0, 0, k)) {
+				return
+			}
+		case "WEEKLY":
+			if len(r.ByDay) == 0 {
+				if !emit(start.AddDate(0, 0, 7*k)) {
+					return
+				}
+				continue
+			}
+			weekStart := start.AddDate(0, 0, 7*k-mondayIndex(start.Weekday()))
+			for _, d := range r.ByDay {
+				if !emit(weekStart.AddDate(0, 0, mondayIndex(d))) {
+					return
+				}
+			}
+		case "MONTHLY":
+			t := start.AddDate(0, k, 0)
+			if t.Day() == start.Day() && !emit(t) {
+				return
+			}
+		case "YEARLY":
+			t := start.AddDate(k, 0, 0)
+			if t.Day() == start.Day() && !emit(t) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Serinin son tekrarının bitişi; sonsuz serilerde sıfır zaman döner
+func seriesEnd(e Event) (time.Time, error) {
+	if e.RRule == "" {
+		return e.EndAt, nil
+	}
+	r, err := ParseRRule(e.RRule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, nil
+	}
+	var last time.Time
+	r.each(e.StartAt, func(t time.Time) bool {
+		last = t
+		return true
+	})
+	if last.IsZero() {
+		return e.EndAt, nil
+	}
+	return last.Add(e.EndAt.Sub(e.StartAt)), nil
+}
+
+// Etkinliğin [from, to) aralığıyla kesişen gerçekleşmeleri (en fazla maxOccurrences)
+func Occurrences(e Event, from, to time.Time) []Occurrence {
+	duration := e.EndAt.Sub(e.StartAt)
+	var out []Occurrence
+	add := func(start time.Time) bool {
+		if !start.Before(to) || len(out) >= maxOccurrences {
+			return false
+		}
+		if end := start.Add(duration); end.After(from) {
+			out = append(out, Occurrence{Event: e, OccurrenceStart: start, OccurrenceEnd: end})
+		}
+		return true
+	}
+	if e.RRule == "" {
+		add(e.StartAt)
+		return out
+	}
+	r, err := ParseRRule(e.RRule)
+	if err != nil {
+		return nil
+	}
+	r.each(e.StartAt, add)
+	return out
+}
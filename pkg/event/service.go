@@ -0,0 +1,462 @@
+package event
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/notification"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrEventNotFound = errors.New("Etkinlik bulunamadı")
+	ErrForbidden     = errors.New("Bu etkinliği sadece organizatör veya bir yönetici değiştirebilir")
+	ErrFeedNotFound  = errors.New("Takvim akışı bulunamadı")
+)
+
+const (
+	maxListedEvents = 5000
+	maxAttendees    = 100
+	// Tek listelemede en fazla bir yıllık aralık açılır
+	maxListRange = 366 * 24 * time.Hour
+	// Abonelik akışı son 90 günü ve önümüzdeki iki yılı kapsar
+	feedPast   = 90 * 24 * time.Hour
+	feedFuture = 2 * 366 * 24 * time.Hour
+)
+
+// Takvim listeleme parametreleri. UserID 0 ise tenant'ın tüm etkinlikleri.
+type EventListParams struct {
+	TenantID         int
+	UserID           int // Organizatör veya katılımcı olduğu etkinlikler
+	CustomerID       int
+	DealID           int
+	From             time.Time
+	To               time.Time
+	IncludeCancelled bool
+}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// [From, To) aralığındaki gerçekleşmeler, başlangıca göre sıralı. Tekrarlayan etkinlikler açılır.
+func GetEvents(db *sql.DB, params EventListParams) ([]Occurrence, error) {
+	if params.TenantID == 0 {
+		params.TenantID = common.DefaultTenantID
+	}
+	if params.From.IsZero() {
+		params.From = time.Now().Truncate(24 * time.Hour)
+	}
+	if params.To.IsZero() {
+		params.To = params.From.AddDate(0, 0, 30)
+	}
+	if !params.To.After(params.From) {
+		return nil, errors.New("Bitiş (to) başlangıçtan (from) sonra olmalı")
+	}
+	if params.To.Sub(params.From) > maxListRange {
+		return nil, errors.New("Tarih aralığı en fazla bir yıl olabilir")
+	}
+	events, err := getEventsRepo(db, params)
+	if err != nil {
+		return nil, err
+	}
+	occurrences := []Occurrence{}
+	for _, e := range events {
+		occurrences = append(occurrences, Occurrences(e, params.From, params.To)...)
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].OccurrenceStart.Before(occurrences[j].OccurrenceStart)
+	})
+	if len(occurrences) > maxOccurrences {
+		occurrences = occurrences[:maxOccurrences]
+	}
+	return occurrences, nil
+}
+
+// Oturum kullanıcısının görebileceği etkinlik (başka tenant'ın etkinliği bulunamadı sayılır)
+func GetEvent(db *sql.DB, user common.AuthUser, id int) (Event, error) {
+	e, err := getEventRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && e.TenantID != tenantOf(user)) {
+		return Event{}, ErrEventNotFound
+	}
+	if err != nil {
+		return Event{}, err
+	}
+	if e.OrganizerID != user.ID && !isAttendee(e, user.ID) && !user.IsManager() {
+		return Event{}, ErrEventNotFound
+	}
+	return e, nil
+}
+
+// Etkinlik ekleme. Organizatör oturum kullanıcısıdır; katılımcılara davet (.ics) gönderilir.
+func CreateEvent(db *sql.DB, user common.AuthUser, e *Event) error {
+	e.TenantID = tenantOf(user)
+	e.OrganizerID = user.ID
+	if e.Status == "" {
+		e.Status = StatusConfirmed
+	}
+	if err := prepareEvent(db, e, nil); err != nil {
+		return err
+	}
+	end, err := seriesEnd(*e)
+	if err != nil {
+		return err
+	}
+	if err := createEventRepo(db, e, end); err != nil {
+		return err
+	}
+	sendInvitations(*e, MethodRequest, e.Attendees)
+	return nil
+}
+
+// Etkinlik güncelleme. Verilmeyen zamanlar, durum ve katılımcılar korunur;
+// listeden çıkarılan katılımcılara iptal, kalanlara güncel davet gönderilir.
+func UpdateEvent(db *sql.DB, user common.AuthUser, e *Event) error {
+	current, err := GetEvent(db, user, e.ID)
+	if err != nil {
+		return err
+	}
+	if !canModify(user, current) {
+		return ErrForbidden
+	}
+	if e.StartAt.IsZero() {
+		e.StartAt = current.StartAt
+	}
+	if e.EndAt.IsZero() {
+		e.EndAt = current.EndAt
+	}
+	if e.Status == "" {
+		e.Status = current.Status
+	}
+	if e.Attendees == nil {
+		e.Attendees = current.Attendees
+	}
+	e.TenantID = current.TenantID
+	e.OrganizerID = current.OrganizerID
+	if err := prepareEvent(db, e, current.Attendees); err != nil {
+		return err
+	}
+	end, err := seriesEnd(*e)
+	if err != nil {
+		return err
+	}
+	if err := updateEventRepo(db, e, end); err != nil {
+		return err
+	}
+
+	kept := map[string]bool{}
+	for _, a := range e.Attendees {
+		kept[strings.ToLower(a.Email)] = true
+	}
+	var removed []Attendee
+	for _, a := range current.Attendees {
+		if !kept[strings.ToLower(a.Email)] {
+			removed = append(removed, a)
+		}
+	}
+	if e.Status == StatusCancelled {
+		if current.Status != StatusCancelled {
+			sendInvitations(*e, MethodCancel, append(e.Attendees, removed...))
+		}
+		return nil
+	}
+	sendInvitations(*e, MethodRequest, e.Attendees)
+	sendInvitations(*e, MethodCancel, removed)
+	return nil
+}
+
+// Etkinlik silme; iptal edilmemişse katılımcılara iptal bildirimi gider
+func DeleteEvent(db *sql.DB, user common.AuthUser, id int) error {
+	current, err := GetEvent(db, user, id)
+	if err != nil {
+		return err
+	}
+	if !canModify(user, current) {
+		return ErrForbidden
+	}
+	if err := deleteEventRepo(db, id); err != nil {
+		return err
+	}
+	if current.Status != StatusCancelled {
+		current.Sequence++
+		sendInvitations(current, MethodCancel, current.Attendees)
+	}
+	return nil
+}
+
+func canModify(user common.AuthUser, e Event) bool {
+	return e.OrganizerID == user.ID || user.IsManager()
+}
+
+func isAttendee(e Event, userID int) bool {
+	for _, a := range e.Attendees {
+		if a.UserID != 0 && a.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Alanları normalleştirir, doğrular ve katılımcıları kullanıcılarla eşleştirir.
+// previous, güncellemede katılımcıların önceki yanıtlarını korumak içindir.
+func prepareEvent(db *sql.DB, e *Event, previous []Attendee) error {
+	normalizeEvent(e)
+	if err := validateEvent(*e); err != nil {
+		return err
+	}
+	if e.CustomerID > 0 {
+		tenantID, err := customerTenantRepo(db, e.CustomerID)
+		if err == sql.ErrNoRows || (err == nil && tenantID != e.TenantID) {
+			return errors.New("Müşteri bulunamadı")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if e.DealID > 0 {
+		tenantID, err := dealTenantRepo(db, e.DealID)
+		if err == sql.ErrNoRows || (err == nil && tenantID != e.TenantID) {
+			return errors.New("Fırsat bulunamadı")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	prevStatus := map[string]string{}
+	for _, a := range previous {
+		prevStatus[strings.ToLower(a.Email)] = a.Status
+	}
+	seen := map[string]bool{}
+	for i := range e.Attendees {
+		a := &e.Attendees[i]
+		if a.UserID > 0 {
+			email, tenantID, err := getUserRepo(db, a.UserID)
+			if err == sql.ErrNoRows || (err == nil && tenantID != e.TenantID) {
+				return fmt.Errorf("Geçersiz katılımcı kullanıcı: %d", a.UserID)
+			}
+			if err != nil {
+				return err
+			}
+			a.Email = email
+		} else {
+			id, err := findUserByEmailRepo(db, e.TenantID, a.Email)
+			if err != nil {
+				return err
+			}
+			a.UserID = id
+		}
+		key := strings.ToLower(a.Email)
+		if seen[key] {
+			return fmt.Errorf("Katılımcı birden fazla kez eklenmiş: %s", a.Email)
+		}
+		seen[key] = true
+		if a.Status == "" {
+			a.Status = prevStatus[key]
+		}
+		if a.Status == "" {
+			a.Status = AttendeeNeedsAction
+		}
+	}
+	return nil
+}
+
+// Zamanlar UTC'ye çevrilir, tüm gün etkinlikleri gün sınırlarına yuvarlanır, tekrar kuralı standart yazılır
+func normalizeEvent(e *Event) {
+	e.Title = strings.TrimSpace(e.Title)
+	e.Location = strings.TrimSpace(e.Location)
+	e.StartAt, e.EndAt = e.StartAt.UTC(), e.EndAt.UTC()
+	if e.AllDay && !e.StartAt.IsZero() {
+		e.StartAt = time.Date(e.StartAt.Year(), e.StartAt.Month(), e.StartAt.Day(), 0, 0, 0, 0, time.UTC)
+		if !e.EndAt.IsZero() {
+			e.EndAt = time.Date(e.EndAt.Year(), e.EndAt.Month(), e.EndAt.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		if !e.EndAt.After(e.StartAt) {
+			e.EndAt = e.StartAt.AddDate(0, 0, 1)
+		}
+	}
+	if e.RRule != "" {
+		if r, err := ParseRRule(e.RRule); err == nil {
+			e.RRule = r.String()
+		}
+	}
+	if e.Attendees == nil {
+		e.Attendees = []Attendee{}
+	}
+	for i := range e.Attendees {
+		e.Attendees[i].Email = strings.TrimSpace(e.Attendees[i].Email)
+		e.Attendees[i].Name = strings.TrimSpace(e.Attendees[i].Name)
+	}
+}
+
+// Katılımcılara iTIP daveti (REQUEST) veya iptali (CANCEL) .ics eki olarak gönderir.
+// Organizatörün kendisine gönderilmez; CRM kullanıcısı olmayanlara doğrudan e-posta gider.
+func sendInvitations(e Event, method string, attendees []Attendee) {
+	if len(attendees) == 0 {
+		return
+	}
+	attachment := notification.Attachment{
+		Filename:    "invite.ics",
+		ContentType: "text/calendar; charset=UTF-8; method=" + method,
+		Data:        []byte(MarshalCalendar([]Event{e}, method, "")),
+	}
+	for _, a := range attendees {
+		if a.UserID != 0 && a.UserID == e.OrganizerID {
+			continue
+		}
+		cmd := notification.Command{
+			UserID:      a.UserID,
+			Event:       "event.invitation",
+			Subject:     "Davet: " + e.Title,
+			Body:        invitationBody(e),
+			Attachments: []notification.Attachment{attachment},
+		}
+		if method == MethodCancel {
+			cmd.Event = "event.cancelled"
+			cmd.Subject = "İptal edildi: " + e.Title
+		}
+		if a.UserID == 0 {
+			cmd.Email = a.Email
+		}
+		if err := notification.Publish(context.Background(), cmd); err != nil {
+			log.Printf("Etkinlik daveti gönderilemedi (etkinlik=%d, alıcı=%s): %v", e.ID, a.Email, err)
+		}
+	}
+}
+
+func invitationBody(e Event) string {
+	var body string
+	if e.AllDay {
+		body = fmt.Sprintf("Tarih: %s (tüm gün)", e.StartAt.Format("02-01-2006"))
+	} else {
+		body = fmt.Sprintf("Başlangıç: %s UTC\nBitiş: %s UTC", e.StartAt.UTC().Format("02-01-2006 15:04"), e.EndAt.UTC().Format("02-01-2006 15:04"))
+	}
+	if e.RRule != "" {
+		body += "\nTekrar: " + e.RRule
+	}
+	if e.Location != "" {
+		body += "\nYer: " + e.Location
+	}
+	if e.OrganizerEmail != "" {
+		body += "\nOrganizatör: " + e.OrganizerEmail
+	}
+	if e.Description != "" {
+		body += "\n\n" + e.Description
+	}
+	return body
+}
+
+// Kullanıcıya yeni bir gizli akış anahtarı üretir; önceki abonelik URL'si geçersiz olur.
+// Anahtarın sadece özeti saklanır, URL bir daha gösterilemez.
+func RotateFeedToken(db *sql.DB, user common.AuthUser) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := upsertFeedTokenRepo(db, user.ID, hashFeedToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func RevokeFeedToken(db *sql.DB, user common.AuthUser) error {
+	return deleteFeedTokenRepo(db, user.ID)
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Abonelik URL'sinin yolu (JWT gerektirmez)
+func FeedPath(token string) string {
+	return "/calendar/" + token + ".ics"
+}
+
+// Gizli anahtarın sahibinin organizatör veya katılımcı olduğu etkinlikleri .ics olarak döner.
+// İptal edilen etkinlikler istemcilerden silinsin diye STATUS:CANCELLED ile yer alır.
+func CalendarFeed(db *sql.DB, token string) (string, error) {
+	if len(token) != 64 {
+		return "", ErrFeedNotFound
+	}
+	if _, err := hex.DecodeString(token); err != nil {
+		return "", ErrFeedNotFound
+	}
+	userID, tenantID, email, err := getFeedUserRepo(db, hashFeedToken(token))
+	if err == sql.ErrNoRows {
+		return "", ErrFeedNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	events, err := getEventsRepo(db, EventListParams{
+		TenantID:         tenantID,
+		UserID:           userID,
+		From:             now.Add(-feedPast),
+		To:               now.Add(feedFuture),
+		IncludeCancelled: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return MarshalCalendar(events, "", "CRM Takvimi ("+email+")"), nil
+}
+
+// --- Validasyon Fonksiyonları ---
+func isStatus(s string) bool {
+	switch s {
+	case StatusConfirmed, StatusTentative, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+func validateEvent(e Event) error {
+	if n := utf8.RuneCountInString(e.Title); n < 2 || n > 255 {
+		return errors.New("Başlık 2-255 karakter olmalı")
+	}
+	if e.StartAt.IsZero() || e.EndAt.IsZero() {
+		return errors.New("Başlangıç ve bitiş zamanı zorunlu")
+	}
+	if !e.EndAt.After(e.StartAt) {
+		return errors.New("Bitiş zamanı başlangıçtan sonra olmalı")
+	}
+	if e.CustomerID < 0 || e.DealID < 0 {
+		return errors.New("Geçersiz müşteri veya fırsat ID")
+	}
+	if !isStatus(e.Status) {
+		return errors.New("Geçersiz etkinlik durumu")
+	}
+	if e.RRule != "" {
+		if _, err := ParseRRule(e.RRule); err != nil {
+			return err
+		}
+	}
+	if len(e.Attendees) > maxAttendees {
+		return fmt.Errorf("En fazla %d katılımcı eklenebilir", maxAttendees)
+	}
+	for _, a := range e.Attendees {
+		if a.UserID < 0 || (a.UserID == 0 && !common.IsEmailValid(a.Email)) {
+			return fmt.Errorf("Geçersiz katılımcı: %q", a.Email)
+		}
+		switch a.Status {
+		case "", AttendeeNeedsAction, AttendeeAccepted, AttendeeDeclined, AttendeeTentative:
+		default:
+			return fmt.Errorf("Geçersiz katılımcı yanıtı: %s", a.Status)
+		}
+	}
+	return nil
+}
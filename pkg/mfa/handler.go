@@ -0,0 +1,226 @@
+package mfa
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+	// Challenge token'larının imza anahtarı türetilir (oturum JWT anahtarı)
+	JWTKey []byte
+	// İkinci adım başarılı olunca oturum JWT'sini üretir
+	IssueToken func(user common.AuthUser) (string, error)
+}
+
+// Girişin ikinci adımı için istek: /api/login yanıtındaki mfa_token ve kod
+type loginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	CodeRequest
+}
+
+// MFA durumu (GET /api/mfa)
+func (h *Handler) GetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	s, err := GetStatus(h.DBPrimary, user)
+	if err != nil {
+		writeMFAError(w, "MFA durumu alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// Kayıt başlatma (POST /api/mfa/enroll); dönen provisioning_uri QR kod olarak gösterilir
+func (h *Handler) StartEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	e, err := StartEnrollment(h.DBPrimary, user)
+	if err != nil {
+		writeMFAError(w, "MFA kaydı başlatılamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// Kaydı onaylama (POST /api/mfa/confirm) {"code": "123456"}; kurtarma kodları bir kez gösterilir
+func (h *Handler) ConfirmEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var req CodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	codes, err := ConfirmEnrollment(h.DBPrimary, user.ID, req.Code, time.Now())
+	if err != nil {
+		writeMFAError(w, "MFA kaydı onaylanamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+// Kurtarma kodlarını yenileme (POST /api/mfa/recovery-codes) {"code": "123456"}
+func (h *Handler) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var req CodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	codes, err := RegenerateRecoveryCodes(h.DBPrimary, user, req, time.Now())
+	if err != nil {
+		writeMFAError(w, "Kurtarma kodları yenilenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+// MFA'yı kapatma (DELETE /api/mfa) {"code": "123456"} veya {"recovery_code": "abcd-efgh"}
+func (h *Handler) DisableHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var req CodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := Disable(h.DBPrimary, user, req, time.Now()); err != nil {
+		writeMFAError(w, "MFA kapatılamadı", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Admin: kullanıcının MFA'sını sıfırlama (DELETE /api/mfa/users/{id})
+func (h *Handler) ResetUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := idFromPath(r.URL.Path, "/api/mfa/users/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz kullanıcı ID", err)
+		return
+	}
+	if err := ResetUser(h.DBPrimary, user, id); err != nil {
+		writeMFAError(w, "MFA sıfırlanamadı", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Admin: MFA politikası (GET /api/mfa/policy)
+func (h *Handler) GetPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	p, err := GetPolicy(h.DBReplica, user)
+	if err != nil {
+		writeMFAError(w, "MFA politikası alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// Admin: MFA politikasını güncelleme (PUT /api/mfa/policy) {"required_roles": ["admin", "manager"]}
+func (h *Handler) UpdatePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var p Policy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := UpdatePolicy(h.DBPrimary, user, &p); err != nil {
+		writeMFAError(w, "MFA politikası güncellenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// Girişin ikinci adımı (POST /api/login/mfa) {"mfa_token": "...", "code": "123456"}; kayıt
+// challenge'ında ilk kod kaydı onaylar ve yanıtta kurtarma kodları da döner
+func (h *Handler) LoginVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	now := time.Now()
+	ch, err := ParseChallengeToken(h.JWTKey, req.MFAToken, now)
+	if err != nil {
+		writeMFAError(w, "MFA doğrulanamadı", err)
+		return
+	}
+	user, codes, err := CompleteLogin(h.DBPrimary, ch, req.CodeRequest, now)
+	if err != nil {
+		writeMFAError(w, "MFA doğrulanamadı", err)
+		return
+	}
+	token, err := h.IssueToken(user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Token oluşturulamadı", err)
+		return
+	}
+	resp := map[string]interface{}{"token": token}
+	if codes != nil {
+		resp["recovery_codes"] = codes
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Zorunlu MFA'da giriş sırasında kayıt başlatma (POST /api/login/mfa/enroll) {"mfa_token": "..."}
+func (h *Handler) LoginEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	ch, err := ParseChallengeToken(h.JWTKey, req.MFAToken, time.Now())
+	if err != nil {
+		writeMFAError(w, "MFA kaydı başlatılamadı", err)
+		return
+	}
+	e, err := StartLoginEnrollment(h.DBPrimary, ch)
+	if err != nil {
+		writeMFAError(w, "MFA kaydı başlatılamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func writeMFAError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrAdminOnly), errors.Is(err, ErrMFARequired):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrUserNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrNotEnrolled), errors.Is(err, ErrAlreadyEnabled):
+		common.WriteError(w, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, ErrCodeRequired), errors.Is(err, ErrInvalidRole):
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+	case errors.Is(err, ErrInvalidCode), errors.Is(err, ErrInvalidChallenge):
+		common.WriteError(w, http.StatusUnauthorized, err.Error(), err)
+	case errors.Is(err, ErrTooManyAttempts):
+		common.WriteError(w, http.StatusTooManyRequests, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusInternalServerError, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// /api/mfa/users/12 gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
@@ -0,0 +1,47 @@
+package mfa
+
+import "time"
+
+// Kullanıcının MFA durumu (GET /api/mfa)
+type Status struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"` // Kayıt başlatılmış ama kodla onaylanmamış
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	Required               bool       `json:"required"` // Kullanıcının rolü için politika MFA'yı zorunlu tutuyor
+}
+
+// Kayıt başlangıcında dönen gizli anahtar. ProvisioningURI (otpauth://) QR kod olarak gösterilir;
+// QR okutulamıyorsa Secret elle girilebilir.
+type Enrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// Doğrulama isteği; Code authenticator uygulamasındaki 6 haneli kod, RecoveryCode tek kullanımlık kurtarma kodu
+type CodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// Tenant'ın MFA politikası; listedeki rollerdeki kullanıcılar MFA olmadan giriş yapamaz
+type Policy struct {
+	RequiredRoles []string   `json:"required_roles"`
+	UpdatedBy     int        `json:"updated_by,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// Şifre doğrulandıktan sonra verilen kısa ömürlü challenge token'ının içeriği
+type Challenge struct {
+	UserID  int
+	Purpose string // PurposeVerify veya PurposeEnroll
+}
+
+// user_mfa satırı
+type record struct {
+	secret         string
+	confirmedAt    *time.Time
+	lastUsedStep   int64
+	failedAttempts int
+	lockedUntil    *time.Time
+}
@@ -0,0 +1,200 @@
+package mfa
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func getRecordRepo(db *sql.DB, userID int) (record, error) {
+	var rec record
+	var confirmedAt, lockedUntil sql.NullTime
+	err := db.QueryRow(`SELECT secret, confirmed_at, last_used_step, failed_attempts, locked_until
+		FROM user_mfa WHERE user_id = $1`, userID).
+		Scan(&rec.secret, &confirmedAt, &rec.lastUsedStep, &rec.failedAttempts, &lockedUntil)
+	if err != nil {
+		return record{}, err
+	}
+	if confirmedAt.Valid {
+		rec.confirmedAt = &confirmedAt.Time
+	}
+	if lockedUntil.Valid {
+		rec.lockedUntil = &lockedUntil.Time
+	}
+	return rec, nil
+}
+
+// Onaylanmamış kaydı yeni anahtarla değiştirir; onaylı MFA varsa false döner
+func upsertPendingRepo(db *sql.DB, userID int, secret string) (bool, error) {
+	res, err := db.Exec(`INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0,
+			failed_attempts = 0, locked_until = NULL, created_at = now()
+		WHERE user_mfa.confirmed_at IS NULL`, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Kaydı onaylar ve kurtarma kodlarını aynı transaction'da oluşturur
+func confirmRepo(db *sql.DB, userID int, step int64, codeHashes []string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_mfa SET confirmed_at = now(), last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND confirmed_at IS NULL`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := replaceRecoveryCodesTx(tx, userID, codeHashes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func replaceRecoveryCodesTx(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM user_mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO user_mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])`, userID, pq.Array(codeHashes))
+	return err
+}
+
+func replaceRecoveryCodesRepo(db *sql.DB, userID int, codeHashes []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := replaceRecoveryCodesTx(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Başarılı TOTP doğrulaması; adım koşulu aynı kodun eşzamanlı iki istekte kullanılmasını engeller
+func recordSuccessRepo(db *sql.DB, userID int, step int64) (bool, error) {
+	res, err := db.Exec(`UPDATE user_mfa SET last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Kurtarma kodunu harcar; kod yoksa veya daha önce kullanıldıysa false döner
+func useRecoveryCodeRepo(db *sql.DB, userID int, codeHash string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_mfa_recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE user_mfa SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1", userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Hatalı denemeyi sayar; sınıra ulaşılınca sayaç sıfırlanır ve hesap lockUntil'e kadar kilitlenir
+func recordFailureRepo(db *sql.DB, userID, maxAttempts int, lockUntil time.Time) error {
+	_, err := db.Exec(`UPDATE user_mfa SET
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END,
+			failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END
+		WHERE user_id = $1`, userID, maxAttempts, lockUntil)
+	return err
+}
+
+func countRecoveryCodesRepo(db *sql.DB, userID int) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM user_mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID).Scan(&n)
+	return n, err
+}
+
+func deleteRepo(db *sql.DB, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM user_mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func getUserRepo(db *sql.DB, userID int) (common.AuthUser, error) {
+	var u common.AuthUser
+	err := db.QueryRow("SELECT id, email, role, tenant_id FROM users WHERE id = $1", userID).
+		Scan(&u.ID, &u.Email, &u.Role, &u.TenantID)
+	return u, err
+}
+
+func isRequiredRepo(db *sql.DB, tenantID int, role string) (bool, error) {
+	var required bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM mfa_policies WHERE tenant_id = $1 AND role = $2)", tenantID, role).Scan(&required)
+	return required, err
+}
+
+func getPolicyRepo(db *sql.DB, tenantID int) (Policy, error) {
+	rows, err := db.Query("SELECT role, updated_by, updated_at FROM mfa_policies WHERE tenant_id = $1 ORDER BY role", tenantID)
+	if err != nil {
+		return Policy{}, err
+	}
+	defer rows.Close()
+
+	p := Policy{RequiredRoles: []string{}}
+	for rows.Next() {
+		var role string
+		var updatedBy sql.NullInt64
+		var updatedAt time.Time
+		if err := rows.Scan(&role, &updatedBy, &updatedAt); err != nil {
+			return Policy{}, err
+		}
+		p.RequiredRoles = append(p.RequiredRoles, role)
+		if p.UpdatedAt == nil || updatedAt.After(*p.UpdatedAt) {
+			p.UpdatedAt = &updatedAt
+			p.UpdatedBy = int(updatedBy.Int64)
+		}
+	}
+	return p, rows.Err()
+}
+
+func setPolicyRepo(db *sql.DB, tenantID int, roles []string, updatedBy int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM mfa_policies WHERE tenant_id = $1", tenantID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO mfa_policies (tenant_id, role, updated_by)
+		SELECT $1, unnest($2::text[]), $3`, tenantID, pq.Array(roles), updatedBy)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,409 @@
+package mfa
+
+import (
+	"Go-CRM/pkg/common"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrAdminOnly        = errors.New("Bu işlem sadece adminler içindir")
+	ErrNotEnrolled      = errors.New("MFA etkin değil")
+	ErrAlreadyEnabled   = errors.New("MFA zaten etkin")
+	ErrCodeRequired     = errors.New("Doğrulama kodu zorunludur")
+	ErrInvalidCode      = errors.New("Doğrulama kodu hatalı")
+	ErrTooManyAttempts  = fmt.Errorf("Çok fazla hatalı deneme, %d dakika sonra tekrar deneyin", int(lockDuration.Minutes()))
+	ErrMFARequired      = errors.New("MFA bu rol için zorunlu, kapatılamaz")
+	ErrInvalidRole      = errors.New("Geçersiz rol")
+	ErrUserNotFound     = errors.New("Kullanıcı bulunamadı")
+	ErrInvalidChallenge = errors.New("MFA oturumu geçersiz veya süresi dolmuş, tekrar giriş yapın")
+)
+
+const (
+	// Challenge token amaçları: kayıtlı kullanıcı kod girer, zorunlu tutulan ama kaydı olmayan kullanıcı
+	// önce authenticator'ını ekler
+	PurposeVerify = "mfa"
+	PurposeEnroll = "mfa_enroll"
+
+	ChallengeTTL = 5 * time.Minute
+	// Authenticator uygulamasında hesabın yanında görünen ad
+	Issuer = "Go-CRM"
+
+	recoveryCodeCount = 10
+	maxFailedAttempts = 5
+	lockDuration      = 15 * time.Minute
+	challengeAudience = "crm-mfa"
+)
+
+var validRoles = map[string]bool{common.RoleRep: true, common.RoleManager: true, common.RoleAdmin: true}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// API anahtarları MFA ayarlarını değiştiremez
+func requireAdmin(user common.AuthUser) error {
+	if user.APIKeyID != 0 || !user.IsAdmin() {
+		return ErrAdminOnly
+	}
+	return nil
+}
+
+type challengeClaims struct {
+	UserID  int    `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// Challenge token'ları oturum JWT'sinden türetilmiş ayrı bir anahtarla imzalanır; böylece bir challenge
+// token'ı API'de oturum token'ı olarak kabul edilmez (ve tersi)
+func challengeKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("mfa-challenge"))
+	return mac.Sum(nil)
+}
+
+// Şifre doğrulandıktan sonra ikinci adım için verilen kısa ömürlü token
+func NewChallengeToken(key []byte, userID int, purpose string, now time.Time) (string, error) {
+	claims := &challengeClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ChallengeTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(challengeKey(key))
+}
+
+func ParseChallengeToken(key []byte, token string, now time.Time) (Challenge, error) {
+	claims := &challengeClaims{}
+	t, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return challengeKey(key), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience(challengeAudience), jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }))
+	if err != nil || !t.Valid || claims.UserID <= 0 || (claims.Purpose != PurposeVerify && claims.Purpose != PurposeEnroll) {
+		return Challenge{}, ErrInvalidChallenge
+	}
+	return Challenge{UserID: claims.UserID, Purpose: claims.Purpose}, nil
+}
+
+// Şifresi doğrulanan kullanıcı için gereken ikinci adım: MFA etkinse PurposeVerify, etkin değil ama
+// rolü için zorunluysa PurposeEnroll, hiçbiri değilse boş
+func LoginRequirement(db *sql.DB, user common.AuthUser) (string, error) {
+	rec, err := getRecordRepo(db, user.ID)
+	if err == nil && rec.confirmedAt != nil {
+		return PurposeVerify, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	required, err := isRequiredRepo(db, tenantOf(user), user.Role)
+	if err != nil || !required {
+		return "", err
+	}
+	return PurposeEnroll, nil
+}
+
+func GetStatus(db *sql.DB, user common.AuthUser) (Status, error) {
+	var s Status
+	required, err := isRequiredRepo(db, tenantOf(user), user.Role)
+	if err != nil {
+		return s, err
+	}
+	s.Required = required
+	rec, err := getRecordRepo(db, user.ID)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	s.Enabled = rec.confirmedAt != nil
+	s.Pending = !s.Enabled
+	s.ConfirmedAt = rec.confirmedAt
+	if s.Enabled {
+		if s.RecoveryCodesRemaining, err = countRecoveryCodesRepo(db, user.ID); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// Yeni gizli anahtar üretir; onaylanmamış önceki kayıt varsa yerine geçer
+func StartEnrollment(db *sql.DB, user common.AuthUser) (Enrollment, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+	ok, err := upsertPendingRepo(db, user.ID, secret)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if !ok {
+		return Enrollment{}, ErrAlreadyEnabled
+	}
+	return Enrollment{Secret: secret, ProvisioningURI: ProvisioningURI(Issuer, user.Email, secret)}, nil
+}
+
+// Authenticator'dan gelen ilk kodla kaydı onaylar ve kurtarma kodlarını döner. Kodlar sadece
+// burada düz metin olarak görünür.
+func ConfirmEnrollment(db *sql.DB, userID int, code string, now time.Time) ([]string, error) {
+	rec, err := getRecordRepo(db, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.confirmedAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+	if err := checkLock(rec, now); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(code) == "" {
+		return nil, ErrCodeRequired
+	}
+	step, ok := ValidateCode(rec.secret, code, now, 0)
+	if !ok {
+		return nil, recordFailure(db, userID, now)
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err = confirmRepo(db, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAlreadyEnabled
+	}
+	return codes, nil
+}
+
+// TOTP kodunu veya kurtarma kodunu doğrular. Hatalı denemeler sayılır; art arda maxFailedAttempts
+// hatadan sonra doğrulama lockDuration boyunca kilitlenir.
+func Verify(db *sql.DB, userID int, req CodeRequest, now time.Time) error {
+	rec, err := getRecordRepo(db, userID)
+	if err == sql.ErrNoRows || (err == nil && rec.confirmedAt == nil) {
+		return ErrNotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkLock(rec, now); err != nil {
+		return err
+	}
+
+	if req.RecoveryCode != "" {
+		ok, err := useRecoveryCodeRepo(db, userID, HashRecoveryCode(req.RecoveryCode))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return recordFailure(db, userID, now)
+		}
+		return nil
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		return ErrCodeRequired
+	}
+	step, ok := ValidateCode(rec.secret, req.Code, now, rec.lastUsedStep)
+	if ok {
+		// Aynı kod eşzamanlı başka bir istekte kullanıldıysa güncelleme satır etkilemez
+		if ok, err = recordSuccessRepo(db, userID, step); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return recordFailure(db, userID, now)
+	}
+	return nil
+}
+
+func checkLock(rec record, now time.Time) error {
+	if rec.lockedUntil != nil && now.Before(*rec.lockedUntil) {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+func recordFailure(db *sql.DB, userID int, now time.Time) error {
+	if err := recordFailureRepo(db, userID, maxFailedAttempts, now.Add(lockDuration)); err != nil {
+		return err
+	}
+	return ErrInvalidCode
+}
+
+// Girişin ikinci adımı: challenge amacına göre kodu doğrular veya kaydı onaylar ve token
+// verilecek kullanıcıyı döner. Kayıt onaylandıysa kurtarma kodları da döner.
+func CompleteLogin(db *sql.DB, ch Challenge, req CodeRequest, now time.Time) (common.AuthUser, []string, error) {
+	var codes []string
+	var err error
+	if ch.Purpose == PurposeEnroll {
+		codes, err = ConfirmEnrollment(db, ch.UserID, req.Code, now)
+	} else {
+		err = Verify(db, ch.UserID, req, now)
+	}
+	if err != nil {
+		return common.AuthUser{}, nil, err
+	}
+	user, err := getUserRepo(db, ch.UserID)
+	if err == sql.ErrNoRows {
+		return common.AuthUser{}, nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return common.AuthUser{}, nil, err
+	}
+	if user.TenantID == 0 {
+		user.TenantID = common.DefaultTenantID
+	}
+	return user, codes, nil
+}
+
+// Zorunlu MFA'da girişin ortasında kayıt başlatma; sadece PurposeEnroll challenge'ı ile
+func StartLoginEnrollment(db *sql.DB, ch Challenge) (Enrollment, error) {
+	if ch.Purpose != PurposeEnroll {
+		return Enrollment{}, ErrInvalidChallenge
+	}
+	user, err := getUserRepo(db, ch.UserID)
+	if err == sql.ErrNoRows {
+		return Enrollment{}, ErrInvalidChallenge
+	}
+	if err != nil {
+		return Enrollment{}, err
+	}
+	return StartEnrollment(db, user)
+}
+
+// Kurtarma kodlarını yeniler; eski kodlar geçersiz olur. Authenticator kodu gerekir.
+func RegenerateRecoveryCodes(db *sql.DB, user common.AuthUser, req CodeRequest, now time.Time) ([]string, error) {
+	if err := Verify(db, user.ID, CodeRequest{Code: req.Code}, now); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := replaceRecoveryCodesRepo(db, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Kullanıcının kendi MFA'sını kapatması; rolü için zorunluysa izin verilmez
+func Disable(db *sql.DB, user common.AuthUser, req CodeRequest, now time.Time) error {
+	required, err := isRequiredRepo(db, tenantOf(user), user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+	if err := Verify(db, user.ID, req, now); err != nil {
+		return err
+	}
+	return deleteRepo(db, user.ID)
+}
+
+// Cihazını ve kurtarma kodlarını kaybeden kullanıcının MFA'sını admin sıfırlar; zorunluysa
+// kullanıcı bir sonraki girişte yeniden kayıt olur
+func ResetUser(db *sql.DB, admin common.AuthUser, userID int) error {
+	if err := requireAdmin(admin); err != nil {
+		return err
+	}
+	target, err := getUserRepo(db, userID)
+	if err == sql.ErrNoRows || (err == nil && tenantOf(target) != tenantOf(admin)) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return deleteRepo(db, userID)
+}
+
+func GetPolicy(db *sql.DB, user common.AuthUser) (Policy, error) {
+	if err := requireAdmin(user); err != nil {
+		return Policy{}, err
+	}
+	return getPolicyRepo(db, tenantOf(user))
+}
+
+// Politika değişikliği mevcut oturumları kapatmaz; zorunlu hale gelen kullanıcılar bir sonraki
+// girişlerinde MFA kaydına yönlendirilir
+func UpdatePolicy(db *sql.DB, user common.AuthUser, p *Policy) error {
+	if err := requireAdmin(user); err != nil {
+		return err
+	}
+	if err := ValidatePolicy(p); err != nil {
+		return err
+	}
+	if err := setPolicyRepo(db, tenantOf(user), p.RequiredRoles, user.ID); err != nil {
+		return err
+	}
+	updated, err := getPolicyRepo(db, tenantOf(user))
+	if err != nil {
+		return err
+	}
+	*p = updated
+	return nil
+}
+
+// Rolleri doğrular, tekrarları atar ve sıralar
+func ValidatePolicy(p *Policy) error {
+	seen := map[string]bool{}
+	roles := []string{}
+	for _, r := range p.RequiredRoles {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if !validRoles[r] {
+			return fmt.Errorf("%w: %q", ErrInvalidRole, r)
+		}
+		if !seen[r] {
+			seen[r] = true
+			roles = append(roles, r)
+		}
+	}
+	sort.Strings(roles)
+	p.RequiredRoles = roles
+	return nil
+}
+
+// xxxx-xxxx biçiminde tek kullanımlık kodlar; veritabanında sadece SHA-256 özetleri tutulur
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(b32.EncodeToString(b))
+		codes[i] = s[:4] + "-" + s[4:]
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// Kullanıcının yazım farklarını (büyük harf, tire, boşluk) yok sayarak özet alır
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
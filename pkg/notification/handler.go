@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+)
+
+// Handler fonksiyonları sade tutulur, iş mantığı service katmanında
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Oturum kullanıcısının bildirim tercihleri (GET /api/notifications/preferences)
+func (h *Handler) GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	prefs, err := GetPreferences(h.DBReplica, user.ID)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Bildirim tercihleri alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// Bildirim tercihlerini güncelleme (PUT /api/notifications/preferences)
+func (h *Handler) UpdatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var prefs Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	prefs.UserID = user.ID
+	if err := SavePreferences(h.DBPrimary, &prefs); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Bildirim tercihleri kaydedilemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
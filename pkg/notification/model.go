@@ -0,0 +1,61 @@
+package notification
+
+import "time"
+
+// Desteklenen bildirim kanalları
+const (
+	ChannelEmail     = "email"
+	ChannelSMS       = "sms"
+	ChannelWebSocket = "websocket"
+)
+
+// Özet (digest) modları
+const (
+	DigestOff    = "off"
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+// Kullanıcının bildirim tercihleri
+// Events: olay tipi -> kanal listesi. Listede olmayan olaylar DefaultChannels ile gönderilir,
+// boş liste verilen olaylar hiç gönderilmez.
+type Preferences struct {
+	UserID          int                 `json:"user_id"`
+	Events          map[string][]string `json:"events"`
+	Timezone        string              `json:"timezone"`
+	QuietHoursStart string              `json:"quiet_hours_start"` // "22:00" (boşsa sessiz saat yok)
+	QuietHoursEnd   string              `json:"quiet_hours_end"`   // "08:00"
+	DigestMode      string              `json:"digest_mode"`       // off, hourly, daily
+}
+
+// notification.command topic'ine yazılan bildirim komutu.
+// UserID 0 ise Email'e (CRM kullanıcısı olmayan alıcı, ör. müşteri) tercihlere bakılmadan e-posta gönderilir.
+type Command struct {
+	UserID      int          `json:"user_id"`
+	Email       string       `json:"email,omitempty"`
+	Event       string       `json:"event"`
+	Subject     string       `json:"subject"`
+	Body        string       `json:"body"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// E-posta eki (ör. takvim daveti için text/calendar); SMS ve WebSocket kanallarında yok sayılır
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Sessiz saat veya özet modu nedeniyle ertelenmiş bildirim
+type QueuedNotification struct {
+	ID           int
+	UserID       int
+	Channel      string
+	Event        string
+	Subject      string
+	Body         string
+	Attachments  []Attachment
+	DeliverAfter time.Time
+	Attempts     int // Başarısız gönderim denemesi sayısı
+	CreatedAt    time.Time
+}
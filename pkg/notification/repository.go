@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Kullanıcının tercihlerini getirir, kayıt yoksa varsayılanları döner
+func getPreferencesRepo(db *sql.DB, userID int) (Preferences, error) {
+	p := DefaultPreferences(userID)
+	var events []byte
+	err := db.QueryRow(
+		"SELECT events, timezone, quiet_hours_start, quiet_hours_end, digest_mode FROM notification_preferences WHERE user_id = $1",
+		userID,
+	).Scan(&events, &p.Timezone, &p.QuietHoursStart, &p.QuietHoursEnd, &p.DigestMode)
+	if err == sql.ErrNoRows {
+		return p, nil
+	}
+	if err != nil {
+		return Preferences{}, err
+	}
+	if err := json.Unmarshal(events, &p.Events); err != nil {
+		return Preferences{}, err
+	}
+	return p, nil
+}
+
+// Tercihleri ekler veya günceller
+func upsertPreferencesRepo(db *sql.DB, p Preferences) error {
+	events, err := json.Marshal(p.Events)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO notification_preferences (user_id, events, timezone, quiet_hours_start, quiet_hours_end, digest_mode, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (user_id) DO UPDATE SET
+			events = EXCLUDED.events,
+			timezone = EXCLUDED.timezone,
+			quiet_hours_start = EXCLUDED.quiet_hours_start,
+			quiet_hours_end = EXCLUDED.quiet_hours_end,
+			digest_mode = EXCLUDED.digest_mode,
+			updated_at = now()`,
+		p.UserID, events, p.Timezone, p.QuietHoursStart, p.QuietHoursEnd, p.DigestMode,
+	)
+	return err
+}
+
+// Bildirimi daha sonra gönderilmek üzere kuyruğa ekler
+func enqueueRepo(db *sql.DB, n QueuedNotification) error {
+	attachments, err := json.Marshal(n.Attachments)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(
+		"INSERT INTO notification_queue (user_id, channel, event, subject, body, attachments, deliver_after) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		n.UserID, n.Channel, n.Event, n.Subject, n.Body, attachments, n.DeliverAfter,
+	)
+	return err
+}
+
+// Zamanı gelmiş bildirimleri kilitleyip lease süresi kadar ileri atar; birden fazla notification-svc
+// aynı kaydı almaz, gönderemeden çöken kopyanın aldıkları lease bitince yeniden denenir.
+// Her seferde en fazla limit kayıt alınır; kayıtlar ancak gönderim başarılı olunca deleteQueuedRepo ile silinir.
+func claimDueRepo(db *sql.DB, now time.Time, lease time.Duration, limit int) ([]QueuedNotification, error) {
+	rows, err := db.Query(`
+		WITH due AS (
+			SELECT id FROM notification_queue WHERE deliver_after <= $1
+			ORDER BY deliver_after LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notification_queue q SET deliver_after = $1::timestamptz + $2 * interval '1 second'
+		FROM due WHERE q.id = due.id
+		RETURNING q.id, q.user_id, q.channel, q.event, q.subject, q.body, q.attachments, q.attempts, q.created_at`,
+		now, int(lease.Seconds()), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []QueuedNotification
+	for rows.Next() {
+		var n QueuedNotification
+		var attachments []byte
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Channel, &n.Event, &n.Subject, &n.Body, &attachments, &n.Attempts, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attachments, &n.Attachments); err != nil {
+			return nil, err
+		}
+		items = append(items, n)
+	}
+	return items, rows.Err()
+}
+
+// Gönderilen bildirimleri kuyruktan siler
+func deleteQueuedRepo(db *sql.DB, ids []int64) error {
+	_, err := db.Exec("DELETE FROM notification_queue WHERE id = ANY($1)", pq.Array(ids))
+	return err
+}
+
+// Gönderilemeyen bildirimlerin deneme sayısını artırır ve bir sonraki denemeye erteler
+func retryQueuedRepo(db *sql.DB, ids []int64, next time.Time) error {
+	_, err := db.Exec(
+		"UPDATE notification_queue SET attempts = attempts + 1, deliver_after = $2 WHERE id = ANY($1)",
+		pq.Array(ids), next,
+	)
+	return err
+}
+
+// Bildirim alıcısının e-posta adresini getirir
+func getUserEmailRepo(db *sql.DB, userID int) (string, error) {
+	var email string
+	err := db.QueryRow("SELECT email FROM users WHERE id = $1", userID).Scan(&email)
+	return email, err
+}
@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kanal adaptörlerine iletilen mesaj
+type Message struct {
+	UserID      int
+	To          string // E-posta adresi (e-posta kanalı için)
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Kanal adaptörü (e-posta, SMS, WebSocket)
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mesajı sadece loglayan adaptör (henüz entegrasyonu olmayan kanallar için)
+type LogSender struct {
+	Channel string
+}
+
+func (s LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("[%s] kullanıcı=%d konu=%q", s.Channel, msg.UserID, msg.Subject)
+	return nil
+}
+
+// SMTP üzerinden e-posta gönderen adaptör
+type SMTPSender struct {
+	Addr string // host:port
+	From string
+	Auth smtp.Auth
+}
+
+func (s SMTPSender) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("kullanıcı %d için e-posta adresi yok", msg.UserID)
+	}
+	data, err := BuildEmail(s.From, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{msg.To}, data)
+}
+
+// E-postayı MIME olarak oluşturur. Ek yoksa düz metin, varsa metin + ekler (multipart/mixed).
+// text/calendar ekleri ayrıca satır içi de eklenir ki istemciler daveti kabul/ret düğmeleriyle göstersin.
+func BuildEmail(from string, msg Message) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		b.WriteString(msg.Body)
+		return b.Bytes(), nil
+	}
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	io.WriteString(part, msg.Body)
+	for _, a := range msg.Attachments {
+		if strings.HasPrefix(a.ContentType, "text/calendar") {
+			inline, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {a.ContentType},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			writeBase64(inline, a.Data)
+		}
+		att, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType + "; name=" + strconv.Quote(a.Filename)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(att, a.Data)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// RFC 2045: base64 satırları en fazla 76 karakter
+func writeBase64(w io.Writer, data []byte) {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		io.WriteString(w, enc[:76]+"\r\n")
+		enc = enc[76:]
+	}
+	io.WriteString(w, enc+"\r\n")
+}
+
+// Ortam değişkenlerine göre kanal adaptörlerini oluşturur.
+// SMTP_ADDR tanımlı değilse e-postalar da sadece loglanır.
+func DefaultSenders() map[string]Sender {
+	senders := map[string]Sender{
+		ChannelEmail:     LogSender{Channel: ChannelEmail},
+		ChannelSMS:       LogSender{Channel: ChannelSMS},
+		ChannelWebSocket: LogSender{Channel: ChannelWebSocket},
+	}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			from = "crm@localhost"
+		}
+		var auth smtp.Auth
+		if user := os.Getenv("SMTP_USER"); user != "" {
+			host := addr
+			if i := strings.LastIndex(addr, ":"); i != -1 {
+				host = addr[:i]
+			}
+			auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		senders[ChannelEmail] = SMTPSender{Addr: addr, From: from, Auth: auth}
+	}
+	return senders
+}
@@ -0,0 +1,346 @@
+package notification
+
+import (
+	"Go-CRM/pkg/common"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// notification-svc'nin dinlediği komut topic'i
+const CommandTopic = "notification.command"
+
+// Bildirim komutunu notification-svc'ye iletir
+func Publish(ctx context.Context, cmd Command) error {
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	return common.PublishToTopic(ctx, CommandTopic, fmt.Sprintf("user-%d", cmd.UserID), string(payload))
+}
+
+// Tercih tanımlanmamış olaylar için kullanılan kanallar
+var DefaultChannels = []string{ChannelEmail}
+
+// Günlük özetin kullanıcının yerel saatine göre gönderileceği saat
+const DailyDigestHour = 8
+
+// Tercih kaydı olmayan kullanıcı için varsayılanlar
+func DefaultPreferences(userID int) Preferences {
+	return Preferences{
+		UserID:     userID,
+		Events:     map[string][]string{},
+		Timezone:   "Europe/Istanbul",
+		DigestMode: DigestOff,
+	}
+}
+
+// Olay için kullanılacak kanalları döner
+func (p Preferences) ChannelsFor(event string) []string {
+	if channels, ok := p.Events[event]; ok {
+		return channels
+	}
+	return DefaultChannels
+}
+
+// Bildirimin gönderilebileceği en erken zamanı hesaplar.
+// Özet modu açıksa bir sonraki özet zamanına, sessiz saatler içindeyse sessiz saatlerin bitişine ertelenir.
+// Dönen değer now'a eşitse bildirim hemen gönderilmelidir.
+func DeliveryTime(p Preferences, now time.Time) time.Time {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	at := local
+
+	switch p.DigestMode {
+	case DigestHourly:
+		at = local.Truncate(time.Hour).Add(time.Hour)
+	case DigestDaily:
+		at = time.Date(local.Year(), local.Month(), local.Day(), DailyDigestHour, 0, 0, 0, loc)
+		if !at.After(local) {
+			at = at.AddDate(0, 0, 1)
+		}
+	}
+
+	if until, ok := quietUntil(p, at); ok {
+		at = until
+	}
+	if at.Equal(local) {
+		return now
+	}
+	return at
+}
+
+// t sessiz saatler içindeyse sessiz saatlerin bittiği zamanı döner
+func quietUntil(p Preferences, t time.Time) (time.Time, bool) {
+	start, okStart := parseClock(p.QuietHoursStart)
+	end, okEnd := parseClock(p.QuietHoursEnd)
+	if !okStart || !okEnd || start == end {
+		return time.Time{}, false
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	endToday := time.Date(t.Year(), t.Month(), t.Day(), end/60, end%60, 0, 0, t.Location())
+	if start < end {
+		// Örn. 13:00-14:00
+		if minutes >= start && minutes < end {
+			return endToday, true
+		}
+		return time.Time{}, false
+	}
+	// Gece yarısını aşan aralık, örn. 22:00-08:00
+	if minutes >= start {
+		return endToday.AddDate(0, 0, 1), true
+	}
+	if minutes < end {
+		return endToday, true
+	}
+	return time.Time{}, false
+}
+
+// "HH:MM" biçimindeki saati gün içindeki dakikaya çevirir
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// Kullanıcının bildirim tercihlerini getirir
+func GetPreferences(db *sql.DB, userID int) (Preferences, error) {
+	if userID <= 0 {
+		return Preferences{}, errors.New("Geçersiz kullanıcı ID")
+	}
+	return getPreferencesRepo(db, userID)
+}
+
+// Bildirim tercihlerini kaydeder (validasyon)
+func SavePreferences(db *sql.DB, p *Preferences) error {
+	if p.Events == nil {
+		p.Events = map[string][]string{}
+	}
+	if p.Timezone == "" {
+		p.Timezone = "Europe/Istanbul"
+	}
+	if p.DigestMode == "" {
+		p.DigestMode = DigestOff
+	}
+	if err := validatePreferences(*p); err != nil {
+		return err
+	}
+	return upsertPreferencesRepo(db, *p)
+}
+
+// Komutu kullanıcının tercihlerine göre hemen gönderir veya kuyruğa ekler
+func Dispatch(ctx context.Context, db *sql.DB, senders map[string]Sender, cmd Command) error {
+	if cmd.UserID == 0 && cmd.Email != "" {
+		// Tercihi ve hesabı olmayan alıcı: sadece e-posta, hemen
+		msg := Message{To: cmd.Email, Subject: cmd.Subject, Body: cmd.Body, Attachments: cmd.Attachments}
+		return send(ctx, db, senders, ChannelEmail, msg)
+	}
+	prefs, err := GetPreferences(db, cmd.UserID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	deliverAt := DeliveryTime(prefs, now)
+	for _, channel := range prefs.ChannelsFor(cmd.Event) {
+		if deliverAt.After(now) {
+			err := enqueueRepo(db, QueuedNotification{
+				UserID:       cmd.UserID,
+				Channel:      channel,
+				Event:        cmd.Event,
+				Subject:      cmd.Subject,
+				Body:         cmd.Body,
+				Attachments:  cmd.Attachments,
+				DeliverAfter: deliverAt,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		msg := Message{UserID: cmd.UserID, Subject: cmd.Subject, Body: cmd.Body, Attachments: cmd.Attachments}
+		if err := send(ctx, db, senders, channel, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	MaxFlushAttempts = 8 // Ertelenmiş bir bildirim için toplam gönderim denemesi
+
+	flushLease     = 5 * time.Minute // Alınan kayıtların başka kopyaya verilmeyeceği süre
+	flushBatchSize = 200             // Tek seferde alınan kayıt sayısı; kalanları diğer kopyalar paylaşır
+	baseRetryDelay = time.Minute
+	maxRetryDelay  = 2 * time.Hour
+)
+
+// n. başarısız gönderimden sonraki bekleme: 1dk, 2dk, 4dk, ... en fazla 2 saat
+func RetryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := baseRetryDelay
+	for i := 1; i < attempt && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d
+}
+
+// Zamanı gelmiş bildirimleri kullanıcı ve kanal bazında gruplayıp gönderir.
+// Tek bildirim olduğu gibi, birden fazlası tek bir özet mesajı olarak iletilir.
+// Kayıtlar gönderim başarılı olunca silinir; başarısız gruplar artan beklemeyle yeniden kuyruğa alınır,
+// MaxFlushAttempts denemeden sonra bırakılır.
+// Kuyruk flushBatchSize'lık partilerle alınır; dolu bir parti geldiyse sıradaki parti beklemeden alınır.
+// Parti sınırına denk gelen bir grubun kalanı ayrı bir özet olarak gider.
+func FlushDue(ctx context.Context, db *sql.DB, senders map[string]Sender) error {
+	for {
+		n, err := flushBatch(ctx, db, senders)
+		if err != nil || n < flushBatchSize || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// Bir parti bildirimi alıp gönderir, alınan kayıt sayısını döner
+func flushBatch(ctx context.Context, db *sql.DB, senders map[string]Sender) (int, error) {
+	items, err := claimDueRepo(db, time.Now(), flushLease, flushBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	type groupKey struct {
+		userID  int
+		channel string
+	}
+	groups := map[groupKey][]QueuedNotification{}
+	var keys []groupKey
+	for _, n := range items {
+		k := groupKey{n.UserID, n.Channel}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], n)
+	}
+	for _, k := range keys {
+		group := groups[k]
+		ids := make([]int64, len(group))
+		attempts := 0
+		for i, n := range group {
+			ids[i] = int64(n.ID)
+			if n.Attempts > attempts {
+				attempts = n.Attempts
+			}
+		}
+		msg := digestMessage(group)
+		msg.UserID = k.userID
+		sendErr := send(ctx, db, senders, k.channel, msg)
+		if sendErr == nil {
+			if err := deleteQueuedRepo(db, ids); err != nil {
+				log.Printf("Gönderilen bildirimler kuyruktan silinemedi (kullanıcı=%d, kanal=%s): %v", k.userID, k.channel, err)
+			}
+			continue
+		}
+		attempts++
+		if attempts >= MaxFlushAttempts {
+			log.Printf("Özet bildirimi %d denemede gönderilemedi, bırakılıyor (kullanıcı=%d, kanal=%s): %v", attempts, k.userID, k.channel, sendErr)
+			if err := deleteQueuedRepo(db, ids); err != nil {
+				log.Printf("Bildirimler kuyruktan silinemedi (kullanıcı=%d, kanal=%s): %v", k.userID, k.channel, err)
+			}
+			continue
+		}
+		log.Printf("Özet bildirimi gönderilemedi, yeniden denenecek (kullanıcı=%d, kanal=%s, deneme=%d): %v", k.userID, k.channel, attempts, sendErr)
+		if err := retryQueuedRepo(db, ids, time.Now().Add(RetryDelay(attempts))); err != nil {
+			log.Printf("Bildirimler yeniden kuyruğa alınamadı (kullanıcı=%d, kanal=%s): %v", k.userID, k.channel, err)
+		}
+	}
+	return len(items), nil
+}
+
+// Birden fazla bildirimi tek bir özet mesajına dönüştürür; ekler özet mesajına taşınır
+func digestMessage(items []QueuedNotification) Message {
+	if len(items) == 1 {
+		return Message{Subject: items[0].Subject, Body: items[0].Body, Attachments: items[0].Attachments}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	var b strings.Builder
+	var attachments []Attachment
+	for _, n := range items {
+		attachments = append(attachments, n.Attachments...)
+		fmt.Fprintf(&b, "- [%s] %s\n", n.CreatedAt.Format("02-01-2006 15:04"), n.Subject)
+		if n.Body != "" {
+			fmt.Fprintf(&b, "  %s\n", strings.ReplaceAll(n.Body, "\n", "\n  "))
+		}
+	}
+	return Message{
+		Subject:     fmt.Sprintf("%d yeni bildirim", len(items)),
+		Body:        b.String(),
+		Attachments: attachments,
+	}
+}
+
+func send(ctx context.Context, db *sql.DB, senders map[string]Sender, channel string, msg Message) error {
+	sender, ok := senders[channel]
+	if !ok {
+		return fmt.Errorf("bilinmeyen bildirim kanalı: %s", channel)
+	}
+	if channel == ChannelEmail && msg.To == "" {
+		email, err := getUserEmailRepo(db, msg.UserID)
+		if err != nil {
+			return err
+		}
+		msg.To = email
+	}
+	return sender.Send(ctx, msg)
+}
+
+// --- Validasyon Fonksiyonları ---
+func validatePreferences(p Preferences) error {
+	if p.UserID <= 0 {
+		return errors.New("Geçersiz kullanıcı ID")
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return errors.New("Geçersiz saat dilimi")
+	}
+	if (p.QuietHoursStart == "") != (p.QuietHoursEnd == "") {
+		return errors.New("Sessiz saatlerin başlangıcı ve bitişi birlikte verilmeli")
+	}
+	if p.QuietHoursStart != "" {
+		if _, ok := parseClock(p.QuietHoursStart); !ok {
+			return errors.New("Sessiz saat başlangıcı HH:MM biçiminde olmalı")
+		}
+		if _, ok := parseClock(p.QuietHoursEnd); !ok {
+			return errors.New("Sessiz saat bitişi HH:MM biçiminde olmalı")
+		}
+	}
+	switch p.DigestMode {
+	case DigestOff, DigestHourly, DigestDaily:
+	default:
+		return errors.New("Geçersiz özet modu")
+	}
+	for event, channels := range p.Events {
+		if strings.TrimSpace(event) == "" {
+			return errors.New("Olay tipi boş olamaz")
+		}
+		for _, c := range channels {
+			switch c {
+			case ChannelEmail, ChannelSMS, ChannelWebSocket:
+			default:
+				return fmt.Errorf("Geçersiz bildirim kanalı: %s", c)
+			}
+		}
+	}
+	return nil
+}
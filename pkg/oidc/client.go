@@ -0,0 +1,340 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrIssuerMismatch = errors.New("Sağlayıcının bildirdiği issuer yapılandırmayla uyuşmuyor")
+	ErrTokenExchange  = errors.New("Yetkilendirme kodu token ile değiştirilemedi")
+	ErrInvalidIDToken = errors.New("ID token geçersiz")
+	ErrUnknownKey     = errors.New("Token imza anahtarı bulunamadı")
+)
+
+// Anahtarı bilinmeyen bir token gelirse JWKS en fazla bu sıklıkla yeniden çekilir
+const jwksRefreshInterval = time.Minute
+
+// Tek bir sağlayıcı ve istemci için OIDC işlemleri. JWKS anahtarları önbellekte tutulur,
+// sağlayıcı anahtar değiştirdiğinde (bilinmeyen kid) yeniden çekilir.
+type Client struct {
+	Provider Provider
+	Config   Config
+	HTTP     *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// Discovery belgesini okur ve istemciyi hazırlar
+func Discover(ctx context.Context, httpClient *http.Client, cfg Config) (*Client, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	issuer := strings.TrimSuffix(cfg.IssuerURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var p Provider
+	if err := getJSON(httpClient, req, &p); err != nil {
+		return nil, fmt.Errorf("OIDC discovery okunamadı: %w", err)
+	}
+	// Discovery belgesi başka bir issuer bildiriyorsa token'lar da onunla imzalanır; kabul edilmez
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("%w: %s", ErrIssuerMismatch, p.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery belgesinde uç noktalar eksik")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Client{Provider: p, Config: cfg, HTTP: httpClient}, nil
+}
+
+func getJSON(c *http.Client, req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %d %s", req.URL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// PKCE doğrulayıcısı (43 karakter) ve S256 challenge'ı
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return verifier, S256Challenge(verifier), nil
+}
+
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Kullanıcının yönlendirileceği yetkilendirme adresi
+func (c *Client) AuthCodeURL(state, nonce, challenge string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.Config.ClientID},
+		"redirect_uri":          {c.Config.RedirectURL},
+		"scope":                 {strings.Join(c.Config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(c.Provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return c.Provider.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Yetkilendirme kodunu PKCE doğrulayıcısıyla token'a çevirir. Confidential client'ta
+// secret Basic auth ile gönderilir.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.Config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if c.Config.ClientSecret == "" {
+		form.Set("client_id", c.Config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.Config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.Config.ClientID), url.QueryEscape(c.Config.ClientSecret))
+	}
+	var t Token
+	if err := getJSON(c.HTTP, req, &t); err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	if t.IDToken == "" {
+		return Token{}, fmt.Errorf("%w: yanıtta id_token yok", ErrTokenExchange)
+	}
+	return t, nil
+}
+
+type idClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Roles             []string `json:"roles"`
+	RealmAccess       struct {
+		Roles []string `json:"roles"`
+	} `json:"realm_access"`
+	ResourceAccess map[string]struct {
+		Roles []string `json:"roles"`
+	} `json:"resource_access"`
+}
+
+// ID token'ın imzasını, issuer, audience, süre ve nonce'unu doğrular
+func (c *Client) VerifyIDToken(ctx context.Context, raw, nonce string) (Identity, error) {
+	claims, err := c.verify(ctx, raw, true)
+	if err != nil {
+		return Identity{}, err
+	}
+	// Birden fazla audience varsa token bu istemci için verilmiş olmalı
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != c.Config.ClientID {
+		return Identity{}, fmt.Errorf("%w: azp uyuşmuyor", ErrInvalidIDToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return Identity{}, fmt.Errorf("%w: nonce uyuşmuyor", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: sub yok", ErrInvalidIDToken)
+	}
+	return c.identity(claims), nil
+}
+
+// Keycloak rolleri varsayılan olarak sadece access token'a koyar. Access token da aynı realm
+// anahtarıyla imzalı bir JWT ise roller oradan okunur (audience'ı genelde "account" olduğu için
+// audience kontrol edilmez, issuer ve süre edilir).
+func (c *Client) RolesFromAccessToken(ctx context.Context, raw string) ([]string, error) {
+	claims, err := c.verify(ctx, raw, false)
+	if err != nil {
+		return nil, err
+	}
+	return c.identity(claims).Roles, nil
+}
+
+func (c *Client) identity(claims *idClaims) Identity {
+	roles := append([]string{}, claims.Roles...)
+	roles = append(roles, claims.RealmAccess.Roles...)
+	if ra, ok := claims.ResourceAccess[c.Config.ClientID]; ok {
+		roles = append(roles, ra.Roles...)
+	}
+	id := Identity{
+		Subject:           claims.Subject,
+		Email:             strings.TrimSpace(claims.Email),
+		EmailVerified:     claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+		Roles:             roles,
+	}
+	if claims.ExpiresAt != nil {
+		id.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return id
+}
+
+func (c *Client) verify(ctx context.Context, raw string, checkAudience bool) (*idClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "PS256"}),
+		jwt.WithIssuer(c.Provider.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	}
+	if checkAudience {
+		opts = append(opts, jwt.WithAudience(c.Config.ClientID))
+	}
+	claims := &idClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.key(ctx, kid)
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	return claims, nil
+}
+
+// kid'e karşılık gelen açık anahtar; bulunamazsa JWKS yeniden çekilir
+func (c *Client) key(ctx context.Context, kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+	if c.keys != nil && time.Since(c.fetchedAt) < jwksRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+	keys, err := c.fetchJWKS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.keys, c.fetchedAt = keys, time.Now()
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// kid verilmemişse ve tek anahtar varsa o kullanılır
+func (c *Client) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+	k, ok := c.keys[kid]
+	return k, ok
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (c *Client) fetchJWKS(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Provider.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(c.HTTP, req, &set); err != nil {
+		return nil, fmt.Errorf("JWKS okunamadı: %w", err)
+	}
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		// Şifreleme anahtarları (use=enc) imza doğrulamada kullanılmaz
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := parseJWK(k); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	return keys, nil
+}
+
+func parseJWK(k jwk) (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("desteklenmeyen eğri: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("desteklenmeyen anahtar tipi: %s", k.Kty)
+	}
+}
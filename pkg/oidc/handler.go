@@ -0,0 +1,140 @@
+package oidc
+
+import (
+	"Go-CRM/pkg/common"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	stateCookie = "crm_oidc"
+	stateTTL    = 10 * time.Minute
+)
+
+// auth-svc'nin giriş uç noktaları. Kullanıcı kaydı ve CRM token'ı üretimi dışarıdan verilir;
+// böylece akış veritabanı olmadan sahte bir sağlayıcıyla test edilebilir.
+type Handler struct {
+	Client      *Client
+	StateKey    []byte            // State çerezinin HMAC anahtarı
+	RoleMapping map[string]string // Sağlayıcı rolü -> CRM rolü
+	// Giriş sonrası yönlendirilecek adres; token URL fragment'ında (#token=...) gönderilir.
+	// Boşsa token JSON olarak döner.
+	PostLoginURL string
+	Provision    func(ctx context.Context, id Identity, role string) (common.AuthUser, error)
+	IssueToken   func(u common.AuthUser) (string, error)
+}
+
+// Girişi başlatır (GET /login): state, nonce ve PKCE doğrulayıcısı imzalı çereze yazılır,
+// kullanıcı sağlayıcının giriş sayfasına yönlendirilir
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	st, err := NewLoginState(stateTTL, time.Now())
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Giriş başlatılamadı", err)
+		return
+	}
+	value, err := EncodeState(h.StateKey, st)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Giriş başlatılamadı", err)
+		return
+	}
+	http.SetCookie(w, h.cookie(value, int(stateTTL.Seconds())))
+	http.Redirect(w, r, h.Client.AuthCodeURL(st.State, st.Nonce, S256Challenge(st.Verifier)), http.StatusFound)
+}
+
+// Sağlayıcıdan dönüş (GET /callback?code=...&state=...): kod token'a çevrilir, ID token doğrulanır,
+// roller CRM rolüne eşlenir, kullanıcı bulunur/oluşturulur ve CRM JWT'si verilir
+func (h *Handler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	// Çerez tek kullanımlıktır
+	http.SetCookie(w, h.cookie("", -1))
+
+	if e := q.Get("error"); e != "" {
+		common.WriteError(w, http.StatusUnauthorized, "Kimlik sağlayıcı girişi reddetti", errors.New(e+": "+q.Get("error_description")))
+		return
+	}
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, ErrStateInvalid.Error(), err)
+		return
+	}
+	st, err := DecodeState(h.StateKey, c.Value, time.Now())
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		common.WriteError(w, http.StatusBadRequest, ErrStateInvalid.Error(), errors.New("state uyuşmuyor"))
+		return
+	}
+	code := q.Get("code")
+	if code == "" {
+		common.WriteError(w, http.StatusBadRequest, "Yetkilendirme kodu eksik", nil)
+		return
+	}
+
+	tok, err := h.Client.Exchange(r.Context(), code, st.Verifier)
+	if err != nil {
+		common.WriteError(w, http.StatusBadGateway, "Kimlik sağlayıcıdan token alınamadı", err)
+		return
+	}
+	id, err := h.Client.VerifyIDToken(r.Context(), tok.IDToken, st.Nonce)
+	if err != nil {
+		common.WriteError(w, http.StatusUnauthorized, ErrInvalidIDToken.Error(), err)
+		return
+	}
+	role, err := MapRole(id.Roles, h.RoleMapping)
+	if errors.Is(err, ErrNoCRMRole) && tok.AccessToken != "" {
+		if roles, aerr := h.Client.RolesFromAccessToken(r.Context(), tok.AccessToken); aerr == nil {
+			id.Roles = append(id.Roles, roles...)
+			role, err = MapRole(id.Roles, h.RoleMapping)
+		}
+	}
+	if err != nil {
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	user, err := h.Provision(r.Context(), id, role)
+	switch {
+	case errors.Is(err, ErrEmailRequired), errors.Is(err, ErrEmailTaken):
+		common.WriteError(w, http.StatusConflict, err.Error(), err)
+		return
+	case err != nil:
+		common.WriteError(w, http.StatusInternalServerError, "Kullanıcı oluşturulamadı", err)
+		return
+	}
+	token, err := h.IssueToken(user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Token oluşturulamadı", err)
+		return
+	}
+
+	if h.PostLoginURL == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+		return
+	}
+	// Fragment sunucu loglarına ve Referer başlığına düşmez
+	target := strings.SplitN(h.PostLoginURL, "#", 2)[0] + "#" + url.Values{"token": {token}}.Encode()
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// Sağlayıcıdan dönüş cross-site bir GET olduğu için SameSite=Lax gerekir
+func (h *Handler) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.Client.Config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
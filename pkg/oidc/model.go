@@ -0,0 +1,51 @@
+package oidc
+
+import "time"
+
+// Sağlayıcının /.well-known/openid-configuration yanıtından kullanılan alanlar
+type Provider struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	EndSessionEndpoint    string   `json:"end_session_endpoint,omitempty"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// İstemci ayarları (Keycloak'ta "crm" client'ı)
+type Config struct {
+	IssuerURL    string // Ör. http://keycloak:8080/realms/core
+	ClientID     string
+	ClientSecret string // Public client için boş
+	RedirectURL  string // Ör. http://localhost:8082/callback
+	Scopes       []string
+}
+
+// Token uç noktasının yanıtı
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// Doğrulanmış ID token'dan CRM'in kullandığı bilgiler. Roller realm_access.roles,
+// resource_access.<client_id>.roles ve üst seviye roles claim'lerinden toplanır.
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Roles             []string
+	ExpiresAt         time.Time
+}
+
+// Giriş başlarken üretilen ve geri dönüşte karşılaştırılan değerler; imzalı çerezde taşınır
+type LoginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
@@ -0,0 +1,46 @@
+package oidc
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+)
+
+func findUserBySubjectRepo(db *sql.DB, issuer, subject string) (common.AuthUser, error) {
+	var u common.AuthUser
+	err := db.QueryRow("SELECT id, email, role, tenant_id FROM users WHERE oidc_issuer = $1 AND oidc_subject = $2",
+		issuer, subject).Scan(&u.ID, &u.Email, &u.Role, &u.TenantID)
+	return u, err
+}
+
+// E-postayla kullanıcı; linked başka bir OIDC kimliğine bağlı olup olmadığını gösterir
+func findUserByEmailRepo(db *sql.DB, email string) (common.AuthUser, bool, error) {
+	var u common.AuthUser
+	var linked bool
+	err := db.QueryRow("SELECT id, email, role, tenant_id, oidc_subject IS NOT NULL FROM users WHERE lower(email) = lower($1)",
+		email).Scan(&u.ID, &u.Email, &u.Role, &u.TenantID, &linked)
+	return u, linked, err
+}
+
+func updateUserRoleRepo(db *sql.DB, userID int, role string) error {
+	_, err := db.Exec("UPDATE users SET role = $1 WHERE id = $2", role, userID)
+	return err
+}
+
+// Eşzamanlı iki girişte aynı kullanıcı iki kimliğe bağlanmasın diye sadece bağlı değilse güncellenir
+func linkUserRepo(db *sql.DB, userID int, issuer, subject, role string) error {
+	res, err := db.Exec(`UPDATE users SET oidc_issuer = $1, oidc_subject = $2, role = $3
+		WHERE id = $4 AND oidc_subject IS NULL`, issuer, subject, role, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+func createUserRepo(db *sql.DB, u *common.AuthUser, password, issuer, subject string) error {
+	return db.QueryRow(`INSERT INTO users (email, password, role, tenant_id, oidc_issuer, oidc_subject)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		u.Email, password, u.Role, u.TenantID, issuer, subject).Scan(&u.ID)
+}
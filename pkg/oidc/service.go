@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"Go-CRM/pkg/common"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCRMRole      = errors.New("Kullanıcının CRM'e erişim rolü yok")
+	ErrEmailRequired  = errors.New("Kimlik sağlayıcı e-posta adresi göndermedi")
+	ErrEmailTaken     = errors.New("Bu e-posta başka bir kimlikle bağlı bir kullanıcıya ait")
+	ErrStateInvalid   = errors.New("Giriş oturumu geçersiz")
+	ErrStateExpired   = errors.New("Giriş oturumunun süresi doldu, tekrar deneyin")
+	errInvalidRoleMap = errors.New("Geçersiz rol eşlemesi")
+)
+
+// CRM rollerinin yetki sırası; kullanıcının birden fazla eşleşen rolü varsa en yükseği alınır
+var roleRank = map[string]int{common.RoleRep: 1, common.RoleManager: 2, common.RoleAdmin: 3}
+
+// Keycloak realm-export.json'daki realm rolleri için varsayılan eşleme. customer ve system
+// rollerinin CRM'de karşılığı yoktur, bu rollere sahip kullanıcılar giriş yapamaz.
+const DefaultRoleMapping = "admin=admin,manager=manager,staff=rep"
+
+// "keycloak_rolü=crm_rolü,..." biçimindeki eşlemeyi çözer
+func ParseRoleMapping(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || roleRank[to] == 0 {
+			return nil, fmt.Errorf("%w: %q", errInvalidRoleMap, pair)
+		}
+		m[from] = to
+	}
+	if len(m) == 0 {
+		return nil, errInvalidRoleMap
+	}
+	return m, nil
+}
+
+// Sağlayıcı rollerini CRM rolüne çevirir; eşleşen rol yoksa ErrNoCRMRole
+func MapRole(roles []string, mapping map[string]string) (string, error) {
+	best := ""
+	for _, r := range roles {
+		if crm, ok := mapping[r]; ok && roleRank[crm] > roleRank[best] {
+			best = crm
+		}
+	}
+	if best == "" {
+		return "", ErrNoCRMRole
+	}
+	return best, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Yeni giriş için state, nonce ve PKCE doğrulayıcısı üretir
+func NewLoginState(ttl time.Duration, now time.Time) (LoginState, error) {
+	state, err := randomString(24)
+	if err != nil {
+		return LoginState{}, err
+	}
+	nonce, err := randomString(24)
+	if err != nil {
+		return LoginState{}, err
+	}
+	verifier, _, err := NewPKCE()
+	if err != nil {
+		return LoginState{}, err
+	}
+	return LoginState{State: state, Nonce: nonce, Verifier: verifier, Expires: now.Add(ttl).Unix()}, nil
+}
+
+// Durumu çerezde taşımak için <base64 json>.<base64 hmac> biçiminde imzalar; auth-svc durumsuz
+// kalır ve birden fazla kopya arkasında da çalışır
+func EncodeState(key []byte, s LoginState) (string, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(stateMAC(key, payload)), nil
+}
+
+func DecodeState(key []byte, value string, now time.Time) (LoginState, error) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return LoginState{}, ErrStateInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, stateMAC(key, payload)) {
+		return LoginState{}, ErrStateInvalid
+	}
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return LoginState{}, ErrStateInvalid
+	}
+	var s LoginState
+	if err := json.Unmarshal(body, &s); err != nil {
+		return LoginState{}, ErrStateInvalid
+	}
+	if now.Unix() > s.Expires {
+		return LoginState{}, ErrStateExpired
+	}
+	return s, nil
+}
+
+func stateMAC(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("oidc-state:"))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Giriş yapan kimliği CRM kullanıcısına bağlar (JIT provisioning):
+//   - Daha önce bu issuer/sub ile giriş yapmışsa rolü sağlayıcıdakiyle güncellenir
+//   - Doğrulanmış e-postası mevcut bir yerel kullanıcıyla eşleşiyorsa o kullanıcıya bağlanır
+//   - Hiçbiri yoksa tenantID altında şifresiz (şifreyle giriş yapamayan) kullanıcı oluşturulur
+func ProvisionUser(db *sql.DB, issuer string, id Identity, role string, tenantID int) (common.AuthUser, error) {
+	u, err := findUserBySubjectRepo(db, issuer, id.Subject)
+	if err == nil {
+		u.Role = role
+		return u, updateUserRoleRepo(db, u.ID, role)
+	}
+	if err != sql.ErrNoRows {
+		return common.AuthUser{}, err
+	}
+
+	if id.Email == "" {
+		return common.AuthUser{}, ErrEmailRequired
+	}
+	u, linked, err := findUserByEmailRepo(db, id.Email)
+	if err == nil {
+		// Doğrulanmamış e-postayla mevcut hesabı ele geçirmek engellenir
+		if linked || !id.EmailVerified {
+			return common.AuthUser{}, ErrEmailTaken
+		}
+		u.Role = role
+		return u, linkUserRepo(db, u.ID, issuer, id.Subject, role)
+	}
+	if err != sql.ErrNoRows {
+		return common.AuthUser{}, err
+	}
+
+	// Şifre alanı geçerli bir bcrypt özeti olmadığı için bu kullanıcı şifreyle giriş yapamaz
+	placeholder, err := randomString(16)
+	if err != nil {
+		return common.AuthUser{}, err
+	}
+	u = common.AuthUser{Email: id.Email, Role: role, TenantID: tenantID}
+	if err := createUserRepo(db, &u, "!oidc:"+placeholder, issuer, id.Subject); err != nil {
+		return common.AuthUser{}, err
+	}
+	return u, nil
+}
@@ -0,0 +1,96 @@
+package password
+
+import (
+	"Go-CRM/pkg/apikey"
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+	Policy    Policy
+	// Sıfırlama e-postasındaki bağlantının adresi; token #token=... olarak eklenir
+	ResetURL string
+	ResetTTL time.Duration
+	// Şifre değişikliğinden sonra çağırana verilecek yeni oturum token'ı
+	IssueToken func(user common.AuthUser) (string, error)
+}
+
+// Şifre kuralları (GET /api/password/policy)
+func (h *Handler) GetPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Policy.Info())
+}
+
+// Sıfırlama bağlantısı isteği (POST /api/password/forgot) {"email": "..."}; e-posta kayıtlı olsun
+// olmasın aynı yanıt döner
+func (h *Handler) ForgotHandler(w http.ResponseWriter, r *http.Request) {
+	var req ForgotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := RequestReset(r.Context(), h.DBPrimary, req.Email, h.ResetURL, apikey.RequestIP(r), h.ResetTTL, time.Now()); err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Şifre sıfırlama isteği işlenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"message": "E-posta adresi kayıtlıysa şifre sıfırlama bağlantısı gönderildi",
+	})
+}
+
+// Yeni şifre belirleme (POST /api/password/reset) {"token": "...", "new_password": "..."}
+func (h *Handler) ResetHandler(w http.ResponseWriter, r *http.Request) {
+	var req ResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := ResetPassword(h.DBPrimary, h.Policy, req, time.Now()); err != nil {
+		writePasswordError(w, "Şifre sıfırlanamadı", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Şifre değişikliği (POST /api/password/change) {"current_password": "...", "new_password": "..."};
+// diğer oturumlar kapanır, yanıttaki token bu oturumda kullanılmaya devam eder
+func (h *Handler) ChangeHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var req ChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := ChangePassword(h.DBPrimary, h.Policy, user, req); err != nil {
+		writePasswordError(w, "Şifre değiştirilemedi", err)
+		return
+	}
+	token, err := h.IssueToken(user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Token oluşturulamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func writePasswordError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrWrongPassword), errors.Is(err, ErrManagedByAPIKeys):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrSamePassword), IsPolicyError(err):
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusInternalServerError, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
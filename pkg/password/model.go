@@ -0,0 +1,31 @@
+package password
+
+// Şifre politikası. Breached, sızdırılmış şifre listesindeki şifrelerin SHA-1 özetleridir (büyük harf hex).
+type Policy struct {
+	MinLength int
+	breached  map[string]struct{}
+}
+
+// Arayüzün şifre formunda gösterdiği kurallar (GET /api/password/policy)
+type PolicyInfo struct {
+	MinLength     int  `json:"min_length"`
+	MaxBytes      int  `json:"max_bytes"`
+	BreachedCheck bool `json:"breached_check"`
+}
+
+// Şifre sıfırlama bağlantısı isteği (POST /api/password/forgot)
+type ForgotRequest struct {
+	Email string `json:"email"`
+}
+
+// Yeni şifre belirleme (POST /api/password/reset)
+type ResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// Oturum açmış kullanıcının şifre değişikliği (POST /api/password/change)
+type ChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
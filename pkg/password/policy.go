@@ -0,0 +1,124 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMinLength = 10
+	// bcrypt 72 bayttan sonrasını yok sayar
+	MaxBytes = 72
+)
+
+var (
+	ErrPasswordRequired = errors.New("Yeni şifre zorunludur")
+	ErrTooLong          = fmt.Errorf("Şifre en fazla %d bayt olabilir", MaxBytes)
+	ErrBreached         = errors.New("Bu şifre sızdırılmış şifre listelerinde yer alıyor, başka bir şifre seçin")
+	ErrContainsEmail    = errors.New("Şifre e-posta adresinizi içermemeli")
+	ErrTooShort         = errors.New("Şifre çok kısa")
+)
+
+// Ortam değişkenlerinden politika: PASSWORD_MIN_LENGTH (varsayılan 10) ve PASSWORD_BREACHED_LIST
+// (sızdırılmış şifre listesi dosyası; boşsa liste kontrolü yapılmaz)
+func PolicyFromEnv() (Policy, error) {
+	p := Policy{MinLength: DefaultMinLength}
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxBytes {
+			return Policy{}, fmt.Errorf("PASSWORD_MIN_LENGTH 1 ile %d arasında olmalı: %q", MaxBytes, v)
+		}
+		p.MinLength = n
+	}
+	if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return Policy{}, fmt.Errorf("sızdırılmış şifre listesi açılamadı: %w", err)
+		}
+		defer f.Close()
+		if err := p.LoadBreachedList(f); err != nil {
+			return Policy{}, fmt.Errorf("sızdırılmış şifre listesi okunamadı: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Listeyi satır satır okur. Satır ya düz şifredir ya da Have I Been Pwned biçiminde SHA-1
+// özetidir ("<40 hex>" veya "<40 hex>:<sayı>"). Boş satırlar ve # ile başlayanlar atlanır.
+func (p *Policy) LoadBreachedList(r io.Reader) error {
+	if p.breached == nil {
+		p.breached = map[string]struct{}{}
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[breachedKey(line)] = struct{}{}
+	}
+	return sc.Err()
+}
+
+func breachedKey(line string) string {
+	hash, _, _ := strings.Cut(line, ":")
+	if len(hash) == sha1.Size*2 {
+		if _, err := hex.DecodeString(hash); err == nil {
+			return strings.ToUpper(hash)
+		}
+	}
+	return sha1Hex(line)
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func (p Policy) Info() PolicyInfo {
+	return PolicyInfo{MinLength: p.MinLength, MaxBytes: MaxBytes, BreachedCheck: len(p.breached) > 0}
+}
+
+// Yeni şifreyi politikaya göre doğrular. Uzunluk karakter olarak, üst sınır bcrypt nedeniyle bayt
+// olarak sayılır. Liste kontrolü şifrenin kendisi ve küçük harfli haliyle yapılır.
+func (p Policy) Validate(password, email string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	min := p.MinLength
+	if min <= 0 {
+		min = DefaultMinLength
+	}
+	if utf8.RuneCountInString(password) < min {
+		return fmt.Errorf("%w: en az %d karakter olmalı", ErrTooShort, min)
+	}
+	if len(password) > MaxBytes {
+		return ErrTooLong
+	}
+	if local, _, _ := strings.Cut(strings.ToLower(email), "@"); len(local) >= 3 && strings.Contains(strings.ToLower(password), local) {
+		return ErrContainsEmail
+	}
+	if len(p.breached) > 0 {
+		if _, ok := p.breached[sha1Hex(password)]; ok {
+			return ErrBreached
+		}
+		if _, ok := p.breached[sha1Hex(strings.ToLower(password))]; ok {
+			return ErrBreached
+		}
+	}
+	return nil
+}
+
+// Politika hatası mı (400 dönülecek hatalar)
+func IsPolicyError(err error) bool {
+	return errors.Is(err, ErrPasswordRequired) || errors.Is(err, ErrTooShort) || errors.Is(err, ErrTooLong) ||
+		errors.Is(err, ErrBreached) || errors.Is(err, ErrContainsEmail)
+}
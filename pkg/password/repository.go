@@ -0,0 +1,89 @@
+package password
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"time"
+)
+
+// Şifre sıfırlama gönderilebilecek kullanıcı; şifresi olmayan (sadece Keycloak ile giriş yapan,
+// şifre alanı "!" ile başlayan) kullanıcılar hariç
+func findResettableUserRepo(db *sql.DB, email string) (common.AuthUser, error) {
+	var u common.AuthUser
+	err := db.QueryRow(`SELECT id, email, role, tenant_id FROM users WHERE email = $1 AND password NOT LIKE '!%'`, email).
+		Scan(&u.ID, &u.Email, &u.Role, &u.TenantID)
+	return u, err
+}
+
+func countRecentTokensRepo(db *sql.DB, userID int, since time.Time) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM password_reset_tokens WHERE user_id = $1 AND created_at > $2", userID, since).Scan(&n)
+	return n, err
+}
+
+func createTokenRepo(db *sql.DB, userID int, tokenHash string, expiresAt time.Time, ip string) error {
+	_, err := db.Exec(`INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, requested_ip)
+		VALUES ($1, $2, $3, $4)`, userID, tokenHash, expiresAt, ip)
+	return err
+}
+
+// Token'ı tek seferlik kullanır ve şifreyi değiştirir. validate, token geçerliyse kullanıcının
+// e-postasıyla çağrılır ve şifre özetini döner; hata dönerse token harcanmaz.
+func resetPasswordRepo(db *sql.DB, tokenHash string, now time.Time, validate func(email string) (string, error)) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var email string
+	err = tx.QueryRow(`SELECT t.user_id, u.email FROM password_reset_tokens t JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > $2
+		FOR UPDATE OF t`, tokenHash, now).Scan(&userID, &email)
+	if err != nil {
+		return 0, err
+	}
+	hash, err := validate(email)
+	if err != nil {
+		return 0, err
+	}
+	if err := setPasswordTx(tx, userID, hash); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+func getPasswordHashRepo(db *sql.DB, userID int) (string, error) {
+	var hash string
+	err := db.QueryRow("SELECT password FROM users WHERE id = $1", userID).Scan(&hash)
+	return hash, err
+}
+
+func changePasswordRepo(db *sql.DB, userID int, hash string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := setPasswordTx(tx, userID, hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Şifreyi günceller, oturum sürümünü artırarak mevcut token'ları geçersiz kılar ve kullanılmamış
+// sıfırlama bağlantılarını iptal eder
+func setPasswordTx(tx *sql.Tx, userID int, hash string) error {
+	if _, err := tx.Exec("UPDATE users SET password = $2, session_version = session_version + 1 WHERE id = $1", userID, hash); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE password_reset_tokens SET used_at = now() WHERE user_id = $1 AND used_at IS NULL", userID)
+	return err
+}
+
+func sessionVersionRepo(db *sql.DB, userID int) (int, error) {
+	var v int
+	err := db.QueryRow("SELECT session_version FROM users WHERE id = $1", userID).Scan(&v)
+	return v, err
+}
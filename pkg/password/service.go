@@ -0,0 +1,212 @@
+package password
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/notification"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidToken     = errors.New("Şifre sıfırlama bağlantısı geçersiz veya süresi dolmuş")
+	ErrWrongPassword    = errors.New("Mevcut şifre hatalı")
+	ErrSamePassword     = errors.New("Yeni şifre mevcut şifreyle aynı olamaz")
+	ErrManagedByAPIKeys = errors.New("Şifre API anahtarıyla değiştirilemez")
+)
+
+const (
+	DefaultResetTTL = 30 * time.Minute
+	// Aynı kullanıcı için bir saatte gönderilebilecek en fazla sıfırlama e-postası
+	maxResetRequestsPerHour = 3
+	// Bildirim tercihleri (sessiz saat, özet) sıfırlama e-postasını geciktirmesin diye komut
+	// kullanıcı ID'si olmadan, doğrudan e-posta adresine gönderilir
+	resetEvent = "password.reset"
+)
+
+// Sıfırlama bağlantısı üretir ve notification-svc ile e-postayla gönderir. Kullanıcı bulunamasa da
+// hata dönmez; böylece uç nokta hangi e-postaların kayıtlı olduğunu sızdırmaz.
+func RequestReset(ctx context.Context, db *sql.DB, email, resetURL, ip string, ttl time.Duration, now time.Time) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil
+	}
+	user, err := findResettableUserRepo(db, email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	n, err := countRecentTokensRepo(db, user.ID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if n >= maxResetRequestsPerHour {
+		log.Printf("Şifre sıfırlama sınırı aşıldı (kullanıcı=%d)", user.ID)
+		return nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = DefaultResetTTL
+	}
+	if err := createTokenRepo(db, user.ID, HashToken(token), now.Add(ttl), ip); err != nil {
+		return err
+	}
+	cmd := notification.Command{
+		Email:   user.Email,
+		Event:   resetEvent,
+		Subject: "Şifre sıfırlama",
+		Body: fmt.Sprintf("Şifrenizi sıfırlamak için aşağıdaki bağlantıyı kullanın. Bağlantı %d dakika geçerlidir ve bir kez kullanılabilir.\n\n%s\n\n"+
+			"Bu isteği siz yapmadıysanız bu e-postayı dikkate almayın; şifreniz değişmeyecektir.",
+			int(ttl.Minutes()), ResetLink(resetURL, token)),
+	}
+	if err := notification.Publish(ctx, cmd); err != nil {
+		// Yanıt kullanıcının varlığını belli etmesin diye hata sadece loglanır
+		log.Printf("Şifre sıfırlama e-postası kuyruğa yazılamadı (kullanıcı=%d): %v", user.ID, err)
+	}
+	return nil
+}
+
+// Token URL fragment'ında taşınır; sunucu loglarına ve Referer başlığına düşmez
+func ResetLink(resetURL, token string) string {
+	return strings.SplitN(resetURL, "#", 2)[0] + "#token=" + token
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Token'ın kendisi saklanmaz, SHA-256 özeti tutulur
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sıfırlama bağlantısıyla yeni şifre belirler. Tüm oturumlar kapanır; MFA etkinse girişte yine istenir.
+func ResetPassword(db *sql.DB, policy Policy, req ResetRequest, now time.Time) error {
+	if strings.TrimSpace(req.Token) == "" {
+		return ErrInvalidToken
+	}
+	userID, err := resetPasswordRepo(db, HashToken(strings.TrimSpace(req.Token)), now, func(email string) (string, error) {
+		if err := policy.Validate(req.NewPassword, email); err != nil {
+			return "", err
+		}
+		return hashPassword(req.NewPassword)
+	})
+	if err == sql.ErrNoRows {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	forgetSession(userID)
+	return nil
+}
+
+// Oturum açmış kullanıcının şifresini değiştirir; diğer oturumları kapanır, çağıran yeni token alır
+func ChangePassword(db *sql.DB, policy Policy, user common.AuthUser, req ChangeRequest) error {
+	if user.APIKeyID != 0 {
+		return ErrManagedByAPIKeys
+	}
+	current, err := getPasswordHashRepo(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(current), []byte(req.CurrentPassword)) != nil {
+		return ErrWrongPassword
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return ErrSamePassword
+	}
+	if err := policy.Validate(req.NewPassword, user.Email); err != nil {
+		return err
+	}
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := changePasswordRepo(db, user.ID, hash); err != nil {
+		return err
+	}
+	forgetSession(user.ID)
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(b), err
+}
+
+// --- Oturum sürümü ---
+// Token'lar "sv" claim'inde kullanıcının oturum sürümünü taşır; şifre değişince sürüm artar ve eski
+// token'lar reddedilir. Her istekte veritabanına gitmemek için sürüm kısa süre önbelleklenir; başka bir
+// API kopyasında yapılan değişiklik en geç sessionCacheTTL sonra geçerli olur.
+const sessionCacheTTL = 30 * time.Second
+
+type cachedVersion struct {
+	version int
+	expires time.Time
+}
+
+var (
+	sessionMu    sync.Mutex
+	sessionCache = map[int]cachedVersion{}
+)
+
+// Kullanıcının güncel oturum sürümü; yeni token üretirken kullanılır
+func SessionVersion(db *sql.DB, userID int) (int, error) {
+	return sessionVersionRepo(db, userID)
+}
+
+// Token'daki sürüm güncel mi. Silinmiş kullanıcının token'ı da geçersizdir.
+func CheckSession(db *sql.DB, userID, version int, now time.Time) (bool, error) {
+	sessionMu.Lock()
+	c, ok := sessionCache[userID]
+	sessionMu.Unlock()
+	// Önbellekten yeni bir sürüm başka kopyada yapılmış değişikliği gösterir, tekrar okunur
+	if ok && now.Before(c.expires) && version <= c.version {
+		return version == c.version, nil
+	}
+	current, err := sessionVersionRepo(db, userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	sessionMu.Lock()
+	for id, e := range sessionCache {
+		if now.After(e.expires) {
+			delete(sessionCache, id)
+		}
+	}
+	sessionCache[userID] = cachedVersion{current, now.Add(sessionCacheTTL)}
+	sessionMu.Unlock()
+	return version == current, nil
+}
+
+func forgetSession(userID int) {
+	sessionMu.Lock()
+	delete(sessionCache, userID)
+	sessionMu.Unlock()
+}
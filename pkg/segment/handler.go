@@ -0,0 +1,290 @@
+package segment
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler fonksiyonları sade tutulur, iş mantığı service katmanında
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Etiketler (GET /api/tags)
+func (h *Handler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	tags, err := GetTags(h.DBReplica, user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Etiketler alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// Etiket ekleme (POST /api/tags)
+func (h *Handler) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var t Tag
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateTag(h.DBPrimary, user, &t); err != nil {
+		writeSegmentError(w, "Etiket eklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// Etiket güncelleme (PUT /api/tags/{id})
+func (h *Handler) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/tags/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etiket ID", err)
+		return
+	}
+	var t Tag
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	t.ID = id
+	if err := UpdateTag(h.DBPrimary, user, &t); err != nil {
+		writeSegmentError(w, "Etiket güncellenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// Etiket silme (DELETE /api/tags/{id})
+func (h *Handler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/tags/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etiket ID", err)
+		return
+	}
+	if err := DeleteTag(h.DBPrimary, user, id); err != nil {
+		writeSegmentError(w, "Etiket silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Toplu etiketleme (POST /api/tags/{id}/assign) ve etiket kaldırma (POST /api/tags/{id}/unassign)
+func (h *Handler) BulkTagHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	add := strings.HasSuffix(r.URL.Path, "/assign")
+	suffix := "/unassign"
+	if add {
+		suffix = "/assign"
+	}
+	id, err := idFromPath(r.URL.Path, "/api/tags/", suffix)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz etiket ID", err)
+		return
+	}
+	var req struct {
+		CustomerIDs []int64 `json:"customer_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	affected, err := BulkTag(h.DBPrimary, user, id, req.CustomerIDs, add)
+	if err != nil {
+		writeSegmentError(w, "Toplu etiketleme yapılamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"affected": affected})
+}
+
+// Segmentler: kullanıcının kendi ve paylaşılan segmentleri (GET /api/segments)
+func (h *Handler) GetSegmentsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	segments, err := GetSegments(h.DBReplica, user)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Segmentler alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segments)
+}
+
+// Segment ekleme (POST /api/segments)
+func (h *Handler) CreateSegmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var s Segment
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateSegment(h.DBPrimary, user, &s); err != nil {
+		writeSegmentError(w, "Segment eklenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// Tek segment (GET /api/segments/{id})
+func (h *Handler) GetSegmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/segments/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz segment ID", err)
+		return
+	}
+	s, err := GetSegment(h.DBReplica, user, id)
+	if err != nil {
+		writeSegmentError(w, "Segment alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// Segment güncelleme (PUT /api/segments/{id})
+func (h *Handler) UpdateSegmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/segments/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz segment ID", err)
+		return
+	}
+	var s Segment
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	s.ID = id
+	if err := UpdateSegment(h.DBPrimary, user, &s); err != nil {
+		writeSegmentError(w, "Segment güncellenemedi", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// Segment silme (DELETE /api/segments/{id})
+func (h *Handler) DeleteSegmentHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/segments/", "")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz segment ID", err)
+		return
+	}
+	if err := DeleteSegment(h.DBPrimary, user, id); err != nil {
+		writeSegmentError(w, "Segment silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Segmentteki müşteriler (GET /api/segments/{id}/customers?pageSize=10&cursor=...&include_total=true)
+func (h *Handler) GetSegmentCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/segments/", "/customers")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz segment ID", err)
+		return
+	}
+	q := r.URL.Query()
+	var params customer.CustomerListParams
+	params.Page, _ = strconv.Atoi(q.Get("page"))
+	params.PageSize, _ = strconv.Atoi(q.Get("pageSize"))
+	params.Cursor = q.Get("cursor")
+	params.IncludeTotal, _ = strconv.ParseBool(q.Get("include_total"))
+	result, err := GetSegmentCustomers(h.DBReplica, user, id, params)
+	if err != nil {
+		writeSegmentError(w, "Segment müşterileri alınamadı", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeSegmentError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrTagNotFound), errors.Is(err, ErrSegmentNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrManagerOnly), errors.Is(err, ErrSegmentForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrTagExists):
+		common.WriteError(w, http.StatusConflict, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+// /api/segments/12/customers gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix, suffix string) (int, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
@@ -0,0 +1,45 @@
+package segment
+
+// Müşteri etiketleri ve kayıtlı segmentler için veri modelleri
+
+// Müşteri etiketi (ör. "VIP", "churn-risk"), tenant içinde isim benzersizdir
+type Tag struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Color         string `json:"color,omitempty"` // #RRGGBB
+	CustomerCount int    `json:"customer_count"`
+}
+
+// Segment koşul tipleri
+const (
+	ConditionTag           = "tag"             // TagID etiketine sahip
+	ConditionCustomField   = "custom_field"    // Field özel alanı Value değerine eşit (multi_select'te içerir)
+	ConditionNoContactDays = "no_contact_days" // Son Days gündür iletişim kaydı yok
+	ConditionDealStage     = "deal_stage"      // StageID aşamasında fırsatı var
+)
+
+// Kayıtlı müşteri segmenti; müşteriler her istekte filtreye göre yeniden hesaplanır
+type Segment struct {
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	OwnerID   int           `json:"owner_id"`
+	Shared    bool          `json:"shared"` // true ise tenant'taki tüm ekip görür
+	Filter    SegmentFilter `json:"filter"`
+	CreatedAt string        `json:"created_at"`
+	UpdatedAt string        `json:"updated_at"`
+}
+
+type SegmentFilter struct {
+	Match      string             `json:"match"` // all (VE) veya any (VEYA)
+	Conditions []SegmentCondition `json:"conditions"`
+}
+
+type SegmentCondition struct {
+	Type    string `json:"type"`
+	TagID   int    `json:"tag_id,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Days    int    `json:"days,omitempty"`
+	StageID int    `json:"stage_id,omitempty"`
+	Negate  bool   `json:"negate,omitempty"` // Koşulu tersine çevirir
+}
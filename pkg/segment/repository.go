@@ -0,0 +1,157 @@
+package segment
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+)
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const tagColumns = "t.id, t.name, t.color, (SELECT COUNT(*) FROM customer_tags ctg WHERE ctg.tag_id = t.id)"
+
+func scanTag(row scanner, t *Tag) error {
+	return row.Scan(&t.ID, &t.Name, &t.Color, &t.CustomerCount)
+}
+
+func getTagsRepo(db *sql.DB, tenantID int) ([]Tag, error) {
+	rows, err := db.Query("SELECT "+tagColumns+" FROM tags t WHERE t.tenant_id = $1 ORDER BY LOWER(t.name)", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := scanTag(rows, &t); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func createTagRepo(db *sql.DB, tenantID int, t *Tag) error {
+	return db.QueryRow("INSERT INTO tags (tenant_id, name, color) VALUES ($1, $2, $3) RETURNING id", tenantID, t.Name, t.Color).Scan(&t.ID)
+}
+
+func updateTagRepo(db *sql.DB, tenantID int, t *Tag) error {
+	return scanTag(db.QueryRow(
+		"UPDATE tags t SET name = $1, color = $2 WHERE t.id = $3 AND t.tenant_id = $4 RETURNING "+tagColumns,
+		t.Name, t.Color, t.ID, tenantID,
+	), t)
+}
+
+func deleteTagRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	res, err := db.Exec("DELETE FROM tags WHERE id = $1 AND tenant_id = $2", id, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func tagExistsRepo(db *sql.DB, tenantID, id int) (bool, error) {
+	var found bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM tags WHERE id = $1 AND tenant_id = $2)", id, tenantID).Scan(&found)
+	return found, err
+}
+
+// Etiketi aynı tenant'taki müşterilere ekler, yeni etiketlenen müşteri sayısını döner
+func tagCustomersRepo(db *sql.DB, tenantID, tagID int, customerIDs []int64) (int, error) {
+	res, err := db.Exec(`
+		INSERT INTO customer_tags (customer_id, tag_id)
+		SELECT c.id, $1 FROM customers c WHERE c.id = ANY($2) AND c.tenant_id = $3
+		ON CONFLICT DO NOTHING`, tagID, pq.Array(customerIDs), tenantID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Etiketi müşterilerden kaldırır, etiketi kaldırılan müşteri sayısını döner
+func untagCustomersRepo(db *sql.DB, tagID int, customerIDs []int64) (int, error) {
+	res, err := db.Exec("DELETE FROM customer_tags WHERE tag_id = $1 AND customer_id = ANY($2)", tagID, pq.Array(customerIDs))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+const segmentColumns = "id, name, owner_id, shared, filter, created_at, updated_at"
+
+func scanSegment(row scanner, s *Segment) error {
+	var ownerID sql.NullInt64
+	var filter []byte
+	var createdAt, updatedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.Name, &ownerID, &s.Shared, &filter, &createdAt, &updatedAt); err != nil {
+		return err
+	}
+	s.OwnerID = int(ownerID.Int64)
+	if createdAt.Valid {
+		s.CreatedAt = createdAt.Time.Format("02-01-2006 15:04")
+	}
+	if updatedAt.Valid {
+		s.UpdatedAt = updatedAt.Time.Format("02-01-2006 15:04")
+	}
+	return json.Unmarshal(filter, &s.Filter)
+}
+
+// Kullanıcının kendi segmentleri ve tenant'ta paylaşılanlar
+func getSegmentsRepo(db *sql.DB, tenantID, userID int) ([]Segment, error) {
+	rows, err := db.Query("SELECT "+segmentColumns+" FROM segments WHERE tenant_id = $1 AND (owner_id = $2 OR shared) ORDER BY name, id", tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []Segment{}
+	for rows.Next() {
+		var s Segment
+		if err := scanSegment(rows, &s); err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, rows.Err()
+}
+
+func getSegmentRepo(db *sql.DB, tenantID, id int) (Segment, error) {
+	var s Segment
+	err := scanSegment(db.QueryRow("SELECT "+segmentColumns+" FROM segments WHERE id = $1 AND tenant_id = $2", id, tenantID), &s)
+	return s, err
+}
+
+func createSegmentRepo(db *sql.DB, tenantID int, s *Segment) error {
+	filter, err := json.Marshal(s.Filter)
+	if err != nil {
+		return err
+	}
+	return scanSegment(db.QueryRow(
+		"INSERT INTO segments (tenant_id, name, owner_id, shared, filter) VALUES ($1, $2, NULLIF($3, 0), $4, $5) RETURNING "+segmentColumns,
+		tenantID, s.Name, s.OwnerID, s.Shared, string(filter),
+	), s)
+}
+
+func updateSegmentRepo(db *sql.DB, s *Segment) error {
+	filter, err := json.Marshal(s.Filter)
+	if err != nil {
+		return err
+	}
+	return scanSegment(db.QueryRow(
+		"UPDATE segments SET name = $1, shared = $2, filter = $3, updated_at = now() WHERE id = $4 RETURNING "+segmentColumns,
+		s.Name, s.Shared, string(filter), s.ID,
+	), s)
+}
+
+func deleteSegmentRepo(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM segments WHERE id = $1", id)
+	return err
+}
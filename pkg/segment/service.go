@@ -0,0 +1,280 @@
+package segment
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTagNotFound      = errors.New("Etiket bulunamadı")
+	ErrTagExists        = errors.New("Bu isimde bir etiket zaten var")
+	ErrSegmentNotFound  = errors.New("Segment bulunamadı")
+	ErrSegmentForbidden = errors.New("Segmenti sadece sahibi veya bir yönetici değiştirebilir")
+	ErrManagerOnly      = errors.New("Bu işlem sadece yöneticiler içindir")
+)
+
+const (
+	maxBulkTagCustomers  = 1000 // Tek istekte etiketlenebilecek müşteri sayısı
+	maxSegmentConditions = 20
+)
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+func GetTags(db *sql.DB, user common.AuthUser) ([]Tag, error) {
+	return getTagsRepo(db, tenantOf(user))
+}
+
+func CreateTag(db *sql.DB, user common.AuthUser, t *Tag) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if err := ValidateTag(*t); err != nil {
+		return err
+	}
+	return uniqueTagError(createTagRepo(db, tenantOf(user), t))
+}
+
+func UpdateTag(db *sql.DB, user common.AuthUser, t *Tag) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if err := ValidateTag(*t); err != nil {
+		return err
+	}
+	err := updateTagRepo(db, tenantOf(user), t)
+	if err == sql.ErrNoRows {
+		return ErrTagNotFound
+	}
+	return uniqueTagError(err)
+}
+
+// Etiket silme (sadece yönetici), müşterilerden de kaldırılır
+func DeleteTag(db *sql.DB, user common.AuthUser, id int) error {
+	if !user.IsManager() {
+		return ErrManagerOnly
+	}
+	found, err := deleteTagRepo(db, tenantOf(user), id)
+	if err == nil && !found {
+		return ErrTagNotFound
+	}
+	return err
+}
+
+// Etiketi müşterilere toplu ekler (add) veya kaldırır, etkilenen müşteri sayısını döner
+func BulkTag(db *sql.DB, user common.AuthUser, tagID int, customerIDs []int64, add bool) (int, error) {
+	if len(customerIDs) == 0 || len(customerIDs) > maxBulkTagCustomers {
+		return 0, fmt.Errorf("Müşteri listesi 1 ile %d arasında olmalı", maxBulkTagCustomers)
+	}
+	found, err := tagExistsRepo(db, tenantOf(user), tagID)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrTagNotFound
+	}
+	if add {
+		return tagCustomersRepo(db, tenantOf(user), tagID, customerIDs)
+	}
+	return untagCustomersRepo(db, tagID, customerIDs)
+}
+
+// Aynı tenant'ta aynı isimli etiket eklenirse anlaşılır hata döner
+func uniqueTagError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return ErrTagExists
+	}
+	return err
+}
+
+// Kullanıcının kendi segmentleri ve tenant'ta paylaşılanlar
+func GetSegments(db *sql.DB, user common.AuthUser) ([]Segment, error) {
+	return getSegmentsRepo(db, tenantOf(user), user.ID)
+}
+
+// Segment sahibine veya paylaşılmışsa tenant'taki herkese görünür
+func GetSegment(db *sql.DB, user common.AuthUser, id int) (Segment, error) {
+	s, err := getSegmentRepo(db, tenantOf(user), id)
+	if err == sql.ErrNoRows || (err == nil && s.OwnerID != user.ID && !s.Shared) {
+		return Segment{}, ErrSegmentNotFound
+	}
+	return s, err
+}
+
+func CreateSegment(db *sql.DB, user common.AuthUser, s *Segment) error {
+	s.OwnerID = user.ID
+	if err := validateSegment(db, user, s); err != nil {
+		return err
+	}
+	return createSegmentRepo(db, tenantOf(user), s)
+}
+
+// Segment güncelleme (sahibi veya yönetici)
+func UpdateSegment(db *sql.DB, user common.AuthUser, s *Segment) error {
+	current, err := GetSegment(db, user, s.ID)
+	if err != nil {
+		return err
+	}
+	if current.OwnerID != user.ID && !user.IsManager() {
+		return ErrSegmentForbidden
+	}
+	s.OwnerID = current.OwnerID
+	if err := validateSegment(db, user, s); err != nil {
+		return err
+	}
+	return updateSegmentRepo(db, s)
+}
+
+func DeleteSegment(db *sql.DB, user common.AuthUser, id int) error {
+	current, err := GetSegment(db, user, id)
+	if err != nil {
+		return err
+	}
+	if current.OwnerID != user.ID && !user.IsManager() {
+		return ErrSegmentForbidden
+	}
+	return deleteSegmentRepo(db, id)
+}
+
+// Segment filtresini o anki verilere göre değerlendirip müşterileri sayfalı döner.
+// params'tan sadece sayfalama alanları (Page, PageSize, Cursor, IncludeTotal) kullanılır.
+func GetSegmentCustomers(db *sql.DB, user common.AuthUser, id int, params customer.CustomerListParams) (customer.CustomerListResult, error) {
+	s, err := GetSegment(db, user, id)
+	if err != nil {
+		return customer.CustomerListResult{}, err
+	}
+	var defs []customer.FieldDefinition
+	if s.Filter.usesCustomFields() {
+		if defs, err = customer.GetFieldDefinitions(db, user); err != nil {
+			return customer.CustomerListResult{}, err
+		}
+	}
+	return customer.GetCustomers(db, customer.CustomerListParams{
+		TenantID:     tenantOf(user),
+		Page:         params.Page,
+		PageSize:     params.PageSize,
+		Cursor:       params.Cursor,
+		IncludeTotal: params.IncludeTotal,
+		Where: func(arg func(interface{}) string) (string, error) {
+			return segmentWhere(s.Filter, defs, arg)
+		},
+	})
+}
+
+// Özel alan koşulları tenant'ın alan tanımlarıyla da doğrulanır
+func validateSegment(db *sql.DB, user common.AuthUser, s *Segment) error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Filter.Match == "" {
+		s.Filter.Match = "all"
+	}
+	if err := ValidateSegment(*s); err != nil {
+		return err
+	}
+	if !s.Filter.usesCustomFields() {
+		return nil
+	}
+	defs, err := customer.GetFieldDefinitions(db, user)
+	if err != nil {
+		return err
+	}
+	_, err = segmentWhere(s.Filter, defs, func(interface{}) string { return "$1" })
+	return err
+}
+
+func (f SegmentFilter) usesCustomFields() bool {
+	for _, c := range f.Conditions {
+		if c.Type == ConditionCustomField {
+			return true
+		}
+	}
+	return false
+}
+
+// Segment filtresini customers tablosu üzerinde WHERE koşuluna çevirir
+func segmentWhere(f SegmentFilter, defs []customer.FieldDefinition, arg func(interface{}) string) (string, error) {
+	var clauses []string
+	for _, c := range f.Conditions {
+		var clause string
+		switch c.Type {
+		case ConditionTag:
+			clause = "EXISTS (SELECT 1 FROM customer_tags ctg WHERE ctg.customer_id = customers.id AND ctg.tag_id = " + arg(c.TagID) + ")"
+		case ConditionCustomField:
+			filter, err := customer.CustomFieldFilter(defs, map[string]string{c.Field: c.Value})
+			if err != nil {
+				return "", err
+			}
+			clause = "customers.custom_fields @> " + arg(filter) + "::jsonb"
+		case ConditionNoContactDays:
+			clause = "NOT EXISTS (SELECT 1 FROM contacts ct WHERE ct.customer_id = customers.id AND ct.occurred_at >= now() - " + arg(c.Days) + "::int * INTERVAL '1 day')"
+		case ConditionDealStage:
+			clause = "EXISTS (SELECT 1 FROM deals d WHERE d.customer_id = customers.id AND d.stage_id = " + arg(c.StageID) + ")"
+		default:
+			return "", fmt.Errorf("Geçersiz segment koşulu: %s", c.Type)
+		}
+		if c.Negate {
+			clause = "NOT " + clause
+		}
+		clauses = append(clauses, clause)
+	}
+	op := " AND "
+	if f.Match == "any" {
+		op = " OR "
+	}
+	return "(" + strings.Join(clauses, op) + ")", nil
+}
+
+// --- Validasyon Fonksiyonları ---
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func ValidateTag(t Tag) error {
+	n := utf8.RuneCountInString(strings.TrimSpace(t.Name))
+	if n < 2 || n > 50 {
+		return errors.New("Etiket adı 2-50 karakter olmalı")
+	}
+	if t.Color != "" && !tagColorPattern.MatchString(t.Color) {
+		return errors.New("Renk #RRGGBB biçiminde olmalı")
+	}
+	return nil
+}
+
+func ValidateSegment(s Segment) error {
+	if utf8.RuneCountInString(strings.TrimSpace(s.Name)) < 2 {
+		return errors.New("Segment adı en az 2 karakter olmalı")
+	}
+	switch s.Filter.Match {
+	case "", "all", "any":
+	default:
+		return errors.New("match all veya any olmalı")
+	}
+	if len(s.Filter.Conditions) == 0 || len(s.Filter.Conditions) > maxSegmentConditions {
+		return fmt.Errorf("Segment 1 ile %d arasında koşul içermeli", maxSegmentConditions)
+	}
+	for i, c := range s.Filter.Conditions {
+		var ok bool
+		switch c.Type {
+		case ConditionTag:
+			ok = c.TagID > 0
+		case ConditionCustomField:
+			ok = c.Field != "" && c.Value != ""
+		case ConditionNoContactDays:
+			ok = c.Days > 0 && c.Days <= 3650
+		case ConditionDealStage:
+			ok = c.StageID > 0
+		default:
+			return fmt.Errorf("%d. koşul: geçersiz tip %q", i+1, c.Type)
+		}
+		if !ok {
+			return fmt.Errorf("%d. koşul (%s) eksik veya geçersiz parametre içeriyor", i+1, c.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 uyumlu nesne deposu sürücüsü (AWS S3, MinIO vb.). İstekler AWS Signature V4 ile imzalanır.
+// Yerel deneme: docker run -p 9000:9000 minio/minio server /data, PathStyle true.
+type S3 struct {
+	Endpoint string // ör. https://s3.eu-central-1.amazonaws.com veya http://localhost:9000
+	// Süreli bağlantılarda kullanılacak, tarayıcının erişebildiği adres (boşsa Endpoint)
+	PublicEndpoint string
+	Region         string // Boşsa us-east-1
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	PathStyle      bool // MinIO ve çoğu S3 uyumlu servis için true (adres/bucket/anahtar)
+	Client         *http.Client
+	Now            func() time.Time // Testler için; nil ise time.Now
+}
+
+const (
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// Boş gövdenin SHA-256 özeti
+	emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// S3'ün izin verdiği en uzun süreli bağlantı (7 gün)
+	maxPresignTTL = 7 * 24 * time.Hour
+)
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		return errors.New("S3 yüklemesi için dosya boyutu gerekli")
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp, key)
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Error(resp, key); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := s3Error(resp, key); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Oturumsuz indirilebilecek süreli GET bağlantısı; filename verilirse tarayıcı dosyayı bu adla kaydeder
+func (s *S3) PresignGet(key string, ttl time.Duration, filename, contentType string) (string, error) {
+	endpoint := s.PublicEndpoint
+	if endpoint == "" {
+		endpoint = s.Endpoint
+	}
+	scheme, host, path, err := s.location(endpoint, key)
+	if err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > maxPresignTTL {
+		return "", fmt.Errorf("bağlantı süresi 1 saniye ile 7 gün arasında olmalı: %s", ttl)
+	}
+	t := s.now()
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.AccessKey+"/"+s.scope(t))
+	q.Set("X-Amz-Date", t.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if filename != "" {
+		q.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	if contentType != "" {
+		q.Set("response-content-type", contentType)
+	}
+	query := canonicalQuery(q)
+	canonical := strings.Join([]string{http.MethodGet, path, query, "host:" + host + "\n", "host", unsignedPayload}, "\n")
+	return scheme + "://" + host + path + "?" + query + "&X-Amz-Signature=" + s.signature(t, canonical), nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	scheme, host, path, err := s.location(s.Endpoint, key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, scheme+"://"+host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	payload := emptyPayload
+	if body != nil {
+		// Gövde imzaya katılmaz; büyük dosyalar iki kez okunmasın
+		req.ContentLength = size
+		payload = unsignedPayload
+	}
+	t := s.now()
+	amzDate := t.Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	const signed = "host;x-amz-content-sha256;x-amz-date"
+	headers := "host:" + host + "\nx-amz-content-sha256:" + payload + "\nx-amz-date:" + amzDate + "\n"
+	canonical := strings.Join([]string{method, path, "", headers, signed, payload}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, s.scope(t), signed, s.signature(t, canonical)))
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// İstek adresi; path imzadaki kanonik URI ile aynıdır
+func (s *S3) location(endpoint, key string) (scheme, host, path string, err error) {
+	if err := checkKey(key); err != nil {
+		return "", "", "", err
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || s.Bucket == "" {
+		return "", "", "", fmt.Errorf("geçersiz S3 yapılandırması (adres %q, bucket %q)", endpoint, s.Bucket)
+	}
+	path = "/" + uriEncode(key, false)
+	host = u.Host
+	if s.PathStyle {
+		path = "/" + uriEncode(s.Bucket, true) + path
+	} else {
+		host = s.Bucket + "." + host
+	}
+	return u.Scheme, host, path, nil
+}
+
+func (s *S3) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (s *S3) region() string {
+	if s.Region == "" {
+		return "us-east-1"
+	}
+	return s.Region
+}
+
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region() + "/s3/aws4_request"
+}
+
+func (s *S3) signature(t time.Time, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	toSign := "AWS4-HMAC-SHA256\n" + t.Format(amzDateFormat) + "\n" + s.scope(t) + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	for _, part := range []string{s.region(), "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Parametreler anahtara göre sıralı ve RFC 3986'ya göre kodlanmış
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// AWS kuralı: harf, rakam ve -_.~ dışındaki baytlar %XX; "/" sadece yol içinde korunur
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// 2xx dışındaki yanıtları hataya çevirir; S3 hata gövdesindeki kod mesaja eklenir
+func s3Error(resp *http.Response, key string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	return fmt.Errorf("S3 isteği başarısız (%s, %d %s): %s", key, resp.StatusCode, body.Code, body.Message)
+}
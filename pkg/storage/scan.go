@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var ErrInfected = errors.New("Dosyada zararlı içerik bulundu")
+
+// Yüklenen dosyalar depoya yazılmadan önce taranır. Zararlı içerikte ErrInfected (sarmalanmış)
+// dönmelidir; diğer hatalar taramanın yapılamadığı anlamına gelir ve yükleme reddedilir.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// Fonksiyonu Scanner olarak kullanmak için (ör. testler veya harici tarama servisleri)
+type ScannerFunc func(ctx context.Context, r io.Reader) error
+
+func (f ScannerFunc) Scan(ctx context.Context, r io.Reader) error {
+	return f(ctx, r)
+}
+
+// ClamAV clamd'nin INSTREAM komutuyla tarama. Addr "host:3310" veya unix soket yolu ("/run/clamav/clamd.sock").
+type Clamd struct {
+	Addr    string
+	Timeout time.Duration // Boşsa 2 dakika
+}
+
+const clamdChunkSize = 64 << 10
+
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) error {
+	network := "tcp"
+	if strings.HasPrefix(c.Addr, "/") {
+		network = "unix"
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, c.Addr)
+	if err != nil {
+		return fmt.Errorf("clamd bağlantısı kurulamadı: %w", err)
+	}
+	defer conn.Close()
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// Her parça 4 baytlık (big-endian) uzunlukla gönderilir, sıfır uzunluk akışı bitirir
+	w := bufio.NewWriterSize(conn, clamdChunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, clamdChunkSize)
+	var size [4]byte
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			w.Write(size[:])
+			if _, err := w.Write(buf[:n]); err != nil {
+				return fmt.Errorf("clamd'ye gönderilemedi: %w", err)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	w.Write(size[:])
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("clamd'ye gönderilemedi: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return fmt.Errorf("clamd yanıtı okunamadı: %w", err)
+	}
+	// "stream: OK", "stream: Eicar-Test-Signature FOUND" veya "INSTREAM size limit exceeded. ERROR"
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		name := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return fmt.Errorf("%w: %s", ErrInfected, name)
+	default:
+		return fmt.Errorf("clamd tarama hatası: %s", reply)
+	}
+}
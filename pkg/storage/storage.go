@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Dosya saklama sürücüleri (müşteri ekleri vb.). Anahtarlar "/" ile ayrılmış göreli yollardır,
+// ör. "tenants/1/customers/5/9b2c...". Sürücü seçimi api servisinde ortam değişkenleriyle yapılır.
+
+var (
+	ErrNotFound   = errors.New("Dosya bulunamadı")
+	ErrInvalidKey = errors.New("Geçersiz dosya anahtarı")
+)
+
+type Storage interface {
+	// size bilinmiyorsa -1 verilebilir; S3 sürücüsü bilinen boyut ister
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Olmayan dosyayı silmek hata değildir
+	Delete(ctx context.Context, key string) error
+}
+
+// Dosyayı doğrudan depodan indirmek için süreli bağlantı üretebilen sürücüler (S3).
+// Desteklemeyen sürücülerde dosya api üzerinden akıtılır.
+type Presigner interface {
+	PresignGet(key string, ttl time.Duration, filename, contentType string) (string, error)
+}
+
+// Boş, mutlak veya ".." içeren anahtarlar reddedilir
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package task
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler fonksiyonları sade tutulur, iş mantığı service katmanında
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Görev listeleme (GET /api/tasks?assignee_id=1&customer_id=2&deal_id=3&status=open,in_progress&page=1&pageSize=10)
+func (h *Handler) GetTasksHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	q := r.URL.Query()
+	params := TaskListParams{TenantID: tenantOf(user)}
+	params.AssigneeID, _ = strconv.Atoi(q.Get("assignee_id"))
+	params.CustomerID, _ = strconv.Atoi(q.Get("customer_id"))
+	params.DealID, _ = strconv.Atoi(q.Get("deal_id"))
+	if s := strings.TrimSpace(q.Get("status")); s != "" {
+		params.Statuses = strings.Split(s, ",")
+	}
+	params.Page, _ = strconv.Atoi(q.Get("page"))
+	params.PageSize, _ = strconv.Atoi(q.Get("pageSize"))
+
+	result, err := GetTasks(h.DBReplica, params)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Görev listesi alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Oturum kullanıcısının açık görevleri (GET /api/tasks/my)
+func (h *Handler) GetMyTasksHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	result, err := GetMyTasks(h.DBReplica, user, page, pageSize)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Görevler alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Geciken görevler (GET /api/tasks/overdue, yöneticiler ?all=true ile tüm ekibi görebilir)
+func (h *Handler) GetOverdueTasksHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	assigneeID := user.ID
+	if r.URL.Query().Get("all") == "true" {
+		if !user.IsManager() {
+			common.WriteError(w, http.StatusForbidden, "Tüm ekibin görevlerini sadece yöneticiler görebilir", nil)
+			return
+		}
+		assigneeID = 0
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	result, err := GetOverdueTasks(h.DBReplica, user, assigneeID, page, pageSize)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Geciken görevler alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Görev ekleme (POST /api/tasks)
+func (h *Handler) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	var t Task
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateTask(h.DBPrimary, user, &t); err != nil {
+		writeTaskError(w, "Görev eklenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// Tek görev (GET /api/tasks/{id})
+func (h *Handler) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/tasks/")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz görev ID", err)
+		return
+	}
+	t, err := GetTask(h.DBReplica, user, id)
+	if err != nil {
+		writeTaskError(w, "Görev alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// Görev güncelleme (PUT /api/tasks/{id})
+func (h *Handler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/tasks/")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz görev ID", err)
+		return
+	}
+	var t Task
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	t.ID = id
+	if err := UpdateTask(h.DBPrimary, user, &t); err != nil {
+		writeTaskError(w, "Görev güncellenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// Görev silme (DELETE /api/tasks/{id})
+func (h *Handler) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.UserFromContext(r.Context())
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, "Yetkilendirme gerekli", nil)
+		return
+	}
+	id, err := idFromPath(r.URL.Path, "/api/tasks/")
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz görev ID", err)
+		return
+	}
+	if err := DeleteTask(h.DBPrimary, user, id); err != nil {
+		writeTaskError(w, "Görev silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Servis hatasını uygun HTTP durum koduna çevirir
+func writeTaskError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrForbidden):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusBadRequest, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// /api/tasks/12 gibi yollardan ID'yi çıkarır
+func idFromPath(path, prefix string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(path, prefix))
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
@@ -0,0 +1,37 @@
+package task
+
+import "time"
+
+// Görev durumları
+const (
+	StatusOpen       = "open"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusCancelled  = "cancelled"
+)
+
+// Görev öncelikleri
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// Müşteri veya fırsatla ilişkili takip görevi (ör. "Cuma günü Ayşe'yi geri ara")
+type Task struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	DueAt       time.Time  `json:"due_at"`
+	AssigneeID  int        `json:"assignee_id"`
+	CustomerID  int        `json:"customer_id,omitempty"`
+	DealID      int        `json:"deal_id,omitempty"`
+	Status      string     `json:"status"`   // open, in_progress, done, cancelled
+	Priority    string     `json:"priority"` // low, normal, high, urgent
+	CreatedBy   int        `json:"created_by,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	RemindedAt  *time.Time `json:"reminded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	TenantID    int        `json:"-"`
+}
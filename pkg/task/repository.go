@@ -0,0 +1,175 @@
+package task
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const taskColumns = "id, title, description, due_at, assignee_id, customer_id, deal_id, status, priority, created_by, completed_at, reminded_at, created_at, tenant_id"
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row scanner, t *Task) error {
+	var assigneeID, customerID, dealID, createdBy sql.NullInt64
+	var completedAt, remindedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.Title, &t.Description, &t.DueAt, &assigneeID, &customerID, &dealID,
+		&t.Status, &t.Priority, &createdBy, &completedAt, &remindedAt, &t.CreatedAt, &t.TenantID); err != nil {
+		return err
+	}
+	t.AssigneeID = int(assigneeID.Int64)
+	t.CustomerID = int(customerID.Int64)
+	t.DealID = int(dealID.Int64)
+	t.CreatedBy = int(createdBy.Int64)
+	if completedAt.Valid {
+		t.CompletedAt = &completedAt.Time
+	}
+	if remindedAt.Valid {
+		t.RemindedAt = &remindedAt.Time
+	}
+	return nil
+}
+
+func createTaskRepo(db *sql.DB, t *Task) error {
+	row := db.QueryRow(`
+		INSERT INTO tasks (title, description, due_at, assignee_id, customer_id, deal_id, status, priority, created_by, tenant_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7, $8, NULLIF($9, 0), $10)
+		RETURNING `+taskColumns,
+		t.Title, t.Description, t.DueAt, t.AssigneeID, t.CustomerID, t.DealID, t.Status, t.Priority, t.CreatedBy, t.TenantID,
+	)
+	return scanTask(row, t)
+}
+
+func getTaskRepo(db *sql.DB, tenantID, id int) (Task, error) {
+	var t Task
+	err := scanTask(db.QueryRow("SELECT "+taskColumns+" FROM tasks WHERE id = $1 AND tenant_id = $2", id, tenantID), &t)
+	return t, err
+}
+
+// Görevin bağlandığı müşterinin tenant'ı
+func customerTenantRepo(db *sql.DB, id int) (int, error) {
+	var tenantID int
+	err := db.QueryRow("SELECT tenant_id FROM customers WHERE id = $1", id).Scan(&tenantID)
+	return tenantID, err
+}
+
+// Görevin bağlandığı fırsatın tenant'ı
+func dealTenantRepo(db *sql.DB, id int) (int, error) {
+	var tenantID int
+	err := db.QueryRow("SELECT tenant_id FROM deals WHERE id = $1", id).Scan(&tenantID)
+	return tenantID, err
+}
+
+// Görevi günceller. Tamamlanınca completed_at set edilir,
+// bitiş zamanı değişirse hatırlatma tekrar gönderilebilsin diye reminded_at sıfırlanır.
+func updateTaskRepo(db *sql.DB, t *Task) error {
+	row := db.QueryRow(`
+		UPDATE tasks SET title = $1, description = $2, due_at = $3, assignee_id = $4,
+			customer_id = NULLIF($5, 0), deal_id = NULLIF($6, 0), status = $7, priority = $8,
+			completed_at = CASE WHEN $7 = 'done' THEN COALESCE(completed_at, now()) ELSE NULL END,
+			reminded_at = CASE WHEN due_at = $3 THEN reminded_at ELSE NULL END
+		WHERE id = $9 AND tenant_id = $10 RETURNING `+taskColumns,
+		t.Title, t.Description, t.DueAt, t.AssigneeID, t.CustomerID, t.DealID, t.Status, t.Priority, t.ID, t.TenantID,
+	)
+	return scanTask(row, t)
+}
+
+func deleteTaskRepo(db *sql.DB, tenantID, id int) error {
+	_, err := db.Exec("DELETE FROM tasks WHERE id = $1 AND tenant_id = $2", id, tenantID)
+	return err
+}
+
+// Filtreli görev listesi (pagination), bitiş zamanına göre yakından uzağa
+func getTasksRepo(db *sql.DB, params TaskListParams) (TaskListResult, error) {
+	var (
+		args  []interface{}
+		where []string
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where = append(where, "tenant_id = "+arg(params.TenantID))
+	if params.AssigneeID > 0 {
+		where = append(where, "assignee_id = "+arg(params.AssigneeID))
+	}
+	if params.CustomerID > 0 {
+		where = append(where, "customer_id = "+arg(params.CustomerID))
+	}
+	if params.DealID > 0 {
+		where = append(where, "deal_id = "+arg(params.DealID))
+	}
+	if len(params.Statuses) > 0 {
+		var in []string
+		for _, s := range params.Statuses {
+			in = append(in, arg(s))
+		}
+		where = append(where, "status IN ("+strings.Join(in, ", ")+")")
+	}
+	if !params.DueBefore.IsZero() {
+		where = append(where, "due_at < "+arg(params.DueBefore))
+	}
+	filter := " WHERE " + strings.Join(where, " AND ")
+
+	// Toplam kayıt sayısı
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM tasks"+filter, args...).Scan(&total); err != nil {
+		return TaskListResult{}, err
+	}
+
+	query := "SELECT " + taskColumns + " FROM tasks" + filter +
+		" ORDER BY due_at, id LIMIT " + arg(params.PageSize) + " OFFSET " + arg((params.Page-1)*params.PageSize)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return TaskListResult{}, err
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		var t Task
+		if err := scanTask(rows, &t); err != nil {
+			return TaskListResult{}, err
+		}
+		tasks = append(tasks, t)
+	}
+	return TaskListResult{Tasks: tasks, Total: total, Page: params.Page, PageSize: params.PageSize}, rows.Err()
+}
+
+// Zamanı gelmiş ve henüz hatırlatılmamış açık görevleri işaretleyip döner.
+// UPDATE ... RETURNING sayesinde birden fazla API örneği aynı görevi iki kez hatırlatmaz.
+func claimDueTasksRepo(db *sql.DB, now time.Time, limit int) ([]Task, error) {
+	rows, err := db.Query(`
+		UPDATE tasks SET reminded_at = $1
+		WHERE id IN (
+			SELECT id FROM tasks
+			WHERE status IN ('open', 'in_progress') AND reminded_at IS NULL AND due_at <= $1
+			ORDER BY due_at LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+taskColumns, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		if err := scanTask(rows, &t); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// Gönderilemeyen hatırlatmanın bir sonraki turda tekrar denenmesi için işareti kaldırır
+func unmarkRemindedRepo(db *sql.DB, id int) error {
+	_, err := db.Exec("UPDATE tasks SET reminded_at = NULL WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,268 @@
+package task
+
+import (
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/notification"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrTaskNotFound = errors.New("Görev bulunamadı")
+	ErrForbidden    = errors.New("Bu görevi sadece atanan kişi, oluşturan veya bir yönetici değiştirebilir")
+)
+
+// Pagination ve filtreleme için parametreler
+type TaskListParams struct {
+	TenantID   int
+	AssigneeID int
+	CustomerID int
+	DealID     int
+	Statuses   []string  // Boşsa tüm durumlar
+	DueBefore  time.Time // Sadece bu zamandan önce bitmesi gerekenler (gecikenler için)
+	Page       int
+	PageSize   int
+}
+
+type TaskListResult struct {
+	Tasks    []Task
+	Total    int
+	Page     int
+	PageSize int
+}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// Görev listeleme (pagination + filtreleme)
+func GetTasks(db *sql.DB, params TaskListParams) (TaskListResult, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+	for _, s := range params.Statuses {
+		if !isStatus(s) {
+			return TaskListResult{}, fmt.Errorf("Geçersiz görev durumu: %s", s)
+		}
+	}
+	return getTasksRepo(db, params)
+}
+
+// Kullanıcının açık görevleri
+func GetMyTasks(db *sql.DB, user common.AuthUser, page, pageSize int) (TaskListResult, error) {
+	return GetTasks(db, TaskListParams{
+		TenantID:   tenantOf(user),
+		AssigneeID: user.ID,
+		Statuses:   []string{StatusOpen, StatusInProgress},
+		Page:       page,
+		PageSize:   pageSize,
+	})
+}
+
+// Bitiş zamanı geçmiş açık görevler. assigneeID 0 ise tüm ekibin gecikenleri.
+func GetOverdueTasks(db *sql.DB, user common.AuthUser, assigneeID, page, pageSize int) (TaskListResult, error) {
+	return GetTasks(db, TaskListParams{
+		TenantID:   tenantOf(user),
+		AssigneeID: assigneeID,
+		Statuses:   []string{StatusOpen, StatusInProgress},
+		DueBefore:  time.Now(),
+		Page:       page,
+		PageSize:   pageSize,
+	})
+}
+
+// Oturum kullanıcısının tenant'ındaki görev (başka tenant'ın görevi bulunamadı sayılır)
+func GetTask(db *sql.DB, user common.AuthUser, id int) (Task, error) {
+	t, err := getTaskRepo(db, tenantOf(user), id)
+	if err == sql.ErrNoRows {
+		return Task{}, ErrTaskNotFound
+	}
+	return t, err
+}
+
+// Görev ekleme. Atanan kişi verilmezse görev oluşturana atanır.
+func CreateTask(db *sql.DB, user common.AuthUser, t *Task) error {
+	if t.AssigneeID == 0 {
+		t.AssigneeID = user.ID
+	}
+	if t.Status == "" {
+		t.Status = StatusOpen
+	}
+	if t.Priority == "" {
+		t.Priority = PriorityNormal
+	}
+	t.CreatedBy = user.ID
+	t.TenantID = tenantOf(user)
+	if err := validateTask(*t); err != nil {
+		return err
+	}
+	if err := checkReferences(db, *t); err != nil {
+		return err
+	}
+	return createTaskRepo(db, t)
+}
+
+// Görev güncelleme
+func UpdateTask(db *sql.DB, user common.AuthUser, t *Task) error {
+	current, err := GetTask(db, user, t.ID)
+	if err != nil {
+		return err
+	}
+	if !canModify(user, current) {
+		return ErrForbidden
+	}
+	t.TenantID = current.TenantID
+	if t.AssigneeID == 0 {
+		t.AssigneeID = current.AssigneeID
+	}
+	if t.Status == "" {
+		t.Status = current.Status
+	}
+	if t.Priority == "" {
+		t.Priority = current.Priority
+	}
+	if t.DueAt.IsZero() {
+		t.DueAt = current.DueAt
+	}
+	if err := validateTask(*t); err != nil {
+		return err
+	}
+	if err := checkReferences(db, *t); err != nil {
+		return err
+	}
+	return updateTaskRepo(db, t)
+}
+
+// Görev silme
+func DeleteTask(db *sql.DB, user common.AuthUser, id int) error {
+	current, err := GetTask(db, user, id)
+	if err != nil {
+		return err
+	}
+	if !canModify(user, current) {
+		return ErrForbidden
+	}
+	return deleteTaskRepo(db, current.TenantID, id)
+}
+
+func canModify(user common.AuthUser, t Task) bool {
+	return t.AssigneeID == user.ID || t.CreatedBy == user.ID || user.IsManager()
+}
+
+// Görevin bağlandığı müşteri ve fırsat görevle aynı tenant'ta olmalı
+func checkReferences(db *sql.DB, t Task) error {
+	if t.CustomerID > 0 {
+		tenantID, err := customerTenantRepo(db, t.CustomerID)
+		if err == sql.ErrNoRows || (err == nil && tenantID != t.TenantID) {
+			return errors.New("Müşteri bulunamadı")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if t.DealID > 0 {
+		tenantID, err := dealTenantRepo(db, t.DealID)
+		if err == sql.ErrNoRows || (err == nil && tenantID != t.TenantID) {
+			return errors.New("Fırsat bulunamadı")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Zamanı gelen görevler için notification-svc'ye hatırlatma komutu gönderir
+func SendDueReminders(ctx context.Context, db *sql.DB) error {
+	tasks, err := claimDueTasksRepo(db, time.Now(), 100)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		cmd := notification.Command{
+			UserID:  t.AssigneeID,
+			Event:   "task.due",
+			Subject: "Görev zamanı: " + t.Title,
+			Body:    reminderBody(t),
+		}
+		if err := notification.Publish(ctx, cmd); err != nil {
+			log.Printf("Görev hatırlatması gönderilemedi (görev=%d): %v", t.ID, err)
+			_ = unmarkRemindedRepo(db, t.ID)
+		}
+	}
+	return nil
+}
+
+// Hatırlatıcıyı interval aralıklarla ctx iptal edilene kadar çalıştırır
+func RunReminderScheduler(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := SendDueReminders(ctx, db); err != nil {
+				log.Printf("Görev hatırlatıcı hatası: %v", err)
+			}
+		}
+	}
+}
+
+func reminderBody(t Task) string {
+	body := fmt.Sprintf("Bitiş: %s\nÖncelik: %s", t.DueAt.Format("02-01-2006 15:04"), t.Priority)
+	if t.CustomerID > 0 {
+		body += fmt.Sprintf("\nMüşteri ID: %d", t.CustomerID)
+	}
+	if t.DealID > 0 {
+		body += fmt.Sprintf("\nFırsat ID: %d", t.DealID)
+	}
+	if t.Description != "" {
+		body += "\n\n" + t.Description
+	}
+	return body
+}
+
+// --- Validasyon Fonksiyonları ---
+func isStatus(s string) bool {
+	switch s {
+	case StatusOpen, StatusInProgress, StatusDone, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+func validateTask(t Task) error {
+	if utf8.RuneCountInString(t.Title) < 2 {
+		return errors.New("Başlık en az 2 karakter olmalı")
+	}
+	if t.DueAt.IsZero() {
+		return errors.New("Bitiş zamanı zorunlu")
+	}
+	if t.AssigneeID <= 0 {
+		return errors.New("Geçersiz atanan kullanıcı")
+	}
+	if t.CustomerID < 0 || t.DealID < 0 {
+		return errors.New("Geçersiz müşteri veya fırsat ID")
+	}
+	if !isStatus(t.Status) {
+		return errors.New("Geçersiz görev durumu")
+	}
+	switch t.Priority {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+	default:
+		return errors.New("Geçersiz öncelik")
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	MaxAttempts            = 8  // Bir teslimat için toplam deneme
+	MaxConsecutiveFailures = 20 // Bu kadar ardışık başarısız denemede abonelik kapatılır
+
+	baseBackoff       = 30 * time.Second
+	maxBackoff        = time.Hour
+	requestTimeout    = 10 * time.Second
+	responseBodyLimit = 1024
+
+	SignatureHeader = "X-CRM-Signature"
+	EventHeader     = "X-CRM-Event"
+	DeliveryHeader  = "X-CRM-Delivery"
+	TimestampHeader = "X-CRM-Timestamp"
+)
+
+var (
+	ErrSignatureInvalid = errors.New("Webhook imzası geçersiz")
+	ErrSignatureExpired = errors.New("Webhook imzasının zaman damgası çok eski")
+	errPrivateAddress   = errors.New("Özel ağ adreslerine webhook gönderilemez")
+)
+
+// İmza başlığı: t=<unix zaman>,v1=<hex HMAC-SHA256(secret, "<t>.<gövde>")>.
+// Zaman damgası imzaya dahil olduğu için eski bir isteğin tekrar oynatılması alıcı tarafında reddedilebilir.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Alıcıların kullanabileceği doğrulama. tolerance sıfırsa zaman damgası kontrol edilmez.
+// Secret yenileme sırasında birden fazla v1 gelebileceği için herhangi biri eşleşirse geçerlidir.
+func VerifySignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			t, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return ErrSignatureInvalid
+			}
+			timestamp = t
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrSignatureInvalid
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+	_, expected, _ := strings.Cut(Sign(secret, timestamp, body), ",v1=")
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrSignatureInvalid
+}
+
+// n. başarısız denemeden sonraki bekleme: 30sn, 1dk, 2dk, ... en fazla 1 saat
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := baseBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// Aynı anda düşen teslimatlar alıcıyı birlikte yormasın diye beklemeye %20'ye kadar rastgele ekleme yapılır
+func withJitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// Webhook istemcisi: yönlendirmeler izlenmez, allowPrivate false ise loopback, özel ağ ve
+// link-local adreslere (ör. bulut metadata servisi) bağlanılmaz. Kontrol DNS çözümlemesinden
+// sonra bağlanılan IP üzerinde yapıldığı için DNS ile adres değiştirme işe yaramaz.
+func NewHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// Teslimatı bir kez dener ve sonucu d üzerine yazar: deneme sayısı, yanıt, sonraki deneme zamanı
+// ve durum. 2xx dışındaki her yanıt ve ağ hatası başarısız sayılır. Başarılıysa true döner.
+func Attempt(ctx context.Context, client *http.Client, url, secret string, d *Delivery, now time.Time) bool {
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = 0
+	d.ResponseBody = ""
+	d.Error = ""
+
+	start := time.Now()
+	err := send(ctx, client, url, secret, d, now)
+	d.DurationMs = int(time.Since(start) / time.Millisecond)
+	if err == nil && d.ResponseStatus >= 200 && d.ResponseStatus < 300 {
+		d.Status = StatusSucceeded
+		d.NextAttemptAt = nil
+		return true
+	}
+	if err != nil {
+		d.Error = err.Error()
+	} else {
+		d.Error = fmt.Sprintf("Alıcı %d döndü", d.ResponseStatus)
+	}
+	if d.Attempts >= MaxAttempts {
+		d.Status = StatusFailed
+		d.NextAttemptAt = nil
+		return false
+	}
+	next := now.Add(withJitter(Backoff(d.Attempts)))
+	d.Status = StatusPending
+	d.NextAttemptAt = &next
+	return false
+}
+
+func send(ctx context.Context, client *http.Client, url, secret string, d *Delivery, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Go-CRM-Webhooks/1.0")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(secret, now.Unix(), d.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	d.ResponseStatus = resp.StatusCode
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	d.ResponseBody = strings.ToValidUTF8(string(body), "")
+	// Bağlantının tekrar kullanılabilmesi için kalan gövde (sınırlı) okunur
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return nil
+}
+
+func disabledReason(maxFailures int) string {
+	return fmt.Sprintf("%d ardışık başarısız teslimat", maxFailures)
+}
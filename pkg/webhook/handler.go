@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"Go-CRM/pkg/common"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Webhook aboneliklerinin yönetimi sadece adminlere açıktır
+
+type Handler struct {
+	DBPrimary *sql.DB
+	DBReplica *sql.DB
+}
+
+// Abonelik listeleme (GET /api/webhooks)
+func (h *Handler) GetSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	subs, err := GetSubscriptions(h.DBReplica, user)
+	if err != nil {
+		writeWebhookError(w, "Webhook abonelikleri alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+// Abonelik ekleme (POST /api/webhooks); yanıttaki secret bir daha gösterilmez
+func (h *Handler) CreateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	var s Subscription
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	if err := CreateSubscription(h.DBPrimary, user, &s); err != nil {
+		writeWebhookError(w, "Webhook aboneliği eklenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s)
+}
+
+// Abonelik detayı (GET /api/webhooks/{id})
+func (h *Handler) GetSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := pathID(r.URL.Path, 2)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz abonelik ID", err)
+		return
+	}
+	s, err := GetSubscription(h.DBReplica, user, int(id))
+	if err != nil {
+		writeWebhookError(w, "Webhook aboneliği alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// Abonelik güncelleme (PUT /api/webhooks/{id}); {"active": true} kapatılmış aboneliği yeniden açar
+func (h *Handler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := pathID(r.URL.Path, 2)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz abonelik ID", err)
+		return
+	}
+	var s Subscription
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz istek gövdesi", err)
+		return
+	}
+	s.ID = int(id)
+	if err := UpdateSubscription(h.DBPrimary, user, &s); err != nil {
+		writeWebhookError(w, "Webhook aboneliği güncellenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// Abonelik silme (DELETE /api/webhooks/{id})
+func (h *Handler) DeleteSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := pathID(r.URL.Path, 2)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz abonelik ID", err)
+		return
+	}
+	if err := DeleteSubscription(h.DBPrimary, user, int(id)); err != nil {
+		writeWebhookError(w, "Webhook aboneliği silinemedi", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Secret yenileme (POST /api/webhooks/{id}/secret)
+func (h *Handler) RotateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := pathID(r.URL.Path, 2)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz abonelik ID", err)
+		return
+	}
+	s, err := RotateSecret(h.DBPrimary, user, int(id))
+	if err != nil {
+		writeWebhookError(w, "Webhook secret'ı yenilenemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// Teslimat kayıtları (GET /api/webhooks/{id}/deliveries?status=failed&before_id=120&limit=50)
+func (h *Handler) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := pathID(r.URL.Path, 2)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz abonelik ID", err)
+		return
+	}
+	q := r.URL.Query()
+	params := DeliveryListParams{SubscriptionID: int(id), Status: q.Get("status")}
+	params.BeforeID, _ = strconv.ParseInt(q.Get("before_id"), 10, 64)
+	params.Limit, _ = strconv.Atoi(q.Get("limit"))
+	deliveries, err := GetDeliveries(h.DBReplica, user, params)
+	if err != nil {
+		writeWebhookError(w, "Webhook teslimatları alınamadı", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// Elle yeniden gönderim (POST /api/webhooks/{id}/deliveries/{deliveryId}/redeliver).
+// Yeni teslimat kuyruğa alınır ve 202 ile döner; sonucu teslimat kayıtlarından izlenir.
+func (h *Handler) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := common.UserFromContext(r.Context())
+	id, err := pathID(r.URL.Path, 2)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz abonelik ID", err)
+		return
+	}
+	deliveryID, err := pathID(r.URL.Path, 4)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Geçersiz teslimat ID", err)
+		return
+	}
+	d, err := Redeliver(h.DBPrimary, user, int(id), deliveryID)
+	if err != nil {
+		writeWebhookError(w, "Webhook yeniden gönderilemedi", err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
+
+func writeWebhookError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrAdminOnly):
+		common.WriteError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrDeliveryNotFound):
+		common.WriteError(w, http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrSubscriptionInactive):
+		common.WriteError(w, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrInvalidEventType), errors.Is(err, ErrEventTypesRequired),
+		errors.Is(err, ErrInvalidDeliveryStatus), errors.Is(err, ErrTooManySubscriptions):
+		common.WriteError(w, http.StatusBadRequest, err.Error(), err)
+	default:
+		common.WriteError(w, http.StatusInternalServerError, msg, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// /api/webhooks/3/deliveries/17/redeliver gibi yollardan index'teki ID'yi çıkarır
+// (0: "api", 1: "webhooks", 2: abonelik ID, 4: teslimat ID)
+func pathID(path string, index int) (int64, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if index >= len(parts) {
+		return 0, errors.New("ID bulunamadı")
+	}
+	id, err := strconv.ParseInt(parts[index], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("ID pozitif olmalı")
+	}
+	return id, nil
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tenant'ın webhook aboneliği. Secret sadece oluşturma ve yenileme yanıtında döner.
+type Subscription struct {
+	ID                  int        `json:"id"`
+	URL                 string     `json:"url"`
+	Description         string     `json:"description,omitempty"`
+	EventTypes          []string   `json:"event_types"` // "*" tüm olaylar
+	Secret              string     `json:"secret,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedBy           int        `json:"created_by,omitempty"`
+	TenantID            int        `json:"-"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Teslimat durumları
+const (
+	StatusPending   = "pending" // İlk deneme veya yeniden deneme bekliyor
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed" // Deneme hakkı bitti veya abonelik kapatıldı
+)
+
+// Bir olayın bir aboneliğe teslimatı ve son denemenin sonucu
+type Delivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int             `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty"` // İlk 1KB
+	Error          string          `json:"error,omitempty"`
+	DurationMs     int             `json:"duration_ms,omitempty"`
+	RedeliveryOf   int64           `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Aboneye gönderilen gövde
+type Payload struct {
+	ID         string          `json:"id"` // Olay kimliği; aynı olay tekrar gönderilirse aynı kalır
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type DeliveryListParams struct {
+	SubscriptionID int
+	Status         string
+	BeforeID       int64 // Sayfalama: önceki sayfanın son teslimat ID'si
+	Limit          int
+}
@@ -0,0 +1,274 @@
+package webhook
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const subscriptionColumns = `id, url, description, event_types, secret, active, consecutive_failures, disabled_at,
+	disabled_reason, created_by, tenant_id, created_at, updated_at`
+
+// *sql.Row ve *sql.Rows için ortak arayüz
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row scanner, s *Subscription) error {
+	var createdBy sql.NullInt64
+	var disabledAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.URL, &s.Description, pq.Array(&s.EventTypes), &s.Secret, &s.Active,
+		&s.ConsecutiveFailures, &disabledAt, &s.DisabledReason, &createdBy, &s.TenantID, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return err
+	}
+	s.CreatedBy = int(createdBy.Int64)
+	if disabledAt.Valid {
+		s.DisabledAt = &disabledAt.Time
+	}
+	return nil
+}
+
+func createSubscriptionRepo(db *sql.DB, s *Subscription) error {
+	return scanSubscription(db.QueryRow(`
+		INSERT INTO webhook_subscriptions (tenant_id, url, description, event_types, secret, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0)) RETURNING `+subscriptionColumns,
+		s.TenantID, s.URL, s.Description, pq.Array(s.EventTypes), s.Secret, s.Active, s.CreatedBy), s)
+}
+
+func getSubscriptionRepo(db *sql.DB, id int) (Subscription, error) {
+	var s Subscription
+	err := scanSubscription(db.QueryRow("SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE id = $1", id), &s)
+	return s, err
+}
+
+func getSubscriptionsRepo(db *sql.DB, tenantID int) ([]Subscription, error) {
+	rows, err := db.Query("SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE tenant_id = $1 ORDER BY id", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		var s Subscription
+		if err := scanSubscription(rows, &s); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// Tekrar etkinleştirilen aboneliğin hata sayacı ve kapatılma bilgisi sıfırlanır
+func updateSubscriptionRepo(db *sql.DB, s *Subscription) error {
+	return scanSubscription(db.QueryRow(`
+		UPDATE webhook_subscriptions SET url = $1, description = $2, event_types = $3, active = $4,
+			consecutive_failures = CASE WHEN $4 AND NOT active THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $4 THEN NULL ELSE disabled_at END,
+			disabled_reason = CASE WHEN $4 THEN '' ELSE disabled_reason END,
+			updated_at = now()
+		WHERE id = $5 RETURNING `+subscriptionColumns,
+		s.URL, s.Description, pq.Array(s.EventTypes), s.Active, s.ID), s)
+}
+
+func updateSecretRepo(db *sql.DB, id int, secret string) error {
+	_, err := db.Exec("UPDATE webhook_subscriptions SET secret = $1, updated_at = now() WHERE id = $2", secret, id)
+	return err
+}
+
+func deleteSubscriptionRepo(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	return err
+}
+
+// Olayı, tenant'ın bu olay tipine abone olan aktif aboneliklerine teslimat olarak ekler.
+// Aynı olay (Kafka'dan tekrar okunsa bile) bir aboneliğe bir kez eklenir.
+func enqueueEventRepo(db *sql.DB, tenantID int, eventID, eventType string, payload []byte) (int, error) {
+	res, err := db.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		SELECT id, $2, $3, $4, 'pending', now() FROM webhook_subscriptions
+		WHERE tenant_id = $1 AND active AND ($3 = ANY(event_types) OR '*' = ANY(event_types))
+		ON CONFLICT (subscription_id, event_id) WHERE redelivery_of IS NULL DO NOTHING`,
+		tenantID, eventID, eventType, string(payload))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_attempt_at, d.response_status, d.response_body, d.error, d.duration_ms,
+	d.redelivery_of, d.created_at`
+
+func scanDelivery(row scanner, d *Delivery, extra ...interface{}) error {
+	var payload string
+	var next, last sql.NullTime
+	var redeliveryOf sql.NullInt64
+	dest := []interface{}{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&next, &last, &d.ResponseStatus, &d.ResponseBody, &d.Error, &d.DurationMs, &redeliveryOf, &d.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	d.Payload = []byte(payload)
+	if next.Valid {
+		d.NextAttemptAt = &next.Time
+	}
+	if last.Valid {
+		d.LastAttemptAt = &last.Time
+	}
+	d.RedeliveryOf = redeliveryOf.Int64
+	return nil
+}
+
+func getDeliveryRepo(db *sql.DB, id int64) (Delivery, error) {
+	var d Delivery
+	err := scanDelivery(db.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries d WHERE d.id = $1", id), &d)
+	return d, err
+}
+
+func getDeliveriesRepo(db *sql.DB, params DeliveryListParams) ([]Delivery, error) {
+	args := []interface{}{params.SubscriptionID}
+	where := []string{"d.subscription_id = $1"}
+	if params.Status != "" {
+		args = append(args, params.Status)
+		where = append(where, fmt.Sprintf("d.status = $%d", len(args)))
+	}
+	if params.BeforeID > 0 {
+		args = append(args, params.BeforeID)
+		where = append(where, fmt.Sprintf("d.id < $%d", len(args)))
+	}
+	args = append(args, params.Limit)
+	rows, err := db.Query("SELECT "+deliveryColumns+" FROM webhook_deliveries d WHERE "+strings.Join(where, " AND ")+
+		fmt.Sprintf(" ORDER BY d.id DESC LIMIT $%d", len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Teslimatın aynı gövdeyle yeni bir kopyasını hemen gönderilmek üzere ekler
+func redeliverRepo(db *sql.DB, d Delivery) (Delivery, error) {
+	var created Delivery
+	err := scanDelivery(db.QueryRow(`
+		WITH d AS (
+			INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at, redelivery_of)
+			VALUES ($1, $2, $3, $4, 'pending', now(), $5)
+			RETURNING *
+		)
+		SELECT `+deliveryColumns+" FROM d",
+		d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), d.ID), &created)
+	return created, err
+}
+
+// Zamanı gelen teslimatları kilitleyip lease süresi kadar ileri atar; birden fazla servis kopyası
+// aynı teslimatı almaz, çöken kopyanın aldıkları lease bitince yeniden denenir.
+func claimDueRepo(db *sql.DB, now time.Time, lease time.Duration, limit int) ([]Delivery, []Subscription, error) {
+	rows, err := db.Query(`
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		), d AS (
+			UPDATE webhook_deliveries w SET next_attempt_at = $1::timestamptz + $2 * interval '1 second'
+			FROM due WHERE w.id = due.id
+			RETURNING w.*
+		)
+		SELECT `+deliveryColumns+`, s.url, s.secret, s.active
+		FROM d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		ORDER BY d.id`, now, int(lease.Seconds()), limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	var subs []Subscription
+	for rows.Next() {
+		var d Delivery
+		var s Subscription
+		if err := scanDelivery(rows, &d, &s.URL, &s.Secret, &s.Active); err != nil {
+			return nil, nil, err
+		}
+		s.ID = d.SubscriptionID
+		deliveries = append(deliveries, d)
+		subs = append(subs, s)
+	}
+	return deliveries, subs, rows.Err()
+}
+
+// Deneme sonucunu yazar ve aboneliğin ardışık hata sayacını günceller. Sayaç sınıra ulaşırsa
+// abonelik kapatılır ve true döner.
+func recordAttemptRepo(db *sql.DB, d Delivery, success bool, maxFailures int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var next sql.NullTime
+	if d.NextAttemptAt != nil {
+		next = sql.NullTime{Time: *d.NextAttemptAt, Valid: true}
+	}
+	if _, err := tx.Exec(`
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4,
+			response_status = $5, response_body = $6, error = $7, duration_ms = $8
+		WHERE id = $9`,
+		d.Status, d.Attempts, next, d.LastAttemptAt, d.ResponseStatus, d.ResponseBody, d.Error, d.DurationMs, d.ID); err != nil {
+		return false, err
+	}
+
+	if success {
+		if _, err := tx.Exec("UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1", d.SubscriptionID); err != nil {
+			return false, err
+		}
+		return false, tx.Commit()
+	}
+
+	var active bool
+	var failures int
+	err = tx.QueryRow("SELECT active, consecutive_failures FROM webhook_subscriptions WHERE id = $1 FOR UPDATE",
+		d.SubscriptionID).Scan(&active, &failures)
+	if err == sql.ErrNoRows {
+		return false, tx.Commit()
+	}
+	if err != nil {
+		return false, err
+	}
+	failures++
+	disable := active && failures >= maxFailures
+	if disable {
+		_, err = tx.Exec(`
+			UPDATE webhook_subscriptions SET consecutive_failures = $2, active = false, disabled_at = now(),
+				disabled_reason = $3, updated_at = now()
+			WHERE id = $1`, d.SubscriptionID, failures, disabledReason(maxFailures))
+	} else {
+		_, err = tx.Exec("UPDATE webhook_subscriptions SET consecutive_failures = $2 WHERE id = $1", d.SubscriptionID, failures)
+	}
+	if err != nil {
+		return false, err
+	}
+	return disable, tx.Commit()
+}
+
+// Kapatılan aboneliğin bekleyen teslimatları başarısız sayılır
+func failPendingRepo(db *sql.DB, subscriptionID int, reason string) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries SET status = 'failed', next_attempt_at = NULL, error = $2
+		WHERE subscription_id = $1 AND status = 'pending'`, subscriptionID, reason)
+	return err
+}
@@ -0,0 +1,324 @@
+package webhook
+
+import (
+	"Go-CRM/pkg/common"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAdminOnly             = errors.New("Bu işlem sadece adminler içindir")
+	ErrSubscriptionNotFound  = errors.New("Webhook aboneliği bulunamadı")
+	ErrDeliveryNotFound      = errors.New("Webhook teslimatı bulunamadı")
+	ErrInvalidURL            = errors.New("Webhook adresi http(s) ile başlayan geçerli bir URL olmalı")
+	ErrInvalidEventType      = errors.New("Geçersiz olay tipi")
+	ErrEventTypesRequired    = errors.New("En az bir olay tipi seçilmeli")
+	ErrSubscriptionInactive  = errors.New("Abonelik kapalı; yeniden gönderim için önce etkinleştirin")
+	ErrInvalidDeliveryStatus = errors.New("Geçersiz teslimat durumu")
+	ErrInvalidEvent          = errors.New("Geçersiz olay zarfı")
+	ErrTooManySubscriptions  = fmt.Errorf("Bir tenant en fazla %d webhook aboneliği ekleyebilir", maxSubscriptionsPerTenant)
+)
+
+const maxSubscriptionsPerTenant = 20
+
+// Abone olunabilecek olay tipleri; "*" hepsi demektir
+var EventTypes = []string{
+	"customer.created",
+	"customer.updated",
+	"customer.merged",
+	"deal.created",
+	"deal.updated",
+	"deal.stage_changed",
+	"deal.won",
+	"deal.lost",
+}
+
+func tenantOf(user common.AuthUser) int {
+	if user.TenantID > 0 {
+		return user.TenantID
+	}
+	return common.DefaultTenantID
+}
+
+// Adres ve olay tiplerini doğrular, olay tiplerini tekilleştirip sıralar
+func ValidateSubscription(s *Subscription) error {
+	u, err := url.Parse(strings.TrimSpace(s.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	s.URL = u.String()
+
+	if len(s.EventTypes) == 0 {
+		return ErrEventTypesRequired
+	}
+	seen := map[string]bool{}
+	types := []string{}
+	for _, t := range s.EventTypes {
+		t = strings.TrimSpace(t)
+		if !validEventType(t) {
+			return fmt.Errorf("%w: %q", ErrInvalidEventType, t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	if seen["*"] {
+		types = []string{"*"}
+	}
+	sort.Strings(types)
+	s.EventTypes = types
+	s.Description = strings.TrimSpace(s.Description)
+	return nil
+}
+
+func validEventType(t string) bool {
+	if t == "*" {
+		return true
+	}
+	for _, e := range EventTypes {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func GetSubscriptions(db *sql.DB, user common.AuthUser) ([]Subscription, error) {
+	if !user.IsAdmin() {
+		return nil, ErrAdminOnly
+	}
+	subs, err := getSubscriptionsRepo(db, tenantOf(user))
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, err
+}
+
+// Aboneliği tenant kontrolüyle getirir; başka tenant'ın aboneliği bulunamadı sayılır
+func getSubscription(db *sql.DB, user common.AuthUser, id int) (Subscription, error) {
+	if !user.IsAdmin() {
+		return Subscription{}, ErrAdminOnly
+	}
+	s, err := getSubscriptionRepo(db, id)
+	if err == sql.ErrNoRows || (err == nil && s.TenantID != tenantOf(user)) {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return s, err
+}
+
+func GetSubscription(db *sql.DB, user common.AuthUser, id int) (Subscription, error) {
+	s, err := getSubscription(db, user, id)
+	s.Secret = ""
+	return s, err
+}
+
+// Yeni abonelik; secret sunucuda üretilir ve sadece bu yanıtta döner
+func CreateSubscription(db *sql.DB, user common.AuthUser, s *Subscription) error {
+	if !user.IsAdmin() {
+		return ErrAdminOnly
+	}
+	if err := ValidateSubscription(s); err != nil {
+		return err
+	}
+	existing, err := getSubscriptionsRepo(db, tenantOf(user))
+	if err != nil {
+		return err
+	}
+	if len(existing) >= maxSubscriptionsPerTenant {
+		return ErrTooManySubscriptions
+	}
+	if s.Secret, err = newSecret(); err != nil {
+		return err
+	}
+	s.TenantID = tenantOf(user)
+	s.CreatedBy = user.ID
+	s.Active = true
+	return createSubscriptionRepo(db, s)
+}
+
+// Adres, açıklama, olay tipleri ve aktiflik güncellenir. Kapatılmış abonelik active=true ile
+// yeniden açıldığında ardışık hata sayacı sıfırlanır.
+func UpdateSubscription(db *sql.DB, user common.AuthUser, s *Subscription) error {
+	if _, err := getSubscription(db, user, s.ID); err != nil {
+		return err
+	}
+	if err := ValidateSubscription(s); err != nil {
+		return err
+	}
+	if err := updateSubscriptionRepo(db, s); err != nil {
+		return err
+	}
+	s.Secret = ""
+	return nil
+}
+
+// Secret yenileme; eski secret hemen geçersiz olur
+func RotateSecret(db *sql.DB, user common.AuthUser, id int) (Subscription, error) {
+	s, err := getSubscription(db, user, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if s.Secret, err = newSecret(); err != nil {
+		return Subscription{}, err
+	}
+	return s, updateSecretRepo(db, id, s.Secret)
+}
+
+// Abonelik silinince teslimat kayıtları da silinir
+func DeleteSubscription(db *sql.DB, user common.AuthUser, id int) error {
+	if _, err := getSubscription(db, user, id); err != nil {
+		return err
+	}
+	return deleteSubscriptionRepo(db, id)
+}
+
+// Teslimat kayıtları en yeniden eskiye
+func GetDeliveries(db *sql.DB, user common.AuthUser, params DeliveryListParams) ([]Delivery, error) {
+	if _, err := getSubscription(db, user, params.SubscriptionID); err != nil {
+		return nil, err
+	}
+	switch params.Status {
+	case "", StatusPending, StatusSucceeded, StatusFailed:
+	default:
+		return nil, ErrInvalidDeliveryStatus
+	}
+	if params.Limit <= 0 || params.Limit > 100 {
+		params.Limit = 50
+	}
+	return getDeliveriesRepo(db, params)
+}
+
+// Teslimatı aynı olay kimliği ve gövdeyle yeniden kuyruğa alır; sonucu yeni teslimat kaydında izlenir.
+// Alıcı olay kimliğiyle (payload.id) tekrarları ayıklayabilir.
+func Redeliver(db *sql.DB, user common.AuthUser, subscriptionID int, deliveryID int64) (Delivery, error) {
+	s, err := getSubscription(db, user, subscriptionID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if !s.Active {
+		return Delivery{}, ErrSubscriptionInactive
+	}
+	d, err := getDeliveryRepo(db, deliveryID)
+	if err == sql.ErrNoRows || (err == nil && d.SubscriptionID != subscriptionID) {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return Delivery{}, err
+	}
+	return redeliverRepo(db, d)
+}
+
+// Kafka'dan okunan alan olayını, tenant'ın ilgili aboneliklerine teslimat olarak ekler.
+// Eklenen teslimat sayısını döner; çözümlenemeyen mesajlarda ErrInvalidEvent (sarmalanmış) döner.
+func EnqueueEvent(db *sql.DB, raw []byte) (int, error) {
+	var e common.DomainEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if e.ID == "" || e.Type == "" {
+		return 0, fmt.Errorf("%w: id veya type eksik", ErrInvalidEvent)
+	}
+	if e.TenantID <= 0 {
+		e.TenantID = common.DefaultTenantID
+	}
+	payload, err := json.Marshal(Payload{ID: e.ID, Type: e.Type, OccurredAt: e.OccurredAt, Data: e.Data})
+	if err != nil {
+		return 0, err
+	}
+	return enqueueEventRepo(db, e.TenantID, e.ID, e.Type, payload)
+}
+
+// Bekleyen teslimatları gönderen döngü. Birden fazla kopya çalışabilir; teslimatlar
+// FOR UPDATE SKIP LOCKED ile paylaşılır.
+type Worker struct {
+	DB        *sql.DB
+	Client    *http.Client
+	Interval  time.Duration // Kuyruk boşken bekleme, boşsa 2 saniye
+	BatchSize int           // Boşsa 20
+	Lease     time.Duration // Alınan teslimatın başka kopyaya verilmeyeceği süre, boşsa 1 dakika
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	for {
+		n, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("Webhook teslimatları alınamadı: %v", err)
+		}
+		// Dolu bir parti geldiyse beklemeden devam edilir
+		if n == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Zamanı gelen bir parti teslimatı gönderir, işlenen teslimat sayısını döner
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	batch := w.BatchSize
+	if batch <= 0 {
+		batch = 20
+	}
+	lease := w.Lease
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	deliveries, subs, err := claimDueRepo(w.DB, time.Now(), lease, batch)
+	if err != nil {
+		return 0, err
+	}
+	for i := range deliveries {
+		w.process(ctx, &deliveries[i], subs[i])
+	}
+	return len(deliveries), nil
+}
+
+func (w *Worker) process(ctx context.Context, d *Delivery, s Subscription) {
+	if !s.Active {
+		// Abonelik kuyruğa alındıktan sonra kapatıldı
+		if err := failPendingRepo(w.DB, s.ID, "Abonelik kapalı"); err != nil {
+			log.Printf("Webhook aboneliği %d bekleyen teslimatları kapatılamadı: %v", s.ID, err)
+		}
+		return
+	}
+	ok := Attempt(ctx, w.Client, s.URL, s.Secret, d, time.Now())
+	disabled, err := recordAttemptRepo(w.DB, *d, ok, MaxConsecutiveFailures)
+	if err != nil {
+		log.Printf("Webhook teslimatı %d güncellenemedi: %v", d.ID, err)
+		return
+	}
+	if disabled {
+		log.Printf("Webhook aboneliği %d kapatıldı: %s", s.ID, disabledReason(MaxConsecutiveFailures))
+		if err := failPendingRepo(w.DB, s.ID, "Abonelik kapatıldı"); err != nil {
+			log.Printf("Webhook aboneliği %d bekleyen teslimatları kapatılamadı: %v", s.ID, err)
+		}
+	}
+}
@@ -1,5 +1,12 @@
 import { getCustomers, addCustomer, updateCustomer, deleteCustomer, getContacts, addContact } from './api.js';
 
+// Keycloak girişinden dönüşte auth-svc token'ı URL fragment'ında gönderir
+const fragment = new URLSearchParams(window.location.hash.slice(1));
+if (fragment.get('token')) {
+  localStorage.setItem('token', fragment.get('token'));
+  history.replaceState(null, '', window.location.pathname + window.location.search);
+}
+
 // Token yoksa login'e yönlendir
 if (!localStorage.getItem('token')) {
   window.location.href = 'index.html';
@@ -116,7 +123,8 @@ const contactTableBody = document.querySelector('#contactTable tbody');
 async function loadContacts() {
   contactTableBody.innerHTML = '<tr><td colspan="3">Yükleniyor...</td></tr>';
   try {
-    const contacts = await getContacts();
+    const result = await getContacts();
+    const contacts = result && result.Contacts;
     contactTableBody.innerHTML = '';
     // Gelen veri null değilse ve bir dizi ise döngüye gir
     if (contacts && Array.isArray(contacts)) {
@@ -124,8 +132,8 @@ async function loadContacts() {
         const tr = document.createElement('tr');
         tr.innerHTML = `
           <td>${c.customer_name}</td>
-          <td>${c.message}</td>
-          <td>${c.date}</td>
+          <td>${c.content}</td>
+          <td>${c.created_at}</td>
         `;
         contactTableBody.appendChild(tr);
       });
@@ -1,9 +1,56 @@
+let mfaToken = null;
+
+async function readError(resp, fallback) {
+  try {
+    const data = await resp.json();
+    return data.error || data.message || fallback;
+  } catch (_) {
+    return fallback;
+  }
+}
+
+function showError(err) {
+  const errorDiv = document.getElementById('loginError');
+  errorDiv.textContent = err.message;
+  errorDiv.classList.remove('d-none');
+}
+
+function finishLogin(data) {
+  localStorage.setItem('token', data.token);
+  // Kurtarma kodları sadece MFA kaydı onaylandığında bir kez gösterilir
+  if (data.recovery_codes) {
+    alert('Kurtarma kodlarınızı güvenli bir yere kaydedin:\n\n' + data.recovery_codes.join('\n'));
+  }
+  window.location.href = 'dashboard.html';
+}
+
+// Şifre doğru ama MFA gerekiyorsa kod formuna geçilir; zorunlu kayıtta önce anahtar alınır
+async function startMFA(data) {
+  mfaToken = data.mfa_token;
+  document.getElementById('loginForm').classList.add('d-none');
+  document.getElementById('mfaForm').classList.remove('d-none');
+  if (data.enrollment_required) {
+    const resp = await fetch('/api/login/mfa/enroll', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ mfa_token: mfaToken })
+    });
+    if (!resp.ok) {
+      throw new Error(await readError(resp, 'MFA kaydı başlatılamadı!'));
+    }
+    const enrollment = await resp.json();
+    document.getElementById('mfaSecret').textContent = enrollment.secret;
+    document.getElementById('mfaUri').href = enrollment.provisioning_uri;
+    document.getElementById('mfaEnroll').classList.remove('d-none');
+  }
+  document.getElementById('mfaCode').focus();
+}
+
 document.getElementById('loginForm').addEventListener('submit', async function(e) {
   e.preventDefault();
   const email = document.getElementById('email').value;
   const password = document.getElementById('password').value;
-  const errorDiv = document.getElementById('loginError');
-  errorDiv.classList.add('d-none');
+  document.getElementById('loginError').classList.add('d-none');
 
   try {
     const resp = await fetch('/api/login', {
@@ -12,14 +59,39 @@ document.getElementById('loginForm').addEventListener('submit', async function(e
       body: JSON.stringify({ email, password })
     });
     if (!resp.ok) {
-      const data = await resp.json();
-      throw new Error(data.message || 'Giriş başarısız!');
+      throw new Error(await readError(resp, 'Giriş başarısız!'));
     }
     const data = await resp.json();
-    localStorage.setItem('token', data.token);
-    window.location.href = 'dashboard.html';
+    if (data.mfa_required) {
+      await startMFA(data);
+      return;
+    }
+    finishLogin(data);
+  } catch (err) {
+    showError(err);
+  }
+});
+
+document.getElementById('mfaForm').addEventListener('submit', async function(e) {
+  e.preventDefault();
+  const value = document.getElementById('mfaCode').value.trim();
+  document.getElementById('loginError').classList.add('d-none');
+  // Rakamlardan oluşan giriş authenticator kodu, diğerleri kurtarma kodu kabul edilir
+  const body = /^\d+$/.test(value.replace(/\s/g, ''))
+    ? { mfa_token: mfaToken, code: value }
+    : { mfa_token: mfaToken, recovery_code: value };
+
+  try {
+    const resp = await fetch('/api/login/mfa', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(body)
+    });
+    if (!resp.ok) {
+      throw new Error(await readError(resp, 'Doğrulama başarısız!'));
+    }
+    finishLogin(await resp.json());
   } catch (err) {
-    errorDiv.textContent = err.message;
-    errorDiv.classList.remove('d-none');
+    showError(err);
   }
-}); 
\ No newline at end of file
+});
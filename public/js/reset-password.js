@@ -0,0 +1,76 @@
+// E-postadaki bağlantı token'ı URL fragment'ında (#token=...) taşır
+const resetToken = new URLSearchParams(window.location.hash.slice(1)).get('token');
+
+function showMessage(id, text) {
+  document.getElementById('resetMessage').classList.add('d-none');
+  document.getElementById('resetError').classList.add('d-none');
+  const div = document.getElementById(id);
+  div.textContent = text;
+  div.classList.remove('d-none');
+}
+
+async function readError(resp, fallback) {
+  try {
+    const data = await resp.json();
+    return data.error || data.message || fallback;
+  } catch (_) {
+    return fallback;
+  }
+}
+
+if (resetToken) {
+  // Token adres çubuğunda ve tarayıcı geçmişinde kalmasın
+  history.replaceState(null, '', window.location.pathname);
+  document.getElementById('forgotForm').classList.add('d-none');
+  document.getElementById('resetForm').classList.remove('d-none');
+  fetch('/api/password/policy')
+    .then(resp => resp.ok ? resp.json() : null)
+    .then(policy => {
+      if (policy) {
+        document.getElementById('policyHint').textContent = 'En az ' + policy.min_length + ' karakter; e-posta adresinizi içermemeli.';
+      }
+    })
+    .catch(() => {});
+}
+
+document.getElementById('forgotForm').addEventListener('submit', async function(e) {
+  e.preventDefault();
+  const email = document.getElementById('email').value;
+  try {
+    const resp = await fetch('/api/password/forgot', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ email })
+    });
+    if (!resp.ok) {
+      throw new Error(await readError(resp, 'İstek gönderilemedi!'));
+    }
+    const data = await resp.json();
+    showMessage('resetMessage', data.message);
+  } catch (err) {
+    showMessage('resetError', err.message);
+  }
+});
+
+document.getElementById('resetForm').addEventListener('submit', async function(e) {
+  e.preventDefault();
+  const newPassword = document.getElementById('newPassword').value;
+  if (newPassword !== document.getElementById('newPasswordAgain').value) {
+    showMessage('resetError', 'Şifreler eşleşmiyor');
+    return;
+  }
+  try {
+    const resp = await fetch('/api/password/reset', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token: resetToken, new_password: newPassword })
+    });
+    if (!resp.ok) {
+      throw new Error(await readError(resp, 'Şifre sıfırlanamadı!'));
+    }
+    document.getElementById('resetForm').classList.add('d-none');
+    showMessage('resetMessage', 'Şifreniz değiştirildi, yeni şifrenizle giriş yapabilirsiniz.');
+  } catch (err) {
+    showMessage('resetError', err.message);
+  }
+});
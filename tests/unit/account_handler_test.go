@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/account"
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestValidateAccount(t *testing.T) {
+	cases := map[string]account.Account{
+		"ad-kisa":          {Name: "A"},
+		"gecersiz-alan":    {Name: "Acme", Domain: "acme"},
+		"alan-adi-e-posta": {Name: "Acme", Domain: "info@acme.com"},
+		"kendi-ustu":       {ID: 4, Name: "Acme", ParentID: 4},
+		"negatif-ust":      {Name: "Acme", ParentID: -1},
+	}
+	for name, a := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := account.ValidateAccount(a); err == nil {
+				t.Errorf("Geçersiz hesap kabul edildi: %+v", a)
+			}
+		})
+	}
+	if err := account.ValidateAccount(account.Account{Name: "Acme Holding", Domain: "acme.com.tr", ParentID: 2}); err != nil {
+		t.Errorf("Geçerli hesap reddedildi: %v", err)
+	}
+}
+
+func TestAccountPeopleSubquery_UsesPlaceholder(t *testing.T) {
+	q := customer.AccountPeopleSubquery("$3", "$1")
+	if !strings.Contains(q, "WHERE id = $3 AND tenant_id = $1") || !strings.Contains(q, "WITH RECURSIVE") {
+		t.Errorf("Beklenmeyen alt sorgu: %s", q)
+	}
+	if !strings.Contains(q, "ap.tenant_id = $1") {
+		t.Errorf("Kişi bağlantıları tenant'a göre süzülmüyor: %s", q)
+	}
+}
+
+func TestLinkAccountPersonHandler_InvalidPath(t *testing.T) {
+	h := &account.Handler{DBPrimary: nil, DBReplica: nil}
+	for _, path := range []string{"/api/accounts/3/people/abc", "/api/accounts/x/people/5", "/api/accounts/3/people/0"} {
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(`{"job_title":"CFO"}`)))
+		req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: common.RoleRep}))
+		w := httptest.NewRecorder()
+
+		h.LinkAccountPersonHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s için beklenen 400, gelen %d", path, w.Code)
+		}
+	}
+}
+
+func TestDeleteAccountHandler_ManagerOnly(t *testing.T) {
+	h := &account.Handler{DBPrimary: nil, DBReplica: nil}
+	req := httptest.NewRequest(http.MethodDelete, "/api/accounts/3", nil)
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: common.RoleRep}))
+	w := httptest.NewRecorder()
+
+	h.DeleteAccountHandler(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Beklenen 403, gelen %d", w.Code)
+	}
+}
+
+func TestAccountHandlers_RequireUser(t *testing.T) {
+	h := &account.Handler{DBPrimary: nil, DBReplica: nil}
+	cases := map[string]struct {
+		method  string
+		path    string
+		handler http.HandlerFunc
+	}{
+		"liste":      {http.MethodGet, "/api/accounts", h.GetAccountsHandler},
+		"tek":        {http.MethodGet, "/api/accounts/3", h.GetAccountHandler},
+		"guncelle":   {http.MethodPut, "/api/accounts/3", h.UpdateAccountHandler},
+		"kisiler":    {http.MethodGet, "/api/accounts/3/people", h.GetAccountPeopleHandler},
+		"bagla":      {http.MethodPut, "/api/accounts/3/people/5", h.LinkAccountPersonHandler},
+		"bag-kaldir": {http.MethodDelete, "/api/accounts/3/people/5", h.UnlinkAccountPersonHandler},
+		"ozet":       {http.MethodGet, "/api/accounts/3/summary", h.GetAccountSummaryHandler},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, bytes.NewReader([]byte(`{}`)))
+			w := httptest.NewRecorder()
+
+			c.handler(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Beklenen 401, gelen %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestUpdateContactHandler_RequiresUser(t *testing.T) {
+	h := &customer.Handler{DBPrimary: nil, DBReplica: nil}
+	req := httptest.NewRequest(http.MethodPut, "/api/contacts/5", bytes.NewReader([]byte(`{"content":"Düzeltme"}`)))
+	w := httptest.NewRecorder()
+
+	h.UpdateContactHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Beklenen 401, gelen %d", w.Code)
+	}
+}
+
+func TestDeleteContactHandler_InvalidID(t *testing.T) {
+	h := &customer.Handler{DBPrimary: nil, DBReplica: nil}
+	req := httptest.NewRequest(http.MethodDelete, "/api/contacts/abc", nil)
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: common.RoleRep}))
+	w := httptest.NewRecorder()
+
+	h.DeleteContactHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Beklenen 400, gelen %d", w.Code)
+	}
+}
+
+// Tenant kontrolü oturum kullanıcısı gerektirir; kimliksiz istekler veritabanına gitmeden reddedilir
+func TestContactReadHandlers_RequireUser(t *testing.T) {
+	h := &customer.Handler{DBPrimary: nil, DBReplica: nil}
+	cases := map[string]struct {
+		url     string
+		handler http.HandlerFunc
+	}{
+		"kayitlar":        {"/api/contacts/5", h.GetContactsHandler},
+		"revizyonlar":     {"/api/contacts/5/revisions", h.GetContactRevisionsHandler},
+		"sorumlu-gecmisi": {"/api/customers/5/owner-history", h.GetOwnerHistoryHandler},
+		"istatistikler":   {"/api/contacts/stats", h.GetContactStatsHandler},
+		"atama-kurallari": {"/api/assignment-rules", h.GetAssignmentRulesHandler},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.url, nil)
+			w := httptest.NewRecorder()
+			c.handler(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Beklenen 401, gelen %d", w.Code)
+			}
+		})
+	}
+}
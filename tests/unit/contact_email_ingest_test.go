@@ -0,0 +1,238 @@
+package unit
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+const multipartEmail = "From: =?utf-8?q?Ay=C5=9Fe_Y=C4=B1lmaz?= <ayse@musteri.com>\r\n" +
+	"To: Satış <satis@firma.com>, ali@firma.com\r\n" +
+	"Cc: muhasebe@musteri.com\r\n" +
+	"Subject: =?utf-8?q?Teklif_g=C3=BCncellemesi?=\r\n" +
+	"Date: Mon, 05 Oct 2026 10:30:00 +0300\r\n" +
+	"Message-ID: <yanit-2@musteri.com>\r\n" +
+	"In-Reply-To: <teklif-1@firma.com>\r\n" +
+	"References: <kok@firma.com>\r\n <teklif-1@firma.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"dis\"\r\n" +
+	"\r\n" +
+	"--dis\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"ic\"\r\n" +
+	"\r\n" +
+	"--ic\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-9\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Te=FEekk=FCrler, teklifi inceledik.=\r\n" +
+	" Yeni fiyat uygun.\r\n" +
+	"--ic\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>HTML gövde</p>\r\n" +
+	"--ic--\r\n" +
+	"--dis\r\n" +
+	"Content-Type: application/pdf; name=\"teklif.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"teklif.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0x\r\nLjQK\r\n" +
+	"--dis--\r\n"
+
+func TestParseEmailMultipart(t *testing.T) {
+	e, err := customer.ParseEmail(strings.NewReader(multipartEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.From.Address != "ayse@musteri.com" || e.From.Name != "Ayşe Yılmaz" {
+		t.Errorf("Gönderen çözülmedi: %+v", e.From)
+	}
+	if len(e.To) != 2 || e.To[0].Name != "Satış" || e.To[1].Address != "ali@firma.com" {
+		t.Errorf("Alıcılar yanlış: %+v", e.To)
+	}
+	if len(e.Cc) != 1 || e.Cc[0].Address != "muhasebe@musteri.com" {
+		t.Errorf("Bilgi alıcıları yanlış: %+v", e.Cc)
+	}
+	if e.Subject != "Teklif güncellemesi" {
+		t.Errorf("RFC 2047 konu çözülmedi: %q", e.Subject)
+	}
+	if e.MessageID != "<yanit-2@musteri.com>" || e.InReplyTo != "<teklif-1@firma.com>" {
+		t.Errorf("Mesaj kimlikleri yanlış: %q %q", e.MessageID, e.InReplyTo)
+	}
+	if len(e.References) != 2 || e.References[0] != "<kok@firma.com>" {
+		t.Errorf("References yanlış: %v", e.References)
+	}
+	if want := time.Date(2026, 10, 5, 7, 30, 0, 0, time.UTC); !e.Date.Equal(want) {
+		t.Errorf("Tarih yanlış: %v", e.Date)
+	}
+	if e.Text != "Teşekkürler, teklifi inceledik. Yeni fiyat uygun." {
+		t.Errorf("ISO-8859-9 quoted-printable gövde çözülmedi: %q", e.Text)
+	}
+	if len(e.Attachments) != 1 {
+		t.Fatalf("Bir ek bekleniyordu: %+v", e.Attachments)
+	}
+	a := e.Attachments[0]
+	if a.Filename != "teklif.pdf" || a.ContentType != "application/pdf" || string(a.Data) != "%PDF-1.4\n" || a.Size != 9 {
+		t.Errorf("Base64 ek çözülmedi: %+v %q", a, a.Data)
+	}
+}
+
+func TestParseEmailHTMLOnly(t *testing.T) {
+	raw := "From: musteri@example.com\r\n" +
+		"To: satis@firma.com\r\n" +
+		"Subject: Merhaba\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<html><head><style>p{color:red}</style></head><body><p>Toplantı &amp; demo</p><p>Salı&nbsp;uygun</p></body></html>\r\n"
+	e, err := customer.ParseEmail(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Text != "Toplantı & demo\nSalı uygun" {
+		t.Errorf("HTML metne çevrilmedi: %q", e.Text)
+	}
+	if e.MessageID != "" || len(e.Attachments) != 0 {
+		t.Errorf("Beklenmeyen alanlar: %+v", e)
+	}
+}
+
+func TestParseEmailInvalid(t *testing.T) {
+	for name, raw := range map[string]string{
+		"başlık yok":   "sadece metin",
+		"gönderen yok": "To: a@b.com\r\nSubject: x\r\n\r\ngövde\r\n",
+	} {
+		if _, err := customer.ParseEmail(strings.NewReader(raw)); !errors.Is(err, customer.ErrInvalidEmail) {
+			t.Errorf("%s: ErrInvalidEmail bekleniyordu: %v", name, err)
+		}
+	}
+}
+
+func TestIngestEmailHandlerValidation(t *testing.T) {
+	h := &customer.Handler{}
+	user := common.AuthUser{ID: 3, Email: "ali@firma.com", Role: "user", TenantID: 1}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/contacts/emails", strings.NewReader("bozuk mesaj"))
+	rec := httptest.NewRecorder()
+	h.IngestEmailHandler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Oturumsuz istek 401 olmalı: %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/contacts/emails", strings.NewReader("bozuk mesaj"))
+	req = req.WithContext(common.ContextWithUser(req.Context(), user))
+	rec = httptest.NewRecorder()
+	h.IngestEmailHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Ayrıştırılamayan mesaj 400 olmalı: %d %s", rec.Code, rec.Body)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("not_file", "x")
+	mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/api/contacts/emails", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req = req.WithContext(common.ContextWithUser(req.Context(), user))
+	rec = httptest.NewRecorder()
+	h.IngestEmailHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "file") {
+		t.Errorf("file alanı olmadan 400 bekleniyordu: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSMTPServerReceivesMessage(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	type received struct {
+		from string
+		to   []string
+		data []byte
+	}
+	got := make(chan received, 1)
+	server := &common.SMTPServer{
+		Hostname:   "crm.test",
+		AcceptRcpt: func(addr string) bool { return strings.HasSuffix(addr, "@firma.com") },
+		Handler: func(from string, to []string, data []byte) error {
+			if bytes.Contains(data, []byte("reddet")) {
+				return common.ErrMessageRejected
+			}
+			got <- received{from, to, data}
+			return nil
+		},
+	}
+	go server.Serve(l)
+
+	addr := l.Addr().String()
+	msg := []byte("From: ayse@musteri.com\r\nTo: crm@firma.com\r\nSubject: Deneme\r\n\r\n.nokta ile başlayan satır\r\n")
+	if err := smtp.SendMail(addr, nil, "ayse@musteri.com", []string{"crm@firma.com"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-got:
+		if r.from != "ayse@musteri.com" || len(r.to) != 1 || r.to[0] != "crm@firma.com" {
+			t.Errorf("Zarf bilgisi yanlış: %q %v", r.from, r.to)
+		}
+		if _, err := customer.ParseEmail(bytes.NewReader(r.data)); err != nil {
+			t.Errorf("Alınan mesaj ayrıştırılamadı: %v", err)
+		}
+		if !bytes.Contains(r.data, []byte("\n.nokta ile")) {
+			t.Errorf("Nokta kaçışı geri alınmadı: %q", r.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Mesaj işleyiciye ulaşmadı")
+	}
+
+	if err := smtp.SendMail(addr, nil, "ayse@musteri.com", []string{"baskasi@example.com"}, msg); err == nil {
+		t.Error("Kabul edilmeyen alıcı reddedilmeliydi")
+	}
+	err = smtp.SendMail(addr, nil, "ayse@musteri.com", []string{"crm@firma.com"}, []byte("Subject: reddet\r\n\r\nx\r\n"))
+	if err == nil || !strings.Contains(err.Error(), "554") {
+		t.Errorf("İşleyicinin reddettiği mesaj 554 almalı: %v", err)
+	}
+}
+
+func TestParseInboundRecipients(t *testing.T) {
+	r, err := customer.ParseInboundRecipients(" CRM@acme.com=2, @inbox.globex.com=3 ,destek@inbox.globex.com=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		tenant int
+		ok     bool
+	}{
+		"crm@acme.com":             {2, true},
+		"Crm@Acme.com":             {2, true},
+		"ekip@inbox.globex.com":    {3, true},
+		"destek@inbox.globex.com":  {4, true}, // Tam adres alan adından önceliklidir
+		"crm@baska.com":            {0, false},
+		"x@sahte-inbox.globex.com": {0, false},
+		"crm@acme.com.evil.com":    {0, false},
+	}
+	for rcpt, want := range cases {
+		tenantID, ok := r.TenantOf(rcpt)
+		if tenantID != want.tenant || ok != want.ok {
+			t.Errorf("%s: beklenen (%d, %v), gelen (%d, %v)", rcpt, want.tenant, want.ok, tenantID, ok)
+		}
+	}
+
+	// Alıcı listesi olmadan veya tenant'sız adresle dinleyici başlatılmamalı
+	for _, s := range []string{"", " , ", "crm@acme.com", "@inbox.globex.com=0", "crm@acme.com=abc", "acme.com=2", "crm@=2"} {
+		if _, err := customer.ParseInboundRecipients(s); err == nil {
+			t.Errorf("%q kabul edilmemeliydi", s)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestGetContactFeedHandler_BadRequests(t *testing.T) {
+	h := &customer.Handler{DBPrimary: nil, DBReplica: nil}
+	cases := map[string]string{
+		"tarih":          "/api/contacts?from=31-01-2025",
+		"tarih-araligi":  "/api/contacts?from=2025-02-01&to=2025-01-01",
+		"yazar":          "/api/contacts?author_id=abc",
+		"musteri":        "/api/contacts?customer_id=-1",
+		"cursor":         "/api/contacts?cursor=bm90LWEtY3Vyc29y",
+		"imzasiz-cursor": "/api/contacts?cursor=MTcwMDAwMDAwMDAwMDAwMDAwMDo0Mg",
+		"baska-liste":    "/api/contacts?cursor=" + common.EncodeCursor("customers|x", common.Cursor{Values: []string{"2025-01-01T00:00:00Z", "42"}}),
+	}
+	for name, url := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			w := httptest.NewRecorder()
+			h.GetContactFeedHandler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Beklenen 400, gelen %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+// Geçersiz kayıtlar veritabanına gitmeden reddedilmeli (db nil)
+func TestCreateContact_InvalidTypedInteractions(t *testing.T) {
+	cases := map[string]customer.Contact{
+		"bilinmeyen-tip":        {CustomerID: 1, Content: "Görüşme", Type: "fax"},
+		"aramada-yon-yok":       {CustomerID: 1, Content: "Görüşme", Type: customer.ContactTypeCall},
+		"notta-yon":             {CustomerID: 1, Content: "Not", Type: customer.ContactTypeNote, Direction: customer.DirectionInbound},
+		"epostada-sure":         {CustomerID: 1, Content: "Teklif", Type: customer.ContactTypeEmail, Direction: customer.DirectionOutbound, DurationSeconds: 60},
+		"gecersiz-sonuc":        {CustomerID: 1, Content: "Görüşme", Type: customer.ContactTypeCall, Direction: customer.DirectionOutbound, Outcome: "held"},
+		"katilimcisiz-toplanti": {CustomerID: 1, Content: "Demo", Type: customer.ContactTypeMeeting},
+		"gelecek-zaman":         {CustomerID: 1, Content: "Not", OccurredAt: time.Now().Add(24 * time.Hour)},
+		"negatif-sure":          {CustomerID: 1, Content: "Görüşme", Type: customer.ContactTypeCall, Direction: customer.DirectionInbound, DurationSeconds: -5},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := customer.CreateContact(nil, common.AuthUser{ID: 1, Role: common.RoleRep}, &c); err == nil {
+				t.Errorf("Geçersiz kayıt kabul edildi: %+v", c)
+			}
+		})
+	}
+}
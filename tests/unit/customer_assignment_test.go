@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestMatchRule(t *testing.T) {
+	c := customer.Customer{Name: "Ayşe Yılmaz", Region: "Marmara", PostalCode: "34 710", LeadSource: "web"}
+	cases := []struct {
+		name string
+		rule customer.AssignmentRule
+		want bool
+	}{
+		{"round-robin", customer.AssignmentRule{Type: customer.RuleRoundRobin}, true},
+		{"bolge", customer.AssignmentRule{Type: customer.RuleRegion, MatchValue: "marmara"}, true},
+		{"farkli-bolge", customer.AssignmentRule{Type: customer.RuleRegion, MatchValue: "Ege"}, false},
+		{"posta-kodu-oneki", customer.AssignmentRule{Type: customer.RulePostalCode, MatchValue: "34"}, true},
+		{"farkli-posta-kodu", customer.AssignmentRule{Type: customer.RulePostalCode, MatchValue: "35"}, false},
+		{"kaynak", customer.AssignmentRule{Type: customer.RuleLeadSource, MatchValue: "Web"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.Active = true
+			tc.rule.AssigneeIDs = []int64{3, 5}
+			if got := customer.MatchRule(tc.rule, c); got != tc.want {
+				t.Errorf("MatchRule = %v, beklenen %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchRule_InactiveOrEmpty(t *testing.T) {
+	c := customer.Customer{Name: "Ayşe Yılmaz"}
+	if customer.MatchRule(customer.AssignmentRule{Type: customer.RuleRoundRobin, AssigneeIDs: []int64{3}}, c) {
+		t.Error("Pasif kural eşleşmemeli")
+	}
+	if customer.MatchRule(customer.AssignmentRule{Type: customer.RuleRoundRobin, Active: true}, c) {
+		t.Error("Atanan kullanıcısı olmayan kural eşleşmemeli")
+	}
+}
+
+func TestValidateAssignmentRule(t *testing.T) {
+	cases := map[string]customer.AssignmentRule{
+		"ad-kisa":           {Name: "A", Type: customer.RuleRoundRobin, AssigneeIDs: []int64{1}},
+		"gecersiz-tip":      {Name: "Ege", Type: "city", MatchValue: "İzmir", AssigneeIDs: []int64{1}},
+		"deger-yok":         {Name: "Ege", Type: customer.RuleRegion, AssigneeIDs: []int64{1}},
+		"round-robin-deger": {Name: "Herkes", Type: customer.RuleRoundRobin, MatchValue: "x", AssigneeIDs: []int64{1}},
+		"atanan-yok":        {Name: "Ege", Type: customer.RuleRegion, MatchValue: "Ege"},
+		"negatif-atanan":    {Name: "Ege", Type: customer.RuleRegion, MatchValue: "Ege", AssigneeIDs: []int64{-2}},
+	}
+	for name, r := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := customer.ValidateAssignmentRule(r); err == nil {
+				t.Errorf("Geçersiz kural kabul edildi: %+v", r)
+			}
+		})
+	}
+	ok := customer.AssignmentRule{Name: "İstanbul", Type: customer.RulePostalCode, MatchValue: "34", AssigneeIDs: []int64{2, 4}}
+	if err := customer.ValidateAssignmentRule(ok); err != nil {
+		t.Errorf("Geçerli kural reddedildi: %v", err)
+	}
+}
+
+// Yönetici olmayan kullanıcılar veritabanına gitmeden reddedilmeli (db nil)
+func TestBulkReassign_ManagerOnly(t *testing.T) {
+	rep := common.AuthUser{ID: 7, Role: common.RoleRep}
+	if _, err := customer.BulkReassign(nil, rep, 3, []int64{4, 5}, ""); err != customer.ErrManagerOnly {
+		t.Errorf("Beklenen ErrManagerOnly, alınan: %v", err)
+	}
+	manager := common.AuthUser{ID: 1, Role: common.RoleManager}
+	if _, err := customer.BulkReassign(nil, manager, 3, []int64{3}, ""); err == nil {
+		t.Error("Kaynak sorumluya geri atama kabul edildi")
+	}
+}
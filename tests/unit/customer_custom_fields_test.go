@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func customFieldDefs() []customer.FieldDefinition {
+	return []customer.FieldDefinition{
+		{Key: "tax_number", Label: "Vergi No", Type: customer.FieldText, Required: true},
+		{Key: "employees", Label: "Çalışan Sayısı", Type: customer.FieldNumber},
+		{Key: "renewal_date", Label: "Yenileme Tarihi", Type: customer.FieldDate},
+		{Key: "industry", Label: "Sektör", Type: customer.FieldEnum, Options: []string{"retail", "finance"}},
+		{Key: "tags", Label: "Etiketler", Type: customer.FieldMultiSelect, Options: []string{"vip", "partner"}},
+	}
+}
+
+// JSON'dan çözülmüş gibi değer üretir (sayılar float64, listeler []interface{})
+func decodeFields(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestValidateCustomFields(t *testing.T) {
+	defs := customFieldDefs()
+	valid := `{"tax_number":"1234567890","employees":42,"renewal_date":"2026-03-01","industry":"retail","tags":["vip"]}`
+	if err := customer.ValidateCustomFields(defs, decodeFields(t, valid)); err != nil {
+		t.Errorf("Geçerli alanlar reddedildi: %v", err)
+	}
+
+	cases := map[string]string{
+		"zorunlu-eksik":    `{"employees":3}`,
+		"bilinmeyen-alan":  `{"tax_number":"1","color":"red"}`,
+		"sayi-metin":       `{"tax_number":"1","employees":"kırk"}`,
+		"gecersiz-tarih":   `{"tax_number":"1","renewal_date":"01-03-2026"}`,
+		"gecersiz-secenek": `{"tax_number":"1","industry":"energy"}`,
+		"liste-degil":      `{"tax_number":"1","tags":"vip"}`,
+		"listede-gecersiz": `{"tax_number":"1","tags":["vip","gold"]}`,
+	}
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := customer.ValidateCustomFields(defs, decodeFields(t, raw)); err == nil {
+				t.Errorf("Geçersiz alanlar kabul edildi: %s", raw)
+			}
+		})
+	}
+}
+
+func TestValidateFieldDefinition(t *testing.T) {
+	cases := map[string]customer.FieldDefinition{
+		"gecersiz-anahtar":    {Key: "Tax Number", Label: "Vergi No", Type: customer.FieldText},
+		"etiket-kisa":         {Key: "tax_number", Label: "V", Type: customer.FieldText},
+		"gecersiz-tip":        {Key: "tax_number", Label: "Vergi No", Type: "boolean"},
+		"enum-seceneksiz":     {Key: "industry", Label: "Sektör", Type: customer.FieldEnum},
+		"tekrarli-secenek":    {Key: "industry", Label: "Sektör", Type: customer.FieldEnum, Options: []string{"a", "a"}},
+		"metinde-secenek":     {Key: "note", Label: "Not", Type: customer.FieldText, Options: []string{"a"}},
+		"gecersiz-varsayilan": {Key: "industry", Label: "Sektör", Type: customer.FieldEnum, Options: []string{"retail"}, Default: "energy"},
+	}
+	for name, d := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := customer.ValidateFieldDefinition(d); err == nil {
+				t.Errorf("Geçersiz tanım kabul edildi: %+v", d)
+			}
+		})
+	}
+	ok := customer.FieldDefinition{Key: "industry", Label: "Sektör", Type: customer.FieldEnum, Options: []string{"retail", "finance"}, Default: "retail"}
+	if err := customer.ValidateFieldDefinition(ok); err != nil {
+		t.Errorf("Geçerli tanım reddedildi: %v", err)
+	}
+}
+
+// Alan tanımlarını sadece admin değiştirebilir (db nil, yetki kontrolü veritabanından önce)
+func TestCreateFieldDefinitionHandler_AdminOnly(t *testing.T) {
+	h := &customer.Handler{DBPrimary: nil, DBReplica: nil}
+	body := `{"key":"tax_number","label":"Vergi No","type":"text"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/customer-fields", strings.NewReader(body))
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 2, Role: common.RoleManager, TenantID: 1}))
+	w := httptest.NewRecorder()
+
+	h.CreateFieldDefinitionHandler(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Beklenen 403, gelen %d", w.Code)
+	}
+}
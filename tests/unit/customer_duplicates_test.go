@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	cases := map[string]string{
+		"Ali@Ornek.com":             "ali@ornek.com",
+		"  ali+crm@ornek.com ":      "ali@ornek.com",
+		"ali.veli+a+b@ornek.com.tr": "ali.veli@ornek.com.tr",
+		"gecersiz":                  "gecersiz",
+		"":                          "",
+	}
+	for in, want := range cases {
+		if got := customer.NormalizeEmail(in); got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, beklenen %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	// Aynı numaranın farklı yazımları aynı sonucu vermeli
+	for _, in := range []string{"+90 532 123 45 67", "0532 123 45 67", "(532) 123-4567", "905321234567"} {
+		if got := customer.NormalizePhone(in); got != "5321234567" {
+			t.Errorf("NormalizePhone(%q) = %q, beklenen 5321234567", in, got)
+		}
+	}
+	if got := customer.NormalizePhone("112"); got != "112" {
+		t.Errorf("Kısa numara değişmemeli: %q", got)
+	}
+}
+
+func TestMergeCustomersValidation(t *testing.T) {
+	user := common.AuthUser{ID: 1, Role: "manager"}
+	for _, ids := range [][2]int{{0, 2}, {2, 0}, {3, 3}} {
+		if _, err := customer.MergeCustomers(nil, user, ids[0], ids[1]); err == nil {
+			t.Errorf("Geçersiz birleştirme kabul edildi: %v", ids)
+		}
+	}
+}
+
+func TestMergeCustomersHandlerRejectsSameCustomer(t *testing.T) {
+	h := &customer.Handler{}
+	req := httptest.NewRequest(http.MethodPost, "/api/customers/merge", strings.NewReader(`{"survivor_id":5,"merged_id":5}`))
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: "manager"}))
+	rec := httptest.NewRecorder()
+	h.MergeCustomersHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Aynı müşteri için 400 bekleniyordu, gelen %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/customers/merge", strings.NewReader(`{}`))
+	rec = httptest.NewRecorder()
+	h.MergeCustomersHandler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Oturumsuz istek için 401 bekleniyordu, gelen %d", rec.Code)
+	}
+}
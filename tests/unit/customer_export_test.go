@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestXLSXWriterRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := common.NewSpreadsheetWriter(f, common.FormatXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wide := make([]string, 28)
+	wide[27] = "AB sütunu"
+	rows := [][]string{
+		{"id", "name", "notes"},
+		{"1", "Şirin & Ortakları <Ltd>", "  baştaki boşluk"},
+		{"2", "", "=SUM(A1:A2)"},
+		wide,
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got := readAllRows(t, path, common.FormatXLSX)
+	want := [][]string{rows[0], rows[1], {"2", "", "=SUM(A1:A2)"}, wide}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("XLSX geri okunamadı:\n%q\n%q", got, want)
+	}
+}
+
+func TestCSVWriterEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := common.NewSpreadsheetWriter(&buf, common.FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]string{"=HYPERLINK(\"x\")", "+90 532 123 45 67", "-12,5", "@cmd", "+ekle", "Ali"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := strings.TrimPrefix(buf.String(), "\uFEFF")
+	if out == buf.String() {
+		t.Error("CSV UTF-8 BOM ile başlamalı")
+	}
+	want := "\"'=HYPERLINK(\"\"x\"\")\",+90 532 123 45 67,\"-12,5\",'@cmd,'+ekle,Ali\n"
+	if out != want {
+		t.Errorf("CSV hücreleri hatalı:\n%q\n%q", out, want)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	common.SetURLSigningKey([]byte("test-secret"))
+	link := common.SignURL("/api/exports/7/download", time.Now().Add(time.Minute))
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := common.VerifySignedURL("/api/exports/7/download", u.Query()); err != nil {
+		t.Errorf("Geçerli bağlantı reddedildi: %v", err)
+	}
+	if err := common.VerifySignedURL("/api/exports/8/download", u.Query()); !errors.Is(err, common.ErrLinkInvalid) {
+		t.Errorf("Başka dosyanın imzası kabul edildi: %v", err)
+	}
+	q := u.Query()
+	q.Set("expires", "9999999999")
+	if err := common.VerifySignedURL("/api/exports/7/download", q); !errors.Is(err, common.ErrLinkInvalid) {
+		t.Errorf("Süresi değiştirilmiş bağlantı kabul edildi: %v", err)
+	}
+	old, _ := url.Parse(common.SignURL("/api/exports/7/download", time.Now().Add(-time.Minute)))
+	if err := common.VerifySignedURL("/api/exports/7/download", old.Query()); !errors.Is(err, common.ErrLinkExpired) {
+		t.Errorf("Süresi dolmuş bağlantı kabul edildi: %v", err)
+	}
+}
+
+func TestExportHandlersValidation(t *testing.T) {
+	h := &customer.Handler{}
+	user := common.AuthUser{ID: 1}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/customers/export?format=pdf", nil)
+	rec := httptest.NewRecorder()
+	h.ExportCustomersHandler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Oturumsuz dışa aktarma için 401 bekleniyordu, gelen %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/customers/export?format=pdf", nil)
+	req = req.WithContext(common.ContextWithUser(req.Context(), user))
+	rec = httptest.NewRecorder()
+	h.ExportCustomersHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Geçersiz biçim için 400 bekleniyordu, gelen %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/exports?type=customers&filter=bilinmeyen=1", nil)
+	req = req.WithContext(common.ContextWithUser(req.Context(), user))
+	rec = httptest.NewRecorder()
+	h.CreateExportHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Geçersiz filtre için 400 bekleniyordu, gelen %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/exports/3/download?expires=1&signature=bozuk", nil)
+	rec = httptest.NewRecorder()
+	h.DownloadExportHandler(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("İmzasız indirme için 403 bekleniyordu, gelen %d", rec.Code)
+	}
+}
+
+func TestStartExportRejectsInvalidType(t *testing.T) {
+	for _, p := range []customer.ExportParams{{Type: "deals"}, {Type: customer.ExportCustomers, Format: "pdf"}} {
+		if _, err := customer.StartExport(nil, nil, common.AuthUser{ID: 1}, p); err == nil {
+			t.Errorf("Geçersiz dışa aktarma kabul edildi: %+v", p)
+		}
+	}
+}
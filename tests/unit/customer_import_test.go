@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func readAllRows(t *testing.T, path, format string) [][]string {
+	t.Helper()
+	rr, err := common.OpenSpreadsheet(path, format)
+	if err != nil {
+		t.Fatalf("Dosya açılamadı: %v", err)
+	}
+	defer rr.Close()
+	var rows [][]string
+	for {
+		row, err := rr.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("Satır okunamadı: %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestSpreadsheetCSVSemicolonAndBOM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "musteriler.csv")
+	content := "\uFEFFname;email;phone\nAli Yılmaz;ali@ornek.com;0532 123 45 67\n\"Veli; Ltd\";veli@ornek.com;\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rows := readAllRows(t, path, common.FormatCSV)
+	want := [][]string{
+		{"name", "email", "phone"},
+		{"Ali Yılmaz", "ali@ornek.com", "0532 123 45 67"},
+		{"Veli; Ltd", "veli@ornek.com", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("CSV satırları hatalı:\n%q\n%q", rows, want)
+	}
+}
+
+// Minimum XLSX: paylaşılan metin, satır içi metin, tarih stili ve atlanmış (boş) satır
+func writeTestXLSX(t *testing.T, path string) {
+	t.Helper()
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Müşteriler" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>name</t></si><si><t>email</t></si><si><t>cf.start</t></si><si><r><t>Ayşe </t></r><r><t>Kaya</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<cellXfs count="2"><xf numFmtId="0"/><xf numFmtId="14"/></cellXfs></styleSheet>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>
+<row r="2"><c r="A2" t="s"><v>3</v></c><c r="C2" s="1"><v>45658</v></c></row>
+<row r="4"><c r="A4" t="inlineStr"><is><t>Can</t></is></c><c r="B4" t="inlineStr"><is><t>can@ornek.com</t></is></c></row>
+</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpreadsheetXLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "musteriler.xlsx")
+	writeTestXLSX(t, path)
+	rows := readAllRows(t, path, common.FormatXLSX)
+	want := [][]string{
+		{"name", "email", "cf.start"},
+		{"Ayşe Kaya", "", "2025-01-01"},
+		{},
+		{"Can", "can@ornek.com"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("XLSX satırları hatalı:\n%q\n%q", rows, want)
+	}
+}
+
+func TestSpreadsheetFormat(t *testing.T) {
+	for name, want := range map[string]string{"a.CSV": common.FormatCSV, "b.xlsx": common.FormatXLSX} {
+		if got, err := common.SpreadsheetFormat(name); err != nil || got != want {
+			t.Errorf("SpreadsheetFormat(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := common.SpreadsheetFormat("eski.xls"); err == nil {
+		t.Error("XLS kabul edilmemeli")
+	}
+}
+
+func TestDefaultImportMapping(t *testing.T) {
+	defs := []customer.FieldDefinition{{Key: "tax_number", Label: "Vergi No", Type: customer.FieldText}}
+	got := customer.DefaultImportMapping([]string{"Name", "E-posta", "EMAIL", "vergi no", "Not"}, defs)
+	want := map[string]string{"Name": "name", "EMAIL": "email", "vergi no": "cf.tax_number"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Otomatik eşleme hatalı: %v", got)
+	}
+}
+
+func TestParseImportValue(t *testing.T) {
+	number := customer.FieldDefinition{Label: "Ciro", Type: customer.FieldNumber}
+	if v, err := customer.ParseImportValue(number, "1250,5"); err != nil || v != 1250.5 {
+		t.Errorf("Ondalık virgül çevrilmedi: %v %v", v, err)
+	}
+	if _, err := customer.ParseImportValue(number, "bin"); err == nil {
+		t.Error("Sayı olmayan değer kabul edildi")
+	}
+	date := customer.FieldDefinition{Label: "Başlangıç", Type: customer.FieldDate}
+	if v, _ := customer.ParseImportValue(date, "31.12.2024"); v != "2024-12-31" {
+		t.Errorf("GG.AA.YYYY tarih çevrilmedi: %v", v)
+	}
+	multi := customer.FieldDefinition{Label: "Kanallar", Type: customer.FieldMultiSelect, Options: []string{"Web", "Mağaza"}}
+	if v, _ := customer.ParseImportValue(multi, "web; mağaza"); !reflect.DeepEqual(v, []string{"Web", "Mağaza"}) {
+		t.Errorf("Çoklu seçim ayrıştırılamadı: %v", v)
+	}
+}
+
+func TestStartCustomerImportRejectsInvalidMode(t *testing.T) {
+	job := customer.CustomerImport{Filename: "a.csv", Mode: "replace"}
+	if err := customer.StartCustomerImport(nil, common.AuthUser{ID: 1}, &job, "yok.csv"); err == nil {
+		t.Error("Geçersiz mod kabul edildi")
+	}
+}
+
+func TestCreateImportHandlerRequiresFile(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("mode", "upsert")
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/customers/imports", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1}))
+	rec := httptest.NewRecorder()
+	(&customer.Handler{}).CreateImportHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Dosyasız istek için 400 bekleniyordu, gelen %d", rec.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Go-CRM/pkg/customer"
+)
+
+func TestSearchQuery(t *testing.T) {
+	cases := map[string]string{
+		"ali":                  "ali:*",
+		"  Ali   Yılmaz ":      "Ali:* & Yılmaz:*",
+		"İstanbul şubesi":      "İstanbul:* & şubesi:*",
+		"ali@ornek.com":        "ali:* & ornek:* & com:*",
+		"0532 123":             "0532:* & 123:*",
+		"a & b | !c <-> 'd':*": "a:* & b:* & c:* & d:*",
+		"...":                  "",
+		"bir iki üç dört beş altı yedi sekiz dokuz": "bir:* & iki:* & üç:* & dört:* & beş:* & altı:* & yedi:* & sekiz:*",
+	}
+	for in, want := range cases {
+		if got := customer.SearchQuery(in); got != want {
+			t.Errorf("SearchQuery(%q) = %q, beklenen %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	for _, p := range []customer.SearchParams{
+		{Query: "a"},
+		{Query: "!!"},
+		{Query: "ali", Type: "deals"},
+	} {
+		if _, err := customer.Search(nil, p); err == nil {
+			t.Errorf("Geçersiz arama kabul edildi: %+v", p)
+		}
+	}
+}
+
+func TestSearchHandlerRequiresQuery(t *testing.T) {
+	h := &customer.Handler{}
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=", nil)
+	rec := httptest.NewRecorder()
+	h.SearchHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Boş arama için 400 bekleniyordu, gelen %d", rec.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestVCardRoundTrip(t *testing.T) {
+	c := customer.Customer{
+		ID:           42,
+		Name:         "Ayşe Nur Yılmaz",
+		Email:        "ayse@example.com",
+		Phone:        "+905321112233",
+		Region:       "İstanbul; Avrupa",
+		PostalCode:   "34000",
+		LeadSource:   "fuar",
+		Tags:         []string{"VIP", "a,b"},
+		CustomFields: map[string]interface{}{"sektor": "Perakende", "not": "satır 1\nsatır 2"},
+	}
+	for _, version := range []string{customer.VCard3, customer.VCard4} {
+		data := customer.MarshalVCard(c, version)
+		if !strings.HasPrefix(data, "BEGIN:VCARD\r\nVERSION:"+version+"\r\n") {
+			t.Fatalf("vCard başlığı hatalı:\n%s", data)
+		}
+		if !strings.Contains(data, "UID:go-crm-customer-42\r\n") || !strings.Contains(data, "N:Yılmaz;Ayşe Nur;;;\r\n") {
+			t.Errorf("UID veya N eksik:\n%s", data)
+		}
+		cards, err := customer.ParseVCards(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cards) != 1 {
+			t.Fatalf("1 kart bekleniyordu, %d geldi", len(cards))
+		}
+		got := cards[0]
+		want := c
+		want.ID = 0
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s geri okunamadı:\n%+v\n%+v", version, got, want)
+		}
+	}
+}
+
+func TestVCardFoldsLongLines(t *testing.T) {
+	c := customer.Customer{Name: strings.Repeat("Çağrı ", 30) + "Öztürk"}
+	data := customer.MarshalVCard(c, customer.VCard3)
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Satır 75 bayttan uzun: %q", line)
+		}
+	}
+	cards, err := customer.ParseVCards(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cards[0].Name != c.Name {
+		t.Errorf("Katlanmış isim geri okunamadı: %q", cards[0].Name)
+	}
+}
+
+func TestParseVCardsMultipleAndPreferences(t *testing.T) {
+	data := "BEGIN:VCARD\nVERSION:3.0\nN:Demir;Ali;;;\nEMAIL;TYPE=HOME:ev@example.com\nEMAIL;TYPE=INTERNET,PREF:is@example.com\n" +
+		"TEL;TYPE=WORK:0212 111 22 33\nTEL;TYPE=CELL:0532 444 55 66\nEND:VCARD\n" +
+		"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Veli Kaya\r\nTEL;VALUE=uri:tel:+90-555-000-11-22\r\nEND:VCARD\r\n"
+	cards, err := customer.ParseVCards(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 2 {
+		t.Fatalf("2 kart bekleniyordu, %d geldi", len(cards))
+	}
+	if cards[0].Name != "Ali Demir" || cards[0].Email != "is@example.com" || !strings.HasPrefix(cards[0].Phone, "0532") {
+		t.Errorf("İlk kart hatalı: %+v", cards[0])
+	}
+	if cards[1].Name != "Veli Kaya" || strings.HasPrefix(cards[1].Phone, "tel:") || cards[1].Phone == "" {
+		t.Errorf("İkinci kart hatalı: %+v", cards[1])
+	}
+}
+
+func TestParseVCardsErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"boş":            "",
+		"desteklenmeyen": "BEGIN:VCARD\nVERSION:2.1\nFN:Eski\nEND:VCARD\n",
+	} {
+		if _, err := customer.ParseVCards(strings.NewReader(data)); err == nil {
+			t.Errorf("%s dosya için hata bekleniyordu", name)
+		}
+	}
+}
+
+func TestCardDAVWithoutUser(t *testing.T) {
+	h := &customer.Handler{}
+	rr := httptest.NewRecorder()
+	h.CardDAVHandler(rr, httptest.NewRequest("PROPFIND", "/carddav/", nil))
+	if rr.Code != http.StatusUnauthorized || rr.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("401 ve WWW-Authenticate bekleniyordu, %d geldi", rr.Code)
+	}
+}
+
+func davRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	user := common.AuthUser{ID: 7, Email: "temsilci@example.com", Role: "user", TenantID: 1}
+	return req.WithContext(common.ContextWithUser(req.Context(), user))
+}
+
+func TestCardDAVMethodsAndPaths(t *testing.T) {
+	h := &customer.Handler{}
+	cases := []struct {
+		method, path string
+		code         int
+	}{
+		{"OPTIONS", "/carddav/addressbooks/customers/", http.StatusOK},
+		{"PUT", "/carddav/addressbooks/customers/1.vcf", http.StatusMethodNotAllowed},
+		{"DELETE", "/carddav/addressbooks/customers/1.vcf", http.StatusMethodNotAllowed},
+		{"PROPFIND", "/carddav/baska/", http.StatusNotFound},
+		{"GET", "/carddav/addressbooks/customers/abc.vcf", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		h.CardDAVHandler(rr, davRequest(c.method, c.path, ""))
+		if rr.Code != c.code {
+			t.Errorf("%s %s: %d bekleniyordu, %d geldi", c.method, c.path, c.code, rr.Code)
+		}
+	}
+	rr := httptest.NewRecorder()
+	h.CardDAVHandler(rr, davRequest("OPTIONS", "/carddav/", ""))
+	if !strings.Contains(rr.Header().Get("DAV"), "addressbook") {
+		t.Errorf("DAV başlığında addressbook bekleniyordu: %q", rr.Header().Get("DAV"))
+	}
+}
+
+func TestCardDAVPrincipalPropfind(t *testing.T) {
+	h := &customer.Handler{}
+	body := `<?xml version="1.0"?><D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+		<D:prop><D:current-user-principal/><C:addressbook-home-set/><D:bilinmeyen/></D:prop></D:propfind>`
+	req := davRequest("PROPFIND", "/carddav/", body)
+	req.Header.Set("Depth", "0")
+	rr := httptest.NewRecorder()
+	h.CardDAVHandler(rr, req)
+	if rr.Code != http.StatusMultiStatus {
+		t.Fatalf("207 bekleniyordu, %d geldi: %s", rr.Code, rr.Body.String())
+	}
+	out := rr.Body.String()
+	for _, want := range []string{
+		"<C:addressbook-home-set><D:href>/carddav/addressbooks/</D:href></C:addressbook-home-set>",
+		"<D:current-user-principal><D:href>/carddav/</D:href></D:current-user-principal>",
+		"HTTP/1.1 404 Not Found",
+		"bilinmeyen",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Yanıtta %q bekleniyordu:\n%s", want, out)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	h.CardDAVHandler(rr, davRequest("PROPFIND", "/carddav/", "<bozuk"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Bozuk gövde için 400 bekleniyordu, %d geldi", rr.Code)
+	}
+}
+
+func TestContactsExportRejectsVCF(t *testing.T) {
+	h := &customer.Handler{}
+	req := httptest.NewRequest("GET", "/api/contacts/export?format=vcf", nil)
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: "admin", TenantID: 1}))
+	rr := httptest.NewRecorder()
+	h.ExportContactsHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("İletişim kayıtları vcf için 400 bekleniyordu, %d geldi", rr.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/deal"
+)
+
+func TestGetForecastHandler_InvalidRange(t *testing.T) {
+	h := &deal.Handler{DBPrimary: nil, DBReplica: nil}
+	urls := []string{
+		"/api/deals/forecast",
+		"/api/deals/forecast?from=2025-13&to=2025-06",
+		"/api/deals/forecast?from=2025-06&to=2025-01",
+		"/api/deals/forecast?from=2020-01&to=2025-12",
+	}
+	for _, url := range urls {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: common.RoleRep}))
+		w := httptest.NewRecorder()
+		h.GetForecastHandler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: beklenen 400, gelen %d", url, w.Code)
+		}
+	}
+}
+
+// Fırsatlar tenant'a bağlı olduğundan oturumsuz istekler reddedilir
+func TestDealHandlers_RequireUser(t *testing.T) {
+	h := &deal.Handler{DBPrimary: nil, DBReplica: nil}
+	cases := map[string]struct {
+		method  string
+		path    string
+		handler http.HandlerFunc
+	}{
+		"surecler": {http.MethodGet, "/api/pipelines", h.GetPipelinesHandler},
+		"liste":    {http.MethodGet, "/api/deals", h.GetDealsHandler},
+		"tek":      {http.MethodGet, "/api/deals/3", h.GetDealHandler},
+		"guncelle": {http.MethodPut, "/api/deals/3", h.UpdateDealHandler},
+		"gecmis":   {http.MethodGet, "/api/deals/3/history", h.GetStageHistoryHandler},
+		"tahmin":   {http.MethodGet, "/api/deals/forecast?from=2025-01&to=2025-06", h.GetForecastHandler},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			w := httptest.NewRecorder()
+
+			c.handler(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Beklenen 401, gelen %d", w.Code)
+			}
+		})
+	}
+}
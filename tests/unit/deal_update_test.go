@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"Go-CRM/pkg/deal"
+)
+
+func applyDealPatch(t *testing.T, current deal.Deal, body string) deal.Deal {
+	t.Helper()
+	var patch deal.DealPatch
+	if err := json.Unmarshal([]byte(body), &patch); err != nil {
+		t.Fatalf("Gövde çözülemedi: %v", err)
+	}
+	patch.Apply(&current)
+	return current
+}
+
+// Kısmi PUT, gövdede olmayan alanları (başlık ve tutar dahil) mevcut kayıttan korumalı
+func TestDealPatch_Partial(t *testing.T) {
+	current := deal.Deal{
+		ID: 12, CustomerID: 4, PipelineID: 1, StageID: 3, Title: "Lisans yenileme",
+		Amount: 1500, Currency: "EUR", ExpectedCloseDate: "2025-09-30", OwnerID: 7, Probability: 60,
+	}
+	d := applyDealPatch(t, current, `{"currency": "TRY", "pipeline_id": 2, "stage_id": 9}`)
+
+	if d.Currency != "TRY" {
+		t.Errorf("Verilen alan uygulanmadı: %+v", d)
+	}
+	if d.Title != "Lisans yenileme" || d.Amount != 1500 || d.CustomerID != 4 || d.OwnerID != 7 ||
+		d.Probability != 60 || d.ExpectedCloseDate != "2025-09-30" {
+		t.Errorf("Verilmeyen alanlar korunmadı: %+v", d)
+	}
+	if d.PipelineID != 1 || d.StageID != 3 {
+		t.Errorf("Süreç/aşama PUT ile değişmemeli: %+v", d)
+	}
+}
+
+// Açıkça gönderilen sıfır değerler "verilmedi" sayılmamalı
+func TestDealPatch_ExplicitZeroValues(t *testing.T) {
+	current := deal.Deal{Title: "Lisans yenileme", Amount: 1500, ExpectedCloseDate: "2025-09-30", Probability: 60}
+	d := applyDealPatch(t, current, `{"amount": 0, "probability": 0, "expected_close_date": ""}`)
+
+	if d.Amount != 0 || d.Probability != 0 || d.ExpectedCloseDate != "" {
+		t.Errorf("Sıfır değerler uygulanmadı: %+v", d)
+	}
+	if d.Title != "Lisans yenileme" {
+		t.Errorf("Verilmeyen başlık korunmadı: %+v", d)
+	}
+}
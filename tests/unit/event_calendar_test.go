@@ -0,0 +1,237 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/event"
+	"Go-CRM/pkg/notification"
+)
+
+func TestParseRRule(t *testing.T) {
+	r, err := event.ParseRRule("RRULE:freq=weekly;byday=we,mo;interval=2;count=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.String(); got != "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=4" {
+		t.Errorf("Kural standart yazılmadı: %s", got)
+	}
+	for _, bad := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261231",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYSETPOS=1",
+		"FREQ=WEEKLY;BYDAY=XX",
+	} {
+		if _, err := event.ParseRRule(bad); err == nil {
+			t.Errorf("%q için hata bekleniyordu", bad)
+		}
+	}
+}
+
+func occurrenceStarts(occ []event.Occurrence) []string {
+	var out []string
+	for _, o := range occ {
+		out = append(out, o.OccurrenceStart.Format("2006-01-02 15:04"))
+	}
+	return out
+}
+
+func TestOccurrences(t *testing.T) {
+	// 2026-10-07 çarşamba
+	start := time.Date(2026, 10, 7, 9, 0, 0, 0, time.UTC)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		rrule string
+		want  []string
+	}{
+		{"", []string{"2026-10-07 09:00"}},
+		{"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", []string{"2026-10-07 09:00", "2026-10-12 09:00", "2026-10-14 09:00", "2026-10-19 09:00"}},
+		{"FREQ=DAILY;INTERVAL=3;UNTIL=20261016", []string{"2026-10-07 09:00", "2026-10-10 09:00", "2026-10-13 09:00", "2026-10-16 09:00"}},
+	}
+	for _, c := range cases {
+		e := event.Event{StartAt: start, EndAt: start.Add(time.Hour), RRule: c.rrule}
+		got := occurrenceStarts(event.Occurrences(e, from, to))
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%q: %v, beklenen %v", c.rrule, got, c.want)
+		}
+	}
+
+	// 31'inde tekrarlayan aylık etkinlik 31 çekmeyen ayları atlar
+	start = time.Date(2026, 10, 31, 9, 0, 0, 0, time.UTC)
+	e := event.Event{StartAt: start, EndAt: start.Add(time.Hour), RRule: "FREQ=MONTHLY;COUNT=3"}
+	got := occurrenceStarts(event.Occurrences(e, from, time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)))
+	if strings.Join(got, ",") != "2026-10-31 09:00,2026-12-31 09:00,2027-01-31 09:00" {
+		t.Errorf("Aylık tekrar hatalı: %v", got)
+	}
+
+	// Aralığın başlangıcından önce başlayıp içine taşan gerçekleşme de döner
+	e = event.Event{StartAt: from.Add(-time.Hour), EndAt: from.Add(time.Hour)}
+	if len(event.Occurrences(e, from, to)) != 1 {
+		t.Error("Aralığa taşan etkinlik dönmedi")
+	}
+}
+
+func TestMarshalCalendar(t *testing.T) {
+	e := event.Event{
+		ID:             12,
+		Title:          "Teklif sunumu; Acme, Ltd.",
+		Description:    strings.Repeat("Gündem maddesi ", 10),
+		Location:       "Merkez ofis",
+		StartAt:        time.Date(2026, 10, 20, 7, 30, 0, 0, time.UTC),
+		EndAt:          time.Date(2026, 10, 20, 8, 30, 0, 0, time.UTC),
+		RRule:          "FREQ=WEEKLY;COUNT=2",
+		OrganizerEmail: "temsilci@example.com",
+		Status:         event.StatusConfirmed,
+		Sequence:       2,
+		CustomerID:     4,
+		Attendees: []event.Attendee{
+			{Email: "ayse@example.com", Name: "Ayşe \"AY\" Yılmaz", Status: event.AttendeeNeedsAction},
+			{UserID: 3, Email: "mudur@example.com", Status: event.AttendeeAccepted},
+		},
+	}
+	ics := event.MarshalCalendar([]event.Event{e}, event.MethodRequest, "")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"METHOD:REQUEST\r\n",
+		"UID:go-crm-event-12@go-crm\r\n",
+		"DTSTART:20261020T073000Z\r\n",
+		"DTEND:20261020T083000Z\r\n",
+		"RRULE:FREQ=WEEKLY;COUNT=2\r\n",
+		`SUMMARY:Teklif sunumu\; Acme\, Ltd.` + "\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"SEQUENCE:2\r\n",
+		"ORGANIZER:mailto:temsilci@example.com\r\n",
+		"X-CRM-CUSTOMER-ID:4\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("Çıktıda %q bekleniyordu:\n%s", want, ics)
+		}
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	if !strings.Contains(unfolded, `ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE;CN="Ayşe 'AY' Yılmaz":mailto:ayse@example.com`) {
+		t.Errorf("Katılımcı satırı hatalı:\n%s", unfolded)
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Satır katlanmamış: %q", line)
+		}
+	}
+
+	cancel := event.MarshalCalendar([]event.Event{e}, event.MethodCancel, "")
+	if !strings.Contains(cancel, "METHOD:CANCEL\r\n") || !strings.Contains(cancel, "STATUS:CANCELLED\r\n") {
+		t.Errorf("İptal daveti hatalı:\n%s", cancel)
+	}
+
+	e.AllDay = true
+	e.StartAt = time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	e.EndAt = e.StartAt.AddDate(0, 0, 1)
+	ics = event.MarshalCalendar([]event.Event{e}, "", "CRM")
+	if !strings.Contains(ics, "DTSTART;VALUE=DATE:20261020\r\nDTEND;VALUE=DATE:20261021\r\n") || strings.Contains(ics, "METHOD:") {
+		t.Errorf("Tüm gün etkinliği hatalı:\n%s", ics)
+	}
+}
+
+func TestBuildEmailWithCalendarAttachment(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR\r\n"
+	msg := notification.Message{
+		To:      "ayse@example.com",
+		Subject: "Davet: Görüşme",
+		Body:    "Merhaba",
+		Attachments: []notification.Attachment{
+			{Filename: "invite.ics", ContentType: "text/calendar; charset=UTF-8; method=REQUEST", Data: []byte(ics)},
+		},
+	}
+	data, err := notification.BuildEmail("crm@example.com", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subject != msg.Subject {
+		t.Errorf("Konu çözülemedi: %q", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("multipart/mixed bekleniyordu: %q %v", mediaType, err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	var types []string
+	var attachment string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, p.Header.Get("Content-Type"))
+		if p.FileName() == "invite.ics" {
+			b, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+			attachment = string(b)
+		}
+	}
+	if len(types) != 3 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/calendar") {
+		t.Errorf("Beklenmeyen parçalar: %v", types)
+	}
+	if attachment != ics {
+		t.Errorf("Ek geri okunamadı: %q", attachment)
+	}
+
+	plain, err := notification.BuildEmail("crm@example.com", notification.Message{To: "a@example.com", Subject: "Görev", Body: "x"})
+	if err != nil || !strings.Contains(string(plain), "Content-Type: text/plain; charset=UTF-8\r\n\r\nx") {
+		t.Errorf("Eksiz e-posta düz metin olmalı:\n%s", plain)
+	}
+}
+
+func eventRequest(method, target, body string, user common.AuthUser) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	return req.WithContext(common.ContextWithUser(req.Context(), user))
+}
+
+func TestEventHandlersValidation(t *testing.T) {
+	h := &event.Handler{}
+	rep := common.AuthUser{ID: 2, Role: "rep", TenantID: 1}
+	cases := []struct {
+		name string
+		req  *http.Request
+		call func(http.ResponseWriter, *http.Request)
+		code int
+	}{
+		{"geçersiz from", eventRequest("GET", "/api/events?from=dun", "", rep), h.GetEventsHandler, http.StatusBadRequest},
+		{"temsilci all", eventRequest("GET", "/api/events?all=true", "", rep), h.GetEventsHandler, http.StatusForbidden},
+		{"başka kullanıcı", eventRequest("GET", "/api/events?user_id=9", "", rep), h.GetEventsHandler, http.StatusForbidden},
+		{"ters aralık", eventRequest("GET", "/api/events?from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z", "", rep), h.GetEventsHandler, http.StatusBadRequest},
+		{"bozuk gövde", eventRequest("POST", "/api/events", "{", rep), h.CreateEventHandler, http.StatusBadRequest},
+		{"kısa başlık", eventRequest("POST", "/api/events", `{"title":"a","start_at":"2026-10-20T07:00:00Z","end_at":"2026-10-20T08:00:00Z"}`, rep), h.CreateEventHandler, http.StatusBadRequest},
+		{"ters zaman", eventRequest("POST", "/api/events", `{"title":"Toplantı","start_at":"2026-10-20T08:00:00Z","end_at":"2026-10-20T07:00:00Z"}`, rep), h.CreateEventHandler, http.StatusBadRequest},
+		{"geçersiz kural", eventRequest("POST", "/api/events", `{"title":"Toplantı","start_at":"2026-10-20T07:00:00Z","end_at":"2026-10-20T08:00:00Z","rrule":"FREQ=HOURLY"}`, rep), h.CreateEventHandler, http.StatusBadRequest},
+		{"geçersiz katılımcı", eventRequest("POST", "/api/events", `{"title":"Toplantı","start_at":"2026-10-20T07:00:00Z","end_at":"2026-10-20T08:00:00Z","attendees":[{"email":"yok"}]}`, rep), h.CreateEventHandler, http.StatusBadRequest},
+		{"geçersiz ID", eventRequest("GET", "/api/events/abc", "", rep), h.GetEventHandler, http.StatusBadRequest},
+		{"bilinmeyen akış", httptest.NewRequest("GET", "/calendar/abc.ics", nil), h.CalendarFeedHandler, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		c.call(rr, c.req)
+		if rr.Code != c.code {
+			t.Errorf("%s: %d bekleniyordu, %d geldi (%s)", c.name, c.code, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	common.SetCursorKey([]byte("test-secret"))
+	token := common.EncodeCursor("customers|a", common.Cursor{Values: []string{"2025-01-01T00:00:00Z", "42"}, Backward: true})
+
+	c, err := common.DecodeCursor(token, "customers|a")
+	if err != nil {
+		t.Fatalf("Geçerli cursor reddedildi: %v", err)
+	}
+	if !reflect.DeepEqual(c.Values, []string{"2025-01-01T00:00:00Z", "42"}) || !c.Backward {
+		t.Errorf("Cursor içeriği bozuldu: %+v", c)
+	}
+	if c, err := common.DecodeCursor("", "customers|a"); c != nil || err != nil {
+		t.Errorf("Boş cursor nil dönmeli: %+v %v", c, err)
+	}
+
+	var qe *common.QueryError
+	body, sig, _ := strings.Cut(token, ".")
+	tampered := []string{
+		body + "x." + sig,
+		body + "." + sig[1:],
+		"bozuk",
+	}
+	for _, tok := range tampered {
+		if _, err := common.DecodeCursor(tok, "customers|a"); !errors.As(err, &qe) {
+			t.Errorf("Değiştirilmiş cursor kabul edildi: %q (%v)", tok, err)
+		}
+	}
+	if _, err := common.DecodeCursor(token, "customers|b"); !errors.As(err, &qe) {
+		t.Errorf("Başka sorgunun cursor'ı kabul edildi: %v", err)
+	}
+}
+
+func TestPageCursors(t *testing.T) {
+	keys := [][]string{{"1"}, {"2"}, {"3"}}
+
+	// İlk sayfa, fazladan satır var: sadece sonraki
+	n, next, prev := common.PageCursors("s", nil, keys, 2)
+	if n != 2 || next == "" || prev != "" {
+		t.Errorf("İlk sayfa: n=%d next=%q prev=%q", n, next, prev)
+	}
+	c, _ := common.DecodeCursor(next, "s")
+	if c.Values[0] != "2" || c.Backward {
+		t.Errorf("Sonraki sayfa son satırdan başlamalı: %+v", c)
+	}
+
+	// Son sayfa: sadece önceki
+	n, next, prev = common.PageCursors("s", c, keys[:1], 2)
+	if n != 1 || next != "" || prev == "" {
+		t.Errorf("Son sayfa: n=%d next=%q prev=%q", n, next, prev)
+	}
+
+	// Geri giderken satırlar ters sırada gelir, sonraki her zaman vardır
+	n, next, prev = common.PageCursors("s", &common.Cursor{Backward: true}, [][]string{{"5"}, {"4"}, {"3"}}, 2)
+	nc, _ := common.DecodeCursor(next, "s")
+	pc, _ := common.DecodeCursor(prev, "s")
+	if n != 2 || nc == nil || nc.Values[0] != "5" || pc == nil || pc.Values[0] != "4" || !pc.Backward {
+		t.Errorf("Geri yön: n=%d next=%+v prev=%+v", n, nc, pc)
+	}
+}
+
+func TestKeysetAfter(t *testing.T) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	ks, err := customer.ContactListFields.Keyset([]common.SortKey{{Field: "occurred_at", Desc: true}}, nil, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clause, _ := ks.After(&common.Cursor{Values: []string{"2025-01-01T00:00:00Z", "7"}}, arg)
+	if clause != "(ct.occurred_at, ct.id) < ($1, $2)" {
+		t.Errorf("Aynı yönlü anahtarlar satır karşılaştırması kullanmalı: %s", clause)
+	}
+	if got := ks.OrderBy(true); got != "ct.occurred_at ASC, ct.id ASC" {
+		t.Errorf("Geri yönde sıralama ters çevrilmeli: %s", got)
+	}
+
+	ks, _ = customer.ContactListFields.Keyset([]common.SortKey{{Field: "type"}, {Field: "author_id", Desc: true}}, nil, "id")
+	args = nil
+	clause, _ = ks.After(&common.Cursor{Values: []string{"call", "3", "9"}}, arg)
+	want := "((ct.type > $1) OR (ct.type = $1 AND COALESCE(ct.author_id, 0) < $2) OR (ct.type = $1 AND COALESCE(ct.author_id, 0) = $2 AND ct.id < $3))"
+	if clause != want {
+		t.Errorf("Karışık yönlü keyset hatalı:\n%s\n%s", clause, want)
+	}
+	if _, err := ks.After(&common.Cursor{Values: []string{"call"}}, arg); err == nil {
+		t.Error("Anahtar sayısı uymayan cursor kabul edildi")
+	}
+}
+
+func TestGetCustomersHandlerRejectsInvalidCursor(t *testing.T) {
+	h := &customer.Handler{}
+	req := httptest.NewRequest(http.MethodGet, "/api/customers?cursor=bozuk", nil)
+	rec := httptest.NewRecorder()
+	h.GetCustomersHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Geçersiz cursor için 400 bekleniyordu, gelen %d", rec.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/customer"
+)
+
+func TestParseFilter(t *testing.T) {
+	conds, err := common.ParseFilter(`created_at>=2025-01-01,phone:exists,tag:in:vip|gold,name:contains:a\,b,owner_id!=3`)
+	if err != nil {
+		t.Fatalf("Geçerli filtre reddedildi: %v", err)
+	}
+	want := []common.FilterCondition{
+		{Field: "created_at", Op: common.OpGte, Values: []string{"2025-01-01"}},
+		{Field: "phone", Op: common.OpExists},
+		{Field: "tag", Op: common.OpIn, Values: []string{"vip", "gold"}},
+		{Field: "name", Op: common.OpContains, Values: []string{"a,b"}},
+		{Field: "owner_id", Op: common.OpNe, Values: []string{"3"}},
+	}
+	if !reflect.DeepEqual(conds, want) {
+		t.Errorf("Beklenen %+v, gelen %+v", want, conds)
+	}
+
+	for _, s := range []string{"name", "name:like:ali", "name:eq", "name=", "phone:exists:1", strings.Repeat("id=1,", 21)} {
+		if _, err := common.ParseFilter(s); err == nil {
+			t.Errorf("Geçersiz filtre kabul edildi: %q", s)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	keys, err := common.ParseSort("-created_at,name")
+	if err != nil {
+		t.Fatalf("Geçerli sıralama reddedildi: %v", err)
+	}
+	want := []common.SortKey{{Field: "created_at", Desc: true}, {Field: "name"}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Beklenen %+v, gelen %+v", want, keys)
+	}
+	for _, s := range []string{"name,-name", "a,b,c,d"} {
+		if _, err := common.ParseSort(s); err == nil {
+			t.Errorf("Geçersiz sıralama kabul edildi: %q", s)
+		}
+	}
+}
+
+func TestListFieldsWhereAndOrderBy(t *testing.T) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds, _ := common.ParseFilter("type:in:call|meeting,duration_seconds>60,content:contains:100%")
+	where, err := customer.ContactListFields.Where(conds, arg)
+	if err != nil {
+		t.Fatalf("Koşullar SQL'e çevrilemedi: %v", err)
+	}
+	wantWhere := []string{"ct.type IN ($1, $2)", "ct.duration_seconds > $3", "LOWER(ct.content) LIKE $4"}
+	if !reflect.DeepEqual(where, wantWhere) {
+		t.Errorf("Beklenen %v, gelen %v", wantWhere, where)
+	}
+	if args[2] != int64(60) || args[3] != `%100\%%` {
+		t.Errorf("Parametreler hatalı bağlandı: %v", args)
+	}
+
+	var qe *common.QueryError
+	for _, s := range []string{"password=x", "content>a", "duration_seconds=abc", "occurred_at>=dün"} {
+		conds, _ := common.ParseFilter(s)
+		if _, err := customer.ContactListFields.Where(conds, arg); !errors.As(err, &qe) {
+			t.Errorf("%q için QueryError bekleniyordu, gelen: %v", s, err)
+		}
+	}
+
+	orderBy, err := customer.ContactListFields.OrderBy([]common.SortKey{{Field: "occurred_at", Desc: true}}, nil, "id")
+	if err != nil || orderBy != "ct.occurred_at DESC, ct.id DESC" {
+		t.Errorf("Beklenmeyen ORDER BY: %q (%v)", orderBy, err)
+	}
+	if _, err := customer.ContactListFields.OrderBy([]common.SortKey{{Field: "content"}}, nil, "id"); !errors.As(err, &qe) {
+		t.Errorf("Sıralanamayan alan kabul edildi: %v", err)
+	}
+}
+
+func TestGetCustomersHandlerRejectsUnknownFilterField(t *testing.T) {
+	h := &customer.Handler{}
+	for _, q := range []string{"filter=password:exists", "sort=phone", "filter=tag:gt:vip"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/customers?"+q, nil)
+		rec := httptest.NewRecorder()
+		h.GetCustomersHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s için 400 bekleniyordu, gelen %d", q, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package unit
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/mfa"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RFC 6238 Ek B'deki SHA1 anahtarı ("12345678901234567890")
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPRFCVectors(t *testing.T) {
+	// RFC'deki 8 haneli değerlerin son 6 hanesi
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		got, err := mfa.CodeAt(rfcSecret, mfa.Step(time.Unix(ts, 0)))
+		if err != nil || got != want {
+			t.Errorf("T=%d için %s bekleniyordu, %s geldi (%v)", ts, want, got, err)
+		}
+	}
+}
+
+func TestTOTPValidateCode(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := mfa.CodeAt(rfcSecret, mfa.Step(now))
+	step, ok := mfa.ValidateCode(rfcSecret, code, now, 0)
+	if !ok || step != mfa.Step(now) {
+		t.Fatalf("Geçerli kod reddedildi: %v %d", ok, step)
+	}
+	// Bir adım saat kayması kabul edilir, iki adım edilmez
+	if _, ok := mfa.ValidateCode(rfcSecret, code, now.Add(mfa.Period*time.Second), 0); !ok {
+		t.Error("Bir adım geriden gelen kod kabul edilmeliydi")
+	}
+	if _, ok := mfa.ValidateCode(rfcSecret, code, now.Add(2*mfa.Period*time.Second), 0); ok {
+		t.Error("İki adım eski kod reddedilmeliydi")
+	}
+	// Kullanılmış adımdaki kod tekrar kabul edilmez
+	if _, ok := mfa.ValidateCode(rfcSecret, code, now, step); ok {
+		t.Error("Aynı kod ikinci kez kabul edilmemeliydi")
+	}
+	if _, ok := mfa.ValidateCode(rfcSecret, code[:3]+" "+code[3:], now, 0); !ok {
+		t.Error("Boşluklu kod kabul edilmeliydi")
+	}
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := mfa.ValidateCode(rfcSecret, bad, now, 0); ok {
+			t.Errorf("%q reddedilmeliydi", bad)
+		}
+	}
+}
+
+func TestTOTPSecretAndProvisioningURI(t *testing.T) {
+	secret, err := mfa.GenerateSecret()
+	if err != nil || len(secret) != 32 {
+		t.Fatalf("Gizli anahtar 32 karakter olmalıydı: %q %v", secret, err)
+	}
+	if other, _ := mfa.GenerateSecret(); other == secret {
+		t.Error("Her anahtar farklı olmalıydı")
+	}
+
+	u, err := url.Parse(mfa.ProvisioningURI("Go-CRM", "ayse@ornek.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Go-CRM:ayse@ornek.com" {
+		t.Errorf("URI yanlış: %s", u)
+	}
+	if q.Get("secret") != secret || q.Get("issuer") != "Go-CRM" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("URI parametreleri yanlış: %v", q)
+	}
+}
+
+func TestMFAChallengeToken(t *testing.T) {
+	key := []byte("jwt-secret")
+	now := time.Now()
+	token, err := mfa.NewChallengeToken(key, 7, mfa.PurposeEnroll, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := mfa.ParseChallengeToken(key, token, now.Add(time.Minute))
+	if err != nil || ch.UserID != 7 || ch.Purpose != mfa.PurposeEnroll {
+		t.Errorf("Challenge çözülemedi: %+v %v", ch, err)
+	}
+	if _, err := mfa.ParseChallengeToken(key, token, now.Add(mfa.ChallengeTTL+time.Second)); !errors.Is(err, mfa.ErrInvalidChallenge) {
+		t.Errorf("Süresi dolan challenge reddedilmeliydi: %v", err)
+	}
+	if _, err := mfa.ParseChallengeToken([]byte("baska"), token, now); !errors.Is(err, mfa.ErrInvalidChallenge) {
+		t.Errorf("Başka anahtarla imzalı challenge reddedilmeliydi: %v", err)
+	}
+
+	// Challenge token'ı oturum token'ı olarak doğrulanamaz
+	if _, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return key, nil }); err == nil {
+		t.Error("Challenge token'ı oturum anahtarıyla doğrulanmamalıydı")
+	}
+	// Oturum token'ı da challenge olarak kabul edilmez
+	session, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 7, "purpose": mfa.PurposeVerify, "aud": "crm-mfa", "exp": now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if _, err := mfa.ParseChallengeToken(key, session, now); !errors.Is(err, mfa.ErrInvalidChallenge) {
+		t.Errorf("Oturum anahtarıyla imzalı token reddedilmeliydi: %v", err)
+	}
+}
+
+func TestMFARecoveryCodeHash(t *testing.T) {
+	h := mfa.HashRecoveryCode("abcd-efgh")
+	for _, v := range []string{"ABCD-EFGH", "abcdefgh", " abcd efgh "} {
+		if mfa.HashRecoveryCode(v) != h {
+			t.Errorf("%q aynı koda eşlenmeliydi", v)
+		}
+	}
+	if mfa.HashRecoveryCode("abcd-efgi") == h {
+		t.Error("Farklı kodlar farklı özet vermeliydi")
+	}
+}
+
+func TestMFAPolicyValidation(t *testing.T) {
+	p := mfa.Policy{RequiredRoles: []string{"Manager", "admin", "manager"}}
+	if err := mfa.ValidatePolicy(&p); err != nil || len(p.RequiredRoles) != 2 || p.RequiredRoles[0] != "admin" || p.RequiredRoles[1] != "manager" {
+		t.Errorf("Roller normalize edilmeliydi: %v %v", p.RequiredRoles, err)
+	}
+	p = mfa.Policy{RequiredRoles: []string{"owner"}}
+	if err := mfa.ValidatePolicy(&p); !errors.Is(err, mfa.ErrInvalidRole) {
+		t.Errorf("Geçersiz rol reddedilmeliydi: %v", err)
+	}
+	p = mfa.Policy{}
+	if err := mfa.ValidatePolicy(&p); err != nil || p.RequiredRoles == nil {
+		t.Errorf("Boş politika geçerli olmalıydı: %v", err)
+	}
+
+	// Politika işlemleri sadece admin, API anahtarıyla da yapılamaz
+	if _, err := mfa.GetPolicy(nil, common.AuthUser{ID: 2, Role: common.RoleManager}); !errors.Is(err, mfa.ErrAdminOnly) {
+		t.Errorf("Manager politika görememeli: %v", err)
+	}
+	if err := mfa.ResetUser(nil, common.AuthUser{ID: 1, Role: common.RoleAdmin, APIKeyID: 3}, 5); !errors.Is(err, mfa.ErrAdminOnly) {
+		t.Errorf("API anahtarıyla MFA sıfırlanamamalı: %v", err)
+	}
+}
+
+func TestMFALoginHandlersRejectInvalidChallenge(t *testing.T) {
+	key := []byte("jwt-secret")
+	h := &mfa.Handler{JWTKey: key, IssueToken: func(common.AuthUser) (string, error) {
+		t.Error("Geçersiz challenge ile token verilmemeli")
+		return "", nil
+	}}
+
+	rec := httptest.NewRecorder()
+	h.LoginVerifyHandler(rec, httptest.NewRequest("POST", "/api/login/mfa", bytes.NewBufferString(`{"mfa_token":"bozuk","code":"123456"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Bozuk challenge 401 almalıydı: %d", rec.Code)
+	}
+
+	// Kayıt başlatma sadece enroll amaçlı challenge ile yapılabilir
+	token, _ := mfa.NewChallengeToken(key, 7, mfa.PurposeVerify, time.Now())
+	rec = httptest.NewRecorder()
+	h.LoginEnrollHandler(rec, httptest.NewRequest("POST", "/api/login/mfa/enroll", bytes.NewBufferString(`{"mfa_token":"`+token+`"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Doğrulama challenge'ı ile kayıt başlatılamamalı: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.LoginVerifyHandler(rec, httptest.NewRequest("POST", "/api/login/mfa", bytes.NewBufferString(`{`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Geçersiz gövde 400 almalıydı: %d", rec.Code)
+	}
+}
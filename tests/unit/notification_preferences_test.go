@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/notification"
+)
+
+func TestDeliveryTime_Immediate(t *testing.T) {
+	p := notification.DefaultPreferences(1)
+	now := time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC)
+	if got := notification.DeliveryTime(p, now); !got.Equal(now) {
+		t.Errorf("Bildirim hemen gönderilmeliydi, ertelendi: %v", got)
+	}
+}
+
+func TestDeliveryTime_QuietHoursAcrossMidnight(t *testing.T) {
+	p := notification.DefaultPreferences(1)
+	p.Timezone = "Europe/Istanbul" // UTC+3
+	p.QuietHoursStart = "22:00"
+	p.QuietHoursEnd = "08:00"
+
+	// 20:00 UTC = 23:00 İstanbul -> ertesi gün 08:00 İstanbul (05:00 UTC)
+	now := time.Date(2025, 3, 10, 20, 0, 0, 0, time.UTC)
+	want := time.Date(2025, 3, 11, 5, 0, 0, 0, time.UTC)
+	if got := notification.DeliveryTime(p, now); !got.Equal(want) {
+		t.Errorf("Beklenen %v, gelen %v", want, got.UTC())
+	}
+
+	// 10:00 UTC = 13:00 İstanbul -> sessiz saat dışında
+	now = time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)
+	if got := notification.DeliveryTime(p, now); !got.Equal(now) {
+		t.Errorf("Sessiz saat dışında ertelenmemeliydi: %v", got.UTC())
+	}
+}
+
+func TestDeliveryTime_Digest(t *testing.T) {
+	p := notification.DefaultPreferences(1)
+	p.Timezone = "UTC"
+	now := time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	p.DigestMode = notification.DigestHourly
+	if got, want := notification.DeliveryTime(p, now), time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Saatlik özet: beklenen %v, gelen %v", want, got)
+	}
+
+	p.DigestMode = notification.DigestDaily
+	if got, want := notification.DeliveryTime(p, now), time.Date(2025, 3, 11, notification.DailyDigestHour, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Günlük özet: beklenen %v, gelen %v", want, got)
+	}
+}
+
+func TestPreferences_ChannelsFor(t *testing.T) {
+	p := notification.DefaultPreferences(1)
+	p.Events["task.due"] = []string{notification.ChannelSMS}
+	p.Events["customer.created"] = []string{}
+
+	if c := p.ChannelsFor("task.due"); len(c) != 1 || c[0] != notification.ChannelSMS {
+		t.Errorf("task.due için sms bekleniyordu: %v", c)
+	}
+	if c := p.ChannelsFor("customer.created"); len(c) != 0 {
+		t.Errorf("customer.created kapatılmıştı: %v", c)
+	}
+	if c := p.ChannelsFor("deal.won"); len(c) != 1 || c[0] != notification.ChannelEmail {
+		t.Errorf("Varsayılan kanal e-posta olmalı: %v", c)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  time.Minute,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		8:  2 * time.Hour,
+		20: 2 * time.Hour,
+	}
+	for attempt, want := range cases {
+		if got := notification.RetryDelay(attempt); got != want {
+			t.Errorf("%d. deneme: beklenen %v, gelen %v", attempt, want, got)
+		}
+	}
+}
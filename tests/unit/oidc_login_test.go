@@ -0,0 +1,360 @@
+package unit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Testler için sahte OIDC sağlayıcısı: discovery, JWKS ve PKCE kontrollü token uç noktası
+type mockProvider struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]mockGrant
+	claims func(g mockGrant) jwt.MapClaims // ID token claim'lerini değiştirmek için
+	access jwt.MapClaims                   // Doluysa access token olarak imzalanır
+}
+
+type mockGrant struct {
+	nonce, challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key, codes: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 p.srv.URL,
+			"authorization_endpoint": p.srv.URL + "/auth",
+			"token_endpoint":         p.srv.URL + "/token",
+			"jwks_uri":               p.srv.URL + "/certs",
+		})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1", "kty": "RSA", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, _ := r.BasicAuth()
+		p.mu.Lock()
+		g, ok := p.codes[r.Form.Get("code")]
+		delete(p.codes, r.Form.Get("code"))
+		p.mu.Unlock()
+		if !ok || id != "crm" || secret != "gizli" || r.Form.Get("grant_type") != "authorization_code" ||
+			r.Form.Get("redirect_uri") != "http://localhost:8082/callback" ||
+			oidc.S256Challenge(r.Form.Get("code_verifier")) != g.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		resp := map[string]interface{}{"token_type": "Bearer", "expires_in": 300, "id_token": p.sign(t, p.claims(g))}
+		if p.access != nil {
+			resp["access_token"] = p.sign(t, p.access)
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	p.claims = func(g mockGrant) jwt.MapClaims { return p.idClaims(g.nonce, "staff") }
+	return p
+}
+
+func (p *mockProvider) idClaims(nonce string, roles ...string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": p.srv.URL, "aud": "crm", "sub": "kc-123", "nonce": nonce,
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(),
+		"email": "ayse@ornek.com", "email_verified": true,
+		"realm_access": map[string]interface{}{"roles": roles},
+	}
+}
+
+func (p *mockProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "k1"
+	s, err := tok.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Kullanıcının sağlayıcıda giriş yapmasını taklit eder: yetkilendirme isteğinden kod üretir
+func (p *mockProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" || !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("Yetkilendirme isteği eksik: %s", authURL)
+	}
+	code = "kod-" + q.Get("state")[:8]
+	p.mu.Lock()
+	p.codes[code] = mockGrant{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	p.mu.Unlock()
+	return code, q.Get("state")
+}
+
+type loginResult struct {
+	user  common.AuthUser
+	role  string
+	id    oidc.Identity
+	calls int
+}
+
+func newOIDCTestHandler(t *testing.T, p *mockProvider, res *loginResult) *oidc.Handler {
+	client, err := oidc.Discover(context.Background(), p.srv.Client(), oidc.Config{
+		IssuerURL: p.srv.URL, ClientID: "crm", ClientSecret: "gizli", RedirectURL: "http://localhost:8082/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, _ := oidc.ParseRoleMapping(oidc.DefaultRoleMapping)
+	return &oidc.Handler{
+		Client:       client,
+		StateKey:     []byte("test-anahtari"),
+		RoleMapping:  mapping,
+		PostLoginURL: "http://localhost/dashboard.html",
+		Provision: func(ctx context.Context, id oidc.Identity, role string) (common.AuthUser, error) {
+			res.calls++
+			res.id, res.role = id, role
+			res.user = common.AuthUser{ID: 7, Email: id.Email, Role: role, TenantID: 1}
+			return res.user, nil
+		},
+		IssueToken: func(u common.AuthUser) (string, error) {
+			return "crm-token-" + u.Role, nil
+		},
+	}
+}
+
+// /login -> sağlayıcı -> /callback akışını çalıştırır ve callback yanıtını döner
+func runOIDCLogin(t *testing.T, h *oidc.Handler, p *mockProvider, tamper func(q url.Values)) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.LoginHandler(rec, httptest.NewRequest("GET", "/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Giriş yönlendirmesi bekleniyordu: %d", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("State çerezi yanlış: %+v", cookies)
+	}
+	code, state := p.authorize(t, rec.Header().Get("Location"))
+
+	q := url.Values{"code": {code}, "state": {state}}
+	if tamper != nil {
+		tamper(q)
+	}
+	req := httptest.NewRequest("GET", "/callback?"+q.Encode(), nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	h.CallbackHandler(rec, req)
+	return rec
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	p := newMockProvider(t)
+	var res loginResult
+	h := newOIDCTestHandler(t, p, &res)
+
+	rec := runOIDCLogin(t, h, p, nil)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Callback yönlendirmesi bekleniyordu: %d %s", rec.Code, rec.Body)
+	}
+	if loc := rec.Header().Get("Location"); loc != "http://localhost/dashboard.html#token=crm-token-rep" {
+		t.Errorf("Giriş sonrası yönlendirme yanlış: %s", loc)
+	}
+	if res.role != common.RoleRep || res.id.Subject != "kc-123" || res.id.Email != "ayse@ornek.com" || !res.id.EmailVerified {
+		t.Errorf("Kimlik veya rol yanlış: %+v %s", res.id, res.role)
+	}
+	// Çerez tek kullanımlık: callback çerezi siler
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("State çerezi silinmedi: %+v", c)
+	}
+
+	// PostLoginURL yoksa token JSON döner
+	h.PostLoginURL = ""
+	rec = runOIDCLogin(t, h, p, nil)
+	var body map[string]string
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusOK || body["token"] != "crm-token-rep" {
+		t.Errorf("JSON token yanıtı bekleniyordu: %d %v", rec.Code, body)
+	}
+}
+
+func TestOIDCLoginRejectsBadState(t *testing.T) {
+	p := newMockProvider(t)
+	var res loginResult
+	h := newOIDCTestHandler(t, p, &res)
+
+	rec := runOIDCLogin(t, h, p, func(q url.Values) { q.Set("state", "baska") })
+	if rec.Code != http.StatusBadRequest || res.calls != 0 {
+		t.Errorf("Uyuşmayan state reddedilmeliydi: %d", rec.Code)
+	}
+	// Çerez olmadan
+	rec = httptest.NewRecorder()
+	h.CallbackHandler(rec, httptest.NewRequest("GET", "/callback?code=x&state=y", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Çerezsiz callback reddedilmeliydi: %d", rec.Code)
+	}
+	// Sağlayıcı hata döndürdüyse
+	rec = httptest.NewRecorder()
+	h.CallbackHandler(rec, httptest.NewRequest("GET", "/callback?error=access_denied", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Sağlayıcı hatası 401 olmalıydı: %d", rec.Code)
+	}
+}
+
+func TestOIDCLoginRejectsInvalidIDToken(t *testing.T) {
+	p := newMockProvider(t)
+	var res loginResult
+	h := newOIDCTestHandler(t, p, &res)
+
+	cases := map[string]func(c jwt.MapClaims){
+		"nonce":    func(c jwt.MapClaims) { c["nonce"] = "baska" },
+		"audience": func(c jwt.MapClaims) { c["aud"] = "baska-client" },
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "http://sahte" },
+		"süre":     func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"azp":      func(c jwt.MapClaims) { c["aud"] = []string{"crm", "account"}; c["azp"] = "account" },
+	}
+	for name, tamper := range cases {
+		p.claims = func(g mockGrant) jwt.MapClaims {
+			c := p.idClaims(g.nonce, "staff")
+			tamper(c)
+			return c
+		}
+		if rec := runOIDCLogin(t, h, p, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s bozuk ID token reddedilmeliydi: %d %s", name, rec.Code, rec.Body)
+		}
+	}
+	if res.calls != 0 {
+		t.Error("Geçersiz token ile kullanıcı oluşturulmamalıydı")
+	}
+
+	// Başka anahtarla imzalanmış token
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p.claims = func(g mockGrant) jwt.MapClaims { return p.idClaims(g.nonce, "staff") }
+	realKey := p.key
+	p.key = other
+	rec := runOIDCLogin(t, h, p, nil)
+	p.key = realKey
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Yanlış anahtarla imzalı token reddedilmeliydi: %d", rec.Code)
+	}
+}
+
+func TestOIDCLoginRoleMapping(t *testing.T) {
+	p := newMockProvider(t)
+	var res loginResult
+	h := newOIDCTestHandler(t, p, &res)
+
+	// CRM karşılığı olmayan rol
+	p.claims = func(g mockGrant) jwt.MapClaims { return p.idClaims(g.nonce, "customer") }
+	if rec := runOIDCLogin(t, h, p, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("CRM rolü olmayan kullanıcı 403 almalıydı: %d", rec.Code)
+	}
+
+	// Birden fazla rolde en yükseği
+	p.claims = func(g mockGrant) jwt.MapClaims { return p.idClaims(g.nonce, "staff", "admin", "customer") }
+	if rec := runOIDCLogin(t, h, p, nil); rec.Code != http.StatusFound || res.role != common.RoleAdmin {
+		t.Errorf("En yüksek rol admin olmalıydı: %d %s", rec.Code, res.role)
+	}
+
+	// ID token'da rol yoksa access token'dan okunur (Keycloak varsayılanı)
+	p.claims = func(g mockGrant) jwt.MapClaims { return p.idClaims(g.nonce) }
+	p.access = jwt.MapClaims{
+		"iss": p.srv.URL, "aud": "account", "sub": "kc-123",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(5 * time.Minute).Unix(),
+		"resource_access": map[string]interface{}{"crm": map[string]interface{}{"roles": []string{"manager"}}},
+	}
+	if rec := runOIDCLogin(t, h, p, nil); rec.Code != http.StatusFound || res.role != common.RoleManager {
+		t.Errorf("Access token'daki rol kullanılmalıydı: %d %s", rec.Code, res.role)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	p := newMockProvider(t)
+	_, err := oidc.Discover(context.Background(), p.srv.Client(), oidc.Config{IssuerURL: p.srv.URL + "/realms/baska", ClientID: "crm"})
+	if err == nil {
+		t.Fatal("Discovery hata vermeliydi")
+	}
+	if _, err := oidc.Discover(context.Background(), p.srv.Client(), oidc.Config{IssuerURL: p.srv.URL + "/", ClientID: "crm"}); err != nil {
+		t.Errorf("Sondaki / ile issuer kabul edilmeliydi: %v", err)
+	}
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// RFC 7636 Ek B örneği
+	if got := oidc.S256Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("S256 challenge yanlış: %s", got)
+	}
+	v, c, err := oidc.NewPKCE()
+	if err != nil || len(v) < 43 || oidc.S256Challenge(v) != c {
+		t.Errorf("PKCE üretimi yanlış: %q %q %v", v, c, err)
+	}
+}
+
+func TestOIDCRoleMappingParse(t *testing.T) {
+	m, err := oidc.ParseRoleMapping(" admin=admin , staff=rep,sales-lead=manager")
+	if err != nil || m["sales-lead"] != "manager" || m["staff"] != "rep" {
+		t.Errorf("Eşleme çözülemedi: %v %v", m, err)
+	}
+	for _, bad := range []string{"", "admin", "admin=root", "=rep"} {
+		if _, err := oidc.ParseRoleMapping(bad); err == nil {
+			t.Errorf("%q reddedilmeliydi", bad)
+		}
+	}
+	if _, err := oidc.MapRole([]string{"system"}, m); !errors.Is(err, oidc.ErrNoCRMRole) {
+		t.Errorf("Eşleşmeyen rol için ErrNoCRMRole bekleniyordu: %v", err)
+	}
+}
+
+func TestOIDCStateCookie(t *testing.T) {
+	key := []byte("anahtar")
+	now := time.Now()
+	st, err := oidc.NewLoginState(10*time.Minute, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := oidc.EncodeState(key, st)
+	got, err := oidc.DecodeState(key, v, now)
+	if err != nil || got != st {
+		t.Errorf("State geri çözülemedi: %+v %v", got, err)
+	}
+	if _, err := oidc.DecodeState([]byte("baska"), v, now); !errors.Is(err, oidc.ErrStateInvalid) {
+		t.Errorf("Başka anahtarla imzalı state reddedilmeliydi: %v", err)
+	}
+	payload, sig, _ := strings.Cut(v, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"x","n":"y","v":"z","e":9999999999}`))
+	if _, err := oidc.DecodeState(key, forged+"."+sig, now); !errors.Is(err, oidc.ErrStateInvalid) {
+		t.Errorf("Değiştirilmiş state reddedilmeliydi: %v", err)
+	}
+	if _, err := oidc.DecodeState(key, payload+"."+sig, now.Add(11*time.Minute)); !errors.Is(err, oidc.ErrStateExpired) {
+		t.Errorf("Süresi dolan state reddedilmeliydi: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/password"
+)
+
+func breachedPolicy(t *testing.T, list string) password.Policy {
+	p := password.Policy{MinLength: 10}
+	if err := p.LoadBreachedList(strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	sum := sha1.Sum([]byte("Kis-Bahce-2024"))
+	p := breachedPolicy(t, "# yorum\n\npassword123\r\n"+strings.ToUpper(hex.EncodeToString(sum[:]))+":42\n")
+
+	cases := []struct {
+		password string
+		want     error
+	}{
+		{"", password.ErrPasswordRequired},
+		{"kisa", password.ErrTooShort},
+		{strings.Repeat("a", 73), password.ErrTooLong},
+		{"ayse.yilmaz-2024!", password.ErrContainsEmail},
+		{"password123", password.ErrBreached},
+		{"PASSWORD123", password.ErrBreached},    // Düz listede küçük harfle de aranır
+		{"Kis-Bahce-2024", password.ErrBreached}, // HIBP biçimindeki özet
+	}
+	for _, c := range cases {
+		err := p.Validate(c.password, "Ayse.Yilmaz@ornek.com")
+		if !errors.Is(err, c.want) || !password.IsPolicyError(err) {
+			t.Errorf("%q için %v bekleniyordu, %v geldi", c.password, c.want, err)
+		}
+	}
+
+	for _, ok := range []string{"dogru-at-pil-zımba", "Güçlü şifre 2024", strings.Repeat("ş", 36)} {
+		if err := p.Validate(ok, "ayse@ornek.com"); err != nil {
+			t.Errorf("%q kabul edilmeliydi: %v", ok, err)
+		}
+	}
+	// Uzunluk karakter olarak sayılır: 10 Türkçe karakter 20 bayttır ama yeterlidir
+	if err := p.Validate("çğıöşüçğıö", "x@ornek.com"); err != nil {
+		t.Errorf("Çok baytlı karakterler tek sayılmalıydı: %v", err)
+	}
+	if p.Validate("çğıöşüçğı", "x@ornek.com") == nil {
+		t.Error("9 karakterlik şifre reddedilmeliydi")
+	}
+}
+
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "breached.txt")
+	os.WriteFile(list, []byte("hunter2hunter2\n"), 0o600)
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_BREACHED_LIST", list)
+	p, err := password.PolicyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := p.Info()
+	if info.MinLength != 12 || info.MaxBytes != 72 || !info.BreachedCheck {
+		t.Errorf("Politika bilgisi yanlış: %+v", info)
+	}
+	if !errors.Is(p.Validate("hunter2hunter2", "x@ornek.com"), password.ErrBreached) {
+		t.Error("Dosyadaki şifre reddedilmeliydi")
+	}
+
+	t.Setenv("PASSWORD_BREACHED_LIST", filepath.Join(dir, "yok.txt"))
+	if _, err := password.PolicyFromEnv(); err == nil {
+		t.Error("Olmayan liste dosyası hata vermeliydi")
+	}
+	t.Setenv("PASSWORD_BREACHED_LIST", "")
+	for _, bad := range []string{"0", "abc", "100"} {
+		t.Setenv("PASSWORD_MIN_LENGTH", bad)
+		if _, err := password.PolicyFromEnv(); err == nil {
+			t.Errorf("PASSWORD_MIN_LENGTH=%s reddedilmeliydi", bad)
+		}
+	}
+	t.Setenv("PASSWORD_MIN_LENGTH", "")
+	if p, err := password.PolicyFromEnv(); err != nil || p.Info().MinLength != password.DefaultMinLength || p.Info().BreachedCheck {
+		t.Errorf("Varsayılan politika bekleniyordu: %+v %v", p.Info(), err)
+	}
+}
+
+func TestPasswordResetLink(t *testing.T) {
+	if got := password.ResetLink("http://localhost:3000/reset-password.html#eski", "abc"); got != "http://localhost:3000/reset-password.html#token=abc" {
+		t.Errorf("Bağlantı yanlış: %s", got)
+	}
+	if password.HashToken("abc") != password.HashToken("abc") || password.HashToken("abc") == password.HashToken("abd") || len(password.HashToken("abc")) != 64 {
+		t.Error("Token özeti deterministik ve 64 karakter olmalıydı")
+	}
+}
+
+func TestPasswordHandlersValidation(t *testing.T) {
+	h := &password.Handler{Policy: password.Policy{MinLength: 10}}
+
+	// Token olmadan veritabanına gidilmeden reddedilir
+	rec := httptest.NewRecorder()
+	h.ResetHandler(rec, httptest.NewRequest("POST", "/api/password/reset", bytes.NewBufferString(`{"token":" ","new_password":"yeni-sifre-123"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Boş token 400 almalıydı: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ForgotHandler(rec, httptest.NewRequest("POST", "/api/password/forgot", bytes.NewBufferString(`{`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Geçersiz gövde 400 almalıydı: %d", rec.Code)
+	}
+
+	// Boş e-posta kayıtlı olmayan adresle aynı yanıtı alır
+	rec = httptest.NewRecorder()
+	h.ForgotHandler(rec, httptest.NewRequest("POST", "/api/password/forgot", bytes.NewBufferString(`{"email":""}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("202 bekleniyordu: %d", rec.Code)
+	}
+
+	// API anahtarıyla şifre değiştirilemez
+	req := httptest.NewRequest("POST", "/api/password/change", bytes.NewBufferString(`{"current_password":"a","new_password":"yeni-sifre-123"}`))
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: common.RoleAdmin, APIKeyID: 4}))
+	rec = httptest.NewRecorder()
+	h.ChangeHandler(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("API anahtarı 403 almalıydı: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetPolicyHandler(rec, httptest.NewRequest("GET", "/api/password/policy", nil))
+	var info password.PolicyInfo
+	json.NewDecoder(rec.Body).Decode(&info)
+	if rec.Code != http.StatusOK || info.MinLength != 10 || info.BreachedCheck {
+		t.Errorf("Politika yanıtı yanlış: %d %+v", rec.Code, info)
+	}
+}
@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/segment"
+)
+
+func TestValidateSegment(t *testing.T) {
+	valid := segment.Segment{
+		Name: "Riskli VIP'ler",
+		Filter: segment.SegmentFilter{Match: "all", Conditions: []segment.SegmentCondition{
+			{Type: segment.ConditionTag, TagID: 1},
+			{Type: segment.ConditionNoContactDays, Days: 30},
+			{Type: segment.ConditionDealStage, StageID: 2, Negate: true},
+			{Type: segment.ConditionCustomField, Field: "industry", Value: "retail"},
+		}},
+	}
+	if err := segment.ValidateSegment(valid); err != nil {
+		t.Errorf("Geçerli segment reddedildi: %v", err)
+	}
+
+	cond := func(c segment.SegmentCondition) segment.Segment {
+		return segment.Segment{Name: "Segment", Filter: segment.SegmentFilter{Conditions: []segment.SegmentCondition{c}}}
+	}
+	cases := map[string]segment.Segment{
+		"ad-kisa":        {Name: "S", Filter: valid.Filter},
+		"kosulsuz":       {Name: "Segment"},
+		"gecersiz-match": {Name: "Segment", Filter: segment.SegmentFilter{Match: "none", Conditions: valid.Filter.Conditions}},
+		"gecersiz-tip":   cond(segment.SegmentCondition{Type: "city"}),
+		"etiketsiz":      cond(segment.SegmentCondition{Type: segment.ConditionTag}),
+		"gun-sifir":      cond(segment.SegmentCondition{Type: segment.ConditionNoContactDays}),
+		"alan-degersiz":  cond(segment.SegmentCondition{Type: segment.ConditionCustomField, Field: "industry"}),
+		"asama-gecersiz": cond(segment.SegmentCondition{Type: segment.ConditionDealStage, StageID: -1}),
+	}
+	for name, s := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := segment.ValidateSegment(s); err == nil {
+				t.Errorf("Geçersiz segment kabul edildi: %+v", s)
+			}
+		})
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	for _, tag := range []segment.Tag{{Name: "V"}, {Name: "VIP", Color: "red"}, {Name: strings.Repeat("x", 51)}} {
+		if err := segment.ValidateTag(tag); err == nil {
+			t.Errorf("Geçersiz etiket kabul edildi: %+v", tag)
+		}
+	}
+	if err := segment.ValidateTag(segment.Tag{Name: "churn-risk", Color: "#FF0000"}); err != nil {
+		t.Errorf("Geçerli etiket reddedildi: %v", err)
+	}
+}
+
+// Boş müşteri listesi veritabanına gitmeden reddedilmeli (db nil)
+func TestBulkTagHandler_EmptyCustomerList(t *testing.T) {
+	h := &segment.Handler{DBPrimary: nil, DBReplica: nil}
+	req := httptest.NewRequest(http.MethodPost, "/api/tags/3/assign", strings.NewReader(`{"customer_ids":[]}`))
+	req = req.WithContext(common.ContextWithUser(req.Context(), common.AuthUser{ID: 1, Role: common.RoleRep}))
+	w := httptest.NewRecorder()
+
+	h.BulkTagHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Beklenen 400, gelen %d", w.Code)
+	}
+}
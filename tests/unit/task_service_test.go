@@ -0,0 +1,55 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Go-CRM/pkg/common"
+	"Go-CRM/pkg/task"
+)
+
+// Geçersiz görevler veritabanına gitmeden reddedilmeli (db nil)
+func TestCreateTask_Validation(t *testing.T) {
+	user := common.AuthUser{ID: 7, Role: common.RoleRep}
+	due := time.Now().Add(48 * time.Hour)
+	cases := map[string]task.Task{
+		"baslik-kisa":      {Title: "A", DueAt: due},
+		"bitis-yok":        {Title: "Ayşe'yi geri ara"},
+		"gecersiz-oncelik": {Title: "Ayşe'yi geri ara", DueAt: due, Priority: "asap"},
+		"gecersiz-durum":   {Title: "Ayşe'yi geri ara", DueAt: due, Status: "waiting"},
+		"negatif-musteri":  {Title: "Ayşe'yi geri ara", DueAt: due, CustomerID: -1},
+	}
+	for name, tk := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := task.CreateTask(nil, user, &tk); err == nil {
+				t.Errorf("Geçersiz görev kabul edildi: %+v", tk)
+			}
+		})
+	}
+}
+
+func TestGetTasks_InvalidStatus(t *testing.T) {
+	if _, err := task.GetTasks(nil, task.TaskListParams{Statuses: []string{"open", "archived"}}); err == nil {
+		t.Error("Geçersiz durum filtresi kabul edildi")
+	}
+}
+
+// Görevler tenant'a bağlı olduğundan listeleme ve tek görev oturum ister
+func TestTaskHandlers_RequireUser(t *testing.T) {
+	h := &task.Handler{DBPrimary: nil, DBReplica: nil}
+	for path, handler := range map[string]http.HandlerFunc{
+		"/api/tasks":   h.GetTasksHandler,
+		"/api/tasks/3": h.GetTaskHandler,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s için beklenen 401, gelen %d", path, w.Code)
+		}
+	}
+}